@@ -145,9 +145,10 @@ var Commands = []*cli.Command{
 				page    = ctx.Uint("page")
 				size    = ctx.Uint("size")
 				del     = ctx.Bool("delete")
-				headers = []string{"ID", "UID", "Secret", "Name", "Note", "CreatedAt"}
+				headers = []string{"ID", "UID", "Secret", "Name", "Note", "Size", "MaxSize", "Files", "MaxFiles", "CreatedAt"}
 				data    []string
 				apps    []models.App
+				quota   *models.Quota
 			)
 			if page < 1 || size < 1 {
 				return errors.New("page and size must be greater than 0")
@@ -160,12 +161,19 @@ var Commands = []*cli.Command{
 			table := tablewriter.NewWriter(os.Stdout)
 			table.SetHeader(headers)
 			for _, app := range apps {
+				if quota, err = app.Quota(connection); err != nil {
+					return err
+				}
 				data = []string{
 					strconv.FormatUint(app.ID, 10),
 					app.UID,
 					app.Secret,
 					app.Name,
 					*app.Note,
+					strconv.Itoa(quota.Size),
+					strconv.Itoa(quota.MaxSize),
+					strconv.Itoa(quota.Files),
+					strconv.Itoa(quota.MaxFiles),
 					app.CreatedAt.Format("2006-01-02 15:04:05"),
 				}
 				if del {
@@ -181,4 +189,41 @@ var Commands = []*cli.Command{
 			return nil
 		},
 	},
+	{
+		Name:      "app:quota",
+		Category:  category,
+		Usage:     "set the quota of an application",
+		UsageText: "app:quota [command options]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "uid",
+				Aliases: []string{"u"},
+				Usage:   "application uid",
+			},
+			&cli.IntFlag{
+				Name:    "max-size",
+				Aliases: []string{"s"},
+				Usage:   "max bytes of all files, zero means no limit",
+				Value:   0,
+			},
+			&cli.IntFlag{
+				Name:    "max-files",
+				Aliases: []string{"f"},
+				Usage:   "max count of files, zero means no limit",
+				Value:   0,
+			},
+		},
+		Before: before,
+		Action: func(ctx *cli.Context) error {
+			app, err := models.FindAppByUID(ctx.String("uid"), connection)
+			if err != nil {
+				return err
+			}
+			if err = app.SetQuota(ctx.Int("max-size"), ctx.Int("max-files"), connection); err != nil {
+				return err
+			}
+			logger.Infof("set quota of application %s: max size %d, max files %d", app.UID, app.MaxSize, app.MaxFiles)
+			return nil
+		},
+	},
 }
@@ -14,6 +14,8 @@ import (
 	cmdApp "github.com/bigfile/bigfile/artisan/app"
 	"github.com/bigfile/bigfile/artisan/http"
 	"github.com/bigfile/bigfile/artisan/migrate"
+	"github.com/bigfile/bigfile/artisan/retention"
+	"github.com/bigfile/bigfile/artisan/storage"
 	"github.com/bigfile/bigfile/config"
 	"github.com/bigfile/bigfile/log"
 	"github.com/mitchellh/go-homedir"
@@ -84,6 +86,8 @@ func main() {
 
 	commands = append(commands, cmdApp.Commands...)
 	commands = append(commands, http.Commands...)
+	commands = append(commands, storage.Commands...)
+	commands = append(commands, retention.Commands...)
 	app.Commands = commands
 
 	sort.Sort(cli.FlagsByName(app.Flags))
@@ -0,0 +1,223 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package retention
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/log"
+	"github.com/jinzhu/gorm"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var (
+	category   = "retention"
+	connection *gorm.DB
+	err        error
+	logger     = log.MustNewLogger(nil)
+	before     = func(context *cli.Context) error {
+		connection, err = databases.NewConnection(&config.DefaultConfig.Database)
+		return err
+	}
+)
+
+// Commands is used to manage the retention policies of histories
+var Commands = []*cli.Command{
+	{
+		Name:      "retention:set",
+		Category:  category,
+		Usage:     "create or update the retention policy of an application for a path prefix",
+		UsageText: "retention:set [command options]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "app",
+				Aliases: []string{"a"},
+				Usage:   "application uid",
+			},
+			&cli.StringFlag{
+				Name:    "prefix",
+				Aliases: []string{"p"},
+				Usage:   "the policy is applied to the files under the prefix",
+				Value:   "/",
+			},
+			&cli.IntFlag{
+				Name:    "keep-last",
+				Aliases: []string{"l"},
+				Usage:   "keep the latest n versions, zero means no limit",
+				Value:   0,
+			},
+			&cli.IntFlag{
+				Name:    "keep-days",
+				Aliases: []string{"d"},
+				Usage:   "keep the versions created in recent n days, zero means no limit",
+				Value:   0,
+			},
+		},
+		Before: before,
+		Action: func(ctx *cli.Context) error {
+			app, err := models.FindAppByUID(ctx.String("app"), connection)
+			if err != nil {
+				return err
+			}
+			policy, err := models.SetRetentionPolicy(app, ctx.String("prefix"), ctx.Int("keep-last"), ctx.Int("keep-days"), connection)
+			if err != nil {
+				return err
+			}
+			renderPolicies([]models.RetentionPolicy{*policy})
+			return nil
+		},
+	},
+	{
+		Name:      "retention:list",
+		Category:  category,
+		Usage:     "list the retention policies",
+		UsageText: "retention:list [command options]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "app",
+				Aliases: []string{"a"},
+				Usage:   "application uid, empty means all applications",
+			},
+		},
+		Before: before,
+		Action: func(ctx *cli.Context) error {
+			var app *models.App
+			if uid := ctx.String("app"); uid != "" {
+				if app, err = models.FindAppByUID(uid, connection); err != nil {
+					return err
+				}
+			}
+			policies, err := models.FindRetentionPolicies(app, connection)
+			if err != nil {
+				return err
+			}
+			renderPolicies(policies)
+			return nil
+		},
+	},
+	{
+		Name:      "retention:delete",
+		Category:  category,
+		Usage:     "delete a retention policy",
+		UsageText: "retention:delete [command options]",
+		Flags: []cli.Flag{
+			&cli.Uint64Flag{
+				Name:  "id",
+				Usage: "policy id",
+			},
+		},
+		Before: before,
+		Action: func(ctx *cli.Context) error {
+			var id = ctx.Uint64("id")
+			if id == 0 {
+				return errors.New("id is empty")
+			}
+			if err := models.DeleteRetentionPolicy(id, connection); err != nil {
+				return err
+			}
+			logger.Infof("delete retention policy: %d", id)
+			return nil
+		},
+	},
+	{
+		Name:      "retention:apply",
+		Category:  category,
+		Usage:     "delete the histories that are expired by retention policies, run storage:gc to reclaim their objects",
+		UsageText: "retention:apply [command options]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "dry-run",
+				Aliases: []string{"d"},
+				Usage:   "only list the expired histories, nothing will be deleted",
+				Value:   false,
+			},
+			&cli.DurationFlag{
+				Name:    "interval",
+				Aliases: []string{"i"},
+				Usage:   "run periodically with the interval until interrupted, zero means run only once",
+				Value:   0,
+			},
+		},
+		Before: before,
+		Action: func(ctx *cli.Context) error {
+			var (
+				dryRun   = ctx.Bool("dry-run")
+				interval = ctx.Duration("interval")
+				quit     = make(chan os.Signal, 1)
+			)
+
+			if err := applyPolicies(dryRun); err != nil || interval <= 0 {
+				return err
+			}
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+			for {
+				select {
+				case <-ticker.C:
+					if err := applyPolicies(dryRun); err != nil {
+						logger.Error(err)
+					}
+				case <-quit:
+					return nil
+				}
+			}
+		},
+	},
+}
+
+func renderPolicies(policies []models.RetentionPolicy) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "App", "PathPrefix", "KeepLast", "KeepDays", "UpdatedAt"})
+	for _, policy := range policies {
+		table.Append([]string{
+			strconv.FormatUint(policy.ID, 10),
+			policy.App.UID,
+			policy.PathPrefix,
+			strconv.Itoa(policy.KeepLast),
+			strconv.Itoa(policy.KeepDays),
+			policy.UpdatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	table.Render()
+}
+
+func applyPolicies(dryRun bool) error {
+	histories, err := models.ApplyRetentionPolicies(dryRun, connection)
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "FileID", "ObjectID", "Path", "CreatedAt"})
+	for _, history := range histories {
+		table.Append([]string{
+			strconv.FormatUint(history.ID, 10),
+			strconv.FormatUint(history.FileID, 10),
+			strconv.FormatUint(history.ObjectID, 10),
+			history.Path,
+			history.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	table.Render()
+
+	if dryRun {
+		logger.Infof("dry run, %d histories are expired", len(histories))
+	} else {
+		logger.Infof("%d histories are deleted", len(histories))
+	}
+
+	return nil
+}
@@ -0,0 +1,279 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/log"
+	"github.com/jinzhu/gorm"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var (
+	category   = "storage"
+	connection *gorm.DB
+	err        error
+	logger     = log.MustNewLogger(nil)
+	before     = func(context *cli.Context) error {
+		connection, err = databases.NewConnection(&config.DefaultConfig.Database)
+		return err
+	}
+)
+
+// Commands is used to maintain the underlying storage
+var Commands = []*cli.Command{
+	{
+		Name:      "storage:gc",
+		Category:  category,
+		Usage:     "collect the objects and chunks that are no longer referenced, and the expired upload sessions",
+		UsageText: "storage:gc [command options]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "dry-run",
+				Aliases: []string{"d"},
+				Usage:   "only list the garbage, nothing will be deleted",
+				Value:   false,
+			},
+			&cli.DurationFlag{
+				Name:    "grace",
+				Aliases: []string{"g"},
+				Usage:   "objects and chunks updated within grace are kept, they may belong to uploads in progress",
+				Value:   time.Hour,
+			},
+			&cli.DurationFlag{
+				Name:    "interval",
+				Aliases: []string{"i"},
+				Usage:   "run periodically with the interval until interrupted, zero means run only once",
+				Value:   0,
+			},
+		},
+		Before: before,
+		Action: func(ctx *cli.Context) error {
+			var (
+				dryRun   = ctx.Bool("dry-run")
+				grace    = ctx.Duration("grace")
+				interval = ctx.Duration("interval")
+				quit     = make(chan os.Signal, 1)
+			)
+
+			if err := collectGarbage(grace, dryRun); err != nil || interval <= 0 {
+				return err
+			}
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+			for {
+				select {
+				case <-ticker.C:
+					if err := collectGarbage(grace, dryRun); err != nil {
+						logger.Error(err)
+					}
+				case <-quit:
+					return nil
+				}
+			}
+		},
+	},
+	{
+		Name:      "storage:rekey",
+		Category:  category,
+		Usage:     "encrypt the chunks that aren't encrypted by the current key of keyring again",
+		UsageText: "storage:rekey [command options]",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:    "batch",
+				Aliases: []string{"b"},
+				Usage:   "the count of chunks that are loaded at once",
+				Value:   100,
+			},
+			&cli.DurationFlag{
+				Name:    "pause",
+				Aliases: []string{"p"},
+				Usage:   "pause between batches, so that the store isn't too busy",
+				Value:   0,
+			},
+		},
+		Before: before,
+		Action: func(ctx *cli.Context) error {
+			var (
+				batch = ctx.Int("batch")
+				pause = ctx.Duration("pause")
+				total int
+				quit  = make(chan os.Signal, 1)
+			)
+
+			if batch <= 0 {
+				batch = 100
+			}
+
+			signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+			for {
+				count, err := models.RekeyChunks(batch, nil, connection)
+				total += count
+				if err != nil {
+					return err
+				}
+				logger.Infof("%d chunks are rekeyed", total)
+				if count < batch {
+					return nil
+				}
+				select {
+				case <-time.After(pause):
+				case <-quit:
+					return nil
+				}
+			}
+		},
+	},
+	{
+		Name:      "storage:scrub",
+		Category:  category,
+		Usage:     "verify chunks and objects against the content in store, quarantine the corrupted objects",
+		UsageText: "storage:scrub [command options]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "mark-files",
+				Aliases: []string{"m"},
+				Usage:   "mark the files of corrupted objects as quarantined too",
+				Value:   false,
+			},
+			&cli.DurationFlag{
+				Name:    "interval",
+				Aliases: []string{"i"},
+				Usage:   "run periodically with the interval until interrupted, zero means run only once",
+				Value:   0,
+			},
+		},
+		Before: before,
+		Action: func(ctx *cli.Context) error {
+			var (
+				markFiles = ctx.Bool("mark-files")
+				interval  = ctx.Duration("interval")
+				quit      = make(chan os.Signal, 1)
+			)
+
+			if err := scrub(markFiles); err != nil || interval <= 0 {
+				return err
+			}
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+			for {
+				select {
+				case <-ticker.C:
+					if err := scrub(markFiles); err != nil {
+						logger.Error(err)
+					}
+				case <-quit:
+					return nil
+				}
+			}
+		},
+	},
+	{
+		Name:      "fsck",
+		Category:  category,
+		Usage:     "check the consistency of metadata, such as directory sizes, object chunks and file names",
+		UsageText: "fsck [command options]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "repair",
+				Aliases: []string{"r"},
+				Usage:   "repair the inconsistencies that can be repaired",
+				Value:   false,
+			},
+		},
+		Before: before,
+		Action: func(ctx *cli.Context) error {
+			var repair = ctx.Bool("repair")
+			issues, err := models.Fsck(repair, nil, connection)
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Type", "ID", "Message", "Repaired"})
+			for _, issue := range issues {
+				table.Append([]string{issue.Type, strconv.FormatUint(issue.ID, 10), issue.Message, strconv.FormatBool(issue.Repaired)})
+			}
+			table.Render()
+
+			if err != nil {
+				return err
+			}
+			logger.Infof("%d inconsistencies are found", len(issues))
+			return nil
+		},
+	},
+}
+
+func collectGarbage(grace time.Duration, dryRun bool) error {
+	result, err := models.CollectGarbage(grace, dryRun, nil, connection)
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Type", "ID", "Hash", "Size"})
+	for _, object := range result.Objects {
+		table.Append([]string{"object", strconv.FormatUint(object.ID, 10), object.Hash, strconv.FormatInt(object.Size, 10)})
+	}
+	for _, chunk := range result.Chunks {
+		table.Append([]string{"chunk", strconv.FormatUint(chunk.ID, 10), chunk.Hash, strconv.Itoa(chunk.Size)})
+	}
+	table.Render()
+
+	if dryRun {
+		logger.Infof("dry run, %d objects and %d chunks can be collected, %d bytes",
+			len(result.Objects), len(result.Chunks), result.Size())
+	} else {
+		logger.Infof("%d objects and %d chunks are collected, %d bytes are freed",
+			len(result.Objects), len(result.Chunks), result.Size())
+	}
+
+	return nil
+}
+
+func scrub(markFiles bool) error {
+	result, err := models.Scrub(markFiles, nil, connection)
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Type", "ID", "Hash", "Size", "Error"})
+	for _, failure := range result.ChunkFailures {
+		chunk := failure.Chunk
+		table.Append([]string{"chunk", strconv.FormatUint(chunk.ID, 10), chunk.Hash, strconv.Itoa(chunk.Size), failure.Err.Error()})
+	}
+	for _, failure := range result.ObjectFailures {
+		object := failure.Object
+		table.Append([]string{"object", strconv.FormatUint(object.ID, 10), object.Hash, strconv.FormatInt(object.Size, 10), failure.Err.Error()})
+	}
+	table.Render()
+
+	for _, failure := range result.ChunkErrors {
+		logger.Warningf("chunk %d can't be verified: %s", failure.Chunk.ID, failure.Err)
+	}
+	for _, failure := range result.ObjectErrors {
+		logger.Warningf("object %d can't be verified: %s", failure.Object.ID, failure.Err)
+	}
+	for _, object := range result.Released {
+		logger.Infof("object %d passes verification, it's released from quarantine", object.ID)
+	}
+	logger.Infof("%d chunks and %d objects are verified, %d chunks are corrupted, %d objects are quarantined, "+
+		"%d chunks and %d objects can't be verified", result.Chunks, result.Objects, len(result.ChunkFailures),
+		len(result.ObjectFailures), len(result.ChunkErrors), len(result.ObjectErrors))
+
+	return nil
+}
@@ -7,4 +7,52 @@ package config
 // Chunk represent config for chunk
 type Chunk struct {
 	RootPath string `yaml:"rootPath,omitempty"`
+
+	// Store represent where the content of chunks is saved, it's one of
+	// local, memory and s3. default: local
+	Store string `yaml:"store,omitempty"`
+
+	// S3 is used only when Store is s3
+	S3 ChunkS3 `yaml:"s3,omitempty"`
+
+	// Chunker represent how content is split into chunks, it's one of fixed
+	// and cdc. fixed split content into pieces of the same size, cdc split
+	// content at the positions decided by content itself, so inserting or
+	// deleting some bytes only changes the chunks around. default: fixed
+	Chunker string `yaml:"chunker,omitempty"`
+
+	// CDC is used only when Chunker is cdc
+	CDC ChunkCDC `yaml:"cdc,omitempty"`
+
+	// Compression represent the codec that new chunks are compressed by, it's
+	// one of none and gzip. A chunk is saved raw if it can't be compressed
+	// smaller. default: none
+	Compression string `yaml:"compression,omitempty"`
+
+	// Keyring is the path of keyring file, chunks are encrypted by AES-GCM with
+	// the current key of their apps. An app uses the keys in the apps section of
+	// keyring, or the keys derived from the shared keys. Empty represent that
+	// encryption is disabled. When encryption is enabled, content is only
+	// deduplicated in the same app, because apps have different keys.
+	Keyring string `yaml:"keyring,omitempty"`
+}
+
+// ChunkS3 represent config for s3 compatible chunk store
+type ChunkS3 struct {
+	// Endpoint represent the address of service, example: https://s3.amazonaws.com
+	Endpoint        string `yaml:"endpoint,omitempty"`
+	Region          string `yaml:"region,omitempty"`
+	Bucket          string `yaml:"bucket,omitempty"`
+	AccessKeyID     string `yaml:"accessKeyId,omitempty"`
+	SecretAccessKey string `yaml:"secretAccessKey,omitempty"`
+	// Prefix will be prepended to the key of every chunk
+	Prefix string `yaml:"prefix,omitempty"`
+}
+
+// ChunkCDC represent config for content-defined chunking, sizes are in bytes.
+// MaxSize can't be greater than the size limit of chunk.
+type ChunkCDC struct {
+	MinSize int `yaml:"minSize,omitempty"`
+	AvgSize int `yaml:"avgSize,omitempty"`
+	MaxSize int `yaml:"maxSize,omitempty"`
 }
@@ -52,7 +52,21 @@ http:
   corsAllowAllOrigins: false
   corsMaxAge: 3600
 chunk:
-  rootPath: storage/chunks`
+  rootPath: storage/chunks
+  store: s3
+  s3:
+    endpoint: http://127.0.0.1:9000
+    region: us-east-1
+    bucket: bigfile
+    accessKeyId: access
+    secretAccessKey: secret
+  chunker: cdc
+  cdc:
+    minSize: 131072
+    avgSize: 262144
+    maxSize: 524288
+  compression: gzip
+  keyring: /etc/bigfile/keyring.yaml`
 
 func assertConfigurator(t *testing.T, configurator *Configurator) {
 	confirm := assert.New(t)
@@ -91,6 +105,15 @@ func assertConfigurator(t *testing.T, configurator *Configurator) {
 	confirm.Equal(int64(3600), configurator.HTTP.CORSMaxAge)
 
 	confirm.Equal("storage/chunks", configurator.Chunk.RootPath)
+	confirm.Equal("s3", configurator.Chunk.Store)
+	confirm.Equal("bigfile", configurator.Chunk.S3.Bucket)
+	confirm.Equal("access", configurator.Chunk.S3.AccessKeyID)
+	confirm.Equal("cdc", configurator.Chunk.Chunker)
+	confirm.Equal(131072, configurator.Chunk.CDC.MinSize)
+	confirm.Equal(262144, configurator.Chunk.CDC.AvgSize)
+	confirm.Equal(524288, configurator.Chunk.CDC.MaxSize)
+	confirm.Equal("gzip", configurator.Chunk.Compression)
+	confirm.Equal("/etc/bigfile/keyring.yaml", configurator.Chunk.Keyring)
 }
 
 func TestParseConfigFile(t *testing.T) {
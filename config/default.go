@@ -51,6 +51,14 @@ var (
 		},
 		Chunk{
 			RootPath: "storage/chunks",
+			Store:    "local",
+			Chunker:  "fixed",
+			CDC: ChunkCDC{
+				MinSize: 256 << 10,
+				AvgSize: 512 << 10,
+				MaxSize: 1 << 20,
+			},
+			Compression: "none",
 		},
 	}
 )
@@ -0,0 +1,45 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&UpdateFilesTable20190826103015{})
+}
+
+// UpdateFilesTable20190826103015 represent some database operate
+type UpdateFilesTable20190826103015 struct{}
+
+// Name represent operate name, it's unique
+func (c *UpdateFilesTable20190826103015) Name() string {
+	return "update_files_table_20190826103015"
+}
+
+// Up is executed in upgrading
+func (c *UpdateFilesTable20190826103015) Up(db *gorm.DB) error {
+	// deleted files still occupy their names, deletedId makes them
+	// distinct from the files that have the same path in the future
+	return db.Exec(`
+	alter table files
+		add column deletedId BIGINT(20) UNSIGNED NOT NULL DEFAULT 0 after deletedAt,
+		drop index appId_pid_name_unique,
+		add unique appId_pid_name_deletedId_unique (appId, pid, name, deletedId)
+	`).Error
+}
+
+// Down is executed in downgrading
+func (c *UpdateFilesTable20190826103015) Down(db *gorm.DB) error {
+	// execute when rollback database
+	return db.Exec(`
+	alter table files
+		drop index appId_pid_name_deletedId_unique,
+		add unique appId_pid_name_unique (appId, pid, name),
+		drop column deletedId
+	`).Error
+}
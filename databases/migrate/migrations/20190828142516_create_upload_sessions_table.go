@@ -0,0 +1,52 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&CreateUploadSessionsTable20190828142516{})
+}
+
+// CreateUploadSessionsTable20190828142516 represent some database operate
+type CreateUploadSessionsTable20190828142516 struct{}
+
+// Name represent operate name, it's unique
+func (c *CreateUploadSessionsTable20190828142516) Name() string {
+	return "create_upload_sessions_table_20190828142516"
+}
+
+// Up is executed in upgrading
+func (c *CreateUploadSessionsTable20190828142516) Up(db *gorm.DB) error {
+	// execute when upgrade database
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS upload_sessions (
+		  id BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT,
+		  uid CHAR(32) NOT NULL,
+		  appId BIGINT(20) UNSIGNED NOT NULL DEFAULT 0,
+		  path VARCHAR(1000) NOT NULL DEFAULT '',
+		  size BIGINT(20) UNSIGNED NOT NULL DEFAULT 0,
+		  offset BIGINT(20) UNSIGNED NOT NULL DEFAULT 0,
+		  hashState text NULL,
+		  hidden TINYINT UNSIGNED NOT NULL DEFAULT 0,
+		  expiredAt timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+		  createdAt timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+		  updatedAt timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+		  PRIMARY KEY (id),
+		  KEY appId_idx (appId),
+		  KEY expiredAt_idx (expiredAt),
+		  UNIQUE INDEX uid_UNIQUE (uid ASC))
+		ENGINE = InnoDB
+	`).Error
+}
+
+// Down is executed in downgrading
+func (c *CreateUploadSessionsTable20190828142516) Down(db *gorm.DB) error {
+	// execute when rollback database
+	return db.DropTableIfExists("upload_sessions").Error
+}
@@ -0,0 +1,47 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&CreateUploadSessionChunkTable20190828142538{})
+}
+
+// CreateUploadSessionChunkTable20190828142538 represent some database operate
+type CreateUploadSessionChunkTable20190828142538 struct{}
+
+// Name represent operate name, it's unique
+func (c *CreateUploadSessionChunkTable20190828142538) Name() string {
+	return "create_upload_session_chunk_table_20190828142538"
+}
+
+// Up is executed in upgrading
+func (c *CreateUploadSessionChunkTable20190828142538) Up(db *gorm.DB) error {
+	// execute when upgrade database
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS upload_session_chunk (
+		  id BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT,
+		  sessionId BIGINT(20) UNSIGNED NOT NULL,
+		  chunkId BIGINT(20) UNSIGNED NOT NULL,
+		  hashState text NULL,
+		  number BIGINT(20) NOT NULL,
+		  createdAt timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+		  updatedAt timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+		  PRIMARY KEY (id),
+		  UNIQUE INDEX session_chunk_no_uq (sessionId, number),
+		  KEY chunkId_idx (chunkId)
+		)ENGINE = InnoDB
+	`).Error
+}
+
+// Down is executed in downgrading
+func (c *CreateUploadSessionChunkTable20190828142538) Down(db *gorm.DB) error {
+	// execute when rollback database
+	return db.DropTableIfExists("upload_session_chunk").Error
+}
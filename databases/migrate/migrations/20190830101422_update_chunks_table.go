@@ -0,0 +1,35 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&UpdateChunksTable20190830101422{})
+}
+
+// UpdateChunksTable20190830101422 represent some database operate
+type UpdateChunksTable20190830101422 struct{}
+
+// Name represent operate name, it's unique
+func (c *UpdateChunksTable20190830101422) Name() string {
+	return "update_chunks_table_20190830101422"
+}
+
+// Up is executed in upgrading
+func (c *UpdateChunksTable20190830101422) Up(db *gorm.DB) error {
+	// codec represent how the content of chunk is compressed in store,
+	// size is still the size of uncompressed content
+	return db.Exec("alter table chunks add column codec VARCHAR(16) NOT NULL DEFAULT 'none' after `hash`").Error
+}
+
+// Down is executed in downgrading
+func (c *UpdateChunksTable20190830101422) Down(db *gorm.DB) error {
+	// execute when rollback database
+	return db.Exec(`alter table chunks drop column codec`).Error
+}
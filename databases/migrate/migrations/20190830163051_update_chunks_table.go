@@ -0,0 +1,44 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&UpdateChunksTable20190830163051{})
+}
+
+// UpdateChunksTable20190830163051 represent some database operate
+type UpdateChunksTable20190830163051 struct{}
+
+// Name represent operate name, it's unique
+func (c *UpdateChunksTable20190830163051) Name() string {
+	return "update_chunks_table_20190830163051"
+}
+
+// Up is executed in upgrading
+func (c *UpdateChunksTable20190830163051) Up(db *gorm.DB) error {
+	// keyId represent the key that content is encrypted by, 0 represent
+	// plaintext, chunks that aren't encrypted by the current key are found
+	// by the index when keys are rotated
+	return db.Exec(`
+	alter table chunks
+		add column keyId INT UNSIGNED NOT NULL DEFAULT 0 after codec,
+		add index keyId (keyId)
+	`).Error
+}
+
+// Down is executed in downgrading
+func (c *UpdateChunksTable20190830163051) Down(db *gorm.DB) error {
+	// execute when rollback database
+	return db.Exec(`
+	alter table chunks
+		drop index keyId,
+		drop column keyId
+	`).Error
+}
@@ -0,0 +1,46 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&CreateRetentionPoliciesTable20190902103518{})
+}
+
+// CreateRetentionPoliciesTable20190902103518 represent some database operate
+type CreateRetentionPoliciesTable20190902103518 struct{}
+
+// Name represent operate name, it's unique
+func (c *CreateRetentionPoliciesTable20190902103518) Name() string {
+	return "create_retention_policies_table_20190902103518"
+}
+
+// Up is executed in upgrading
+func (c *CreateRetentionPoliciesTable20190902103518) Up(db *gorm.DB) error {
+	// execute when upgrade database
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS retention_policies (
+		  id BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT,
+		  appId BIGINT(20) UNSIGNED NOT NULL DEFAULT 0,
+		  pathPrefix VARCHAR(1000) NOT NULL DEFAULT '/',
+		  keepLast INT UNSIGNED NOT NULL DEFAULT 0,
+		  keepDays INT UNSIGNED NOT NULL DEFAULT 0,
+		  createdAt timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+		  updatedAt timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+		  PRIMARY KEY (id),
+		  KEY appId_idx (appId))
+		ENGINE = InnoDB
+	`).Error
+}
+
+// Down is executed in downgrading
+func (c *CreateRetentionPoliciesTable20190902103518) Down(db *gorm.DB) error {
+	// execute when rollback database
+	return db.DropTableIfExists("retention_policies").Error
+}
@@ -0,0 +1,55 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&UpdateObjectsTable20190903152740{})
+}
+
+// UpdateObjectsTable20190903152740 represent some database operate
+type UpdateObjectsTable20190903152740 struct{}
+
+// Name represent operate name, it's unique
+func (c *UpdateObjectsTable20190903152740) Name() string {
+	return "update_objects_table_20190903152740"
+}
+
+// Up is executed in upgrading
+func (c *UpdateObjectsTable20190903152740) Up(db *gorm.DB) error {
+	// quarantined is set by scrubber when the content of object is found
+	// corrupted, the files of the object are marked optionally
+	if err := db.Exec(`
+	alter table objects
+		add column quarantined TINYINT UNSIGNED NOT NULL DEFAULT 0,
+		add index quarantined (quarantined)
+	`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`
+	alter table files
+		add column quarantined TINYINT UNSIGNED NOT NULL DEFAULT 0 after hidden
+	`).Error
+}
+
+// Down is executed in downgrading
+func (c *UpdateObjectsTable20190903152740) Down(db *gorm.DB) error {
+	// execute when rollback database
+	if err := db.Exec(`
+	alter table files
+		drop column quarantined
+	`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`
+	alter table objects
+		drop index quarantined,
+		drop column quarantined
+	`).Error
+}
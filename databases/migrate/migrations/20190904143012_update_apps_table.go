@@ -0,0 +1,57 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&UpdateAppsTable20190904143012{})
+}
+
+// UpdateAppsTable20190904143012 represent some database operate
+type UpdateAppsTable20190904143012 struct{}
+
+// Name represent operate name, it's unique
+func (c *UpdateAppsTable20190904143012) Name() string {
+	return "update_apps_table_20190904143012"
+}
+
+// Up is executed in upgrading
+func (c *UpdateAppsTable20190904143012) Up(db *gorm.DB) error {
+	// maxSize and maxFiles limit the bytes and the count of files, zero means
+	// no limit. The quota of token is applied to its path.
+	if err := db.Exec(`
+	alter table apps
+		add column maxSize BIGINT(20) UNSIGNED NOT NULL DEFAULT 0 after note,
+		add column maxFiles INT(10) UNSIGNED NOT NULL DEFAULT 0 after maxSize
+	`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`
+	alter table tokens
+		add column maxSize BIGINT(20) UNSIGNED NOT NULL DEFAULT 0 after expiredAt,
+		add column maxFiles INT(10) UNSIGNED NOT NULL DEFAULT 0 after maxSize
+	`).Error
+}
+
+// Down is executed in downgrading
+func (c *UpdateAppsTable20190904143012) Down(db *gorm.DB) error {
+	// execute when rollback database
+	if err := db.Exec(`
+	alter table tokens
+		drop column maxFiles,
+		drop column maxSize
+	`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`
+	alter table apps
+		drop column maxFiles,
+		drop column maxSize
+	`).Error
+}
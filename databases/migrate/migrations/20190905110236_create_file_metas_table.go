@@ -0,0 +1,45 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&CreateFileMetasTable20190905110236{})
+}
+
+// CreateFileMetasTable20190905110236 represent some database operate
+type CreateFileMetasTable20190905110236 struct{}
+
+// Name represent operate name, it's unique
+func (c *CreateFileMetasTable20190905110236) Name() string {
+	return "create_file_metas_table_20190905110236"
+}
+
+// Up is executed in upgrading
+func (c *CreateFileMetasTable20190905110236) Up(db *gorm.DB) error {
+	// execute when upgrade database
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS file_metas (
+		  id BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT,
+		  fileId BIGINT(20) UNSIGNED NOT NULL DEFAULT 0,
+		  name VARCHAR(64) NOT NULL DEFAULT '',
+		  value VARCHAR(1024) NOT NULL DEFAULT '',
+		  createdAt timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+		  updatedAt timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+		  PRIMARY KEY (id),
+		  UNIQUE KEY fileId_name_unique (fileId, name))
+		ENGINE = InnoDB
+	`).Error
+}
+
+// Down is executed in downgrading
+func (c *CreateFileMetasTable20190905110236) Down(db *gorm.DB) error {
+	// execute when rollback database
+	return db.DropTableIfExists("file_metas").Error
+}
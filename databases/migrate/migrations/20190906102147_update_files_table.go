@@ -0,0 +1,60 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&UpdateFilesTable20190906102147{})
+}
+
+// UpdateFilesTable20190906102147 represent some database operate
+type UpdateFilesTable20190906102147 struct{}
+
+// Name represent operate name, it's unique
+func (c *UpdateFilesTable20190906102147) Name() string {
+	return "update_files_table_20190906102147"
+}
+
+// Up is executed in upgrading
+func (c *UpdateFilesTable20190906102147) Up(db *gorm.DB) error {
+	// path is the complete path of file, so that a file can be found by its path
+	// in one query. The path of root directory is empty.
+	if err := db.Exec(`
+	alter table files
+		add column path VARCHAR(2048) NOT NULL DEFAULT '' after ext,
+		add index appId_path_idx (appId, path(255))
+	`).Error; err != nil {
+		return err
+	}
+	// the paths are filled level by level from the root directories, deleted files
+	// are included
+	for {
+		result := db.Exec(`
+		update files c join files p on p.id = c.pid
+			set c.path = concat(p.path, '/', c.name)
+			where c.pid > 0 and c.path = '' and (p.pid = 0 or p.path <> '')
+		`)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+	}
+}
+
+// Down is executed in downgrading
+func (c *UpdateFilesTable20190906102147) Down(db *gorm.DB) error {
+	// execute when rollback database
+	return db.Exec(`
+	alter table files
+		drop index appId_path_idx,
+		drop column path
+	`).Error
+}
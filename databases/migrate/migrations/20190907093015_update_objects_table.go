@@ -0,0 +1,34 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&UpdateObjectsTable20190907093015{})
+}
+
+// UpdateObjectsTable20190907093015 represent some database operate
+type UpdateObjectsTable20190907093015 struct{}
+
+// Name represent operate name, it's unique
+func (c *UpdateObjectsTable20190907093015) Name() string {
+	return "update_objects_table_20190907093015"
+}
+
+// Up is executed in upgrading
+func (c *UpdateObjectsTable20190907093015) Up(db *gorm.DB) error {
+	// INT UNSIGNED overflows when the object is larger than 4GiB
+	return db.Exec(`alter table objects modify column size BIGINT(20) UNSIGNED NOT NULL`).Error
+}
+
+// Down is executed in downgrading
+func (c *UpdateObjectsTable20190907093015) Down(db *gorm.DB) error {
+	// execute when rollback database
+	return db.Exec(`alter table objects modify column size INT UNSIGNED NOT NULL`).Error
+}
@@ -0,0 +1,41 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&UpdateChunksTable20190908101530{})
+}
+
+// UpdateChunksTable20190908101530 represent some database operate
+type UpdateChunksTable20190908101530 struct{}
+
+// Name represent operate name, it's unique
+func (c *UpdateChunksTable20190908101530) Name() string {
+	return "update_chunks_table_20190908101530"
+}
+
+// Up is executed in upgrading
+func (c *UpdateChunksTable20190908101530) Up(db *gorm.DB) error {
+	// quarantined is set by scrubber when the content of chunk is found
+	// corrupted, the chunk isn't reused by dedup until it's repaired
+	return db.Exec(`
+	alter table chunks
+		add column quarantined TINYINT UNSIGNED NOT NULL DEFAULT 0
+	`).Error
+}
+
+// Down is executed in downgrading
+func (c *UpdateChunksTable20190908101530) Down(db *gorm.DB) error {
+	// execute when rollback database
+	return db.Exec(`
+	alter table chunks
+		drop column quarantined
+	`).Error
+}
@@ -0,0 +1,45 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&UpdateChunksTable20190909101012{})
+}
+
+// UpdateChunksTable20190909101012 represent some database operate
+type UpdateChunksTable20190909101012 struct{}
+
+// Name represent operate name, it's unique
+func (c *UpdateChunksTable20190909101012) Name() string {
+	return "update_chunks_table_20190909101012"
+}
+
+// Up is executed in upgrading
+func (c *UpdateChunksTable20190909101012) Up(db *gorm.DB) error {
+	// the chunk is deduplicated in its app when encryption is enabled, because
+	// the content is encrypted by the keys of app, 0 represent the shared chunks
+	return db.Exec(`
+	alter table chunks
+		add column appId BIGINT(20) UNSIGNED NOT NULL DEFAULT 0 after id,
+		drop index hash_UNIQUE,
+		add unique index appId_hash_UNIQUE (appId ASC, hash ASC)
+	`).Error
+}
+
+// Down is executed in downgrading
+func (c *UpdateChunksTable20190909101012) Down(db *gorm.DB) error {
+	// execute when rollback database
+	return db.Exec(`
+	alter table chunks
+		drop index appId_hash_UNIQUE,
+		drop column appId,
+		add unique index hash_UNIQUE (hash ASC)
+	`).Error
+}
@@ -0,0 +1,45 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&UpdateObjectsTable20190909101538{})
+}
+
+// UpdateObjectsTable20190909101538 represent some database operate
+type UpdateObjectsTable20190909101538 struct{}
+
+// Name represent operate name, it's unique
+func (c *UpdateObjectsTable20190909101538) Name() string {
+	return "update_objects_table_20190909101538"
+}
+
+// Up is executed in upgrading
+func (c *UpdateObjectsTable20190909101538) Up(db *gorm.DB) error {
+	// the object is deduplicated in its app when encryption is enabled, because
+	// the content is encrypted by the keys of app, 0 represent the shared objects
+	return db.Exec(`
+	alter table objects
+		add column appId BIGINT(20) UNSIGNED NOT NULL DEFAULT 0 after id,
+		drop index hash_UNIQUE,
+		add unique index appId_hash_UNIQUE (appId ASC, hash ASC)
+	`).Error
+}
+
+// Down is executed in downgrading
+func (c *UpdateObjectsTable20190909101538) Down(db *gorm.DB) error {
+	// execute when rollback database
+	return db.Exec(`
+	alter table objects
+		drop index appId_hash_UNIQUE,
+		drop column appId,
+		add unique index hash_UNIQUE (hash ASC)
+	`).Error
+}
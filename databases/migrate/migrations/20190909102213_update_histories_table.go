@@ -0,0 +1,35 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"github.com/bigfile/bigfile/databases/migrate"
+	"github.com/jinzhu/gorm"
+)
+
+func init() {
+	migrate.DefaultMC.Register(&UpdateHistoriesTable20190909102213{})
+}
+
+// UpdateHistoriesTable20190909102213 represent some database operate
+type UpdateHistoriesTable20190909102213 struct{}
+
+// Name represent operate name, it's unique
+func (c *UpdateHistoriesTable20190909102213) Name() string {
+	return "update_histories_table_20190909102213"
+}
+
+// Up is executed in upgrading
+func (c *UpdateHistoriesTable20190909102213) Up(db *gorm.DB) error {
+	// moved represent that the history only records the previous path of file,
+	// it isn't counted as a version by retention policies
+	return db.Exec("alter table histories add column moved TINYINT UNSIGNED NOT NULL DEFAULT 0 after `path`").Error
+}
+
+// Down is executed in downgrading
+func (c *UpdateHistoriesTable20190909102213) Down(db *gorm.DB) error {
+	// execute when rollback database
+	return db.Exec("alter table histories drop column moved").Error
+}
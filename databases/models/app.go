@@ -19,6 +19,8 @@ type App struct {
 	Secret    string     `gorm:"type:CHAR(32) NOT NULL"`
 	Name      string     `gorm:"type:VARCHAR(100) NOT NULL"`
 	Note      *string    `gorm:"type:VARCHAR(500) NULL"`
+	MaxSize   int        `gorm:"type:BIGINT(20) UNSIGNED NOT NULL;column:maxSize;DEFAULT:0"`
+	MaxFiles  int        `gorm:"type:INT(10) UNSIGNED NOT NULL;column:maxFiles;DEFAULT:0"`
 	CreatedAt time.Time  `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:createdAt"`
 	UpdatedAt time.Time  `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:updatedAt"`
 	DeletedAt *time.Time `gorm:"type:TIMESTAMP(6);INDEX;column:deletedAt"`
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -32,13 +33,22 @@ var (
 	ErrChunkExceedLimit = fmt.Errorf("total length exceed limit: %d bytes", ChunkSize)
 )
 
-// Chunk represents every chunk of file
+// Chunk represents every chunk of file. Size and Hash are always about the
+// uncompressed content, Codec represent how the content is compressed in store.
+// KeyID represent the key that content is encrypted by, 0 represent plaintext.
+// AppID represent the app that chunk belongs to, the content is encrypted by the
+// keys of app, 0 represent that chunk is shared by all apps.
+// Quarantined is set by scrubber when the content in store is corrupted.
 type Chunk struct {
-	ID        uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT;primary_key"`
-	Size      int       `gorm:"type:int;column:size"`
-	Hash      string    `gorm:"type:CHAR(64) NOT NULL;UNIQUE;column:hash"`
-	CreatedAt time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:createdAt"`
-	UpdatedAt time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:updatedAt"`
+	ID          uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT;primary_key"`
+	AppID       uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL;DEFAULT:0;column:appId;unique_index:appId_hash_UNIQUE"`
+	Size        int       `gorm:"type:int;column:size"`
+	Hash        string    `gorm:"type:CHAR(64) NOT NULL;column:hash;unique_index:appId_hash_UNIQUE"`
+	Codec       string    `gorm:"type:VARCHAR(16) NOT NULL;DEFAULT:'none';column:codec"`
+	KeyID       uint32    `gorm:"type:INT UNSIGNED NOT NULL;DEFAULT:0;column:keyId"`
+	Quarantined int8      `gorm:"type:tinyint;column:quarantined;DEFAULT:0"`
+	CreatedAt   time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:createdAt"`
+	UpdatedAt   time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:updatedAt"`
 }
 
 // TableName represent table name
@@ -46,28 +56,150 @@ func (c Chunk) TableName() string {
 	return "chunks"
 }
 
-// Reader return a reader with buffer
-func (c *Chunk) Reader(rootPath *string) (*os.File, error) {
+// Reader return a reader of the content of chunk, encrypted or compressed content
+// is decoded into memory, it's acceptable since chunk is limited by ChunkSize. The
+// content of plaintext chunk is read from store directly, unless it looks encrypted,
+// see open.
+func (c *Chunk) Reader(rootPath *string) (ChunkReader, error) {
 	var (
-		err  error
-		file *os.File
+		err     error
+		sealed  bool
+		codec   ChunkCodec
+		reader  ChunkReader
+		content []byte
 	)
-	if file, err = os.Open(c.Path(rootPath)); err != nil {
+	if codec, err = GetChunkCodec(c.Codec); err != nil {
+		return nil, err
+	}
+	if reader, err = chunkStore(rootPath).Get(c.ID); err != nil {
+		return nil, err
+	}
+	if codec == nil && c.KeyID == 0 {
+		if sealed, err = c.looksSealed(reader); err != nil {
+			_ = reader.Close()
+			return nil, err
+		}
+		if !sealed {
+			return reader, nil
+		}
+	}
+	content, err = ioutil.ReadAll(reader)
+	_ = reader.Close()
+	if err != nil {
 		return nil, err
 	}
-	return file, nil
+	if content, err = c.open(content); err != nil {
+		return nil, err
+	}
+	if codec != nil {
+		if content, err = codec.Decode(content); err != nil {
+			return nil, err
+		}
+	}
+	return &bytesChunkReader{bytes.NewReader(content)}, nil
 }
 
-// Path represent the actual storage path
-func (c Chunk) Path(rootPath *string) string {
+// looksSealed represent whether the content of plaintext chunk starts with the
+// header of content encrypted by a key of its app, the reader is rewound after
+// the header is read
+func (c *Chunk) looksSealed(reader ChunkReader) (bool, error) {
+	keyring, err := DefaultKeyring()
+	if err != nil || keyring == nil {
+		return false, err
+	}
+	var header = make([]byte, 5)
+	readLen, err := io.ReadFull(reader, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	if _, err = reader.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	return keyring.canOpen(c.AppID, header[:readLen]), nil
+}
 
-	if rootPath == nil {
-		rootPath = &config.DefaultConfig.Chunk.RootPath
+// seal is used to encrypt p by the key of chunk, p is returned directly if chunk
+// isn't encrypted
+func (c *Chunk) seal(p []byte) ([]byte, error) {
+	if c.KeyID == 0 {
+		return p, nil
+	}
+	keyring, err := DefaultKeyring()
+	if err != nil {
+		return nil, err
+	}
+	if keyring == nil {
+		return nil, ErrKeyNotFound
+	}
+	return keyring.Seal(c.AppID, c.KeyID, c.ID, p)
+}
+
+// open is used to decrypt the content of chunk that is read from store. The content
+// of plaintext chunk may be encrypted too, because Rekey writes content to store
+// before the key id is saved in db. So it's decrypted if it's authenticated by the
+// key in its header, otherwise it's plaintext.
+func (c *Chunk) open(p []byte) ([]byte, error) {
+	keyring, err := DefaultKeyring()
+	if err != nil {
+		return nil, err
+	}
+	if c.KeyID == 0 {
+		if keyring != nil {
+			if opened, err := keyring.Open(c.AppID, c.ID, p); err == nil {
+				return opened, nil
+			}
+		}
+		return p, nil
+	}
+	if keyring == nil {
+		return nil, ErrKeyNotFound
 	}
-	if c.ID < 10000 {
+	return keyring.Open(c.AppID, c.ID, p)
+}
+
+// Rekey will encrypt the content of chunk by the current key of its app again,
+// plaintext chunk is encrypted as well. Nothing happens if encryption is disabled. Content is written
+// to store before db is updated, because the key id is saved in the encrypted content
+// too, chunk is still readable if the update fails, even if it was plaintext.
+func (c *Chunk) Rekey(rootPath *string, db *gorm.DB) error {
+	var (
+		err     error
+		keyID   uint32
+		reader  ChunkReader
+		content []byte
+		store   = chunkStore(rootPath)
+	)
+	if keyID, err = currentKeyID(c.AppID); err != nil || keyID == 0 || keyID == c.KeyID {
+		return err
+	}
+	if reader, err = store.Get(c.ID); err != nil {
+		return err
+	}
+	content, err = ioutil.ReadAll(reader)
+	_ = reader.Close()
+	if err != nil {
+		return err
+	}
+	if content, err = c.open(content); err != nil {
+		return err
+	}
+	c.KeyID = keyID
+	if content, err = c.seal(content); err != nil {
+		return err
+	}
+	if err = store.Put(c.ID, content); err != nil {
+		return err
+	}
+	return db.Model(c).UpdateColumn("keyId", keyID).Error
+}
+
+// chunkRelativePath represent the storage path of chunk that relative to the
+// root of store, example: the relative path of chunk 100044 is 100/100044
+func chunkRelativePath(id uint64) string {
+	if id < 10000 {
 		panic(ErrInvalidChunkID)
 	}
-	idStr := strconv.FormatUint(c.ID, 10)
+	idStr := strconv.FormatUint(id, 10)
 	parts := make([]string, (len(idStr)/3)+1)
 	index := 0
 	for ; len(idStr) > 3; index++ {
@@ -77,85 +209,71 @@ func (c Chunk) Path(rootPath *string) string {
 	parts[index] = idStr
 	parts = parts[1:]
 	util.ReverseSlice(parts)
-	dir := fmt.Sprintf("%s/%s", strings.TrimSuffix(*rootPath, "/"), filepath.Join(parts...))
-	if !util.IsDir(dir) {
+	return filepath.Join(append(parts, strconv.FormatUint(id, 10))...)
+}
+
+// Path represent the actual storage path in local disk
+func (c Chunk) Path(rootPath *string) string {
+
+	if rootPath == nil {
+		rootPath = &config.DefaultConfig.Chunk.RootPath
+	}
+	path := fmt.Sprintf("%s/%s", strings.TrimSuffix(*rootPath, "/"), chunkRelativePath(c.ID))
+	if dir := filepath.Dir(path); !util.IsDir(dir) {
 		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
 			panic(err)
 		}
 	}
-	return fmt.Sprintf("%s/%s", dir, strconv.FormatUint(c.ID, 10))
+	return path
 }
 
-// AppendBytes is used to append bytes to chunk. Firstly, this function will check whether
-// there is already a chunk its hash value is equal to the hash of complete content. If exist,
-// return it, otherwise, append content to origin chunk.
-func (c *Chunk) AppendBytes(p []byte, rootPath *string, db *gorm.DB) (*Chunk, int, error) {
+// AppendBytes is used to append bytes to chunk. Chunks are immutable once they are
+// written, so that readers never see the content that is being changed. The complete
+// content is saved as a new chunk of app, or the existing chunk whose hash is equal
+// to the hash of complete content is returned. The old chunk is left as it is, it
+// will be collected by gc after it isn't referenced.
+func (c *Chunk) AppendBytes(appID uint64, p []byte, rootPath *string, db *gorm.DB) (*Chunk, int, error) {
 	var (
-		file       *os.File
 		err        error
-		writeCount int
 		buf        bytes.Buffer
+		reader     ChunkReader
 		oldContent []byte
-		hash       string
+		chunk      *Chunk
 	)
 
 	if len(p) > ChunkSize-c.Size {
 		panic(ErrChunkExceedLimit)
 	}
 
-	if oldContent, err = ioutil.ReadFile(c.Path(rootPath)); err != nil {
-		return nil, 0, err
-	}
-	buf.Write(oldContent)
-	buf.Write(p)
-
-	// calculate the hash value of complete content
-	if hash, err = util.Sha256Hash2String(buf.Bytes()); err != nil {
+	if reader, err = c.Reader(rootPath); err != nil {
 		return nil, 0, err
 	}
-
-	// find chunk by the hash value of complete content
-	if chunk, err := FindChunkByHash(hash, db); err == nil && chunk.ID > 0 && util.IsFile(chunk.Path(rootPath)) {
-		return chunk, len(p), err
-	}
-
-	// if the current chunk is referenced by other objects, it should be copied and appended
-	if count, err := CountObjectChunkByChunkID(c.ID, db); err != nil {
-		return nil, 0, err
-	} else if count > 1 {
-		newChunk, err := CreateChunkFromBytes(buf.Bytes(), rootPath, db)
-		if err != nil {
-			return nil, 0, err
-		}
-		return newChunk, len(p), nil
-	}
-
-	c.Size = buf.Len()
-	c.Hash = hash
-
-	if file, err = os.OpenFile(c.Path(rootPath), os.O_APPEND|os.O_WRONLY, 0644); err != nil {
-		return nil, 0, err
-	}
-	defer file.Close()
-
-	if writeCount, err = file.Write(p); err != nil {
+	oldContent, err = ioutil.ReadAll(reader)
+	_ = reader.Close()
+	if err != nil {
 		return nil, 0, err
 	}
+	buf.Write(oldContent)
+	buf.Write(p)
 
-	if err = db.Save(c).Error; err != nil {
+	if chunk, err = CreateChunkFromBytes(appID, buf.Bytes(), rootPath, db); err != nil {
 		return nil, 0, err
 	}
 
-	return c, writeCount, err
+	return chunk, len(p), nil
 }
 
-// CreateChunkFromBytes will crate a chunk from the specify byte content
-func CreateChunkFromBytes(p []byte, rootPath *string, db *gorm.DB) (*Chunk, error) {
+// CreateChunkFromBytes will crate a chunk of app from the specify byte content,
+// appID 0 represent that content doesn't belong to any app. The chunk is shared
+// by all apps if encryption is disabled, see contentScope.
+func CreateChunkFromBytes(appID uint64, p []byte, rootPath *string, db *gorm.DB) (*Chunk, error) {
 	var (
 		chunk   *Chunk
 		err     error
 		hashStr string
 		size    int
+		content []byte
+		store   = chunkStore(rootPath)
 	)
 
 	if size = len(p); int64(size) > ChunkSize {
@@ -166,57 +284,187 @@ func CreateChunkFromBytes(p []byte, rootPath *string, db *gorm.DB) (*Chunk, erro
 		return nil, err
 	}
 
-	if chunk, err = FindChunkByHash(hashStr, db); err == nil && chunk.ID > 0 && util.IsFile(chunk.Path(rootPath)) {
-		return chunk, err
+	if appID, err = contentScope(appID); err != nil {
+		return nil, err
+	}
+
+	if chunk, err = findReusableChunk(appID, hashStr, store, db); err == nil {
+		return chunk, nil
 	}
 
 	chunk = &Chunk{
-		Size: size,
-		Hash: hashStr,
+		AppID: appID,
+		Size:  size,
+		Hash:  hashStr,
+	}
+	if chunk.Codec, content, err = encodeChunkContent(p); err != nil {
+		return nil, err
+	}
+	if chunk.KeyID, err = currentKeyID(appID); err != nil {
+		return nil, err
 	}
 	if err = db.Create(chunk).Error; err != nil {
+		return findDuplicateChunk(appID, chunk.Hash, p, err, store, db)
+	}
+	trackChunkCreated(store, chunk.ID, db)
+
+	if p, err = chunk.seal(content); err != nil {
 		return nil, err
 	}
+	if err = store.Put(chunk.ID, p); err != nil {
+		return nil, err
+	}
+
+	return chunk, nil
+}
 
-	if err = ioutil.WriteFile(chunk.Path(rootPath), p, 0644); err != nil {
+// RekeyChunks will encrypt at most limit chunks whose key isn't the current key
+// of their apps again, the count of rekeyed chunks is returned. It's used to
+// migrate chunks after encryption is enabled or the key is rotated.
+func RekeyChunks(limit int, rootPath *string, db *gorm.DB) (int, error) {
+	var (
+		err     error
+		keyring *Keyring
+		chunks  []Chunk
+		query   = db
+	)
+	if keyring, err = DefaultKeyring(); err != nil || keyring == nil {
+		return 0, err
+	}
+	if len(keyring.apps) == 0 {
+		query = query.Where("keyId <> ?", keyring.Current)
+	} else {
+		var (
+			appIDs     []uint64
+			conditions []string
+			values     []interface{}
+		)
+		for appID, own := range keyring.apps {
+			appIDs = append(appIDs, appID)
+			conditions = append(conditions, "(appId = ? and keyId <> ?)")
+			values = append(values, appID, own.Current)
+		}
+		conditions = append(conditions, "(appId not in (?) and keyId <> ?)")
+		values = append(values, appIDs, keyring.Current)
+		query = query.Where(strings.Join(conditions, " or "), values...)
+	}
+	if err = query.Order("id asc").Limit(limit).Find(&chunks).Error; err != nil {
+		return 0, err
+	}
+	for index := range chunks {
+		if err = chunks[index].Rekey(rootPath, db); err != nil {
+			return index, err
+		}
+	}
+	return len(chunks), nil
+}
+
+// findDuplicateChunk is used to find the chunk of app that has the same hash when
+// err is caused by the unique index of hash, it's read by a locking read, so the row
+// committed by others can be seen in a transaction. If the chunk is quarantined
+// or its content is lost from store, it's repaired by content under the lock of
+// row, so the content is always shareable again.
+func findDuplicateChunk(appID uint64, h string, content []byte, err error, store ChunkStore, db *gorm.DB) (*Chunk, error) {
+	if !util.IsDuplicateEntry(err) {
+		return nil, err
+	}
+	chunk, findErr := FindChunkByHash(appID, h, forUpdate(db))
+	if findErr != nil {
 		return nil, err
 	}
+	if chunk.Quarantined == 1 || !chunkExists(store, chunk.ID) {
+		if findErr = chunk.repair(content, store, db); findErr != nil {
+			return nil, findErr
+		}
+	}
+	if findErr = touch(chunk, db); findErr != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// repair writes content to store again for the chunk whose content is corrupted
+// or lost, and releases the quarantine of it. content is encoded and encrypted as the chunk
+// records, so that the row of chunk needn't be changed, and the chunk is still
+// readable if the release is rolled back.
+func (c *Chunk) repair(content []byte, store ChunkStore, db *gorm.DB) error {
+	codec, err := GetChunkCodec(c.Codec)
+	if err != nil {
+		return err
+	}
+	if codec != nil {
+		if content, err = codec.Encode(content); err != nil {
+			return err
+		}
+	}
+	if content, err = c.seal(content); err != nil {
+		return err
+	}
+	if err = store.Put(c.ID, content); err != nil {
+		return err
+	}
+	c.Quarantined = 0
+	return db.Model(c).UpdateColumn("quarantined", 0).Error
+}
 
+// findReusableChunk is used to find the chunk of app that has the same content for dedup,
+// the chunk whose content is lost or quarantined can't be reused. Its updatedAt is
+// bumped, so that it won't be collected by gc while it's reused.
+func findReusableChunk(appID uint64, h string, store ChunkStore, db *gorm.DB) (*Chunk, error) {
+	chunk, err := FindChunkByHash(appID, h, db)
+	if err == nil && (chunk.Quarantined == 1 || !chunkExists(store, chunk.ID)) {
+		err = ErrChunkNotExist
+	}
+	if err == nil {
+		err = touch(chunk, db)
+	}
+	if err != nil {
+		return nil, err
+	}
 	return chunk, nil
 }
 
-// FindChunkByHash will find chunk by the specify hash
-func FindChunkByHash(h string, db *gorm.DB) (*Chunk, error) {
+// FindChunkByHash will find the chunk of app by the specify hash, appID 0
+// represent the chunks shared by all apps
+func FindChunkByHash(appID uint64, h string, db *gorm.DB) (*Chunk, error) {
 	var chunk Chunk
-	var err = db.Where("hash = ?", h).First(&chunk).Error
+	var err = db.Where("appId = ? and hash = ?", appID, h).First(&chunk).Error
 	return &chunk, err
 }
 
-// CreateEmptyContentChunk is used to create a chunk with empty content
-func CreateEmptyContentChunk(rootPath *string, db *gorm.DB) (*Chunk, error) {
+// CreateEmptyContentChunk is used to create a chunk of app with empty content
+func CreateEmptyContentChunk(appID uint64, rootPath *string, db *gorm.DB) (*Chunk, error) {
 	var (
 		chunk            *Chunk
 		err              error
 		emptyContentHash string
+		store            = chunkStore(rootPath)
 	)
 	if emptyContentHash, err = util.Sha256Hash2String(nil); err != nil {
 		return nil, err
 	}
 
-	if chunk, err = FindChunkByHash(emptyContentHash, db); err == nil && chunk.ID > 0 && util.IsFile(chunk.Path(rootPath)) {
-		return chunk, err
+	if appID, err = contentScope(appID); err != nil {
+		return nil, err
+	}
+
+	if chunk, err = findReusableChunk(appID, emptyContentHash, store, db); err == nil {
+		return chunk, nil
 	}
 
 	chunk = &Chunk{
-		Size: 0,
-		Hash: emptyContentHash,
+		AppID: appID,
+		Size:  0,
+		Hash:  emptyContentHash,
+		Codec: ChunkCodecNone,
 	}
 
 	if err = db.Create(chunk).Error; err != nil {
-		return nil, err
+		return findDuplicateChunk(appID, chunk.Hash, nil, err, store, db)
 	}
+	trackChunkCreated(store, chunk.ID, db)
 
-	if err = ioutil.WriteFile(chunk.Path(rootPath), nil, 0644); err != nil {
+	if err = store.Put(chunk.ID, nil); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,90 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/bigfile/bigfile/config"
+)
+
+const (
+	// ChunkCodecNone represent that the content of chunk is saved raw
+	ChunkCodecNone = "none"
+	// ChunkCodecGzip represent that the content of chunk is compressed by gzip
+	ChunkCodecGzip = "gzip"
+)
+
+// ChunkCodec is used to compress the content of chunk before it's saved in store,
+// and decompress it after it's read from store
+type ChunkCodec interface {
+	Encode(p []byte) ([]byte, error)
+	Decode(p []byte) ([]byte, error)
+}
+
+var chunkCodecs = map[string]ChunkCodec{
+	ChunkCodecGzip: gzipChunkCodec{},
+}
+
+// GetChunkCodec return the codec by name, nil represent that content is saved raw
+func GetChunkCodec(name string) (ChunkCodec, error) {
+	if name == "" || name == ChunkCodecNone {
+		return nil, nil
+	}
+	if codec, ok := chunkCodecs[name]; ok {
+		return codec, nil
+	}
+	return nil, fmt.Errorf("unknown chunk codec: %s", name)
+}
+
+// encodeChunkContent compress p by the codec of global config. If p can't be
+// compressed smaller, it's saved raw. The name of the used codec is returned.
+func encodeChunkContent(p []byte) (string, []byte, error) {
+	var name = config.DefaultConfig.Chunk.Compression
+	codec, err := GetChunkCodec(name)
+	if err != nil {
+		return "", nil, err
+	}
+	if codec == nil || len(p) == 0 {
+		return ChunkCodecNone, p, nil
+	}
+	encoded, err := codec.Encode(p)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(encoded) >= len(p) {
+		return ChunkCodecNone, p, nil
+	}
+	return name, encoded, nil
+}
+
+// gzipChunkCodec compress content by gzip
+type gzipChunkCodec struct{}
+
+// Encode implements ChunkCodec
+func (gzipChunkCodec) Encode(p []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(p); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode implements ChunkCodec
+func (gzipChunkCodec) Decode(p []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(p))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
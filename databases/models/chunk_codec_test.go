@@ -0,0 +1,78 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// useCompressionForTest switch the compression of new chunks to codec, the
+// returned function is used to restore it.
+func useCompressionForTest(codec string) func() {
+	var old = config.DefaultConfig.Chunk.Compression
+	config.DefaultConfig.Chunk.Compression = codec
+	return func() {
+		config.DefaultConfig.Chunk.Compression = old
+	}
+}
+
+func TestGetChunkCodec(t *testing.T) {
+	for _, name := range []string{"", ChunkCodecNone} {
+		codec, err := GetChunkCodec(name)
+		assert.Nil(t, err)
+		assert.Nil(t, codec)
+	}
+	codec, err := GetChunkCodec(ChunkCodecGzip)
+	assert.Nil(t, err)
+	assert.IsType(t, gzipChunkCodec{}, codec)
+	_, err = GetChunkCodec("unknown")
+	assert.NotNil(t, err)
+}
+
+func TestGzipChunkCodec(t *testing.T) {
+	var (
+		codec   = gzipChunkCodec{}
+		content = bytes.Repeat([]byte("bigfile"), 1000)
+	)
+	encoded, err := codec.Encode(content)
+	assert.Nil(t, err)
+	assert.True(t, len(encoded) < len(content))
+	decoded, err := codec.Decode(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, content, decoded)
+
+	_, err = codec.Decode(content)
+	assert.NotNil(t, err)
+}
+
+func TestEncodeChunkContent(t *testing.T) {
+	var content = bytes.Repeat([]byte("bigfile"), 1000)
+
+	name, encoded, err := encodeChunkContent(content)
+	assert.Nil(t, err)
+	assert.Equal(t, ChunkCodecNone, name)
+	assert.Equal(t, content, encoded)
+
+	defer useCompressionForTest(ChunkCodecGzip)()
+	name, encoded, err = encodeChunkContent(content)
+	assert.Nil(t, err)
+	assert.Equal(t, ChunkCodecGzip, name)
+	assert.True(t, len(encoded) < len(content))
+
+	// random content can't be compressed smaller, it's saved raw
+	random := Random(1024)
+	name, encoded, err = encodeChunkContent(random)
+	assert.Nil(t, err)
+	assert.Equal(t, ChunkCodecNone, name)
+	assert.Equal(t, random, encoded)
+
+	config.DefaultConfig.Chunk.Compression = "unknown"
+	_, _, err = encodeChunkContent(content)
+	assert.NotNil(t, err)
+}
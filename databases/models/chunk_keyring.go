@@ -0,0 +1,331 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/bigfile/bigfile/config"
+	"gopkg.in/yaml.v2"
+)
+
+// sealedChunkVersion is the first byte of encrypted content, the layout of
+// encrypted content is: version(1) | key id(4) | nonce(12) | ciphertext
+const sealedChunkVersion = 1
+
+var (
+	// ErrInvalidKeyring represent that the keyring is malformed
+	ErrInvalidKeyring = errors.New("invalid keyring")
+	// ErrKeyNotFound represent that the key used to encrypt chunk isn't in keyring
+	ErrKeyNotFound = errors.New("key isn't found in keyring")
+	// ErrInvalidSealedChunk represent that the encrypted content of chunk is malformed
+	ErrInvalidSealedChunk = errors.New("invalid encrypted chunk")
+
+	defaultKeyring     *Keyring
+	defaultKeyringPath string
+	defaultKeyringLock sync.Mutex
+)
+
+// Keyring holds the keys that chunks are encrypted by. New content is always
+// encrypted by the current key, the old keys are kept to decrypt the content
+// that hasn't been encrypted again. Key id 0 is reserved for plaintext. Every app
+// has its own keys, they are configured in keyring, or derived from the shared
+// keys of keyring with the same ids. Content is deduplicated only in an app when
+// encryption is enabled, so that a chunk is never shared by the apps that have
+// different keys. The shared keys themselves are used by the chunks that are
+// written before encryption is enabled, they may be shared by apps.
+type Keyring struct {
+	Current uint32
+	keys    map[uint32][]byte
+	aeads   map[uint32]cipher.AEAD
+	apps    map[uint64]*Keyring
+
+	derivedLock sync.Mutex
+	derived     map[appKey]cipher.AEAD
+}
+
+// appKey identifies the key of app that is derived from a shared key
+type appKey struct {
+	appID uint64
+	keyID uint32
+}
+
+// keyringFile represent the content of keyring file, example:
+//
+//	current: 2
+//	keys:
+//	  1: base64 encoded key
+//	  2: base64 encoded key
+//	apps:
+//	  10:
+//	    current: 1
+//	    keys:
+//	      1: base64 encoded key
+//
+// Keys must be 16, 24 or 32 bytes, they select AES-128, AES-192 or AES-256. The
+// apps that aren't in apps use the keys derived from the shared keys.
+type keyringFile struct {
+	Current uint32                 `yaml:"current"`
+	Keys    map[uint32]string      `yaml:"keys"`
+	Apps    map[uint64]keyringFile `yaml:"apps,omitempty"`
+}
+
+// NewKeyring is used to create a keyring, current must be one of keys
+func NewKeyring(current uint32, keys map[uint32][]byte) (*Keyring, error) {
+	var keyring = &Keyring{
+		Current: current,
+		keys:    keys,
+		aeads:   make(map[uint32]cipher.AEAD),
+		apps:    make(map[uint64]*Keyring),
+		derived: make(map[appKey]cipher.AEAD),
+	}
+	if _, ok := keys[current]; !ok {
+		return nil, ErrInvalidKeyring
+	}
+	for id, key := range keys {
+		if id == 0 {
+			return nil, ErrInvalidKeyring
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %s", id, err)
+		}
+		keyring.aeads[id] = aead
+	}
+	return keyring, nil
+}
+
+// SetAppKeys is used to give app its own keys, instead of the derived ones
+func (k *Keyring) SetAppKeys(appID uint64, keys *Keyring) error {
+	if appID == 0 || len(keys.apps) > 0 {
+		return ErrInvalidKeyring
+	}
+	k.apps[appID] = keys
+	return nil
+}
+
+// newAEAD create an AES-GCM cipher by key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decodeKeys decode the base64 encoded keys of keyring file
+func decodeKeys(encoded map[uint32]string) (map[uint32][]byte, error) {
+	var (
+		err  error
+		keys = make(map[uint32][]byte)
+	)
+	for id, key := range encoded {
+		if keys[id], err = base64.StdEncoding.DecodeString(key); err != nil {
+			return nil, fmt.Errorf("key %d: %s", id, err)
+		}
+	}
+	return keys, nil
+}
+
+// LoadKeyring is used to load keyring from file
+func LoadKeyring(path string) (*Keyring, error) {
+	var (
+		err     error
+		content []byte
+		file    keyringFile
+		keys    map[uint32][]byte
+		keyring *Keyring
+	)
+	if content, err = ioutil.ReadFile(path); err != nil {
+		return nil, err
+	}
+	if err = yaml.Unmarshal(content, &file); err != nil {
+		return nil, err
+	}
+	if keys, err = decodeKeys(file.Keys); err != nil {
+		return nil, err
+	}
+	if keyring, err = NewKeyring(file.Current, keys); err != nil {
+		return nil, err
+	}
+	for appID, appFile := range file.Apps {
+		var appKeyring *Keyring
+		if keys, err = decodeKeys(appFile.Keys); err != nil {
+			return nil, fmt.Errorf("app %d: %s", appID, err)
+		}
+		if appKeyring, err = NewKeyring(appFile.Current, keys); err != nil {
+			return nil, fmt.Errorf("app %d: %s", appID, err)
+		}
+		if err = keyring.SetAppKeys(appID, appKeyring); err != nil {
+			return nil, fmt.Errorf("app %d: %s", appID, err)
+		}
+	}
+	return keyring, nil
+}
+
+// DefaultKeyring return the keyring that is configured by the global config,
+// nil represent that encryption is disabled. The keyring file is loaded again
+// when its path is changed.
+func DefaultKeyring() (*Keyring, error) {
+	defaultKeyringLock.Lock()
+	defer defaultKeyringLock.Unlock()
+	path := config.DefaultConfig.Chunk.Keyring
+	if path == "" {
+		return nil, nil
+	}
+	if defaultKeyring == nil || defaultKeyringPath != path {
+		keyring, err := LoadKeyring(path)
+		if err != nil {
+			return nil, err
+		}
+		defaultKeyring, defaultKeyringPath = keyring, path
+	}
+	return defaultKeyring, nil
+}
+
+// SetDefaultKeyring is used to replace the default keyring, nil will make the
+// keyring be loaded from the file of global config again.
+func SetDefaultKeyring(keyring *Keyring) {
+	defaultKeyringLock.Lock()
+	defer defaultKeyringLock.Unlock()
+	defaultKeyring, defaultKeyringPath = keyring, config.DefaultConfig.Chunk.Keyring
+}
+
+// CurrentKeyID return the id of key that new content of app is encrypted by,
+// appID 0 represent the content shared by apps
+func (k *Keyring) CurrentKeyID(appID uint64) uint32 {
+	if own, ok := k.apps[appID]; ok {
+		return own.Current
+	}
+	return k.Current
+}
+
+// aead return the cipher of key of app. The key is derived from the shared key
+// by HMAC-SHA256 with the id of app, unless app has its own keys.
+func (k *Keyring) aead(appID uint64, keyID uint32) (cipher.AEAD, error) {
+	if own, ok := k.apps[appID]; ok {
+		return own.aead(0, keyID)
+	}
+	if appID == 0 {
+		if aead, ok := k.aeads[keyID]; ok {
+			return aead, nil
+		}
+		return nil, ErrKeyNotFound
+	}
+
+	k.derivedLock.Lock()
+	defer k.derivedLock.Unlock()
+	if aead, ok := k.derived[appKey{appID, keyID}]; ok {
+		return aead, nil
+	}
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	var (
+		mac  = hmac.New(sha256.New, key)
+		info = make([]byte, 8)
+	)
+	binary.BigEndian.PutUint64(info, appID)
+	mac.Write([]byte("bigfile app key"))
+	mac.Write(info)
+	aead, err := newAEAD(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	k.derived[appKey{appID, keyID}] = aead
+	return aead, nil
+}
+
+// currentKeyID return the id of key that new content of app is encrypted by,
+// 0 represent that encryption is disabled
+func currentKeyID(appID uint64) (uint32, error) {
+	keyring, err := DefaultKeyring()
+	if err != nil || keyring == nil {
+		return 0, err
+	}
+	return keyring.CurrentKeyID(appID), nil
+}
+
+// contentScope return the app that new content of app is deduplicated in. If
+// encryption is enabled, content is only shared in app, because it's encrypted
+// by the keys of app. Otherwise, content is shared by all apps, 0 is returned.
+func contentScope(appID uint64) (uint64, error) {
+	keyring, err := DefaultKeyring()
+	if err != nil || keyring == nil {
+		return 0, err
+	}
+	return appID, nil
+}
+
+// additionalData binds encrypted content to chunk, so that the content of
+// a chunk can't be replaced by the content of another one
+func additionalData(chunkID uint64) []byte {
+	var data = make([]byte, 8)
+	binary.BigEndian.PutUint64(data, chunkID)
+	return data
+}
+
+// Seal is used to encrypt the content of chunk of app by key
+func (k *Keyring) Seal(appID uint64, keyID uint32, chunkID uint64, p []byte) ([]byte, error) {
+	aead, err := k.aead(appID, keyID)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		headerSize = 5 + aead.NonceSize()
+		sealed     = make([]byte, headerSize, headerSize+len(p)+aead.Overhead())
+	)
+	sealed[0] = sealedChunkVersion
+	binary.BigEndian.PutUint32(sealed[1:5], keyID)
+	if _, err := io.ReadFull(rand.Reader, sealed[5:headerSize]); err != nil {
+		return nil, err
+	}
+	return aead.Seal(sealed, sealed[5:headerSize], p, additionalData(chunkID)), nil
+}
+
+// Open is used to decrypt the content of chunk of app, the key is decided by
+// the key id in the content, so it doesn't matter which key is recorded in db.
+func (k *Keyring) Open(appID uint64, chunkID uint64, sealed []byte) ([]byte, error) {
+	if !looksSealed(sealed) {
+		return nil, ErrInvalidSealedChunk
+	}
+	aead, err := k.aead(appID, binary.BigEndian.Uint32(sealed[1:5]))
+	if err != nil {
+		return nil, err
+	}
+	var headerSize = 5 + aead.NonceSize()
+	if len(sealed) < headerSize {
+		return nil, ErrInvalidSealedChunk
+	}
+	return aead.Open(nil, sealed[5:headerSize], sealed[headerSize:], additionalData(chunkID))
+}
+
+// looksSealed represent whether p starts with the header of encrypted content,
+// plaintext may look sealed as well, it's decided by Open finally.
+func looksSealed(p []byte) bool {
+	return len(p) >= 5 && p[0] == sealedChunkVersion
+}
+
+// canOpen represent whether p starts with the header of content that is
+// encrypted by a key of app
+func (k *Keyring) canOpen(appID uint64, p []byte) bool {
+	if !looksSealed(p) {
+		return false
+	}
+	_, err := k.aead(appID, binary.BigEndian.Uint32(p[1:5]))
+	return err == nil
+}
@@ -0,0 +1,186 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// useKeyringForTest write a keyring file that contains keys to dir, and make it
+// the keyring of global config. The returned function is used to restore it.
+func useKeyringForTest(t *testing.T, dir string, current uint32, keys map[uint32][]byte) func() {
+	var (
+		old     = config.DefaultConfig.Chunk.Keyring
+		path    = filepath.Join(dir, fmt.Sprintf("keyring-%d.yaml", current))
+		content = fmt.Sprintf("current: %d\nkeys:\n", current)
+	)
+	for id, key := range keys {
+		content += fmt.Sprintf("  %d: %s\n", id, base64.StdEncoding.EncodeToString(key))
+	}
+	assert.Nil(t, os.MkdirAll(dir, os.ModePerm))
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+	config.DefaultConfig.Chunk.Keyring = path
+	return func() {
+		config.DefaultConfig.Chunk.Keyring = old
+		SetDefaultKeyring(nil)
+	}
+}
+
+func TestNewKeyring(t *testing.T) {
+	_, err := NewKeyring(1, map[uint32][]byte{2: Random(32)})
+	assert.Equal(t, ErrInvalidKeyring, err)
+	_, err = NewKeyring(0, map[uint32][]byte{0: Random(32)})
+	assert.Equal(t, ErrInvalidKeyring, err)
+	_, err = NewKeyring(1, map[uint32][]byte{1: Random(10)})
+	assert.NotNil(t, err)
+
+	keyring, err := NewKeyring(2, map[uint32][]byte{1: Random(16), 2: Random(32)})
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), keyring.Current)
+}
+
+func TestLoadKeyring(t *testing.T) {
+	tempDir := NewTempDirForTest()
+	defer os.RemoveAll(tempDir)
+	defer useKeyringForTest(t, tempDir, 1, map[uint32][]byte{1: Random(32)})()
+
+	keyring, err := LoadKeyring(config.DefaultConfig.Chunk.Keyring)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), keyring.Current)
+
+	keyring2, err := DefaultKeyring()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), keyring2.Current)
+	keyID, err := currentKeyID(0)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), keyID)
+
+	_, err = LoadKeyring(filepath.Join(tempDir, "not-exist.yaml"))
+	assert.NotNil(t, err)
+
+	path := filepath.Join(tempDir, "invalid.yaml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("current: 1\nkeys:\n  1: '#'\n"), 0644))
+	_, err = LoadKeyring(path)
+	assert.NotNil(t, err)
+
+	config.DefaultConfig.Chunk.Keyring = ""
+	keyring, err = DefaultKeyring()
+	assert.Nil(t, err)
+	assert.Nil(t, keyring)
+}
+
+func TestKeyring_Seal(t *testing.T) {
+	var (
+		content = []byte("hello world")
+		key1    = Random(32)
+	)
+	keyring, err := NewKeyring(1, map[uint32][]byte{1: key1})
+	assert.Nil(t, err)
+
+	sealed, err := keyring.Seal(0, 1, 10001, content)
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(sealed, content))
+
+	opened, err := keyring.Open(0, 10001, sealed)
+	assert.Nil(t, err)
+	assert.Equal(t, content, opened)
+
+	// the content of another chunk can't be opened
+	_, err = keyring.Open(0, 10002, sealed)
+	assert.NotNil(t, err)
+
+	_, err = keyring.Seal(0, 2, 10001, content)
+	assert.Equal(t, ErrKeyNotFound, err)
+	_, err = keyring.Open(0, 10001, content)
+	assert.Equal(t, ErrInvalidSealedChunk, err)
+
+	// the old key is still used to open the content sealed by it
+	keyring2, err := NewKeyring(2, map[uint32][]byte{1: key1, 2: Random(32)})
+	assert.Nil(t, err)
+	opened, err = keyring2.Open(0, 10001, sealed)
+	assert.Nil(t, err)
+	assert.Equal(t, content, opened)
+
+	keyring3, err := NewKeyring(2, map[uint32][]byte{2: Random(32)})
+	assert.Nil(t, err)
+	_, err = keyring3.Open(0, 10001, sealed)
+	assert.Equal(t, ErrKeyNotFound, err)
+}
+
+func TestKeyring_Seal2(t *testing.T) {
+	var content = []byte("hello world")
+	keyring, err := NewKeyring(1, map[uint32][]byte{1: Random(32)})
+	assert.Nil(t, err)
+
+	// the keys of apps are derived from the shared key, they are different
+	sealed, err := keyring.Seal(10, 1, 10001, content)
+	assert.Nil(t, err)
+	opened, err := keyring.Open(10, 10001, sealed)
+	assert.Nil(t, err)
+	assert.Equal(t, content, opened)
+	_, err = keyring.Open(11, 10001, sealed)
+	assert.NotNil(t, err)
+	_, err = keyring.Open(0, 10001, sealed)
+	assert.NotNil(t, err)
+	assert.True(t, keyring.canOpen(10, sealed))
+	assert.False(t, keyring.canOpen(10, content))
+
+	// app uses its own keys instead of the derived ones
+	own, err := NewKeyring(3, map[uint32][]byte{3: Random(16)})
+	assert.Nil(t, err)
+	assert.Equal(t, ErrInvalidKeyring, keyring.SetAppKeys(0, own))
+	assert.Nil(t, keyring.SetAppKeys(11, own))
+	assert.Equal(t, uint32(3), keyring.CurrentKeyID(11))
+	assert.Equal(t, uint32(1), keyring.CurrentKeyID(10))
+	_, err = keyring.Seal(11, 1, 10001, content)
+	assert.Equal(t, ErrKeyNotFound, err)
+	assert.False(t, keyring.canOpen(11, sealed))
+	sealed, err = keyring.Seal(11, 3, 10001, content)
+	assert.Nil(t, err)
+	opened, err = keyring.Open(11, 10001, sealed)
+	assert.Nil(t, err)
+	assert.Equal(t, content, opened)
+	_, err = own.Open(0, 10001, sealed)
+	assert.Nil(t, err)
+}
+
+func TestLoadKeyring2(t *testing.T) {
+	tempDir := NewTempDirForTest()
+	defer os.RemoveAll(tempDir)
+
+	var (
+		path    = filepath.Join(tempDir, "keyring.yaml")
+		content = fmt.Sprintf(
+			"current: 1\nkeys:\n  1: %s\napps:\n  10:\n    current: 2\n    keys:\n      2: %s\n",
+			base64.StdEncoding.EncodeToString(Random(32)),
+			base64.StdEncoding.EncodeToString(Random(32)),
+		)
+	)
+	assert.Nil(t, os.MkdirAll(tempDir, os.ModePerm))
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+	keyring, err := LoadKeyring(path)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), keyring.CurrentKeyID(0))
+	assert.Equal(t, uint32(2), keyring.CurrentKeyID(10))
+	assert.Equal(t, uint32(1), keyring.CurrentKeyID(11))
+
+	content = fmt.Sprintf(
+		"current: 1\nkeys:\n  1: %s\napps:\n  10:\n    current: 2\n    keys:\n      1: %s\n",
+		base64.StdEncoding.EncodeToString(Random(32)),
+		base64.StdEncoding.EncodeToString(Random(32)),
+	)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+	_, err = LoadKeyring(path)
+	assert.NotNil(t, err)
+}
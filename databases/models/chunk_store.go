@@ -0,0 +1,102 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/bigfile/bigfile/config"
+)
+
+var (
+	// ErrChunkNotExist represent that the content of chunk can't be found in store
+	ErrChunkNotExist = errors.New("chunk doesn't exist in store")
+
+	defaultChunkStore     ChunkStore
+	defaultChunkStoreLock sync.Mutex
+)
+
+// ChunkReader is used to read the content of chunk, it must be closed after reading
+type ChunkReader interface {
+	io.Reader
+	io.Seeker
+	io.Closer
+}
+
+// ChunkStore is used to save the content of chunks, chunk is identified by its id
+type ChunkStore interface {
+	// Put will save p as the whole content of chunk, the old content will be replaced
+	Put(id uint64, p []byte) error
+	// Get return a reader of the content of chunk
+	Get(id uint64) (ChunkReader, error)
+	// Append will append p to the end of chunk
+	Append(id uint64, p []byte) error
+	// Delete will remove chunk, it's not an error to delete a chunk that doesn't exist
+	Delete(id uint64) error
+	// Stat return the size of chunk, if chunk doesn't exist, ErrChunkNotExist is returned
+	Stat(id uint64) (int64, error)
+}
+
+// NewChunkStore is used to create a chunk store by config
+func NewChunkStore(cfg *config.Chunk) (ChunkStore, error) {
+	switch cfg.Store {
+	case "", "local":
+		return NewLocalChunkStore(cfg.RootPath), nil
+	case "memory":
+		return NewMemoryChunkStore(), nil
+	case "s3":
+		return NewS3ChunkStore(&cfg.S3), nil
+	}
+	return nil, fmt.Errorf("unknown chunk store: %s", cfg.Store)
+}
+
+// DefaultChunkStore return the chunk store that is decided by the global config
+func DefaultChunkStore() ChunkStore {
+	defaultChunkStoreLock.Lock()
+	defer defaultChunkStoreLock.Unlock()
+	if defaultChunkStore == nil {
+		var err error
+		if defaultChunkStore, err = NewChunkStore(&config.DefaultConfig.Chunk); err != nil {
+			panic(err)
+		}
+	}
+	return defaultChunkStore
+}
+
+// SetDefaultChunkStore is used to replace the default chunk store
+func SetDefaultChunkStore(store ChunkStore) {
+	defaultChunkStoreLock.Lock()
+	defer defaultChunkStoreLock.Unlock()
+	defaultChunkStore = store
+}
+
+// chunkStore return the store that chunks are saved in. If rootPath isn't nil,
+// a local store under rootPath is used, otherwise, the default chunk store.
+func chunkStore(rootPath *string) ChunkStore {
+	if rootPath != nil {
+		return NewLocalChunkStore(*rootPath)
+	}
+	return DefaultChunkStore()
+}
+
+// chunkExists is used to check whether the content of chunk exists in store
+func chunkExists(store ChunkStore, id uint64) bool {
+	_, err := store.Stat(id)
+	return err == nil
+}
+
+// bytesChunkReader wrap bytes.Reader as a ChunkReader
+type bytesChunkReader struct {
+	*bytes.Reader
+}
+
+// Close implements io.Closer, nothing need to be released
+func (b *bytesChunkReader) Close() error {
+	return nil
+}
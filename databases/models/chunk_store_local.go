@@ -0,0 +1,101 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// LocalChunkStore save chunks in local disk, the layout is decided by Chunk.Path
+type LocalChunkStore struct {
+	RootPath string
+}
+
+// NewLocalChunkStore is used to create a chunk store under rootPath
+func NewLocalChunkStore(rootPath string) *LocalChunkStore {
+	return &LocalChunkStore{RootPath: rootPath}
+}
+
+func (l *LocalChunkStore) path(id uint64) string {
+	return Chunk{ID: id}.Path(&l.RootPath)
+}
+
+// Put implements ChunkStore, content is written to a temporary file first, and then
+// renamed, so that the old content is never lost when it's replaced, and readers
+// never see a partial content.
+func (l *LocalChunkStore) Put(id uint64, p []byte) error {
+	var (
+		err  error
+		tmp  *os.File
+		path = l.path(id)
+	)
+	if tmp, err = ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp"); err != nil {
+		return err
+	}
+	if _, err = tmp.Write(p); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Get implements ChunkStore
+func (l *LocalChunkStore) Get(id uint64) (ChunkReader, error) {
+	file, err := os.Open(l.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrChunkNotExist
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// Append implements ChunkStore
+func (l *LocalChunkStore) Append(id uint64, p []byte) error {
+	file, err := os.OpenFile(l.path(id), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrChunkNotExist
+		}
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(p)
+	return err
+}
+
+// Delete implements ChunkStore
+func (l *LocalChunkStore) Delete(id uint64) error {
+	if err := os.Remove(l.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Stat implements ChunkStore
+func (l *LocalChunkStore) Stat(id uint64) (int64, error) {
+	fileInfo, err := os.Stat(l.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, ErrChunkNotExist
+		}
+		return 0, err
+	}
+	if fileInfo.IsDir() {
+		return 0, ErrChunkNotExist
+	}
+	return fileInfo.Size(), nil
+}
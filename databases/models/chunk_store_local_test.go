@@ -0,0 +1,53 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalChunkStore(t *testing.T) {
+	tempDir := NewTempDirForTest()
+	defer func() {
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+	store := NewLocalChunkStore(tempDir)
+	testChunkStore(t, store)
+
+	assert.Nil(t, store.Put(10001, []byte("hello")))
+	assert.True(t, util.IsFile(tempDir+"/10/10001"))
+}
+
+func TestLocalChunkStore_Put(t *testing.T) {
+	tempDir := NewTempDirForTest()
+	defer func() {
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+	store := NewLocalChunkStore(tempDir)
+	assert.Nil(t, store.Put(10001, []byte("hello")))
+
+	// the reader opened before replacing still sees the old content
+	reader, err := store.Get(10001)
+	assert.Nil(t, err)
+	defer reader.Close()
+	assert.Nil(t, store.Put(10001, []byte("hello world")))
+	content, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	// temporary files are renamed or removed
+	files, err := ioutil.ReadDir(tempDir + "/10")
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+}
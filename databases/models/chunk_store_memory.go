@@ -0,0 +1,72 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"sync"
+)
+
+// MemoryChunkStore save chunks in memory, it's designed for test
+type MemoryChunkStore struct {
+	lock   sync.RWMutex
+	chunks map[uint64][]byte
+}
+
+// NewMemoryChunkStore is used to create an empty memory chunk store
+func NewMemoryChunkStore() *MemoryChunkStore {
+	return &MemoryChunkStore{chunks: make(map[uint64][]byte)}
+}
+
+// Put implements ChunkStore
+func (m *MemoryChunkStore) Put(id uint64, p []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.chunks[id] = append([]byte{}, p...)
+	return nil
+}
+
+// Get implements ChunkStore
+func (m *MemoryChunkStore) Get(id uint64) (ChunkReader, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	content, ok := m.chunks[id]
+	if !ok {
+		return nil, ErrChunkNotExist
+	}
+	return &bytesChunkReader{bytes.NewReader(content)}, nil
+}
+
+// Append implements ChunkStore
+func (m *MemoryChunkStore) Append(id uint64, p []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	content, ok := m.chunks[id]
+	if !ok {
+		return ErrChunkNotExist
+	}
+	// always copy, the readers returned before must not see the new content
+	m.chunks[id] = append(append([]byte{}, content...), p...)
+	return nil
+}
+
+// Delete implements ChunkStore
+func (m *MemoryChunkStore) Delete(id uint64) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.chunks, id)
+	return nil
+}
+
+// Stat implements ChunkStore
+func (m *MemoryChunkStore) Stat(id uint64) (int64, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	content, ok := m.chunks[id]
+	if !ok {
+		return 0, ErrChunkNotExist
+	}
+	return int64(len(content)), nil
+}
@@ -0,0 +1,25 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryChunkStore(t *testing.T) {
+	store := NewMemoryChunkStore()
+	testChunkStore(t, store)
+
+	assert.Nil(t, store.Put(10001, []byte("hello")))
+	reader, err := store.Get(10001)
+	assert.Nil(t, err)
+	assert.Nil(t, store.Append(10001, []byte(" world")))
+	content, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hello"), content)
+}
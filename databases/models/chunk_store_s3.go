@@ -0,0 +1,224 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bigfile/bigfile/config"
+)
+
+const s3TimeFormat = "20060102T150405Z"
+
+// S3ChunkStore save chunks in a s3 compatible object storage service, every chunk
+// is an object. Path-style addressing is used, so that it's able to work with the
+// services deployed privately. S3 can't append data to an object, so Append will
+// download the whole chunk and upload it again, it's acceptable since the size of
+// chunk is limited by ChunkSize.
+type S3ChunkStore struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Prefix          string
+	Client          *http.Client
+
+	// now is used to get the time of signature, it can be replaced in test
+	now func() time.Time
+}
+
+// NewS3ChunkStore is used to create a s3 chunk store by config
+func NewS3ChunkStore(cfg *config.ChunkS3) *S3ChunkStore {
+	return &S3ChunkStore{
+		Endpoint:        strings.TrimSuffix(cfg.Endpoint, "/"),
+		Region:          cfg.Region,
+		Bucket:          cfg.Bucket,
+		AccessKeyID:     cfg.AccessKeyID,
+		SecretAccessKey: cfg.SecretAccessKey,
+		Prefix:          cfg.Prefix,
+		Client:          &http.Client{Timeout: time.Minute},
+		now:             time.Now,
+	}
+}
+
+func (s *S3ChunkStore) url(id uint64) string {
+	return fmt.Sprintf("%s/%s/%s%s", s.Endpoint, s.Bucket, s.Prefix, chunkRelativePath(id))
+}
+
+func (s *S3ChunkStore) do(method string, id uint64, body []byte) (*http.Response, error) {
+	var (
+		err      error
+		request  *http.Request
+		response *http.Response
+	)
+	if request, err = http.NewRequest(method, s.url(id), bytes.NewReader(body)); err != nil {
+		return nil, err
+	}
+	if body == nil {
+		request.Body = nil
+	}
+	request.ContentLength = int64(len(body))
+	s.sign(request, body)
+	if response, err = s.Client.Do(request); err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusNotFound {
+		response.Body.Close()
+		return nil, ErrChunkNotExist
+	}
+	if response.StatusCode >= 300 {
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+		response.Body.Close()
+		return nil, fmt.Errorf("s3: %s %s: %s %s", method, request.URL.Path, response.Status, message)
+	}
+	return response, nil
+}
+
+// sign will sign the request with AWS Signature Version 4, all headers of
+// request are signed.
+func (s *S3ChunkStore) sign(request *http.Request, body []byte) {
+	var (
+		now           = s.now().UTC()
+		amzDate       = now.Format(s3TimeFormat)
+		date          = now.Format("20060102")
+		scope         = fmt.Sprintf("%s/%s/s3/aws4_request", date, s.Region)
+		payloadHash   = sha256.Sum256(body)
+		headerNames   []string
+		headerEntries []string
+	)
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	for name := range headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	for _, name := range headerNames {
+		headerEntries = append(headerEntries, name+":"+headers[name]+"\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		s3EscapePath(request.URL.Path),
+		request.URL.Query().Encode(),
+		strings.Join(headerEntries, ""),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+
+	signingKey := s3HMAC([]byte("AWS4"+s.SecretAccessKey), date)
+	signingKey = s3HMAC(signingKey, s.Region)
+	signingKey = s3HMAC(signingKey, "s3")
+	signingKey = s3HMAC(signingKey, "aws4_request")
+
+	request.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, hex.EncodeToString(s3HMAC(signingKey, stringToSign)),
+	))
+}
+
+func s3HMAC(key []byte, content string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(content))
+	return mac.Sum(nil)
+}
+
+func s3EscapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for index, segment := range segments {
+		segments[index] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Put implements ChunkStore
+func (s *S3ChunkStore) Put(id uint64, p []byte) error {
+	if p == nil {
+		p = []byte{}
+	}
+	response, err := s.do(http.MethodPut, id, p)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// Get implements ChunkStore, the whole chunk is read into memory
+func (s *S3ChunkStore) Get(id uint64) (ChunkReader, error) {
+	var (
+		err      error
+		content  []byte
+		response *http.Response
+	)
+	if response, err = s.do(http.MethodGet, id, nil); err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if content, err = ioutil.ReadAll(response.Body); err != nil {
+		return nil, err
+	}
+	return &bytesChunkReader{bytes.NewReader(content)}, nil
+}
+
+// Append implements ChunkStore
+func (s *S3ChunkStore) Append(id uint64, p []byte) error {
+	var (
+		err     error
+		reader  ChunkReader
+		content []byte
+	)
+	if reader, err = s.Get(id); err != nil {
+		return err
+	}
+	if content, err = ioutil.ReadAll(reader); err != nil {
+		return err
+	}
+	return s.Put(id, append(content, p...))
+}
+
+// Delete implements ChunkStore
+func (s *S3ChunkStore) Delete(id uint64) error {
+	response, err := s.do(http.MethodDelete, id, nil)
+	if err != nil {
+		if err == ErrChunkNotExist {
+			return nil
+		}
+		return err
+	}
+	return response.Body.Close()
+}
+
+// Stat implements ChunkStore
+func (s *S3ChunkStore) Stat(id uint64) (int64, error) {
+	response, err := s.do(http.MethodHead, id, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	return response.ContentLength, nil
+}
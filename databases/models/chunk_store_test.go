@@ -0,0 +1,138 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// testChunkStore is used to test the behaviors that every ChunkStore must have
+func testChunkStore(t *testing.T, store ChunkStore) {
+	var (
+		id      = uint64(100044)
+		content = Random(256)
+	)
+
+	_, err := store.Get(id)
+	assert.Equal(t, ErrChunkNotExist, err)
+	_, err = store.Stat(id)
+	assert.Equal(t, ErrChunkNotExist, err)
+	assert.Equal(t, ErrChunkNotExist, store.Append(id, content))
+	assert.Nil(t, store.Delete(id))
+
+	assert.Nil(t, store.Put(id, content))
+	size, err := store.Stat(id)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(256), size)
+
+	assert.Nil(t, store.Append(id, []byte("hello")))
+	reader, err := store.Get(id)
+	assert.Nil(t, err)
+	_, err = reader.Seek(256, io.SeekStart)
+	assert.Nil(t, err)
+	tail, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Nil(t, reader.Close())
+	assert.Equal(t, []byte("hello"), tail)
+
+	assert.Nil(t, store.Put(id, nil))
+	size, err = store.Stat(id)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), size)
+
+	assert.Nil(t, store.Delete(id))
+	_, err = store.Stat(id)
+	assert.Equal(t, ErrChunkNotExist, err)
+}
+
+func TestNewChunkStore(t *testing.T) {
+	store, err := NewChunkStore(&config.Chunk{RootPath: "storage/chunks"})
+	assert.Nil(t, err)
+	assert.Equal(t, "storage/chunks", store.(*LocalChunkStore).RootPath)
+
+	store, err = NewChunkStore(&config.Chunk{Store: "memory"})
+	assert.Nil(t, err)
+	assert.IsType(t, &MemoryChunkStore{}, store)
+
+	store, err = NewChunkStore(&config.Chunk{Store: "s3", S3: config.ChunkS3{Bucket: "bigfile"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "bigfile", store.(*S3ChunkStore).Bucket)
+
+	_, err = NewChunkStore(&config.Chunk{Store: "ftp"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unknown chunk store")
+}
+
+func TestSetDefaultChunkStore(t *testing.T) {
+	var (
+		memoryStore = NewMemoryChunkStore()
+		tempDir     = NewTempDirForTest()
+	)
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	SetDefaultChunkStore(memoryStore)
+	defer func() {
+		SetDefaultChunkStore(nil)
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	assert.Equal(t, memoryStore, DefaultChunkStore())
+	assert.IsType(t, &LocalChunkStore{}, chunkStore(&tempDir))
+
+	object, err := CreateObjectFromReader(0, bytes.NewReader(Random(ChunkSize+10)), nil, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, trx.Preload("Chunks").Find(object).Error)
+	for _, chunk := range object.Chunks {
+		assert.True(t, chunkExists(memoryStore, chunk.ID))
+	}
+	reader, err := object.Reader(nil)
+	assert.Nil(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	contentHash, err := util.Sha256Hash2String(content)
+	assert.Nil(t, err)
+	assert.Equal(t, object.Hash, contentHash)
+	assert.False(t, util.IsDir(tempDir+"/10"))
+}
+
+// faultyChunkStore wraps a ChunkStore to inject the errors of store
+type faultyChunkStore struct {
+	ChunkStore
+	puts    int
+	failPut int   // the nth Put fails, zero represent Put never fails
+	err     error // it's returned by Get and Stat if it isn't nil
+}
+
+func (f *faultyChunkStore) Put(id uint64, p []byte) error {
+	if f.puts++; f.puts == f.failPut {
+		return errors.New("put failed")
+	}
+	return f.ChunkStore.Put(id, p)
+}
+
+func (f *faultyChunkStore) Get(id uint64) (ChunkReader, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.ChunkStore.Get(id)
+}
+
+func (f *faultyChunkStore) Stat(id uint64) (int64, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	return f.ChunkStore.Stat(id)
+}
@@ -5,12 +5,15 @@
 package models
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/bigfile/bigfile/config"
 	"github.com/bigfile/bigfile/internal/util"
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
@@ -63,7 +66,7 @@ func TestFindChunkByHash(t *testing.T) {
 	err = trx.Create(chunk).Error
 	assert.Nil(t, err)
 
-	chunkTmp, err := FindChunkByHash(strHash, trx)
+	chunkTmp, err := FindChunkByHash(0, strHash, trx)
 	assert.Nil(t, err)
 	assert.Equal(t, chunk.ID, chunkTmp.ID)
 }
@@ -86,12 +89,12 @@ func TestCreateChunkFromBytes(t *testing.T) {
 		}
 	}()
 
-	_, err = CreateChunkFromBytes(bigBytes, &tempDir, trx)
+	_, err = CreateChunkFromBytes(0, bigBytes, &tempDir, trx)
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "the size of chunk must be less than")
 
 	bigBytes = bigBytes[:ChunkSize]
-	chunk, err = CreateChunkFromBytes(bigBytes, &tempDir, trx)
+	chunk, err = CreateChunkFromBytes(0, bigBytes, &tempDir, trx)
 	assert.Nil(t, err)
 	assert.True(t, chunk.ID > 0)
 
@@ -122,16 +125,26 @@ func TestChunk_AppendBytes(t *testing.T) {
 		}
 	}()
 
-	chunk, err = CreateChunkFromBytes(bigBytes, &tempDir, trx)
+	chunk, err = CreateChunkFromBytes(0, bigBytes, &tempDir, trx)
 	assert.Nil(t, err)
 	assert.Equal(t, 5, chunk.Size)
 	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", chunk.Hash)
 
-	_, writeCount, err = chunk.AppendBytes([]byte(" world"), &tempDir, trx)
+	newChunk, writeCount, err := chunk.AppendBytes(0, []byte(" world"), &tempDir, trx)
 	assert.Nil(t, err)
 	assert.Equal(t, 6, writeCount)
-	assert.Equal(t, 11, chunk.Size)
-	assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", chunk.Hash)
+	assert.Equal(t, 11, newChunk.Size)
+	assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", newChunk.Hash)
+	assert.NotEqual(t, chunk.ID, newChunk.ID)
+
+	// the old chunk isn't changed
+	assert.Equal(t, 5, chunk.Size)
+	reader, err := chunk.Reader(&tempDir)
+	assert.Nil(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Nil(t, reader.Close())
+	assert.Equal(t, bigBytes, content)
 }
 
 func TestChunk_AppendBytes2(t *testing.T) {
@@ -154,17 +167,17 @@ func TestChunk_AppendBytes2(t *testing.T) {
 		}
 	}()
 
-	chunk, err = CreateChunkFromBytes(bigBytes, &tempDir, trx)
+	chunk, err = CreateChunkFromBytes(0, bigBytes, &tempDir, trx)
 	assert.Nil(t, err)
 	assert.Equal(t, 5, chunk.Size)
 	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", chunk.Hash)
 
-	chunk2, err := CreateChunkFromBytes(contentBytes, &tempDir, trx)
+	chunk2, err := CreateChunkFromBytes(0, contentBytes, &tempDir, trx)
 	assert.Nil(t, err)
 	assert.Equal(t, 11, chunk2.Size)
 	assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", chunk2.Hash)
 
-	chunkTmp, writeCount, err := chunk.AppendBytes([]byte(" world"), &tempDir, trx)
+	chunkTmp, writeCount, err := chunk.AppendBytes(0, []byte(" world"), &tempDir, trx)
 	assert.Nil(t, err)
 	assert.Equal(t, 6, writeCount)
 	assert.Equal(t, 11, chunkTmp.Size)
@@ -190,7 +203,7 @@ func TestChunk_AppendBytes3(t *testing.T) {
 		}
 	}()
 
-	chunk, err = CreateChunkFromBytes(bigBytes, &tempDir, trx)
+	chunk, err = CreateChunkFromBytes(0, bigBytes, &tempDir, trx)
 	assert.Nil(t, err)
 	assert.Equal(t, 5, chunk.Size)
 	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", chunk.Hash)
@@ -207,7 +220,7 @@ func TestChunk_AppendBytes3(t *testing.T) {
 		Number:   1,
 	}).Error)
 
-	newChunk, writeCount, err := chunk.AppendBytes([]byte(" world"), &tempDir, trx)
+	newChunk, writeCount, err := chunk.AppendBytes(0, []byte(" world"), &tempDir, trx)
 	assert.Nil(t, err)
 	assert.Equal(t, 6, writeCount)
 	assert.Equal(t, 11, newChunk.Size)
@@ -231,10 +244,17 @@ func TestCreateEmptyContentChunk(t *testing.T) {
 		}
 	}()
 
-	chunk, err = CreateEmptyContentChunk(&tempDir, trx)
+	chunk, err = CreateEmptyContentChunk(0, &tempDir, trx)
 	assert.Nil(t, err)
 	assert.True(t, chunk.ID > 0)
 	fmt.Println(tempDir)
+
+	// the content of empty chunk is lost
+	assert.Nil(t, chunkStore(&tempDir).Delete(chunk.ID))
+	found, err := CreateEmptyContentChunk(0, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, chunk.ID, found.ID)
+	assert.Nil(t, found.Verify(&tempDir))
 }
 
 func TestChunk_Reader(t *testing.T) {
@@ -255,7 +275,7 @@ func TestChunk_Reader(t *testing.T) {
 		}
 	}()
 
-	chunk, err = CreateChunkFromBytes(randomBytes, &tempDir, trx)
+	chunk, err = CreateChunkFromBytes(0, randomBytes, &tempDir, trx)
 	assert.Nil(t, err)
 	randomBytesHash, err := util.Sha256Hash2String(randomBytes)
 	assert.Nil(t, err)
@@ -269,3 +289,351 @@ func TestChunk_Reader(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, allContentHash, randomBytesHash)
 }
+
+func TestCreateChunkFromBytes2(t *testing.T) {
+	var (
+		content = []byte(strings.Repeat("bigfile ", 1000))
+		tempDir = NewTempDirForTest()
+	)
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	restore := useCompressionForTest(ChunkCodecGzip)
+	chunk, err := CreateChunkFromBytes(0, content, &tempDir, trx)
+	restore()
+	assert.Nil(t, err)
+	assert.Equal(t, ChunkCodecGzip, chunk.Codec)
+	assert.Equal(t, len(content), chunk.Size)
+
+	fileInfo, err := os.Stat(chunk.Path(&tempDir))
+	assert.Nil(t, err)
+	assert.True(t, fileInfo.Size() < int64(len(content)))
+
+	reader, err := chunk.Reader(&tempDir)
+	assert.Nil(t, err)
+	readContent, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, content, readContent)
+
+	// the same content is recognized, whether compression is enabled or not
+	chunk2, err := CreateChunkFromBytes(0, content, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, chunk.ID, chunk2.ID)
+
+	// compressed chunk is saved again when content is appended
+	chunk3, _, err := chunk.AppendBytes(0, []byte("appended"), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, len(content)+8, chunk3.Size)
+	reader, err = chunk3.Reader(&tempDir)
+	assert.Nil(t, err)
+	readContent, err = ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, append(content, "appended"...), readContent)
+
+	chunk.Codec = "unknown"
+	_, err = chunk.Reader(&tempDir)
+	assert.NotNil(t, err)
+}
+
+func TestCreateChunkFromBytes3(t *testing.T) {
+	var (
+		content = []byte(strings.Repeat("bigfile ", 1000))
+		tempDir = NewTempDirForTest()
+		key1    = Random(32)
+	)
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	// chunks that are created before encryption is enabled
+	plain, err := CreateChunkFromBytes(0, []byte("plaintext"), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(0), plain.KeyID)
+
+	restore := useKeyringForTest(t, tempDir, 1, map[uint32][]byte{1: key1})
+	defer restore()
+
+	chunk, err := CreateChunkFromBytes(0, content, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), chunk.KeyID)
+	stored, err := ioutil.ReadFile(chunk.Path(&tempDir))
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(stored, []byte("bigfile")))
+
+	for _, c := range []*Chunk{chunk, plain} {
+		reader, err := c.Reader(&tempDir)
+		assert.Nil(t, err)
+		readContent, err := ioutil.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.Equal(t, c.Size, len(readContent))
+	}
+
+	// encrypted chunk is saved again when content is appended, plaintext
+	// chunk is encrypted at the same time
+	for _, c := range []*Chunk{chunk, plain} {
+		size := c.Size
+		c, _, err = c.AppendBytes(0, []byte("appended"), &tempDir, trx)
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(1), c.KeyID)
+		reader, err := c.Reader(&tempDir)
+		assert.Nil(t, err)
+		readContent, err := ioutil.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.Equal(t, size+8, len(readContent))
+		assert.True(t, bytes.HasSuffix(readContent, []byte("appended")))
+	}
+
+	// encryption can't be read without keyring
+	config.DefaultConfig.Chunk.Keyring = ""
+	_, err = chunk.Reader(&tempDir)
+	assert.Equal(t, ErrKeyNotFound, err)
+}
+
+func TestCreateChunkFromBytes4(t *testing.T) {
+	var (
+		content = Random(256)
+		tempDir = NewTempDirForTest()
+	)
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	// content is shared by apps when encryption is disabled
+	chunk1, err := CreateChunkFromBytes(10, content, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), chunk1.AppID)
+	chunk2, err := CreateChunkFromBytes(11, content, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, chunk1.ID, chunk2.ID)
+
+	// plaintext is read from store directly
+	reader, err := chunk1.Reader(&tempDir)
+	assert.Nil(t, err)
+	_, buffered := reader.(*bytesChunkReader)
+	assert.False(t, buffered)
+	assert.Nil(t, reader.Close())
+
+	// content is only shared in app when encryption is enabled
+	defer useKeyringForTest(t, tempDir, 1, map[uint32][]byte{1: Random(32)})()
+	chunk1, err = CreateChunkFromBytes(10, content, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), chunk1.AppID)
+	chunk2, err = CreateChunkFromBytes(11, content, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(11), chunk2.AppID)
+	assert.NotEqual(t, chunk1.ID, chunk2.ID)
+	chunk3, err := CreateChunkFromBytes(10, content, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, chunk1.ID, chunk3.ID)
+
+	for _, c := range []*Chunk{chunk1, chunk2} {
+		reader, err = c.Reader(&tempDir)
+		assert.Nil(t, err)
+		readContent, err := ioutil.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.Equal(t, content, readContent)
+	}
+
+	// the content of app can't be read by the keys of another app
+	chunk1.AppID = 11
+	_, err = chunk1.Reader(&tempDir)
+	assert.NotNil(t, err)
+
+	// plaintext chunk is still read from store directly
+	plain, err := FindChunkByHash(0, chunk2.Hash, trx)
+	assert.Nil(t, err)
+	reader, err = plain.Reader(&tempDir)
+	assert.Nil(t, err)
+	_, buffered = reader.(*bytesChunkReader)
+	assert.False(t, buffered)
+	readContent, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, content, readContent)
+	assert.Nil(t, reader.Close())
+}
+
+func TestChunk_Rekey(t *testing.T) {
+	var (
+		tempDir = NewTempDirForTest()
+		key1    = Random(32)
+		content = []byte("hello world")
+	)
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	plain, err := CreateChunkFromBytes(0, content, &tempDir, trx)
+	assert.Nil(t, err)
+
+	// nothing happens when encryption is disabled
+	count, err := RekeyChunks(10, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	restore := useKeyringForTest(t, tempDir, 1, map[uint32][]byte{1: key1})
+	defer restore()
+	encrypted, err := CreateChunkFromBytes(0, Random(64), &tempDir, trx)
+	assert.Nil(t, err)
+
+	// rotate the key, the old key is still able to decrypt the old chunks
+	useKeyringForTest(t, tempDir, 2, map[uint32][]byte{1: key1, 2: Random(32)})
+	for _, c := range []*Chunk{plain, encrypted} {
+		reader, err := c.Reader(&tempDir)
+		assert.Nil(t, err)
+		_, err = ioutil.ReadAll(reader)
+		assert.Nil(t, err)
+	}
+
+	count, err = RekeyChunks(1000, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.True(t, count >= 2)
+	count, err = RekeyChunks(1000, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	chunk, err := FindChunkByHash(0, plain.Hash, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), chunk.KeyID)
+	reader, err := chunk.Reader(&tempDir)
+	assert.Nil(t, err)
+	readContent, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, content, readContent)
+
+	// the chunk is readable even if db isn't updated after rekeying
+	for _, keyID := range []uint32{1, 0} {
+		plain.KeyID = keyID
+		reader, err = plain.Reader(&tempDir)
+		assert.Nil(t, err)
+		readContent, err = ioutil.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.Equal(t, content, readContent)
+	}
+
+	// the plaintext that looks like encrypted content is kept
+	fake := append([]byte{sealedChunkVersion, 0, 0, 0, 2}, Random(64)...)
+	chunk, err = CreateChunkFromBytes(0, fake, &tempDir, trx)
+	assert.Nil(t, err)
+	chunk.KeyID = 0
+	assert.Nil(t, chunkStore(&tempDir).Put(chunk.ID, fake))
+	reader, err = chunk.Reader(&tempDir)
+	assert.Nil(t, err)
+	readContent, err = ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, fake, readContent)
+}
+
+func TestRekeyChunks(t *testing.T) {
+	var (
+		tempDir = NewTempDirForTest()
+		shared  = Random(32)
+		own     = Random(32)
+		old     = config.DefaultConfig.Chunk.Keyring
+	)
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	defer func() {
+		down(t)
+		config.DefaultConfig.Chunk.Keyring = old
+		SetDefaultKeyring(nil)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+	useAppKeys := func(current uint32, keys map[uint32][]byte) {
+		keyring, err := NewKeyring(1, map[uint32][]byte{1: shared})
+		assert.Nil(t, err)
+		appKeyring, err := NewKeyring(current, keys)
+		assert.Nil(t, err)
+		assert.Nil(t, keyring.SetAppKeys(10, appKeyring))
+		config.DefaultConfig.Chunk.Keyring = "keyring.yaml"
+		SetDefaultKeyring(keyring)
+	}
+
+	useAppKeys(3, map[uint32][]byte{3: own})
+	chunk1, err := CreateChunkFromBytes(10, Random(64), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), chunk1.KeyID)
+	chunk2, err := CreateChunkFromBytes(11, Random(64), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), chunk2.KeyID)
+	_, err = RekeyChunks(1000, &tempDir, trx)
+	assert.Nil(t, err)
+
+	// only the chunks of app whose key is rotated are encrypted again
+	useAppKeys(4, map[uint32][]byte{3: own, 4: Random(32)})
+	count, err := RekeyChunks(1000, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.Nil(t, trx.First(chunk1, chunk1.ID).Error)
+	assert.Equal(t, uint32(4), chunk1.KeyID)
+	assert.Nil(t, trx.First(chunk2, chunk2.ID).Error)
+	assert.Equal(t, uint32(1), chunk2.KeyID)
+	reader, err := chunk1.Reader(&tempDir)
+	assert.Nil(t, err)
+	readContent, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, chunk1.Size, len(readContent))
+}
+
+func TestFindDuplicateChunk(t *testing.T) {
+	var (
+		tempDir = NewTempDirForTest()
+		store   = chunkStore(&tempDir)
+		errDup  = errors.New("Error 1062: Duplicate entry")
+	)
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	defer func() {
+		down(t)
+		os.RemoveAll(tempDir)
+	}()
+
+	content := Random(256)
+	chunk, err := CreateChunkFromBytes(0, content, &tempDir, trx)
+	assert.Nil(t, err)
+
+	found, err := findDuplicateChunk(0, chunk.Hash, content, errDup, store, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, chunk.ID, found.ID)
+
+	_, err = findDuplicateChunk(0, chunk.Hash, content, errors.New("connection refused"), store, trx)
+	assert.Equal(t, "connection refused", err.Error())
+
+	// the quarantined chunk is repaired by content
+	assert.Nil(t, store.Put(chunk.ID, Random(256)))
+	assert.Nil(t, trx.Model(chunk).UpdateColumn("quarantined", 1).Error)
+	_, err = findReusableChunk(0, chunk.Hash, store, trx)
+	assert.Equal(t, ErrChunkNotExist, err)
+	found, err = findDuplicateChunk(0, chunk.Hash, content, errDup, store, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, int8(0), found.Quarantined)
+	assert.Nil(t, found.Verify(&tempDir))
+	found, err = findReusableChunk(0, chunk.Hash, store, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, chunk.ID, found.ID)
+
+	// the content of chunk is lost, it's written to store again
+	assert.Nil(t, store.Delete(chunk.ID))
+	_, err = findReusableChunk(0, chunk.Hash, store, trx)
+	assert.Equal(t, ErrChunkNotExist, err)
+	found, err = findDuplicateChunk(0, chunk.Hash, content, errDup, store, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, chunk.ID, found.ID)
+	assert.Nil(t, found.Verify(&tempDir))
+}
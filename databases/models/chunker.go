@@ -0,0 +1,202 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/bigfile/bigfile/config"
+)
+
+var (
+	// ErrInvalidCDCSize represent that the sizes of content-defined chunking are
+	// illegal, they must satisfy 0 < min <= avg <= max <= ChunkSize
+	ErrInvalidCDCSize = fmt.Errorf("cdc sizes must satisfy 0 < min <= avg <= max <= %d", ChunkSize)
+
+	// gearTable is used by the rolling hash of content-defined chunking. The values
+	// are generated from a fixed seed, they must never be changed, otherwise, the
+	// chunks of the same content will be different from the saved ones.
+	gearTable = newGearTable(0x62696766696c65)
+)
+
+// Chunker is used to split content into chunks
+type Chunker interface {
+	// Next return the next chunk of content, the returned bytes are only valid until
+	// the next call. io.EOF is returned when content is exhausted. If reading content
+	// fails, the content read before is returned first, then the error.
+	Next() ([]byte, error)
+}
+
+// NewChunker is used to create a chunker that reads content from reader by config
+func NewChunker(reader io.Reader, cfg *config.Chunk) (Chunker, error) {
+	switch cfg.Chunker {
+	case "", "fixed":
+		return NewFixedChunker(reader), nil
+	case "cdc":
+		return NewCDCChunker(reader, cfg.CDC.MinSize, cfg.CDC.AvgSize, cfg.CDC.MaxSize)
+	}
+	return nil, fmt.Errorf("unknown chunker: %s", cfg.Chunker)
+}
+
+// newDefaultChunker create a chunker by the global config
+func newDefaultChunker(reader io.Reader) (Chunker, error) {
+	return NewChunker(reader, &config.DefaultConfig.Chunk)
+}
+
+// isFixedChunking represent whether content is split into chunks of the same size
+func isFixedChunking() bool {
+	chunker := config.DefaultConfig.Chunk.Chunker
+	return chunker == "" || chunker == "fixed"
+}
+
+// FixedChunker split content into chunks of ChunkSize, except the last one
+type FixedChunker struct {
+	reader io.Reader
+	buf    []byte
+	err    error
+}
+
+// NewFixedChunker is used to create a fixed-size chunker
+func NewFixedChunker(reader io.Reader) *FixedChunker {
+	return &FixedChunker{reader: reader, buf: make([]byte, ChunkSize)}
+}
+
+// Next implements Chunker
+func (f *FixedChunker) Next() ([]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	readLen, err := io.ReadFull(f.reader, f.buf)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	if readLen == 0 {
+		return nil, err
+	}
+	f.err = err
+	return f.buf[:readLen], nil
+}
+
+// CDCChunker split content by FastCDC, a content-defined chunking algorithm. A gear
+// based rolling hash is calculated over content, and content is cut at the position
+// where the hash matches a mask. So the boundaries of chunks are decided by content
+// itself, inserting or deleting some bytes only changes the chunks around them. The
+// sizes of chunks are between min and max, and are normalized around avg.
+type CDCChunker struct {
+	reader   io.Reader
+	buf      []byte
+	start    int
+	end      int
+	err      error
+	min      int
+	avg      int
+	maskHard uint64
+	maskEasy uint64
+}
+
+// NewCDCChunker is used to create a content-defined chunker
+func NewCDCChunker(reader io.Reader, min, avg, max int) (*CDCChunker, error) {
+	if min <= 0 || min > avg || avg > max || max > ChunkSize {
+		return nil, ErrInvalidCDCSize
+	}
+	var avgBits = bits.Len(uint(avg)) - 1
+	return &CDCChunker{
+		reader: reader,
+		buf:    make([]byte, max),
+		min:    min,
+		avg:    avg,
+		// more bits are harder to match, it's used before avg, so that chunks smaller
+		// than avg are rare. And fewer bits are used after avg for the opposite reason.
+		maskHard: gearMask(avgBits + 2),
+		maskEasy: gearMask(avgBits - 2),
+	}, nil
+}
+
+// Next implements Chunker
+func (c *CDCChunker) Next() ([]byte, error) {
+	if c.start > 0 {
+		c.end = copy(c.buf, c.buf[c.start:c.end])
+		c.start = 0
+	}
+
+	if c.err == nil && c.end < len(c.buf) {
+		var readLen int
+		readLen, c.err = io.ReadFull(c.reader, c.buf[c.end:])
+		if c.err == io.ErrUnexpectedEOF {
+			c.err = io.EOF
+		}
+		c.end += readLen
+	}
+
+	if c.end == 0 {
+		if c.err == nil {
+			return nil, io.EOF
+		}
+		return nil, c.err
+	}
+
+	c.start = c.cut(c.buf[:c.end])
+	return c.buf[:c.start], nil
+}
+
+// cut return the length of the first chunk of p
+func (c *CDCChunker) cut(p []byte) int {
+	var (
+		fp     uint64
+		i      = c.min
+		n      = len(p)
+		normal = c.avg
+	)
+
+	if n <= c.min {
+		return n
+	}
+	if normal > n {
+		normal = n
+	}
+
+	for ; i < normal; i++ {
+		fp = (fp << 1) + gearTable[p[i]]
+		if fp&c.maskHard == 0 {
+			return i + 1
+		}
+	}
+
+	for ; i < n; i++ {
+		fp = (fp << 1) + gearTable[p[i]]
+		if fp&c.maskEasy == 0 {
+			return i + 1
+		}
+	}
+
+	return n
+}
+
+// gearMask return a mask with the n highest bits set. The highest bits of gear hash
+// are affected by the most recent 64 bytes, so that they form a sliding window.
+func gearMask(n int) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	if n > 64 {
+		n = 64
+	}
+	return ^uint64(0) << uint(64-n)
+}
+
+// newGearTable generate the random values of gear hash by splitmix64
+func newGearTable(seed uint64) [256]uint64 {
+	var table [256]uint64
+	for index := range table {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[index] = z ^ (z >> 31)
+	}
+	return table
+}
@@ -0,0 +1,133 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// useCDCForTest switch the default chunker to content-defined chunking with small
+// sizes, the returned function is used to restore it.
+func useCDCForTest() func() {
+	var old = config.DefaultConfig.Chunk
+	config.DefaultConfig.Chunk.Chunker = "cdc"
+	config.DefaultConfig.Chunk.CDC = config.ChunkCDC{MinSize: 1 << 10, AvgSize: 4 << 10, MaxSize: 16 << 10}
+	return func() {
+		config.DefaultConfig.Chunk = old
+	}
+}
+
+func splitForTest(t *testing.T, chunker Chunker) [][]byte {
+	var chunks [][]byte
+	for {
+		content, err := chunker.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		assert.Nil(t, err)
+		chunks = append(chunks, append([]byte{}, content...))
+	}
+}
+
+func TestNewChunker(t *testing.T) {
+	chunker, err := NewChunker(bytes.NewReader(nil), &config.Chunk{})
+	assert.Nil(t, err)
+	assert.IsType(t, &FixedChunker{}, chunker)
+
+	chunker, err = NewChunker(bytes.NewReader(nil), &config.Chunk{
+		Chunker: "cdc",
+		CDC:     config.ChunkCDC{MinSize: 1, AvgSize: 2, MaxSize: 3},
+	})
+	assert.Nil(t, err)
+	assert.IsType(t, &CDCChunker{}, chunker)
+
+	_, err = NewChunker(bytes.NewReader(nil), &config.Chunk{Chunker: "unknown"})
+	assert.NotNil(t, err)
+
+	for _, sizes := range [][3]int{{0, 1, 2}, {3, 2, 4}, {1, 3, 2}, {1, 2, ChunkSize + 1}} {
+		_, err = NewCDCChunker(bytes.NewReader(nil), sizes[0], sizes[1], sizes[2])
+		assert.Equal(t, ErrInvalidCDCSize, err)
+	}
+}
+
+func TestFixedChunker_Next(t *testing.T) {
+	content := Random(2*ChunkSize + 10)
+	chunks := splitForTest(t, NewFixedChunker(bytes.NewReader(content)))
+	assert.Equal(t, 3, len(chunks))
+	assert.Equal(t, ChunkSize, len(chunks[0]))
+	assert.Equal(t, ChunkSize, len(chunks[1]))
+	assert.Equal(t, 10, len(chunks[2]))
+	assert.Equal(t, content, bytes.Join(chunks, nil))
+
+	assert.Equal(t, 0, len(splitForTest(t, NewFixedChunker(bytes.NewReader(nil)))))
+}
+
+func TestCDCChunker_Next(t *testing.T) {
+	var (
+		min, avg, max = 1 << 10, 4 << 10, 16 << 10
+		content       = Random(256 << 10)
+	)
+
+	chunker, err := NewCDCChunker(bytes.NewReader(content), min, avg, max)
+	assert.Nil(t, err)
+	chunks := splitForTest(t, chunker)
+	assert.True(t, len(chunks) > 1)
+	assert.Equal(t, content, bytes.Join(chunks, nil))
+	for index, chunk := range chunks {
+		assert.True(t, len(chunk) <= max)
+		if index < len(chunks)-1 {
+			assert.True(t, len(chunk) >= min)
+		}
+	}
+
+	// the same content is always split into the same chunks
+	chunker, err = NewCDCChunker(iotest.OneByteReader(bytes.NewReader(content)), min, avg, max)
+	assert.Nil(t, err)
+	assert.Equal(t, chunks, splitForTest(t, chunker))
+
+	// inserting some bytes only changes the chunks around them
+	modified := append(append(append([]byte{}, content[:100]...), 'a', 'b', 'c'), content[100:]...)
+	chunker, err = NewCDCChunker(bytes.NewReader(modified), min, avg, max)
+	assert.Nil(t, err)
+	modifiedChunks := splitForTest(t, chunker)
+	assert.Equal(t, modified, bytes.Join(modifiedChunks, nil))
+	var (
+		same   int
+		hashes = make(map[string]bool)
+	)
+	for _, chunk := range chunks {
+		hashes[string(chunk)] = true
+	}
+	for _, chunk := range modifiedChunks {
+		if hashes[string(chunk)] {
+			same++
+		}
+	}
+	assert.True(t, same >= len(chunks)-2)
+}
+
+func TestCDCChunker_Next2(t *testing.T) {
+	content := Random(8 << 10)
+	chunker, err := NewCDCChunker(iotest.TimeoutReader(bytes.NewReader(content)), 1<<10, 4<<10, 16<<10)
+	assert.Nil(t, err)
+
+	// the content read before an error is returned first
+	var received []byte
+	for {
+		chunk, err := chunker.Next()
+		if err != nil {
+			assert.Equal(t, iotest.ErrTimeout, err)
+			break
+		}
+		received = append(received, chunk...)
+	}
+	assert.Equal(t, content, received)
+}
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/bigfile/bigfile/internal/util"
 	"github.com/jinzhu/gorm"
@@ -29,6 +30,28 @@ var (
 	ErrReadDir = errors.New("can't read a directory")
 	// ErrAccessDenied represent a file can't be accessed by some tokens
 	ErrAccessDenied = errors.New("file can't be accessed by some tokens")
+	// ErrDeleteRootDir represent that try to delete the root directory of app
+	ErrDeleteRootDir = errors.New("root directory can't be deleted")
+	// ErrFileNotInTrash represent that the file isn't deleted directly, it can't
+	// be restored or purged alone
+	ErrFileNotInTrash = errors.New("file isn't in trash")
+	// ErrMoveIntoItself represent that try to move a directory into itself
+	ErrMoveIntoItself = errors.New("directory can't be moved into itself")
+	// ErrCopyIntoItself represent that try to copy a directory into itself
+	ErrCopyIntoItself = errors.New("directory can't be copied into itself")
+	// ErrListFile represent that try to list a file, only directory can be listed
+	ErrListFile = errors.New("can't list a file, only directory")
+
+	sortableFileColumns = map[string]string{
+		"name":      "name",
+		"size":      "size",
+		"createdAt": "createdAt",
+		"updatedAt": "updatedAt",
+	}
+
+	// likeEscaper escapes the wildcards of LIKE with '!', so that they match literally.
+	// The escape character is given explicitly, backslash depends on sql mode.
+	likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
 )
 
 // File represent a file or a directory of system. If it's a file
@@ -43,12 +66,15 @@ type File struct {
 	Size          int        `gorm:"type:int;column:size"`
 	Name          string     `gorm:"type:VARCHAR(255);NOT NULL;column:name"`
 	Ext           string     `gorm:"type:VARCHAR(255);NOT NULL;column:ext"`
+	FullPath      string     `gorm:"type:VARCHAR(2048);NOT NULL;column:path"`
 	IsDir         int8       `gorm:"type:tinyint;column:isDir;DEFAULT:0"`
 	Hidden        int8       `gorm:"type:tinyint;column:hidden;DEFAULT:0"`
+	Quarantined   int8       `gorm:"type:tinyint;column:quarantined;DEFAULT:0"`
 	DownloadCount uint64     `gorm:"type:BIGINT(20);column:downloadCount;DEFAULT:0"`
 	CreatedAt     time.Time  `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:createdAt"`
 	UpdatedAt     time.Time  `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:updatedAt"`
 	DeletedAt     *time.Time `gorm:"type:TIMESTAMP(6);INDEX;column:deletedAt"`
+	DeletedID     uint64     `gorm:"type:BIGINT(20) UNSIGNED NOT NULL;column:deletedId;DEFAULT:0"`
 
 	Object    Object    `gorm:"foreignkey:objectId;association_autoupdate:false;association_autocreate:false"`
 	App       App       `gorm:"foreignkey:appId;association_autoupdate:false;association_autocreate:false"`
@@ -60,11 +86,45 @@ func pathCacheKey(app *App, path string) string {
 	return fmt.Sprintf("%d-%s", app.ID, path)
 }
 
+// deletePathCache will remove the cache of path and all paths under it
+func deletePathCache(app *App, path string) {
+	var (
+		cacheKey  = pathCacheKey(app, path)
+		keyPrefix = strings.TrimSuffix(cacheKey, "/") + "/"
+	)
+	pathToFileCache.Delete(cacheKey)
+	for key := range pathToFileCache.Items() {
+		if strings.HasPrefix(key, keyPrefix) {
+			pathToFileCache.Delete(key)
+		}
+	}
+}
+
+// subtreePattern return the LIKE pattern that matches the paths of all files
+// under the directory whose path is path
+func subtreePattern(path string) string {
+	return likeEscaper.Replace(strings.TrimSuffix(path, "/")) + "/%"
+}
+
 // TableName represent the name of files table
 func (f *File) TableName() string {
 	return "files"
 }
 
+// BeforeCreate hooks will be called automatically before file created, the path
+// of file is generated by its parent if it's not set
+func (f *File) BeforeCreate(tx *gorm.DB) error {
+	if f.FullPath != "" || f.PID == 0 {
+		return nil
+	}
+	var parent = &File{}
+	if err := tx.Unscoped().Select("path").Where("id = ?", f.PID).Find(parent).Error; err != nil {
+		return err
+	}
+	f.FullPath = parent.FullPath + "/" + f.Name
+	return nil
+}
+
 // CanBeAccessedByToken represent whether the file can be accessed by the token
 func (f *File) CanBeAccessedByToken(token *Token, db *gorm.DB) error {
 	var (
@@ -81,8 +141,8 @@ func (f *File) CanBeAccessedByToken(token *Token, db *gorm.DB) error {
 }
 
 // Reader is used to get reader that continues to read data from underlying
-// chunk until io.EOF
-func (f *File) Reader(rootPath *string, db *gorm.DB) (io.Reader, error) {
+// chunk until io.EOF, the reader is also able to seek.
+func (f *File) Reader(rootPath *string, db *gorm.DB) (ObjectReader, error) {
 	if f.IsDir == 1 {
 		return nil, ErrReadDir
 	}
@@ -90,66 +150,106 @@ func (f *File) Reader(rootPath *string, db *gorm.DB) (io.Reader, error) {
 		err error
 	)
 	if len(f.Object.Chunks) == 0 {
-		if err = db.Preload("Chunks").Find(&f.Object).Error; err != nil {
+		if err = db.Preload("Chunks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("object_chunk.number asc")
+		}).Where("id = ?", f.ObjectID).Find(&f.Object).Error; err != nil {
 			return nil, err
 		}
 	}
 	return (&f.Object).Reader(rootPath)
 }
 
-// Path is used to get the complete path of file
+// Path is used to get the complete path of file. It's loaded again, because the
+// ancestors may be moved after the file is loaded. The path of root directory is
+// empty.
 func (f *File) Path(db *gorm.DB) (string, error) {
-	var (
-		err     error
-		parts   []string
-		current = *f
-	)
-	for {
-		parts = append(parts, current.Name)
-		if current.PID == 0 {
-			break
-		}
-		temp := &File{}
-		if err = db.Where("id = ?", current.PID).Find(temp).Error; err != nil {
-			return "", err
-		}
-		current = *temp
+	var file = &File{}
+	// the file may be deleted
+	if err := db.Unscoped().Select("path").Where("id = ?", f.ID).Find(file).Error; err != nil {
+		return "", err
 	}
-
-	util.ReverseSlice(parts)
-
-	return strings.Join(parts, "/"), nil
+	f.FullPath = file.FullPath
+	return file.FullPath, nil
 }
 
-// UpdateParentSize is used to update parent size. note, size may be a negative number.
+// UpdateParentSize is used to update the size of directory and all its ancestors
+// by one statement. note, size may be a negative number.
 func (f *File) UpdateParentSize(size int, db *gorm.DB) error {
-	if err := db.Model(f).UpdateColumn("size", gorm.Expr("size + ?", size)).Error; err != nil {
+	ids, err := ancestorIDs(f.ID, db)
+	if err != nil {
 		return err
 	}
-	if f.PID == 0 {
-		return nil
-	}
-	if err := db.Preload("Parent").Find(f).Error; err != nil {
+	if err = updateSize(ids, size, db); err != nil {
 		return err
 	}
-	return f.Parent.UpdateParentSize(size, db)
+	f.Size += size
+	return nil
 }
 
-func (f *File) createHistory(objectID uint64, path string, db *gorm.DB) error {
-	return db.Save(&History{ObjectID: objectID, FileID: f.ID, Path: path}).Error
+// createHistory save the object and the path of file as a history, moved represent
+// that only the path of file is changed
+func (f *File) createHistory(objectID uint64, path string, moved int8, db *gorm.DB) error {
+	return db.Save(&History{ObjectID: objectID, FileID: f.ID, Path: path, Moved: moved}).Error
 }
 
-// OverWriteFromReader is used to overwrite the object
+// OverWriteFromReader is used to overwrite the object, all changes are made in
+// a transaction. The file is locked before the content is saved, so that no rows
+// are held by the transaction while it waits for the lock.
 func (f *File) OverWriteFromReader(reader io.Reader, hidden int8, rootPath *string, db *gorm.DB) error {
 
 	if f.IsDir == 1 {
 		return ErrOverwriteDir
 	}
 
+	return f.withLock(db, func(tx *gorm.DB) error {
+		object, err := CreateObjectFromReader(f.AppID, reader, rootPath, tx)
+		if err != nil {
+			return err
+		}
+		return f.replaceObject(object, hidden, tx)
+	})
+}
+
+// RestoreHistory is used to restore the content of file to the version of history.
+// The current content is saved as a history as well, so it can be restored too.
+func (f *File) RestoreHistory(history *History, db *gorm.DB) error {
+
+	if f.IsDir == 1 {
+		return ErrOverwriteDir
+	}
+
+	if history.FileID != f.ID {
+		return ErrHistoryNotBelongToFile
+	}
+
+	if history.Object.ID == 0 {
+		if err := db.Where("id = ?", history.ObjectID).Find(&history.Object).Error; err != nil {
+			return err
+		}
+	}
+
+	return f.withLock(db, func(tx *gorm.DB) error {
+		return f.replaceObject(&history.Object, f.Hidden, tx)
+	})
+}
+
+// OverWriteFromObject is used to overwrite the file by an existing object, the
+// content isn't copied
+func (f *File) OverWriteFromObject(object *Object, hidden int8, db *gorm.DB) error {
+	if f.IsDir == 1 {
+		return ErrOverwriteDir
+	}
+	return f.withLock(db, func(tx *gorm.DB) error {
+		return f.replaceObject(object, hidden, tx)
+	})
+}
+
+// replaceObject is used to replace the object of file, the previous object is
+// saved as a history
+func (f *File) replaceObject(object *Object, hidden int8, db *gorm.DB) error {
 	var (
 		err      error
 		path     string
-		object   *Object
 		sizeDiff int
 	)
 
@@ -157,24 +257,22 @@ func (f *File) OverWriteFromReader(reader io.Reader, hidden int8, rootPath *stri
 		return err
 	}
 
-	if err := f.createHistory(f.ObjectID, path, db); err != nil {
-		return err
-	}
-
-	if object, err = CreateObjectFromReader(reader, rootPath, db); err != nil {
+	if err = f.createHistory(f.ObjectID, path, 0, db); err != nil {
 		return err
 	}
 
 	f.Object = *object
 	f.ObjectID = object.ID
 	f.Hidden = hidden
-	sizeDiff = object.Size - f.Size
+	f.Quarantined = 0
+	sizeDiff = int(object.Size) - f.Size
 	f.Size += sizeDiff
 
 	if err = db.Model(f).Update(map[string]interface{}{
-		"objectId": object.ID,
-		"hidden":   hidden,
-		"size":     f.Size,
+		"objectId":    object.ID,
+		"hidden":      hidden,
+		"size":        f.Size,
+		"quarantined": 0,
 	}).Error; err != nil {
 		return err
 	}
@@ -198,75 +296,685 @@ func (f *File) mustPath(db *gorm.DB) string {
 }
 
 // MoveTo move file to another path, the input path must be complete and new path.
-// if the input path ios the same as the previous path, nothing changes.
+// if the input path ios the same as the previous path, nothing changes. If file is
+// a directory, all files under it are moved together, a directory can't be moved
+// into itself. The previous paths of moved files are saved as histories. All
+// changes are made in a transaction that holds the lock of file.
 func (f *File) MoveTo(newPath string, db *gorm.DB) error {
+	if f.App.ID == 0 {
+		if err := db.Preload("App").Find(f).Error; err != nil {
+			return err
+		}
+	}
+
+	return f.withLock(db, func(tx *gorm.DB) error {
+		// the file may be moved by others before the lock is acquired
+		previousPath, err := f.Path(tx)
+		if err != nil {
+			return err
+		}
+
+		if previousPath == newPath {
+			return nil
+		}
+
+		if f.IsDir == 1 && (f.PID == 0 || strings.HasPrefix(newPath, strings.TrimSuffix(previousPath, "/")+"/")) {
+			return ErrMoveIntoItself
+		}
+
+		if file, err := FindFileByPath(&f.App, newPath, tx); err == nil && file.ID > 0 {
+			return ErrFileExisted
+		}
+
+		// the cached paths of the whole subtree are stale, even if moving fails
+		defer deletePathCache(&f.App, previousPath)
+
+		return f.moveTo(previousPath, newPath, tx)
+	})
+}
+
+func (f *File) moveTo(previousPath, newPath string, db *gorm.DB) error {
 	var (
 		err             error
-		newPathDir      = filepath.Dir(newPath)
+		previousPID     = f.PID
 		newPathDirFile  *File
 		newPathFileName = filepath.Base(newPath)
 		newPathExt      = strings.TrimPrefix(filepath.Ext(newPathFileName), ".")
-		previousPath    string
 	)
 
-	if previousPath, err = f.Path(db); err != nil {
+	if newPathDirFile, err = CreateOrGetLastDirectory(&f.App, filepath.Dir(newPath), db); err != nil {
+		return err
+	}
+
+	if err = f.createMovedHistories(previousPath, db); err != nil {
+		return err
+	}
+
+	newPath = newPathDirFile.FullPath + "/" + newPathFileName
+	if err = db.Model(f).UpdateColumns(map[string]interface{}{
+		"pid":  newPathDirFile.ID,
+		"name": newPathFileName,
+		"ext":  newPathExt,
+		"path": newPath,
+	}).Error; err != nil {
+		return err
+	}
+
+	if err = f.updateChildrenPath(previousPath, newPath, db); err != nil {
+		return err
+	}
+
+	if newPathDirFile.ID != previousPID {
+		if err = moveSize(previousPID, newPathDirFile.ID, f.Size, db); err != nil {
+			return err
+		}
+	}
+
+	f.PID = newPathDirFile.ID
+	f.Parent = newPathDirFile
+	f.Name = newPathFileName
+	f.Ext = newPathExt
+	f.FullPath = newPath
+
+	return db.Where("id = ?", newPathDirFile.ID).Find(newPathDirFile).Error
+}
+
+// createMovedHistories save the previous path of file as a moved history. If file
+// is a directory, the previous paths of all files under it are saved.
+func (f *File) createMovedHistories(previousPath string, db *gorm.DB) error {
+	if f.IsDir == 0 {
+		return f.createHistory(f.ObjectID, previousPath, 1, db)
+	}
+
+	var (
+		err   error
+		dirs  = map[uint64]string{f.ID: previousPath}
+		level = []uint64{f.ID}
+	)
+	for len(level) > 0 {
+		var children []File
+		if err = db.Select("id, pid, name, isDir, objectId").Where("pid in (?)", level).
+			Order("id asc").Find(&children).Error; err != nil {
+			return err
+		}
+		level = level[:0]
+		for index := range children {
+			child := &children[index]
+			path := strings.TrimSuffix(dirs[child.PID], "/") + "/" + child.Name
+			if child.IsDir == 1 {
+				dirs[child.ID] = path
+				level = append(level, child.ID)
+				continue
+			}
+			if err = child.createHistory(child.ObjectID, path, 1, db); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// updateChildrenPath replace the prefix previousPath of the paths of all files
+// under the directory with newPath level by level, deleted files are included.
+func (f *File) updateChildrenPath(previousPath, newPath string, db *gorm.DB) error {
+	var (
+		err   error
+		start = utf8.RuneCountInString(previousPath) + 1
+		pids  = []uint64{f.ID}
+	)
+	db = db.Unscoped()
+	for f.IsDir == 1 && len(pids) > 0 {
+		var (
+			ids      []uint64
+			children []File
+		)
+		if err = db.Select("id, isDir").Where("pid in (?)", pids).Find(&children).Error; err != nil {
+			return err
+		}
+		pids = pids[:0]
+		for _, child := range children {
+			ids = append(ids, child.ID)
+			if child.IsDir == 1 {
+				pids = append(pids, child.ID)
+			}
+		}
+		if len(ids) == 0 {
+			break
+		}
+		if err = db.Model(&File{}).Where("id in (?)", ids).
+			UpdateColumn("path", gorm.Expr("concat(?, substring(path, ?))", newPath, start)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ancestorIDs return the ids of directory and all its ancestors, they are found
+// by the prefixes of its path in one query
+func ancestorIDs(dirID uint64, db *gorm.DB) ([]uint64, error) {
+	var (
+		ids   []uint64
+		dir   = &File{}
+		paths = []string{""}
+	)
+	if err := db.Unscoped().Select("id, appId, path").Where("id = ?", dirID).Find(dir).Error; err != nil {
+		return nil, err
+	}
+	for index, char := range dir.FullPath {
+		if char == '/' && index > 0 {
+			paths = append(paths, dir.FullPath[:index])
+		}
+	}
+	if dir.FullPath != "" {
+		paths = append(paths, dir.FullPath)
+	}
+	if err := db.Model(&File{}).Where("appId = ? and path in (?)", dir.AppID, paths).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// moveSize move size from the ancestor chain of fromDirID to the chain of toDirID,
+// the common ancestors are untouched. Each chain is updated by one statement.
+func moveSize(fromDirID, toDirID uint64, size int, db *gorm.DB) error {
+	var (
+		err      error
+		fromIDs  []uint64
+		toIDs    []uint64
+		decrease []uint64
+		increase []uint64
+		inFrom   = make(map[uint64]bool)
+		inTo     = make(map[uint64]bool)
+	)
+	if fromIDs, err = ancestorIDs(fromDirID, db); err != nil {
+		return err
+	}
+	if toIDs, err = ancestorIDs(toDirID, db); err != nil {
+		return err
+	}
+	for _, id := range fromIDs {
+		inFrom[id] = true
+	}
+	for _, id := range toIDs {
+		inTo[id] = true
+		if !inFrom[id] {
+			increase = append(increase, id)
+		}
+	}
+	for _, id := range fromIDs {
+		if !inTo[id] {
+			decrease = append(decrease, id)
+		}
+	}
+	if err = updateSize(decrease, -size, db); err != nil {
 		return err
 	}
+	return updateSize(increase, size, db)
+}
 
-	if previousPath == newPath {
+func updateSize(ids []uint64, size int, db *gorm.DB) error {
+	if len(ids) == 0 || size == 0 {
 		return nil
 	}
+	return db.Unscoped().Model(&File{}).Where("id in (?)", ids).
+		UpdateColumn("size", gorm.Expr("size + ?", size)).Error
+}
+
+// CopyTo copy file to newPath, the input path must be complete. The copy shares
+// the object with file, so no content is copied. If file is a directory, all
+// files under it are copied too. If overwrite is true, existing files are
+// overwritten and existing directories are merged, otherwise ErrFileExisted
+// is returned. The metas are copied along with files, they replace the metas
+// of the overwritten files. The copy of file is returned. All changes are made in a
+// transaction, the files that will be overwritten are locked before that.
+func (f *File) CopyTo(newPath string, overwrite bool, db *gorm.DB) (*File, error) {
+	var (
+		err  error
+		path string
+		file *File
+	)
 
 	if f.App.ID == 0 {
 		if err = db.Preload("App").Find(f).Error; err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	if newPathDirFile, err = CreateOrGetLastDirectory(&f.App, newPathDir, db); err != nil {
+	if f.IsDir == 1 {
+		if path, err = f.Path(db); err != nil {
+			return nil, err
+		}
+		if newPath == path || strings.HasPrefix(newPath, strings.TrimSuffix(path, "/")+"/") {
+			return nil, ErrCopyIntoItself
+		}
+	}
+
+	if err = Transaction(db, func(tx *gorm.DB) error {
+		if overwrite {
+			var ids []uint64
+			if ids, err = f.findOverwrittenFiles(newPath, tx); err != nil {
+				return err
+			}
+			lockFiles(tx, ids...)
+		}
+		file, err = f.copyTo(newPath, overwrite, tx)
 		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// findOverwrittenFiles return the ids of files that will be overwritten if file
+// is copied to newPath
+func (f *File) findOverwrittenFiles(newPath string, db *gorm.DB) ([]uint64, error) {
+	var (
+		err      error
+		ids      []uint64
+		childIDs []uint64
+		existed  *File
+		children []File
+	)
+
+	if existed, err = FindFileByPath(&f.App, newPath, db); err != nil {
+		if util.IsRecordNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if existed.IsDir != f.IsDir {
+		return nil, nil
 	}
 
 	if f.IsDir == 0 {
-		if err = f.createHistory(f.ObjectID, previousPath, db); err != nil {
-			return err
+		return []uint64{existed.ID}, nil
+	}
+
+	if err = db.Where("pid = ?", f.ID).Find(&children).Error; err != nil {
+		return nil, err
+	}
+	for index := range children {
+		child := &children[index]
+		child.App = f.App
+		if childIDs, err = child.findOverwrittenFiles(strings.TrimSuffix(newPath, "/")+"/"+child.Name, db); err != nil {
+			return nil, err
 		}
+		ids = append(ids, childIDs...)
 	}
 
-	f.Name = newPathFileName
-	f.Ext = newPathExt
+	return ids, nil
+}
 
-	defer func() {
-		pathToFileCache.Delete(previousPath)
-		_ = pathToFileCache.Add(pathCacheKey(&f.App, f.mustPath(db)), f, time.Hour*48)
-	}()
+func (f *File) copyTo(newPath string, overwrite bool, db *gorm.DB) (*File, error) {
+	var (
+		err      error
+		existed  *File
+		children []File
+	)
+
+	if existed, err = FindFileByPath(&f.App, newPath, db); err != nil && !util.IsRecordNotFound(err) {
+		return nil, err
+	}
+
+	if existed != nil && existed.ID > 0 {
+		if !overwrite {
+			return nil, ErrFileExisted
+		}
+		if existed.IsDir != f.IsDir {
+			if existed.IsDir == 1 {
+				return nil, ErrOverwriteDir
+			}
+			return nil, ErrFileExisted
+		}
+	}
+
+	if f.IsDir == 0 {
+		if f.Object.ID != f.ObjectID {
+			if err = db.Where("id = ?", f.ObjectID).Find(&f.Object).Error; err != nil {
+				return nil, err
+			}
+		}
+		if existed != nil && existed.ID > 0 {
+			if err = existed.OverWriteFromObject(&f.Object, f.Hidden, db); err != nil {
+				return nil, err
+			}
+		} else if existed, err = CreateFileFromObject(&f.App, newPath, &f.Object, f.Hidden, db); err != nil {
+			return nil, err
+		}
+		return existed, existed.copyMetas(f, db)
+	}
 
-	// only change the file name, still is in the same directory
-	if newPathDirFile.ID == f.PID {
-		return db.Save(f).Error
+	if existed == nil || existed.ID == 0 {
+		if existed, err = CreateOrGetLastDirectory(&f.App, newPath, db); err != nil {
+			return nil, err
+		}
+		if existed.Hidden != f.Hidden {
+			existed.Hidden = f.Hidden
+			if err = db.Model(existed).UpdateColumn("hidden", f.Hidden).Error; err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	if f.Parent == nil || f.Parent.ID == 0 {
-		if err = db.Preload("Parent").Find(f).Error; err != nil {
+	if err = existed.copyMetas(f, db); err != nil {
+		return nil, err
+	}
+
+	if err = db.Where("pid = ?", f.ID).Order("id asc").Find(&children).Error; err != nil {
+		return nil, err
+	}
+	for index := range children {
+		child := &children[index]
+		child.App = f.App
+		if _, err = child.copyTo(strings.TrimSuffix(newPath, "/")+"/"+child.Name, overwrite, db); err != nil {
+			return nil, err
+		}
+	}
+
+	return existed, db.Where("id = ?", existed.ID).Find(existed).Error
+}
+
+// Delete is used to delete file softly. If the file is a directory, all files
+// under it will be deleted together, and they share the same deletedAt. Only
+// the file itself records deletedId, it's used to distinguish the file that
+// is deleted directly from the files that are deleted along with directory.
+// All changes are made in a transaction.
+func (f *File) Delete(db *gorm.DB) error {
+	if f.PID == 0 {
+		return ErrDeleteRootDir
+	}
+
+	var (
+		err       error
+		path      string
+		deletedAt = time.Now()
+	)
+
+	if path, err = f.Path(db); err != nil {
+		return err
+	}
+
+	if f.App.ID == 0 || f.Parent == nil || f.Parent.ID == 0 {
+		if err = db.Preload("App").Preload("Parent").Find(f).Error; err != nil {
 			return err
 		}
 	}
 
-	if err = newPathDirFile.UpdateParentSize(f.Size, db); err != nil {
+	if err = Transaction(db, func(tx *gorm.DB) error {
+		if f.IsDir == 1 {
+			if err := f.deleteChildren(deletedAt, tx); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(f).UpdateColumns(map[string]interface{}{
+			"deletedAt": deletedAt,
+			"deletedId": f.ID,
+		}).Error; err != nil {
+			return err
+		}
+		return f.Parent.UpdateParentSize(-f.Size, tx)
+	}); err != nil {
 		return err
 	}
+	f.DeletedAt = &deletedAt
+	f.DeletedID = f.ID
+
+	deletePathCache(&f.App, path)
+
+	return nil
+}
+
+// deleteChildren is used to delete all files under the directory level by level
+func (f *File) deleteChildren(deletedAt time.Time, db *gorm.DB) error {
+	var (
+		err  error
+		pids = []uint64{f.ID}
+	)
+	for len(pids) > 0 {
+		var dirIDs []uint64
+		if err = db.Model(&File{}).Where("pid in (?) and isDir = 1", pids).Pluck("id", &dirIDs).Error; err != nil {
+			return err
+		}
+		if err = db.Model(&File{}).Where("pid in (?)", pids).UpdateColumn("deletedAt", deletedAt).Error; err != nil {
+			return err
+		}
+		pids = dirIDs
+	}
+	return nil
+}
 
-	if err = f.Parent.UpdateParentSize(-f.Size, db); err != nil {
+// InTrash represent whether the file is deleted directly, only these files
+// can be restored or purged.
+func (f *File) InTrash() bool {
+	return f.DeletedAt != nil && f.DeletedID == f.ID
+}
+
+// Restore is used to restore a file from trash. If newPath is empty, the file
+// will be restored to its original path. If it's a directory, the files deleted
+// along with it will be restored together. All changes are made in a transaction.
+func (f *File) Restore(newPath string, db *gorm.DB) error {
+	if !f.InTrash() {
+		return ErrFileNotInTrash
+	}
+
+	var (
+		err          error
+		parentDir    *File
+		fileName     string
+		previousPath string
+	)
+
+	if previousPath, err = f.Path(db); err != nil {
 		return err
 	}
-	f.PID = newPathDirFile.ID
-	f.Parent = newPathDirFile
-	f.Name = newPathFileName
-	f.Ext = newPathExt
-	return db.Save(f).Error
+
+	if newPath == "" {
+		newPath = previousPath
+	}
+
+	if f.App.ID == 0 {
+		if err = db.Preload("App").Unscoped().Find(f).Error; err != nil {
+			return err
+		}
+	}
+
+	if file, err := FindFileByPath(&f.App, newPath, db); err == nil && file.ID > 0 {
+		return ErrFileExisted
+	}
+
+	fileName = filepath.Base(newPath)
+	if err = Transaction(db, func(tx *gorm.DB) error {
+		var err error
+		if parentDir, err = CreateOrGetLastDirectory(&f.App, filepath.Dir(newPath), tx); err != nil {
+			return err
+		}
+		if f.IsDir == 1 {
+			if err = f.restoreChildren(tx); err != nil {
+				return err
+			}
+		}
+		newPath = parentDir.FullPath + "/" + fileName
+		if err = tx.Unscoped().Model(f).UpdateColumns(map[string]interface{}{
+			"deletedAt": nil,
+			"deletedId": 0,
+			"pid":       parentDir.ID,
+			"name":      fileName,
+			"ext":       strings.TrimPrefix(filepath.Ext(fileName), "."),
+			"path":      newPath,
+		}).Error; err != nil {
+			return err
+		}
+		if newPath != previousPath {
+			if err = f.updateChildrenPath(previousPath, newPath, tx); err != nil {
+				return err
+			}
+		}
+		return parentDir.UpdateParentSize(f.Size, tx)
+	}); err != nil {
+		return err
+	}
+	f.DeletedAt = nil
+	f.DeletedID = 0
+	f.PID = parentDir.ID
+	f.Parent = parentDir
+	f.Name = fileName
+	f.Ext = strings.TrimPrefix(filepath.Ext(fileName), ".")
+	f.FullPath = newPath
+
+	deletePathCache(&f.App, newPath)
+
+	return nil
 }
 
-// AppendFromReader is used to append content from reader to file
+// restoreChildren is used to restore the files that are deleted along with directory
+func (f *File) restoreChildren(db *gorm.DB) error {
+	var (
+		err  error
+		pids = []uint64{f.ID}
+	)
+	db = db.Unscoped()
+	for len(pids) > 0 {
+		var dirIDs []uint64
+		if err = db.Model(&File{}).
+			Where("pid in (?) and isDir = 1 and deletedAt = ? and deletedId = 0", pids, f.DeletedAt).
+			Pluck("id", &dirIDs).Error; err != nil {
+			return err
+		}
+		if err = db.Model(&File{}).
+			Where("pid in (?) and deletedAt = ? and deletedId = 0", pids, f.DeletedAt).
+			UpdateColumn("deletedAt", nil).Error; err != nil {
+			return err
+		}
+		pids = dirIDs
+	}
+	return nil
+}
+
+// Purge is used to delete a file in trash permanently. If it's a directory, all
+// files under it will be deleted permanently too, and histories of these files
+// will be removed. All changes are made in a transaction, the files under the
+// directory are collected by locking reads in it, so that no file is moved into
+// or restored under the directory before they are deleted.
+func (f *File) Purge(db *gorm.DB) error {
+	if !f.InTrash() {
+		return ErrFileNotInTrash
+	}
+
+	return Transaction(db.Unscoped(), func(tx *gorm.DB) error {
+		var (
+			err  error
+			ids  = []uint64{f.ID}
+			pids = []uint64{f.ID}
+		)
+		for f.IsDir == 1 && len(pids) > 0 {
+			var children []File
+			if err = forUpdate(tx).Select("id, isDir").Where("pid in (?)", pids).Find(&children).Error; err != nil {
+				return err
+			}
+			pids = pids[:0]
+			for _, child := range children {
+				ids = append(ids, child.ID)
+				if child.IsDir == 1 {
+					pids = append(pids, child.ID)
+				}
+			}
+		}
+		if err = tx.Where("fileId in (?)", ids).Delete(&History{}).Error; err != nil {
+			return err
+		}
+		if err = tx.Where("fileId in (?)", ids).Delete(&FileMeta{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id in (?)", ids).Delete(&File{}).Error
+	})
+}
+
+// FindTrashedFiles is used to find the files in trash page by page, only the files
+// under the specify path will be returned. Files that deleted recently are in the
+// front. The total number of trashed files is also returned.
+func FindTrashedFiles(app *App, path string, offset, limit int, db *gorm.DB) (int, []File, error) {
+	var (
+		err     error
+		total   int
+		trashed []File
+		query   = db.Unscoped().Model(&File{}).
+			Where("appId = ? and deletedId > 0 and deletedId = id and path like ? escape '!'",
+				app.ID, likeEscaper.Replace(path)+"%")
+	)
+	if err = query.Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+	if err = query.Order("deletedAt desc").Order("id desc").
+		Offset(offset).Limit(limit).Find(&trashed).Error; err != nil {
+		return 0, nil, err
+	}
+	for index := range trashed {
+		trashed[index].App = *app
+	}
+	return total, trashed, nil
+}
+
+// FindChildren is used to find the children of directory page by page. Directories
+// always come first, then children are sorted by the specify column, a column with
+// prefix '-' means descending order. If ext isn't nil, only the files with this ext
+// will be returned, hidden files are returned only when showHidden is true. The
+// total number of matched children is also returned.
+func (f *File) FindChildren(ext *string, showHidden bool, sort string, offset, limit int, db *gorm.DB) (int, []File, error) {
+	var (
+		err       error
+		total     int
+		children  []File
+		direction = "asc"
+		column    string
+		ok        bool
+	)
+
+	if f.IsDir == 0 {
+		return 0, nil, ErrListFile
+	}
+
+	if strings.HasPrefix(sort, "-") {
+		sort = strings.TrimPrefix(sort, "-")
+		direction = "desc"
+	}
+	if column, ok = sortableFileColumns[sort]; !ok {
+		column = "name"
+	}
+
+	query := db.Model(&File{}).Where("appId = ? and pid = ?", f.AppID, f.ID)
+	if ext != nil {
+		query = query.Where("isDir = 0 and ext = ?", *ext)
+	}
+	if !showHidden {
+		query = query.Where("hidden = 0")
+	}
+
+	if err = query.Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+
+	if err = query.Preload("Object").
+		Order("isDir desc").
+		Order(fmt.Sprintf("%s %s", column, direction)).
+		Order("id asc").
+		Offset(offset).Limit(limit).
+		Find(&children).Error; err != nil {
+		return 0, nil, err
+	}
+
+	for index := range children {
+		children[index].App = f.App
+	}
+
+	return total, children, nil
+}
+
+// AppendFromReader is used to append content from reader to file. All changes
+// are made in a transaction, and the content of chunks is restored if it fails.
+// The file is locked until the content is appended, so appends to a file are
+// serialized.
 func (f *File) AppendFromReader(reader io.Reader, hidden int8, rootPath *string, db *gorm.DB) error {
 
 	if f.IsDir == 1 {
@@ -278,52 +986,76 @@ func (f *File) AppendFromReader(reader io.Reader, hidden int8, rootPath *string,
 		size   int
 		object *Object
 	)
-	if err = db.Preload("Object").Preload("Parent").Preload("App").Find(f).Error; err != nil {
-		return err
-	}
 
-	if object, size, err = f.Object.AppendFromReader(reader, rootPath, db); err != nil {
-		return err
-	}
+	return f.withLock(db, func(tx *gorm.DB) error {
+		if err = tx.Preload("Object").Preload("Parent").Preload("App").Find(f).Error; err != nil {
+			return err
+		}
 
-	f.Hidden = hidden
-	f.Size += size
-	f.Object = *object
-	f.ObjectID = object.ID
+		if object, size, err = f.Object.AppendFromReader(f.AppID, reader, rootPath, tx); err != nil {
+			return err
+		}
 
-	if err = db.Save(f).Error; err != nil {
-		return err
-	}
+		f.Hidden = hidden
+		f.Size += size
+		f.Object = *object
+		f.ObjectID = object.ID
+		f.Quarantined = 0
 
-	return f.Parent.UpdateParentSize(size, db)
+		if err = tx.Save(f).Error; err != nil {
+			return err
+		}
+
+		return f.Parent.UpdateParentSize(size, tx)
+	})
 }
 
-// CreateOrGetLastDirectory is used to get last level directory
+// CreateOrGetLastDirectory is used to get last level directory. The existing
+// directories of the path are found in one query, the others are created.
 func CreateOrGetLastDirectory(app *App, parentDirs string, db *gorm.DB) (*File, error) {
 	var (
-		parent *File
-		err    error
-		parts  = strings.Split(strings.Trim(strings.TrimSpace(parentDirs), "/"), string(os.PathSeparator))
+		parent  *File
+		err     error
+		dirs    []File
+		paths   []string
+		existed = make(map[string]*File)
+		trimmed = strings.Trim(strings.TrimSpace(parentDirs), "/")
+		parts   = strings.Split(trimmed, string(os.PathSeparator))
 	)
 
 	if parent, err = CreateOrGetRootPath(app, db); err != nil {
 		return nil, err
 	}
 
-	for _, part := range parts {
-		file := &File{}
-		if err = db.Where("appId = ? and pid = ? and name = ?", app.ID, parent.ID, part).Find(file).Error; err != nil {
-			if !util.IsRecordNotFound(err) {
-				return nil, err
-			}
-			file.AppID = app.ID
-			file.PID = parent.ID
-			file.Name = part
-			file.IsDir = 1
-			file.UID = bson.NewObjectId().Hex()
-			if err = db.Save(file).Error; err != nil {
-				return nil, err
-			}
+	if trimmed == "" {
+		return parent, nil
+	}
+
+	for index := range parts {
+		paths = append(paths, "/"+strings.Join(parts[:index+1], "/"))
+	}
+	if err = db.Where("appId = ? and path in (?)", app.ID, paths).Find(&dirs).Error; err != nil {
+		return nil, err
+	}
+	for index := range dirs {
+		existed[dirs[index].FullPath] = &dirs[index]
+	}
+
+	for index, part := range parts {
+		if dir, ok := existed[paths[index]]; ok {
+			parent = dir
+			continue
+		}
+		file := &File{
+			UID:      bson.NewObjectId().Hex(),
+			PID:      parent.ID,
+			AppID:    app.ID,
+			Name:     part,
+			FullPath: paths[index],
+			IsDir:    1,
+		}
+		if err = db.Save(file).Error; err != nil {
+			return nil, err
 		}
 		parent = file
 	}
@@ -337,15 +1069,41 @@ func CreateOrGetRootPath(app *App, db *gorm.DB) (*File, error) {
 		file = &File{}
 		err  error
 	)
-	err = db.Where("appId = ? & pid = 0 and name = ''", app.ID).Find(file).Error
+	err = db.Where("appId = ? and pid = 0", app.ID).Find(file).Error
 	file.App = *app
 	return file, err
 }
 
-// CreateFileFromReader is used to create a file from reader.
+// CreateFileFromReader is used to create a file from reader. All changes are made
+// in a transaction, the chunks created for it are removed if it fails.
 func CreateFileFromReader(app *App, path string, reader io.Reader, hidden int8, rootPath *string, db *gorm.DB) (*File, error) {
 	var (
-		object    *Object
+		file   *File
+		object *Object
+		err    error
+	)
+
+	if f, err := FindFileByPath(app, path, db); err == nil && f.ID > 0 {
+		return nil, ErrFileExisted
+	}
+
+	if err = Transaction(db, func(tx *gorm.DB) error {
+		if object, err = CreateObjectFromReader(app.ID, reader, rootPath, tx); err != nil {
+			return err
+		}
+		file, err = CreateFileFromObject(app, path, object, hidden, tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// CreateFileFromObject is used to create a file that holds the object, all changes
+// are made in a transaction
+func CreateFileFromObject(app *App, path string, object *Object, hidden int8, db *gorm.DB) (*File, error) {
+	var (
 		err       error
 		file      *File
 		parentDir *File
@@ -357,37 +1115,36 @@ func CreateFileFromReader(app *App, path string, reader io.Reader, hidden int8,
 		return nil, ErrFileExisted
 	}
 
-	if parentDir, err = CreateOrGetLastDirectory(app, dirPrefix, db); err != nil {
-		return nil, err
-	}
+	if err = Transaction(db, func(tx *gorm.DB) error {
+		if parentDir, err = CreateOrGetLastDirectory(app, dirPrefix, tx); err != nil {
+			return err
+		}
 
-	if object, err = CreateObjectFromReader(reader, rootPath, db); err != nil {
-		return nil, err
-	}
+		file = &File{
+			UID:      bson.NewObjectId().Hex(),
+			PID:      parentDir.ID,
+			AppID:    app.ID,
+			ObjectID: object.ID,
+			Size:     int(object.Size),
+			Name:     fileName,
+			Ext:      strings.TrimPrefix(filepath.Ext(fileName), "."),
+			FullPath: parentDir.FullPath + "/" + fileName,
+			Hidden:   hidden,
+			Object:   *object,
+			App:      *app,
+			Parent:   parentDir,
+		}
 
-	file = &File{
-		UID:      bson.NewObjectId().Hex(),
-		PID:      parentDir.ID,
-		AppID:    app.ID,
-		ObjectID: object.ID,
-		Size:     object.Size,
-		Name:     fileName,
-		Ext:      strings.TrimPrefix(filepath.Ext(fileName), "."),
-		Hidden:   hidden,
-		Object:   *object,
-		App:      *app,
-		Parent:   parentDir,
-	}
+		if err = tx.Save(file).Error; err != nil {
+			return err
+		}
 
-	if err = db.Save(file).Error; err != nil {
+		return parentDir.UpdateParentSize(int(object.Size), tx)
+	}); err != nil {
 		return nil, err
 	}
 
-	if err = parentDir.UpdateParentSize(object.Size, db); err != nil {
-		return file, err
-	}
-
-	return file, err
+	return file, nil
 }
 
 // FindFileByUID is used to find a file by uid
@@ -405,38 +1162,37 @@ func FindFileByUID(uid string, trashed bool, db *gorm.DB) (*File, error) {
 	return file, nil
 }
 
-// FindFileByPath is used to find a file by the specify path
+// FindFileByPath is used to find a file by the specify path, it's found by its
+// path in one query
 func FindFileByPath(app *App, path string, db *gorm.DB) (*File, error) {
-	var cacheKey = pathCacheKey(app, path)
+	var (
+		err      error
+		file     = &File{}
+		cacheKey = pathCacheKey(app, path)
+		fullPath = "/" + strings.Trim(strings.TrimSpace(path), "/")
+	)
+
+	if fullPath == "/" {
+		return CreateOrGetRootPath(app, db)
+	}
 
+	// the cached file may be moved by other processes, so its path is checked
 	if fileValue, ok := pathToFileCache.Get(cacheKey); ok {
-		file := fileValue.(*File)
-		if err := db.Where("id = ?", file.ID).Find(file).Error; err != nil {
+		if err = db.Where("id = ? and appId = ? and path = ?",
+			fileValue.(*File).ID, app.ID, fullPath).Find(file).Error; err == nil {
 			file.App = *app
 			return file, nil
 		}
+		file = &File{}
+		pathToFileCache.Delete(cacheKey)
 	}
 
-	var (
-		err    error
-		parent = &File{}
-		parts  = strings.Split(strings.Trim(strings.TrimSpace(path), "/"), string(os.PathSeparator))
-	)
-
-	if parent, err = CreateOrGetRootPath(app, db); err != nil {
+	if err = db.Where("appId = ? and path = ?", app.ID, fullPath).Find(file).Error; err != nil {
 		return nil, err
 	}
+	file.App = *app
 
-	for _, part := range parts {
-		var file = &File{}
-		if err = db.Where("appId = ? and pid = ? and name = ?", app.ID, parent.ID, part).Find(file).Error; err != nil {
-			return nil, err
-		}
-		parent = file
-	}
-	parent.App = *app
-
-	_ = pathToFileCache.Add(cacheKey, parent, time.Hour*48)
+	_ = pathToFileCache.Add(cacheKey, file, time.Hour*48)
 
-	return parent, nil
+	return file, nil
 }
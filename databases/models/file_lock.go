@@ -0,0 +1,108 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+)
+
+// fileLocks serializes the mutations of files in this process
+var fileLocks = newFileLockManager()
+
+// fileLock is the lock of a file, refs is the count of goroutines that hold or
+// wait for it, it's removed from manager when nobody needs it.
+type fileLock struct {
+	sync.Mutex
+	refs int
+}
+
+// fileLockManager holds the locks of files that are being changed
+type fileLockManager struct {
+	mu    sync.Mutex
+	locks map[uint64]*fileLock
+}
+
+// newFileLockManager is used to create a fileLockManager
+func newFileLockManager() *fileLockManager {
+	return &fileLockManager{locks: make(map[uint64]*fileLock)}
+}
+
+// lock will block until the lock of file is acquired, the returned function is
+// used to release it
+func (m *fileLockManager) lock(id uint64) func() {
+	m.mu.Lock()
+	l, ok := m.locks[id]
+	if !ok {
+		l = &fileLock{}
+		m.locks[id] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		m.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(m.locks, id)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// forUpdate makes the query lock the rows that it reads until the transaction
+// ends, the latest committed rows are read
+func forUpdate(db *gorm.DB) *gorm.DB {
+	return db.Set("gorm:query_option", "FOR UPDATE")
+}
+
+// lockFiles takes the locks of files for the transaction of db, they are held
+// until the transaction ends. The locks are taken in ascending order of ids, so
+// all files changed in a transaction must be locked at once before anything is
+// written, otherwise, a transaction may wait for a lock while holding the rows
+// that the holder of the lock waits for, which isn't detected by database. If db
+// isn't in a transaction that is started by Transaction, nothing is locked here,
+// and the files are protected by the rows locked by FOR UPDATE only.
+func lockFiles(db *gorm.DB, ids ...uint64) {
+	var tracker = getTracker(db)
+	if tracker == nil {
+		return
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		if _, ok := tracker.fileLocks[id]; !ok {
+			tracker.fileLocks[id] = fileLocks.lock(id)
+		}
+	}
+}
+
+// withLock run fn in a transaction that holds the lock of file. The lock of this
+// process serializes the mutations of file here, and the row of file is locked
+// by FOR UPDATE for the other instances that share the database. The lock is taken
+// before anything is written in the transaction, and both of them are held until
+// the outermost transaction ends, so nobody sees the file before the changes are
+// committed. The parent, object and size of file are loaded again after the lock
+// is acquired, because they may be changed by others.
+func (f *File) withLock(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return Transaction(db, func(tx *gorm.DB) error {
+		lockFiles(tx, f.ID)
+
+		var current = &File{}
+		if err := forUpdate(tx.Unscoped()).Select("id, pid, objectId, size").
+			Where("id = ?", f.ID).Find(current).Error; err != nil {
+			return err
+		}
+		if current.ObjectID != f.ObjectID {
+			f.Object = Object{}
+		}
+		f.PID = current.PID
+		f.ObjectID = current.ObjectID
+		f.Size = current.Size
+		return fn(tx)
+	})
+}
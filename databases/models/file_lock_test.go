@@ -0,0 +1,113 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bigfile/bigfile/databases"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileLockManager_lock(t *testing.T) {
+	var (
+		manager = newFileLockManager()
+		wg      sync.WaitGroup
+		holders int32
+		count   int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := manager.lock(1)
+			defer unlock()
+			assert.Equal(t, int32(1), atomic.AddInt32(&holders, 1))
+			time.Sleep(time.Millisecond)
+			count++
+			atomic.AddInt32(&holders, -1)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 20, count)
+	assert.Empty(t, manager.locks)
+
+	// the locks of different files don't block each other
+	unlock := manager.lock(1)
+	manager.lock(2)()
+	unlock()
+	assert.Empty(t, manager.locks)
+}
+
+func TestFile_withLock(t *testing.T) {
+	var tempDir = NewTempDirForTest()
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		os.RemoveAll(tempDir)
+	}()
+
+	file, err := CreateFileFromReader(app, "/lock/random.bytes", bytes.NewReader(Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+
+	// the file is changed by others after it's loaded
+	stale := &File{ID: file.ID, ObjectID: file.ObjectID + 1, Size: 1, Object: Object{ID: file.ObjectID + 1}}
+	assert.Nil(t, stale.withLock(trx, func(tx *gorm.DB) error {
+		assert.Equal(t, file.ObjectID, stale.ObjectID)
+		assert.Equal(t, 256, stale.Size)
+		assert.Equal(t, uint64(0), stale.Object.ID)
+		return nil
+	}))
+	assert.Empty(t, fileLocks.locks)
+}
+
+func TestFile_withLock2(t *testing.T) {
+	var (
+		db   = databases.MustNewConnection(nil)
+		file = &File{ID: 1 << 62}
+	)
+
+	// the locks are held until the outermost transaction ends
+	assert.Nil(t, Transaction(db, func(tx *gorm.DB) error {
+		assert.True(t, util.IsRecordNotFound(file.withLock(tx, func(tx *gorm.DB) error { return nil })))
+		assert.Contains(t, fileLocks.locks, file.ID)
+		assert.NotNil(t, (&File{ID: file.ID + 1}).withLock(tx, func(tx *gorm.DB) error { return nil }))
+		assert.Contains(t, fileLocks.locks, file.ID+1)
+		return nil
+	}))
+	assert.Empty(t, fileLocks.locks)
+
+	assert.True(t, util.IsRecordNotFound(file.withLock(db, func(tx *gorm.DB) error { return nil })))
+	assert.Empty(t, fileLocks.locks)
+}
+
+func TestLockFiles(t *testing.T) {
+	var db = databases.MustNewConnection(nil)
+
+	assert.Nil(t, Transaction(db, func(tx *gorm.DB) error {
+		lockFiles(tx, 3, 1, 3, 2)
+		assert.Len(t, getTracker(tx).fileLocks, 3)
+		assert.Len(t, fileLocks.locks, 3)
+		// the locks that have been held aren't taken again
+		lockFiles(tx, 2, 4)
+		assert.Len(t, fileLocks.locks, 4)
+		assert.Equal(t, 1, fileLocks.locks[2].refs)
+		return nil
+	}))
+	assert.Empty(t, fileLocks.locks)
+
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	defer down(t)
+	lockFiles(trx, 1)
+	assert.Empty(t, fileLocks.locks)
+}
@@ -0,0 +1,147 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// MaxMetaCount is the max count of metas that a file can have
+	MaxMetaCount = 32
+	// MaxMetaValueLength is the max length of meta value in bytes
+	MaxMetaValueLength = 1024
+)
+
+var (
+	// ErrInvalidMetaName represent that the name of meta isn't 1 to 64 letters, digits or hyphens
+	ErrInvalidMetaName = errors.New("meta name must be 1 to 64 letters, digits or hyphens")
+	// ErrInvalidMetaValue represent that the value of meta is too long or contains control characters
+	ErrInvalidMetaValue = errors.New("meta value can't be longer than 1024 bytes or contain control characters")
+	// ErrTooManyMetas represent that the count of metas exceeds MaxMetaCount
+	ErrTooManyMetas = errors.New("a file can't have more than 32 metas")
+	// ErrDuplicateMetaName represent that the names of metas only differ in case
+	ErrDuplicateMetaName = errors.New("meta names are case insensitive, they can't only differ in case")
+
+	metaNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9-]{1,64}$`)
+)
+
+// FileMeta represent a custom key/value tag of file, such as owner id or business
+// key. Name is case insensitive, it's saved in lower case, so that it can be sent
+// as a part of http header.
+type FileMeta struct {
+	ID        uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT;primary_key"`
+	FileID    uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL;column:fileId"`
+	Name      string    `gorm:"type:VARCHAR(64) NOT NULL;column:name"`
+	Value     string    `gorm:"type:VARCHAR(1024) NOT NULL;column:value"`
+	CreatedAt time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:createdAt"`
+	UpdatedAt time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:updatedAt"`
+}
+
+// TableName represent the name of file meta table
+func (m *FileMeta) TableName() string {
+	return "file_metas"
+}
+
+// ValidateMetas is used to validate the names and the values of metas, an empty
+// value means that the meta will be deleted. Names are case insensitive, so the
+// names that only differ in case are rejected, rather than one of them is lost.
+func ValidateMetas(metas map[string]string) error {
+	var names = make(map[string]bool)
+	for name, value := range metas {
+		if !metaNameRegexp.MatchString(name) {
+			return ErrInvalidMetaName
+		}
+		if names[strings.ToLower(name)] {
+			return ErrDuplicateMetaName
+		}
+		names[strings.ToLower(name)] = true
+		if len(value) > MaxMetaValueLength || strings.IndexFunc(value, func(r rune) bool {
+			return r < ' ' || r == 0x7f
+		}) >= 0 {
+			return ErrInvalidMetaValue
+		}
+	}
+	return nil
+}
+
+// FindMetas is used to find the metas of file
+func (f *File) FindMetas(db *gorm.DB) (map[string]string, error) {
+	var (
+		fileMetas []FileMeta
+		metas     = make(map[string]string)
+	)
+	if err := db.Where("fileId = ?", f.ID).Find(&fileMetas).Error; err != nil {
+		return nil, err
+	}
+	for _, meta := range fileMetas {
+		metas[meta.Name] = meta.Value
+	}
+	return metas, nil
+}
+
+// SetMetas is used to set the metas of file, the meta whose value is empty is
+// deleted. If replace is true, the metas that aren't in metas are deleted too.
+func (f *File) SetMetas(metas map[string]string, replace bool, db *gorm.DB) error {
+	var (
+		err     error
+		names   []string
+		current = make(map[string]string)
+	)
+
+	if err = ValidateMetas(metas); err != nil {
+		return err
+	}
+
+	if !replace {
+		if current, err = f.FindMetas(db); err != nil {
+			return err
+		}
+	}
+
+	for name, value := range metas {
+		if name = strings.ToLower(name); value == "" {
+			delete(current, name)
+		} else {
+			current[name] = value
+		}
+	}
+
+	if len(current) > MaxMetaCount {
+		return ErrTooManyMetas
+	}
+
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return Transaction(db, func(tx *gorm.DB) error {
+		if err := tx.Where("fileId = ?", f.ID).Delete(&FileMeta{}).Error; err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := tx.Create(&FileMeta{FileID: f.ID, Name: name, Value: current[name]}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// copyMetas is used to replace the metas of file by the metas of src
+func (f *File) copyMetas(src *File, db *gorm.DB) error {
+	metas, err := src.FindMetas(db)
+	if err != nil {
+		return err
+	}
+	return f.SetMetas(metas, true, db)
+}
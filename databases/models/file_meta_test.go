@@ -0,0 +1,68 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMetas(t *testing.T) {
+	assert.Nil(t, ValidateMetas(map[string]string{"Owner-Id": "1", "business-key": ""}))
+	assert.Equal(t, ErrInvalidMetaName, ValidateMetas(map[string]string{"owner_id": "1"}))
+	assert.Equal(t, ErrInvalidMetaName, ValidateMetas(map[string]string{"": "1"}))
+	assert.Equal(t, ErrInvalidMetaName, ValidateMetas(map[string]string{strings.Repeat("a", 65): "1"}))
+	assert.Equal(t, ErrInvalidMetaValue, ValidateMetas(map[string]string{"owner": "1\r\nX-Other: 2"}))
+	assert.Equal(t, ErrInvalidMetaValue, ValidateMetas(map[string]string{"owner": strings.Repeat("a", MaxMetaValueLength+1)}))
+	assert.Equal(t, ErrDuplicateMetaName, ValidateMetas(map[string]string{"Owner-Id": "1", "owner-id": "2"}))
+}
+
+func TestFile_SetMetas(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		os.RemoveAll(tempDir)
+	}()
+
+	file, err := CreateFileFromReader(app, "/meta/random.bytes", bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	metas, err := file.FindMetas(trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(metas))
+
+	assert.Nil(t, file.SetMetas(map[string]string{"Owner-Id": "1", "desc": "random bytes"}, false, trx))
+	assert.Nil(t, file.SetMetas(map[string]string{"owner-id": "2", "desc": "", "key": "k"}, false, trx))
+	metas, err = file.FindMetas(trx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"owner-id": "2", "key": "k"}, metas)
+
+	assert.Nil(t, file.SetMetas(map[string]string{"desc": "replaced"}, true, trx))
+	metas, err = file.FindMetas(trx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"desc": "replaced"}, metas)
+
+	var tooMany = make(map[string]string)
+	for i := 0; i < MaxMetaCount; i++ {
+		tooMany[fmt.Sprintf("key-%d", i)] = "value"
+	}
+	assert.Equal(t, ErrTooManyMetas, file.SetMetas(tooMany, false, trx))
+	assert.Nil(t, file.SetMetas(tooMany, true, trx))
+	assert.Equal(t, ErrInvalidMetaName, file.SetMetas(map[string]string{"a b": "c"}, false, trx))
+	assert.Equal(t, ErrDuplicateMetaName, file.SetMetas(map[string]string{"Desc": "1", "desc": "2"}, true, trx))
+
+	// metas are deleted along with the file
+	assert.Nil(t, file.Delete(trx))
+	assert.Nil(t, file.Purge(trx))
+	var count int
+	assert.Nil(t, trx.Model(&FileMeta{}).Where("fileId = ?", file.ID).Count(&count).Error)
+	assert.Equal(t, 0, count)
+}
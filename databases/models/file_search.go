@@ -0,0 +1,127 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// FileSearchCondition represent the filters of file search, the fields that
+// are nil or empty are ignored. Name is a substring of file name, or a glob
+// pattern if it contains * or ?. Metas must all be matched.
+type FileSearchCondition struct {
+	Name          string
+	Ext           *string
+	MinSize       *int
+	MaxSize       *int
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Hidden        *int8
+	Hash          *string
+	Metas         map[string]string
+	Sort          string
+}
+
+// namePattern convert Name to a pattern of LIKE
+func (c *FileSearchCondition) namePattern() string {
+	var pattern = likeEscaper.Replace(c.Name)
+	if !strings.ContainsAny(c.Name, "*?") {
+		return "%" + pattern + "%"
+	}
+	return strings.NewReplacer("*", "%", "?", "_").Replace(pattern)
+}
+
+// apply add the filters of condition to query
+func (c *FileSearchCondition) apply(query *gorm.DB) *gorm.DB {
+	if c.Name != "" {
+		query = query.Where("files.name like ? escape '!'", c.namePattern())
+	}
+	if c.Ext != nil {
+		query = query.Where("files.isDir = 0 and files.ext = ?", *c.Ext)
+	}
+	if c.MinSize != nil {
+		query = query.Where("files.size >= ?", *c.MinSize)
+	}
+	if c.MaxSize != nil {
+		query = query.Where("files.size <= ?", *c.MaxSize)
+	}
+	if c.CreatedAfter != nil {
+		query = query.Where("files.createdAt >= ?", *c.CreatedAfter)
+	}
+	if c.CreatedBefore != nil {
+		query = query.Where("files.createdAt <= ?", *c.CreatedBefore)
+	}
+	if c.UpdatedAfter != nil {
+		query = query.Where("files.updatedAt >= ?", *c.UpdatedAfter)
+	}
+	if c.UpdatedBefore != nil {
+		query = query.Where("files.updatedAt <= ?", *c.UpdatedBefore)
+	}
+	if c.Hidden != nil {
+		query = query.Where("files.hidden = ?", *c.Hidden)
+	}
+	if c.Hash != nil {
+		query = query.Where("files.isDir = 0 and files.objectId in (select id from objects where hash = ?)", *c.Hash)
+	}
+	for name, value := range c.Metas {
+		query = query.Where(
+			"files.id in (select fileId from file_metas where name = ? and value = ?)", strings.ToLower(name), value)
+	}
+	return query
+}
+
+// Search is used to search the files under the directory, the directory itself
+// isn't included. Files are filtered by the prefix of their paths.
+func (f *File) Search(cond *FileSearchCondition, offset, limit int, db *gorm.DB) (int, []File, error) {
+	var (
+		err       error
+		total     int
+		files     []File
+		sort      = cond.Sort
+		direction = "asc"
+		column    string
+		ok        bool
+		query     = db.Model(&File{}).Where(
+			"files.appId = ? and files.path like ? escape '!'", f.AppID, subtreePattern(f.FullPath))
+	)
+
+	if f.IsDir == 0 {
+		return 0, nil, ErrListFile
+	}
+
+	query = cond.apply(query)
+
+	if strings.HasPrefix(sort, "-") {
+		sort = strings.TrimPrefix(sort, "-")
+		direction = "desc"
+	}
+	if column, ok = sortableFileColumns[sort]; !ok {
+		column = "name"
+	}
+
+	if err = query.Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+
+	if err = query.Preload("Object").
+		Order(fmt.Sprintf("files.%s %s", column, direction)).
+		Order("files.id asc").
+		Offset(offset).Limit(limit).
+		Find(&files).Error; err != nil {
+		return 0, nil, err
+	}
+
+	for index := range files {
+		files[index].App = f.App
+	}
+
+	return total, files, nil
+}
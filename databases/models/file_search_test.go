@@ -0,0 +1,90 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSearchCondition_namePattern(t *testing.T) {
+	assert.Equal(t, "%name%", (&FileSearchCondition{Name: "name"}).namePattern())
+	assert.Equal(t, "%100!%!_a!!%", (&FileSearchCondition{Name: "100%_a!"}).namePattern())
+	assert.Equal(t, `%.jp_g`, (&FileSearchCondition{Name: "*.jp?g"}).namePattern())
+}
+
+func TestFile_Search(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		os.RemoveAll(tempDir)
+	}()
+
+	var files = make(map[string]*File)
+	for path, size := range map[string]uint{
+		"/search/a/report.pdf":     10,
+		"/search/a/b/report_2.pdf": 20,
+		"/search/a/b/photo.jpg":    30,
+		"/search/c/report.txt":     40,
+		"/other/report.pdf":        50,
+	} {
+		files[path], err = CreateFileFromReader(app, path, bytes.NewReader(Random(size)), int8(0), &tempDir, trx)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, files["/search/a/b/photo.jpg"].SetMetas(map[string]string{"owner": "1"}, false, trx))
+	assert.Nil(t, trx.Model(files["/search/c/report.txt"]).UpdateColumn("hidden", 1).Error)
+
+	dir, err := FindFileByPath(app, "/search", trx)
+	assert.Nil(t, err)
+	root, err := CreateOrGetRootPath(app, trx)
+	assert.Nil(t, err)
+
+	var (
+		pdf    = "pdf"
+		min    = 15
+		hidden = int8(1)
+		hash   = files["/search/a/report.pdf"].Object.Hash
+		future = time.Now().Add(time.Hour)
+	)
+	for _, c := range []struct {
+		dir   *File
+		cond  *FileSearchCondition
+		paths []string
+	}{
+		{dir, &FileSearchCondition{Name: "report"}, []string{"/search/a/report.pdf", "/search/c/report.txt", "/search/a/b/report_2.pdf"}},
+		{root, &FileSearchCondition{Name: "report", Ext: &pdf, Sort: "-size"}, []string{"/other/report.pdf", "/search/a/b/report_2.pdf", "/search/a/report.pdf"}},
+		{dir, &FileSearchCondition{Name: "report_*", MinSize: &min}, []string{"/search/a/b/report_2.pdf"}},
+		{dir, &FileSearchCondition{Name: "report.???"}, []string{"/search/a/report.pdf", "/search/c/report.txt"}},
+		{dir, &FileSearchCondition{Hidden: &hidden}, []string{"/search/c/report.txt"}},
+		{dir, &FileSearchCondition{Hash: &hash}, []string{"/search/a/report.pdf"}},
+		{dir, &FileSearchCondition{Metas: map[string]string{"Owner": "1"}}, []string{"/search/a/b/photo.jpg"}},
+		{dir, &FileSearchCondition{Name: "b"}, []string{"/search/a/b"}},
+		{dir, &FileSearchCondition{CreatedAfter: &future}, nil},
+	} {
+		total, result, err := c.dir.Search(c.cond, 0, 10, trx)
+		assert.Nil(t, err)
+		assert.Equal(t, len(c.paths), total)
+		var resultPaths []string
+		for _, file := range result {
+			resultPaths = append(resultPaths, file.FullPath)
+		}
+		assert.Equal(t, c.paths, resultPaths)
+	}
+
+	total, result, err := dir.Search(&FileSearchCondition{Name: "report"}, 1, 1, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, files["/search/c/report.txt"].ID, result[0].ID)
+
+	_, _, err = files["/other/report.pdf"].Search(&FileSearchCondition{}, 0, 10, trx)
+	assert.Equal(t, ErrListFile, err)
+}
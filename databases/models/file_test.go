@@ -144,7 +144,7 @@ func TestCreateFileFromReader(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, ChunkSize*2+145, file.Size)
 	assert.Equal(t, "random.txt", file.Name)
-	assert.Equal(t, ChunkSize*2+145, file.Object.Size)
+	assert.Equal(t, int64(ChunkSize*2+145), file.Object.Size)
 	assert.Equal(t, randomBytesHash, file.Object.Hash)
 	assert.Equal(t, app.ID, file.App.ID)
 	assert.Equal(t, app.ID, file.AppID)
@@ -194,7 +194,7 @@ func TestFile_AppendFromReader(t *testing.T) {
 	assert.Nil(t, file.AppendFromReader(bytes.NewBuffer(randomBytes), int8(0), &tempDir, trx))
 	assert.Equal(t, hex.EncodeToString(h.Sum(nil)), file.Object.Hash)
 	assert.Equal(t, ChunkSize*2+145+256, file.Size)
-	assert.Equal(t, ChunkSize*2+145+256, file.Object.Size)
+	assert.Equal(t, int64(ChunkSize*2+145+256), file.Object.Size)
 	assert.Equal(t, app.ID, file.App.ID)
 	assert.Equal(t, app.ID, file.AppID)
 
@@ -220,6 +220,49 @@ func TestFile_Path(t *testing.T) {
 	assert.Equal(t, "/save/to/images/test.png", path)
 }
 
+func TestFile_FullPath(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		os.RemoveAll(tempDir)
+	}()
+
+	// the files of root directory
+	file, err := CreateFileFromReader(app, "/root.bytes", bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, "/root.bytes", file.FullPath)
+	root, err := FindFileByPath(app, "/", trx)
+	assert.Nil(t, err)
+	assert.Equal(t, root.ID, file.PID)
+	assert.Equal(t, "", root.FullPath)
+
+	// the files that are deleted along with directory are restored to the new path
+	_, err = CreateFileFromReader(app, "/full/a/b/c.bytes", bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	dir, err := FindFileByPath(app, "/full/a", trx)
+	assert.Nil(t, err)
+	assert.Nil(t, dir.Delete(trx))
+	assert.Nil(t, dir.Restore("/full/restored", trx))
+	assert.Equal(t, "/full/restored", dir.FullPath)
+	file, err = FindFileByPath(app, "/full/restored/b/c.bytes", trx)
+	assert.Nil(t, err)
+	assert.Equal(t, "/full/restored/b/c.bytes", file.FullPath)
+	_, err = FindFileByPath(app, "/full/a/b/c.bytes", trx)
+	assert.True(t, util.IsRecordNotFound(err))
+
+	// trashed files are found by the prefix of path
+	assert.Nil(t, file.Delete(trx))
+	_, trashed, err := FindTrashedFiles(app, "/full/restored/", 0, 10, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(trashed))
+	assert.Equal(t, file.ID, trashed[0].ID)
+	_, trashed, err = FindTrashedFiles(app, "/full/a", 0, 10, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(trashed))
+}
+
 func TestFile_OverWriteFromReader(t *testing.T) {
 	var (
 		trx             *gorm.DB
@@ -457,3 +500,355 @@ func TestFile_MoveTo2(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 255, saveAsDir.Size)
 }
+
+// TestFile_MoveTo3 is used to move a directory tree
+func TestFile_MoveTo3(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		os.RemoveAll(tempDir)
+	}()
+
+	c, err := CreateFileFromReader(app, "/move/a/b/c.bytes", bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = CreateFileFromReader(app, "/move/a/d.bytes", bytes.NewReader(Random(20)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = CreateFileFromReader(app, "/move/e.bytes", bytes.NewReader(Random(40)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	dir, err := FindFileByPath(app, "/move/a", trx)
+	assert.Nil(t, err)
+	// cache the paths under the directory
+	_, err = FindFileByPath(app, "/move/a/b/c.bytes", trx)
+	assert.Nil(t, err)
+
+	assert.Equal(t, ErrMoveIntoItself, dir.MoveTo("/move/a/b/a", trx))
+	assert.Equal(t, ErrMoveIntoItself, dir.MoveTo("/move/a/a", trx))
+	assert.Equal(t, ErrFileExisted, dir.MoveTo("/move/e.bytes", trx))
+	root, err := CreateOrGetRootPath(app, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrMoveIntoItself, root.MoveTo("/move/root", trx))
+
+	assert.Nil(t, dir.MoveTo("/move/to/a", trx))
+	assert.Equal(t, "/move/to/a/b/c.bytes", c.mustPath(trx))
+	_, err = FindFileByPath(app, "/move/a/b/c.bytes", trx)
+	assert.True(t, util.IsRecordNotFound(err))
+	moved, err := FindFileByPath(app, "/move/to/a/b/c.bytes", trx)
+	assert.Nil(t, err)
+	assert.Equal(t, c.ID, moved.ID)
+
+	// sizes of the common ancestors aren't changed
+	for path, size := range map[string]int{"/move": 70, "/move/to": 30, "/move/to/a": 30, "/move/to/a/b": 10} {
+		dir, err := FindFileByPath(app, path, trx)
+		assert.Nil(t, err)
+		assert.Equal(t, size, dir.Size, path)
+	}
+	root, err = CreateOrGetRootPath(app, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 70, root.Size)
+
+	// previous paths of the files are saved
+	_, histories, err := c.FindHistories(0, 10, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(histories))
+	assert.Equal(t, "/move/a/b/c.bytes", histories[0].Path)
+}
+
+func TestFile_Delete(t *testing.T) {
+	var (
+		err     error
+		app     *App
+		trx     *gorm.DB
+		file    *File
+		down    func(*testing.T)
+		tempDir = NewTempDirForTest()
+	)
+	app, trx, down, err = newAppForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	file, err = CreateFileFromReader(app, "/save/to/a/1.bytes", bytes.NewReader(Random(255)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = CreateFileFromReader(app, "/save/to/b/2.bytes", bytes.NewReader(Random(100)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = FindFileByPath(app, "/save/to/a/1.bytes", trx)
+	assert.Nil(t, err)
+
+	assert.Nil(t, file.Delete(trx))
+	assert.NotNil(t, file.DeletedAt)
+	assert.Equal(t, file.ID, file.DeletedID)
+	_, err = FindFileByPath(app, "/save/to/a/1.bytes", trx)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "record not found")
+	rootDir, err := CreateOrGetRootPath(app, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 100, rootDir.Size)
+
+	// the path can be used again after the file is deleted
+	_, err = CreateFileFromReader(app, "/save/to/a/1.bytes", bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+
+	toDir, err := FindFileByPath(app, "/save/to", trx)
+	assert.Nil(t, err)
+	assert.Nil(t, toDir.Delete(trx))
+	var count int
+	assert.Nil(t, trx.Model(&File{}).Where("appId = ? and pid > 0", app.ID).Count(&count).Error)
+	assert.Equal(t, 1, count)
+	assert.Nil(t, trx.Unscoped().Model(&File{}).Where("deletedAt = ?", toDir.DeletedAt).Count(&count).Error)
+	assert.Equal(t, 5, count)
+	rootDir, err = CreateOrGetRootPath(app, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, rootDir.Size)
+
+	assert.Equal(t, ErrDeleteRootDir, rootDir.Delete(trx))
+}
+
+func TestFile_Restore(t *testing.T) {
+	var (
+		err     error
+		app     *App
+		trx     *gorm.DB
+		file    *File
+		down    func(*testing.T)
+		tempDir = NewTempDirForTest()
+	)
+	app, trx, down, err = newAppForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	file, err = CreateFileFromReader(app, "/save/to/a/1.bytes", bytes.NewReader(Random(255)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = CreateFileFromReader(app, "/save/to/b/2.bytes", bytes.NewReader(Random(100)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	toDir, err := FindFileByPath(app, "/save/to", trx)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrFileNotInTrash, toDir.Restore("", trx))
+	assert.Nil(t, toDir.Delete(trx))
+
+	// the file is deleted along with directory, it can't be restored alone
+	file, err = FindFileByUID(file.UID, true, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrFileNotInTrash, file.Restore("", trx))
+
+	toDir, err = FindFileByUID(toDir.UID, true, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, toDir.Restore("", trx))
+	assert.Nil(t, toDir.DeletedAt)
+	file, err = FindFileByPath(app, "/save/to/a/1.bytes", trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 255, file.Size)
+	rootDir, err := CreateOrGetRootPath(app, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 355, rootDir.Size)
+
+	// the original path has been occupied
+	assert.Nil(t, file.Delete(trx))
+	_, err = CreateFileFromReader(app, "/save/to/a/1.bytes", bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	file, err = FindFileByUID(file.UID, true, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrFileExisted, file.Restore("", trx))
+	assert.Nil(t, file.Restore("/restore/1.bytes", trx))
+	assert.Equal(t, "/restore/1.bytes", file.mustPath(trx))
+	rootDir, err = CreateOrGetRootPath(app, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 365, rootDir.Size)
+}
+
+func TestFile_Purge(t *testing.T) {
+	var (
+		err     error
+		app     *App
+		trx     *gorm.DB
+		file    *File
+		down    func(*testing.T)
+		tempDir = NewTempDirForTest()
+	)
+	app, trx, down, err = newAppForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	file, err = CreateFileFromReader(app, "/save/to/a/1.bytes", bytes.NewReader(Random(255)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, file.OverWriteFromReader(bytes.NewReader(Random(12)), int8(0), &tempDir, trx))
+	toDir, err := FindFileByPath(app, "/save/to", trx)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrFileNotInTrash, toDir.Purge(trx))
+	assert.Nil(t, toDir.Delete(trx))
+	assert.Nil(t, toDir.Purge(trx))
+
+	var count int
+	assert.Nil(t, trx.Unscoped().Model(&File{}).Where("appId = ? and pid > 0", app.ID).Count(&count).Error)
+	assert.Equal(t, 1, count)
+	assert.Nil(t, trx.Model(&History{}).Where("fileId = ?", file.ID).Count(&count).Error)
+	assert.Equal(t, 0, count)
+}
+
+func TestFindTrashedFiles(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	defer down(t)
+
+	saveDir, err := CreateOrGetLastDirectory(app, "/save/to/images", trx)
+	assert.Nil(t, err)
+	testDir, err := CreateOrGetLastDirectory(app, "/test/dir", trx)
+	assert.Nil(t, err)
+	assert.Nil(t, saveDir.Delete(trx))
+	assert.Nil(t, testDir.Delete(trx))
+
+	total, files, err := FindTrashedFiles(app, "/", 0, 10, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, 2, len(files))
+	assert.Equal(t, testDir.ID, files[0].ID)
+
+	total, files, err = FindTrashedFiles(app, "/", 1, 1, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, saveDir.ID, files[0].ID)
+
+	total, files, err = FindTrashedFiles(app, "/save", 0, 10, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, saveDir.ID, files[0].ID)
+}
+
+func TestFile_FindChildren(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		os.RemoveAll(tempDir)
+	}()
+
+	dir, err := CreateOrGetLastDirectory(app, "/save/to", trx)
+	assert.Nil(t, err)
+	_, err = CreateOrGetLastDirectory(app, "/save/to/images", trx)
+	assert.Nil(t, err)
+	_, err = CreateFileFromReader(app, "/save/to/b.txt", bytes.NewReader(Random(64)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = CreateFileFromReader(app, "/save/to/a.png", bytes.NewReader(Random(128)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = CreateFileFromReader(app, "/save/to/c.txt", bytes.NewReader(Random(32)), int8(1), &tempDir, trx)
+	assert.Nil(t, err)
+
+	total, children, err := dir.FindChildren(nil, false, "name", 0, 10, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []string{"images", "a.png", "b.txt"}, []string{children[0].Name, children[1].Name, children[2].Name})
+
+	total, children, err = dir.FindChildren(nil, true, "-size", 1, 2, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, total)
+	assert.Equal(t, 2, len(children))
+	assert.Equal(t, "a.png", children[0].Name)
+	assert.Equal(t, "b.txt", children[1].Name)
+
+	ext := "txt"
+	total, children, err = dir.FindChildren(&ext, true, "size", 0, 10, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, "c.txt", children[0].Name)
+
+	_, _, err = children[0].FindChildren(nil, false, "name", 0, 10, trx)
+	assert.Equal(t, ErrListFile, err)
+}
+
+func TestFile_CopyTo(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		os.RemoveAll(tempDir)
+	}()
+
+	file, err := CreateFileFromReader(app, "/copy/from/a.txt", bytes.NewReader(Random(64)), int8(1), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = CreateFileFromReader(app, "/copy/from/images/b.png", bytes.NewReader(Random(128)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	dir, err := FindFileByPath(app, "/copy/from", trx)
+	assert.Nil(t, err)
+	assert.Nil(t, file.SetMetas(map[string]string{"owner": "1"}, false, trx))
+	assert.Nil(t, dir.SetMetas(map[string]string{"desc": "from"}, false, trx))
+
+	// copy a single file, the object is shared
+	fileCopy, err := file.CopyTo("/copy/to/a.txt", false, trx)
+	assert.Nil(t, err)
+	assert.NotEqual(t, file.ID, fileCopy.ID)
+	assert.Equal(t, file.ObjectID, fileCopy.ObjectID)
+	assert.Equal(t, int8(1), fileCopy.Hidden)
+	assert.Equal(t, "/copy/to/a.txt", fileCopy.mustPath(trx))
+	metas, err := fileCopy.FindMetas(trx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"owner": "1"}, metas)
+
+	_, err = file.CopyTo("/copy/to/a.txt", false, trx)
+	assert.Equal(t, ErrFileExisted, err)
+
+	_, err = dir.CopyTo("/copy/from/images/from", false, trx)
+	assert.Equal(t, ErrCopyIntoItself, err)
+
+	// only the existing files are overwritten, they are locked before copying
+	ids, err := dir.findOverwrittenFiles("/copy/to", trx)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{fileCopy.ID}, ids)
+
+	// copy a directory tree
+	dirCopy, err := dir.CopyTo("/copy/to", false, trx)
+	assert.Equal(t, ErrFileExisted, err)
+	assert.Nil(t, dirCopy)
+	dirCopy, err = dir.CopyTo("/copy/to", true, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 64+128, dirCopy.Size)
+	b, err := FindFileByPath(app, "/copy/to/images/b.png", trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 128, b.Size)
+	metas, err = dirCopy.FindMetas(trx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"desc": "from"}, metas)
+
+	// overwrite the file by a copy, the metas of overwritten file are replaced
+	assert.Nil(t, fileCopy.SetMetas(map[string]string{"owner": "2", "key": "k"}, true, trx))
+	assert.Nil(t, file.SetMetas(map[string]string{"owner": "3"}, true, trx))
+	assert.Nil(t, file.OverWriteFromReader(bytes.NewReader(Random(16)), int8(0), &tempDir, trx))
+	fileCopy2, err := file.CopyTo("/copy/to/a.txt", true, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, fileCopy.ID, fileCopy2.ID)
+	assert.Equal(t, file.ObjectID, fileCopy2.ObjectID)
+	metas, err = fileCopy2.FindMetas(trx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"owner": "3"}, metas)
+	// it has been overwritten by the copy of directory too
+	total, _, err := fileCopy2.FindHistories(0, 10, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, total)
+
+	dirCopy, err = FindFileByPath(app, "/copy/to", trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 16+128, dirCopy.Size)
+
+	_, err = file.CopyTo("/copy/to/images", true, trx)
+	assert.Equal(t, ErrOverwriteDir, err)
+	_, err = dir.CopyTo("/copy/to/a.txt", true, trx)
+	assert.Equal(t, ErrFileExisted, err)
+}
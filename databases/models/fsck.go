@@ -0,0 +1,344 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// FsckDirSize represent that the size of directory isn't the sum of its children
+	FsckDirSize = "dir-size"
+	// FsckMissingObject represent that the object of file doesn't exist
+	FsckMissingObject = "missing-object"
+	// FsckChunkNumber represent that the numbers of object chunks aren't continuous
+	FsckChunkNumber = "chunk-number"
+	// FsckDuplicateName represent that several files have the same name in one directory
+	FsckDuplicateName = "duplicate-name"
+	// FsckMissingChunk represent that the content of chunk doesn't exist in store
+	FsckMissingChunk = "missing-chunk"
+	// FsckUncheckedChunk represent that the content of chunk can't be checked, for
+	// example, the store is unavailable, it's never repaired
+	FsckUncheckedChunk = "unchecked-chunk"
+)
+
+// FsckIssue represent an inconsistency found by Fsck. ID is the id of file, object
+// or chunk, it depends on Type.
+type FsckIssue struct {
+	Type     string
+	ID       uint64
+	Message  string
+	Repaired bool
+}
+
+type fsckChecker func(repair bool, rootPath *string, db *gorm.DB) ([]FsckIssue, error)
+
+// Fsck is used to find the inconsistencies of metadata, and repair them if repair
+// is true. Directory sizes are checked last, because other repairs may change the
+// size of files. Some issues can't be repaired, they are only reported.
+func Fsck(repair bool, rootPath *string, db *gorm.DB) ([]FsckIssue, error) {
+	var (
+		result   []FsckIssue
+		checkers = []fsckChecker{
+			fsckMissingObjects,
+			fsckDuplicateNames,
+			fsckChunkNumbers,
+			fsckMissingChunks,
+			fsckDirSizes,
+		}
+	)
+	for _, checker := range checkers {
+		issues, err := checker(repair, rootPath, db)
+		result = append(result, issues...)
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// fsckMissingObjects finds the files whose object doesn't exist. They are repaired
+// by the latest history whose object exists, files without such history can't be
+// repaired.
+func fsckMissingObjects(repair bool, rootPath *string, db *gorm.DB) ([]FsckIssue, error) {
+	var (
+		err    error
+		files  []File
+		issues []FsckIssue
+	)
+	if err = db.Unscoped().Select("files.*").
+		Joins("left join objects on objects.id = files.objectId").
+		Where("files.isDir = 0 and objects.id is null").Order("files.id asc").Find(&files).Error; err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		var (
+			history = &History{}
+			issue   = FsckIssue{
+				Type:    FsckMissingObject,
+				ID:      file.ID,
+				Message: fmt.Sprintf("object %d of file %s doesn't exist", file.ObjectID, file.UID),
+			}
+		)
+		if repair {
+			err = db.Preload("Object").Joins("join objects on objects.id = histories.objectId").
+				Where("histories.fileId = ?", file.ID).Order("histories.id desc").First(history).Error
+			if err != nil && !gorm.IsRecordNotFoundError(err) {
+				return issues, err
+			}
+			if err == nil {
+				if err = db.Unscoped().Model(&file).UpdateColumns(map[string]interface{}{
+					"objectId": history.ObjectID,
+					"size":     history.Object.Size,
+				}).Error; err != nil {
+					return issues, err
+				}
+				if err = db.Delete(history).Error; err != nil {
+					return issues, err
+				}
+				issue.Repaired = true
+				issue.Message += fmt.Sprintf(", restored to history %d", history.ID)
+			}
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+// fsckDuplicateNames finds the files that aren't deleted but have the same name
+// in one directory. The earliest one keeps the name, the others are renamed by
+// appending their ids.
+func fsckDuplicateNames(repair bool, rootPath *string, db *gorm.DB) ([]FsckIssue, error) {
+	var (
+		err    error
+		rows   []File
+		issues []FsckIssue
+	)
+	if err = db.Select("appId, pid, name").Group("appId, pid, name").
+		Having("count(*) > 1").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		var files []File
+		if err = db.Preload("App").Where("appId = ? and pid = ? and name = ?", row.AppID, row.PID, row.Name).
+			Order("id asc").Find(&files).Error; err != nil {
+			return issues, err
+		}
+		for _, file := range files[1:] {
+			var issue = FsckIssue{
+				Type:    FsckDuplicateName,
+				ID:      file.ID,
+				Message: fmt.Sprintf("file %s has the same name as file %s", file.UID, files[0].UID),
+			}
+			if repair {
+				var (
+					path    string
+					ext     = filepath.Ext(file.Name)
+					name    = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(file.Name, ext), file.ID, ext)
+					newPath string
+				)
+				if path, err = file.Path(db); err != nil {
+					return issues, err
+				}
+				newPath = strings.TrimSuffix(path, file.Name) + name
+				if err = db.Model(&file).UpdateColumns(map[string]interface{}{
+					"name":      name,
+					"path":      newPath,
+					"deletedId": 0,
+				}).Error; err != nil {
+					return issues, err
+				}
+				if err = file.updateChildrenPath(path, newPath, db); err != nil {
+					return issues, err
+				}
+				deletePathCache(&file.App, path)
+				issue.Repaired = true
+				issue.Message += fmt.Sprintf(", renamed to %s", name)
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+// fsckChunkNumbers finds the objects whose chunk numbers aren't 1, 2, ..., n.
+// They are renumbered by the original order.
+func fsckChunkNumbers(repair bool, rootPath *string, db *gorm.DB) ([]FsckIssue, error) {
+	var (
+		err       error
+		objectIDs []uint64
+		issues    []FsckIssue
+	)
+	if err = db.Model(&ObjectChunk{}).Joins("join objects on objects.id = object_chunk.objectId").
+		Group("object_chunk.objectId").
+		Having("min(object_chunk.number) <> 1 or max(object_chunk.number) <> count(*) or "+
+			"count(distinct object_chunk.number) <> count(*)").
+		Pluck("object_chunk.objectId", &objectIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, objectID := range objectIDs {
+		var issue = FsckIssue{
+			Type:    FsckChunkNumber,
+			ID:      objectID,
+			Message: fmt.Sprintf("chunk numbers of object %d aren't continuous", objectID),
+		}
+		if repair {
+			var ocs []ObjectChunk
+			if err = db.Where("objectId = ?", objectID).Order("number asc, id asc").Find(&ocs).Error; err != nil {
+				return issues, err
+			}
+			for index, oc := range ocs {
+				if oc.Number == index+1 {
+					continue
+				}
+				if err = db.Model(&oc).UpdateColumn("number", index+1).Error; err != nil {
+					return issues, err
+				}
+			}
+			issue.Repaired = true
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+// fsckMissingChunks finds the chunks whose content doesn't exist in store. Unused
+// chunks are deleted, the objects that use them are quarantined. Only the chunks
+// that the store reports as not existing are missing, the ones that can't be
+// checked because of the other errors are reported only.
+func fsckMissingChunks(repair bool, rootPath *string, db *gorm.DB) ([]FsckIssue, error) {
+	var (
+		lastID uint64
+		store  = chunkStore(rootPath)
+		issues []FsckIssue
+	)
+	for {
+		var chunks []Chunk
+		if err := db.Where("id > ?", lastID).Order("id asc").Limit(scrubBatchSize).Find(&chunks).Error; err != nil {
+			return issues, err
+		}
+		for _, chunk := range chunks {
+			lastID = chunk.ID
+			if _, err := store.Stat(chunk.ID); err == nil {
+				continue
+			} else if err != ErrChunkNotExist {
+				issues = append(issues, FsckIssue{
+					Type:    FsckUncheckedChunk,
+					ID:      chunk.ID,
+					Message: fmt.Sprintf("content of chunk %d can't be checked: %s", chunk.ID, err),
+				})
+				continue
+			}
+			var issue = FsckIssue{
+				Type:    FsckMissingChunk,
+				ID:      chunk.ID,
+				Message: fmt.Sprintf("content of chunk %d doesn't exist", chunk.ID),
+			}
+			if repair {
+				if err := repairMissingChunk(&chunk, &issue, db); err != nil {
+					return issues, err
+				}
+			}
+			issues = append(issues, issue)
+		}
+		if len(chunks) < scrubBatchSize {
+			return issues, nil
+		}
+	}
+}
+
+func repairMissingChunk(chunk *Chunk, issue *FsckIssue, db *gorm.DB) error {
+	var objects []Object
+	if err := db.Where("id in (select objectId from object_chunk where chunkId = ?)", chunk.ID).
+		Find(&objects).Error; err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		if err := db.Where("not exists (select 1 from upload_session_chunk where chunkId = ?)", chunk.ID).
+			Delete(chunk).Error; err != nil {
+			return err
+		}
+		issue.Repaired = true
+		issue.Message += ", the unused chunk is deleted"
+		return nil
+	}
+	for index := range objects {
+		if err := quarantineObject(&objects[index], 1, false, db); err != nil {
+			return err
+		}
+	}
+	issue.Repaired = true
+	issue.Message += fmt.Sprintf(", %d objects are quarantined", len(objects))
+	return nil
+}
+
+// fsckDirSizes finds the directories whose size isn't the sum of their children,
+// only the files that aren't deleted are counted. The sizes are recalculated from
+// the leaves, so that one wrong size won't spread to all ancestors.
+func fsckDirSizes(repair bool, rootPath *string, db *gorm.DB) ([]FsckIssue, error) {
+	var (
+		err    error
+		appIDs []uint64
+		issues []FsckIssue
+	)
+	if err = db.Model(&File{}).Order("appId asc").Pluck("distinct appId", &appIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, appID := range appIDs {
+		var (
+			files    []File
+			children = make(map[uint64][]*File)
+			expected = make(map[uint64]int)
+		)
+		if err = db.Select("id, uid, pid, size, isDir").Where("appId = ?", appID).Find(&files).Error; err != nil {
+			return issues, err
+		}
+		for index := range files {
+			children[files[index].PID] = append(children[files[index].PID], &files[index])
+		}
+		for index := range files {
+			if files[index].PID == 0 {
+				sumDirSize(&files[index], children, expected)
+			}
+		}
+		for _, file := range files {
+			size, ok := expected[file.ID]
+			if !ok || file.IsDir == 0 || size == file.Size {
+				continue
+			}
+			var issue = FsckIssue{
+				Type:    FsckDirSize,
+				ID:      file.ID,
+				Message: fmt.Sprintf("size of directory %s is %d, but its children have %d", file.UID, file.Size, size),
+			}
+			if repair {
+				if err = db.Model(&file).UpdateColumn("size", size).Error; err != nil {
+					return issues, err
+				}
+				issue.Repaired = true
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+// sumDirSize calculate the size of file from its descendants and save the sizes of
+// directories to sizes
+func sumDirSize(file *File, children map[uint64][]*File, sizes map[uint64]int) int {
+	if file.IsDir == 0 {
+		return file.Size
+	}
+	var size int
+	for _, child := range children[file.ID] {
+		size += sumDirSize(child, children, sizes)
+	}
+	sizes[file.ID] = size
+	return size
+}
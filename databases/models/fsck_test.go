@@ -0,0 +1,160 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+	"labix.org/v2/mgo/bson"
+)
+
+func fsckIssuesByType(issues []FsckIssue, issueType string) map[uint64]FsckIssue {
+	var result = make(map[uint64]FsckIssue)
+	for _, issue := range issues {
+		if issue.Type == issueType {
+			result[issue.ID] = issue
+		}
+	}
+	return result
+}
+
+func TestFsck(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	// the object of file doesn't exist, but it has a history
+	lost, err := CreateFileFromReader(app, "/fsck/lost.bytes", bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	historyObjectID := lost.ObjectID
+	assert.Nil(t, lost.OverWriteFromReader(bytes.NewReader(Random(20)), int8(0), &tempDir, trx))
+	assert.Nil(t, trx.Model(lost).UpdateColumn("objectId", lost.ObjectID+1000000).Error)
+
+	// chunk numbers are 1, 3
+	big, err := CreateFileFromReader(app, "/fsck/big.bytes", bytes.NewReader(Random(ChunkSize+10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	lastOc, err := (&Object{ID: big.ObjectID}).LastObjectChunk(trx)
+	assert.Nil(t, err)
+	assert.Nil(t, trx.Model(lastOc).UpdateColumn("number", 3).Error)
+
+	// the content of chunk is lost
+	missing, err := CreateFileFromReader(app, "/fsck/missing.bytes", bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	missingChunk, err := (&Object{ID: missing.ObjectID}).LastChunk(trx)
+	assert.Nil(t, err)
+	assert.Nil(t, NewLocalChunkStore(tempDir).Delete(missingChunk.ID))
+
+	// two files have the same name
+	duplicate := &File{
+		UID:       bson.NewObjectId().Hex(),
+		PID:       big.PID,
+		AppID:     app.ID,
+		ObjectID:  missing.ObjectID,
+		Size:      10,
+		Name:      "big.bytes",
+		Ext:       "bytes",
+		DeletedID: 1,
+	}
+	assert.Nil(t, trx.Create(duplicate).Error)
+
+	dir, err := FindFileByPath(app, "/fsck", trx)
+	assert.Nil(t, err)
+	assert.Nil(t, trx.Model(dir).UpdateColumn("size", 1).Error)
+
+	issues, err := Fsck(false, &tempDir, trx)
+	assert.Nil(t, err)
+	for issueType, id := range map[string]uint64{
+		FsckMissingObject: lost.ID,
+		FsckChunkNumber:   big.ObjectID,
+		FsckMissingChunk:  missingChunk.ID,
+		FsckDuplicateName: duplicate.ID,
+		FsckDirSize:       dir.ID,
+	} {
+		issue, ok := fsckIssuesByType(issues, issueType)[id]
+		assert.True(t, ok, issueType)
+		assert.False(t, issue.Repaired)
+	}
+
+	issues, err = Fsck(true, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.True(t, fsckIssuesByType(issues, FsckMissingObject)[lost.ID].Repaired)
+	assert.True(t, fsckIssuesByType(issues, FsckDirSize)[dir.ID].Repaired)
+
+	assert.Nil(t, trx.Where("id = ?", lost.ID).Find(lost).Error)
+	assert.Equal(t, historyObjectID, lost.ObjectID)
+	assert.Equal(t, 10, lost.Size)
+
+	var ocs []ObjectChunk
+	assert.Nil(t, trx.Where("objectId = ?", big.ObjectID).Order("number asc").Find(&ocs).Error)
+	assert.Equal(t, 2, ocs[1].Number)
+
+	var object Object
+	assert.Nil(t, trx.Where("id = ?", missing.ObjectID).Find(&object).Error)
+	assert.Equal(t, int8(1), object.Quarantined)
+
+	assert.Nil(t, trx.Where("id = ?", duplicate.ID).Find(duplicate).Error)
+	assert.Equal(t, fmt.Sprintf("big (%d).bytes", duplicate.ID), duplicate.Name)
+	assert.Equal(t, fmt.Sprintf("/fsck/big (%d).bytes", duplicate.ID), duplicate.FullPath)
+	assert.Equal(t, uint64(0), duplicate.DeletedID)
+
+	assert.Nil(t, trx.Where("id = ?", dir.ID).Find(dir).Error)
+	assert.Equal(t, 10+ChunkSize+10+10+10, dir.Size)
+
+	issues, err = Fsck(false, &tempDir, trx)
+	assert.Nil(t, err)
+	for issueType, id := range map[string]uint64{
+		FsckMissingObject: lost.ID,
+		FsckChunkNumber:   big.ObjectID,
+		FsckDuplicateName: duplicate.ID,
+		FsckDirSize:       dir.ID,
+	} {
+		_, ok := fsckIssuesByType(issues, issueType)[id]
+		assert.False(t, ok, issueType)
+	}
+}
+
+func TestFsck2(t *testing.T) {
+	var store = &faultyChunkStore{ChunkStore: NewMemoryChunkStore()}
+	SetDefaultChunkStore(store)
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		SetDefaultChunkStore(nil)
+		down(t)
+	}()
+
+	file, err := CreateFileFromReader(app, "/fsck/random.bytes", bytes.NewReader(Random(10)), int8(0), nil, trx)
+	assert.Nil(t, err)
+	chunk, err := (&Object{ID: file.ObjectID}).LastChunk(trx)
+	assert.Nil(t, err)
+
+	// the chunk isn't missing if the store fails
+	store.err = errors.New("store is unavailable")
+	issues, err := Fsck(true, nil, trx)
+	assert.Nil(t, err)
+	_, ok := fsckIssuesByType(issues, FsckMissingChunk)[chunk.ID]
+	assert.False(t, ok)
+	issue, ok := fsckIssuesByType(issues, FsckUncheckedChunk)[chunk.ID]
+	assert.True(t, ok)
+	assert.False(t, issue.Repaired)
+	assert.Contains(t, issue.Message, store.err.Error())
+
+	var object Object
+	assert.Nil(t, trx.Where("id = ?", file.ObjectID).Find(&object).Error)
+	assert.Equal(t, int8(0), object.Quarantined)
+	assert.Nil(t, trx.Where("id = ?", chunk.ID).Find(&Chunk{}).Error)
+}
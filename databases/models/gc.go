@@ -0,0 +1,183 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const orphanObjectCondition = "not exists (select 1 from files where files.objectId = objects.id) and " +
+	"not exists (select 1 from histories where histories.objectId = objects.id)"
+
+// GarbageCollectResult represent the objects and chunks that are collected
+type GarbageCollectResult struct {
+	Objects []Object
+	Chunks  []Chunk
+}
+
+// Size return the total size of collected chunks
+func (g *GarbageCollectResult) Size() int {
+	var size int
+	for _, chunk := range g.Chunks {
+		size += chunk.Size
+	}
+	return size
+}
+
+// FindOrphanObjects is used to find objects that aren't referenced by any file or
+// history. Files in trash still hold their objects. Objects updated after deadline
+// are ignored, because they may belong to uploads in progress.
+func FindOrphanObjects(deadline time.Time, db *gorm.DB) ([]Object, error) {
+	var objects []Object
+	err := db.Where("updatedAt < ?", deadline).Where(orphanObjectCondition).Order("id asc").Find(&objects).Error
+	return objects, err
+}
+
+// FindOrphanChunks is used to find chunks that aren't referenced by any object except
+// the objects in excludeObjectIDs, and aren't referenced by any upload session. The
+// object_chunk rows left by deleted objects are not counted as reference. Chunks
+// updated after deadline are ignored.
+func FindOrphanChunks(deadline time.Time, excludeObjectIDs []uint64, db *gorm.DB) ([]Chunk, error) {
+	var chunks []Chunk
+	if len(excludeObjectIDs) == 0 {
+		excludeObjectIDs = []uint64{0}
+	}
+	err := db.Where("updatedAt < ?", deadline).
+		Where("not exists (select 1 from object_chunk join objects on objects.id = object_chunk.objectId "+
+			"where object_chunk.chunkId = chunks.id and object_chunk.objectId not in (?))", excludeObjectIDs).
+		Where("not exists (select 1 from upload_session_chunk where upload_session_chunk.chunkId = chunks.id)").
+		Order("id asc").Find(&chunks).Error
+	return chunks, err
+}
+
+// CollectGarbage does mark-and-sweep over objects, object_chunk and chunks. Objects
+// and chunks that are unreachable and not updated within grace are collected, the
+// content of chunks is removed from store as well. Upload sessions that have expired
+// before grace are deleted first, so that their chunks can be collected. If dryRun is true, nothing will
+// be deleted, only the result is returned. Every row is deleted by a conditional
+// statement that checks the reference and updatedAt again, so a row that becomes
+// referenced or is reused by dedup after marking won't be collected.
+func CollectGarbage(grace time.Duration, dryRun bool, rootPath *string, db *gorm.DB) (*GarbageCollectResult, error) {
+	var (
+		err       error
+		objects   []Object
+		chunks    []Chunk
+		objectIDs []uint64
+		deadline  = time.Now().Add(-grace)
+		result    = &GarbageCollectResult{}
+	)
+
+	if !dryRun {
+		if _, err = DeleteExpiredUploadSessions(deadline, db); err != nil {
+			return nil, err
+		}
+	}
+
+	if objects, err = FindOrphanObjects(deadline, db); err != nil {
+		return nil, err
+	}
+
+	for _, object := range objects {
+		objectIDs = append(objectIDs, object.ID)
+	}
+
+	if chunks, err = FindOrphanChunks(deadline, objectIDs, db); err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		result.Objects = objects
+		result.Chunks = chunks
+		return result, nil
+	}
+
+	for _, object := range objects {
+		var swept bool
+		if swept, err = sweepObject(&object, deadline, db); err != nil {
+			return result, err
+		}
+		if swept {
+			result.Objects = append(result.Objects, object)
+		}
+	}
+
+	for _, chunk := range chunks {
+		var swept bool
+		if swept, err = sweepChunk(&chunk, deadline, rootPath, db); err != nil {
+			return result, err
+		}
+		if swept {
+			result.Chunks = append(result.Chunks, chunk)
+		}
+	}
+
+	return result, nil
+}
+
+// sweepObject will delete the object and its object_chunk rows, if the object is
+// still unreferenced and isn't reused after deadline. The references are checked
+// again by the joins of the same delete statement. Both are deleted in a
+// transaction, so that no object is left without its chunks.
+func sweepObject(object *Object, deadline time.Time, db *gorm.DB) (bool, error) {
+	var swept bool
+
+	err := Transaction(db, func(tx *gorm.DB) error {
+		affected, err := execAffected(tx, "delete objects from objects "+
+			"left join files on files.objectId = objects.id "+
+			"left join histories on histories.objectId = objects.id "+
+			"where objects.id = ? and objects.updatedAt < ? and files.id is null and histories.id is null",
+			object.ID, deadline)
+		if err != nil || affected == 0 {
+			return err
+		}
+		swept = true
+		return tx.Where("objectId = ?", object.ID).Delete(&ObjectChunk{}).Error
+	})
+
+	return swept && err == nil, err
+}
+
+// sweepChunk will delete the chunk row and its content in store, if the chunk is still
+// unreferenced and isn't reused after deadline. The row is deleted first, so that
+// uploads won't reuse the chunk anymore.
+func sweepChunk(chunk *Chunk, deadline time.Time, rootPath *string, db *gorm.DB) (bool, error) {
+	var (
+		err      error
+		affected int64
+	)
+
+	if affected, err = execAffected(db, "delete chunks from chunks "+
+		"left join (object_chunk join objects on objects.id = object_chunk.objectId) "+
+		"on object_chunk.chunkId = chunks.id "+
+		"left join upload_session_chunk on upload_session_chunk.chunkId = chunks.id "+
+		"where chunks.id = ? and chunks.updatedAt < ? and object_chunk.id is null and upload_session_chunk.id is null",
+		chunk.ID, deadline); err != nil || affected == 0 {
+		return false, err
+	}
+
+	return true, chunkStore(rootPath).Delete(chunk.ID)
+}
+
+// touch bumps updatedAt of the object or chunk that is reused by dedup, so that gc
+// won't collect it within grace. The row is locked until the transaction of writer
+// ends, the sweep of gc waits for it and then checks updatedAt again. If the row
+// has been collected, gorm.ErrRecordNotFound is returned.
+func touch(value interface{}, db *gorm.DB) error {
+	result := db.Model(value).UpdateColumn("updatedAt", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func execAffected(db *gorm.DB, sql string, values ...interface{}) (int64, error) {
+	result := db.Exec(sql, values...)
+	return result.RowsAffected, result.Error
+}
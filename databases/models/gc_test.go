@@ -0,0 +1,197 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	// negative grace means everything is old enough to be collected
+	grace := -time.Hour
+
+	file, err := CreateFileFromReader(app, "/save/to/file.bytes", bytes.NewReader(Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	purgedFile, err := CreateFileFromReader(app, "/save/to/purged.bytes", bytes.NewReader(Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, purgedFile.Delete(trx))
+	assert.Nil(t, purgedFile.Purge(trx))
+	trashedFile, err := CreateFileFromReader(app, "/save/to/trashed.bytes", bytes.NewReader(Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, trashedFile.Delete(trx))
+	orphanObject, err := CreateObjectFromReader(0, bytes.NewReader(Random(ChunkSize+10)), &tempDir, trx)
+	assert.Nil(t, err)
+	orphanChunk, err := CreateChunkFromBytes(0, Random(64), &tempDir, trx)
+	assert.Nil(t, err)
+	sharedContent := Random(ChunkSize)
+	sharedFile, err := CreateFileFromReader(app, "/save/to/shared.bytes", bytes.NewReader(append(sharedContent, 'a')), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = CreateObjectFromReader(0, bytes.NewReader(append(sharedContent, 'b')), &tempDir, trx)
+	assert.Nil(t, err)
+
+	result, err := CollectGarbage(grace, true, &tempDir, trx)
+	assert.Nil(t, err)
+	objectIDs := map[uint64]bool{}
+	for _, object := range result.Objects {
+		objectIDs[object.ID] = true
+	}
+	assert.True(t, objectIDs[purgedFile.ObjectID])
+	assert.True(t, objectIDs[orphanObject.ID])
+	assert.False(t, objectIDs[file.ObjectID])
+	assert.False(t, objectIDs[trashedFile.ObjectID])
+	chunkIDs := map[uint64]bool{}
+	for _, chunk := range result.Chunks {
+		chunkIDs[chunk.ID] = true
+	}
+	assert.True(t, chunkIDs[orphanChunk.ID])
+	assert.Nil(t, trx.Preload("Chunks").Find(orphanObject).Error)
+	assert.Equal(t, 2, len(orphanObject.Chunks))
+	for _, chunk := range orphanObject.Chunks {
+		assert.True(t, chunkIDs[chunk.ID])
+	}
+	assert.True(t, result.Size() >= ChunkSize+10+64)
+
+	// dry run deletes nothing
+	assert.Nil(t, trx.Where("id = ?", orphanObject.ID).Find(&Object{}).Error)
+	assert.True(t, util.IsFile(orphanChunk.Path(&tempDir)))
+
+	result, err = CollectGarbage(grace, false, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.True(t, len(result.Objects) >= 2)
+	assert.True(t, util.IsRecordNotFound(trx.Where("id = ?", orphanObject.ID).Find(&Object{}).Error))
+	assert.True(t, util.IsRecordNotFound(trx.Where("id = ?", orphanChunk.ID).Find(&Chunk{}).Error))
+	assert.False(t, util.IsFile(orphanChunk.Path(&tempDir)))
+	for _, chunk := range orphanObject.Chunks {
+		assert.False(t, util.IsFile(chunk.Path(&tempDir)))
+	}
+	count := 0
+	assert.Nil(t, trx.Model(&ObjectChunk{}).Where("objectId = ?", orphanObject.ID).Count(&count).Error)
+	assert.Equal(t, 0, count)
+
+	// the files that are alive or in trash are still readable
+	for _, f := range []*File{file, trashedFile, sharedFile} {
+		f.Object = Object{}
+		reader, err := f.Reader(&tempDir, trx)
+		assert.Nil(t, err)
+		content, err := ioutil.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.Equal(t, f.Size, len(content))
+	}
+
+	result, err = CollectGarbage(time.Hour, false, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(result.Objects))
+}
+
+func TestCollectGarbage2(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	content := Random(ChunkSize + 10)
+	session, err := NewUploadSession(app, "/upload/random.bytes", len(content)+10, int8(0), trx)
+	assert.Nil(t, err)
+	_, err = session.AppendFromReader(0, bytes.NewReader(content), &tempDir, trx)
+	assert.Nil(t, err)
+	expired, err := NewUploadSession(app, "/upload/expired.bytes", len(content)+10, int8(0), trx)
+	assert.Nil(t, err)
+	_, err = expired.AppendFromReader(0, bytes.NewReader(Random(64)), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, trx.Model(expired).Update("expiredAt", time.Now().Add(-time.Minute)).Error)
+	assert.Nil(t, trx.Preload("Chunks").Find(session).Error)
+	assert.Nil(t, trx.Preload("Chunks").Find(expired).Error)
+
+	// the chunks of upload sessions in progress are kept
+	result, err := CollectGarbage(-time.Minute, false, &tempDir, trx)
+	assert.Nil(t, err)
+	chunkIDs := map[uint64]bool{}
+	for _, chunk := range result.Chunks {
+		chunkIDs[chunk.ID] = true
+	}
+	for _, sc := range session.Chunks {
+		assert.False(t, chunkIDs[sc.ChunkID])
+	}
+	for _, sc := range expired.Chunks {
+		assert.True(t, chunkIDs[sc.ChunkID])
+	}
+	_, err = FindUploadSessionByUID(expired.UID, trx)
+	assert.True(t, util.IsRecordNotFound(err))
+
+	_, err = session.AppendFromReader(session.Offset, bytes.NewReader(Random(10)), &tempDir, trx)
+	assert.Nil(t, err)
+	file, err := session.Complete(&tempDir, trx)
+	assert.Nil(t, err)
+	reader, err := file.Reader(&tempDir, trx)
+	assert.Nil(t, err)
+	readContent, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, content, readContent[:len(content)])
+}
+
+func TestCollectGarbage3(t *testing.T) {
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	content := Random(256)
+	object, err := CreateObjectFromReader(0, bytes.NewReader(content), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, trx.Preload("Chunks").Find(object).Error)
+	chunk := object.Chunks[0]
+
+	// the orphans are marked, then they are reused by an upload
+	time.Sleep(10 * time.Millisecond)
+	deadline := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	reused, err := CreateObjectFromReader(0, bytes.NewReader(content), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, object.ID, reused.ID)
+	reusedChunk, err := CreateChunkFromBytes(0, content, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, chunk.ID, reusedChunk.ID)
+
+	swept, err := sweepObject(object, deadline, trx)
+	assert.Nil(t, err)
+	assert.False(t, swept)
+	swept, err = sweepChunk(&chunk, deadline, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.False(t, swept)
+	assert.True(t, util.IsFile(chunk.Path(&tempDir)))
+
+	// the rows that have been collected can't be reused
+	deadline = time.Now().Add(time.Hour)
+	swept, err = sweepObject(object, deadline, trx)
+	assert.Nil(t, err)
+	assert.True(t, swept)
+	assert.True(t, util.IsRecordNotFound(touch(object, trx)))
+}
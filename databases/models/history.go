@@ -4,19 +4,69 @@
 
 package models
 
-import "time"
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrHistoryNotBelongToFile represent that try to restore a file to the history of another file
+var ErrHistoryNotBelongToFile = errors.New("history doesn't belong to the file")
 
 // History represent the overwrite history of object. By this, we
-// can easily find kinds of versions of the object.
+// can easily find kinds of versions of the object. Moved represent
+// that the history only records the previous path of a moved file,
+// the content isn't changed, so it isn't counted as a version.
 type History struct {
 	ID        uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT;primary_key"`
 	ObjectID  uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL;column:objectId"`
 	FileID    uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL;column:fileId"`
-	Path      string    `gorm:"type:tinyint;column:path"`
+	Path      string    `gorm:"type:VARCHAR(1000) NOT NULL;column:path"`
+	Moved     int8      `gorm:"type:tinyint;column:moved;DEFAULT:0"`
 	CreatedAt time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:createdAt"`
+
+	Object Object `gorm:"foreignkey:objectId;association_autoupdate:false;association_autocreate:false"`
 }
 
 // TableName represent the name of history table
 func (h *History) TableName() string {
 	return "histories"
 }
+
+// Reader is used to read the content of this version
+func (h *History) Reader(rootPath *string, db *gorm.DB) (ObjectReader, error) {
+	if len(h.Object.Chunks) == 0 {
+		if err := db.Preload("Chunks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("object_chunk.number asc")
+		}).Where("id = ?", h.ObjectID).Find(&h.Object).Error; err != nil {
+			return nil, err
+		}
+	}
+	return (&h.Object).Reader(rootPath)
+}
+
+// FindHistories is used to find the histories of file, the latest one is the first.
+// The total count of histories is returned as well.
+func (f *File) FindHistories(offset, limit int, db *gorm.DB) (int, []History, error) {
+	var (
+		err       error
+		total     int
+		histories []History
+	)
+	if err = db.Model(&History{}).Where("fileId = ?", f.ID).Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+	err = db.Preload("Object").Where("fileId = ?", f.ID).
+		Order("id desc").Offset(offset).Limit(limit).Find(&histories).Error
+	return total, histories, err
+}
+
+// FindHistory is used to find a history of file by id
+func (f *File) FindHistory(id uint64, db *gorm.DB) (*History, error) {
+	var history = &History{}
+	if err := db.Preload("Object").Where("id = ? and fileId = ?", id, f.ID).First(history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
+}
@@ -5,11 +5,116 @@
 package models
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
 	"testing"
 
+	"github.com/bigfile/bigfile/internal/util"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestHistory_TableName(t *testing.T) {
 	assert.Equal(t, "histories", (&History{}).TableName())
 }
+
+func TestFile_FindHistories(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	var contents = [][]byte{Random(10), Random(20), Random(30)}
+	file, err := CreateFileFromReader(app, "/history/random.bytes", bytes.NewReader(contents[0]), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	for _, content := range contents[1:] {
+		assert.Nil(t, file.OverWriteFromReader(bytes.NewReader(content), int8(0), &tempDir, trx))
+	}
+	assert.Nil(t, file.MoveTo("/history/moved.bytes", trx))
+
+	total, histories, err := file.FindHistories(0, 10, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, 3, len(histories))
+	// the latest one is the first
+	assert.Equal(t, "/history/random.bytes", histories[0].Path)
+	assert.Equal(t, int64(30), histories[0].Object.Size)
+	assert.Equal(t, int64(20), histories[1].Object.Size)
+	assert.Equal(t, int64(10), histories[2].Object.Size)
+
+	total, histories, err = file.FindHistories(2, 10, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, 1, len(histories))
+
+	history, err := file.FindHistory(histories[0].ID, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, histories[0].ObjectID, history.Object.ID)
+
+	reader, err := history.Reader(&tempDir, trx)
+	assert.Nil(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, contents[0], content)
+
+	other, err := CreateFileFromReader(app, "/history/other.bytes", bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = other.FindHistory(history.ID, trx)
+	assert.True(t, util.IsRecordNotFound(err))
+}
+
+func TestFile_RestoreHistory(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	var (
+		content1 = Random(10)
+		content2 = Random(20)
+	)
+	file, err := CreateFileFromReader(app, "/history/random.bytes", bytes.NewReader(content1), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, file.OverWriteFromReader(bytes.NewReader(content2), int8(0), &tempDir, trx))
+	currentObjectID := file.ObjectID
+
+	_, histories, err := file.FindHistories(0, 10, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, file.RestoreHistory(&histories[0], trx))
+	assert.Equal(t, histories[0].ObjectID, file.ObjectID)
+	assert.Equal(t, 10, file.Size)
+
+	// restoring creates a history too
+	total, histories, err := file.FindHistories(0, 10, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, currentObjectID, histories[0].ObjectID)
+
+	reader, err := file.Reader(&tempDir, trx)
+	assert.Nil(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, content1, content)
+
+	root, err := CreateOrGetRootPath(app, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, root.Size)
+
+	other, err := CreateFileFromReader(app, "/history/other.bytes", bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrHistoryNotBelongToFile, other.RestoreHistory(&histories[0], trx))
+
+	dir, err := CreateOrGetLastDirectory(app, "/history", trx)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrOverwriteDir, dir.RestoreHistory(&histories[0], trx))
+}
@@ -21,13 +21,17 @@ import (
 
 // Object represent a documentation that is correspond to system
 // An object has many chunks, it's saved in disk by chunk. But,
-// a file is a documentation that is correspond to user.
+// a file is a documentation that is correspond to user. AppID
+// represent the app that object is deduplicated in, 0 represent
+// that object is shared by all apps, see contentScope.
 type Object struct {
-	ID        uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT;primary_key"`
-	Size      int       `gorm:"type:int;column:size"`
-	Hash      string    `gorm:"type:CHAR(64) NOT NULL;UNIQUE;column:hash"`
-	CreatedAt time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:createdAt"`
-	UpdatedAt time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:updatedAt"`
+	ID          uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT;primary_key"`
+	AppID       uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL;DEFAULT:0;column:appId;unique_index:appId_hash_UNIQUE"`
+	Size        int64     `gorm:"type:BIGINT(20) UNSIGNED NOT NULL;column:size"`
+	Hash        string    `gorm:"type:CHAR(64) NOT NULL;column:hash;unique_index:appId_hash_UNIQUE"`
+	Quarantined int8      `gorm:"type:tinyint;column:quarantined;DEFAULT:0"`
+	CreatedAt   time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:createdAt"`
+	UpdatedAt   time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:updatedAt"`
 
 	Files        []File        `gorm:"foreignkey:objectId;association_autoupdate:false;association_autocreate:false"`
 	Chunks       []Chunk       `gorm:"many2many:object_chunk;association_jointable_foreignkey:chunkId;jointable_foreignkey:objectId;association_autoupdate:false;association_autocreate:false"`
@@ -57,7 +61,7 @@ func (o *Object) LastChunk(db *gorm.DB) (*Chunk, error) {
 		chunk           = &Chunk{}
 		err             error
 	)
-	err = db.Joins(joinObjectChunk, o.ID).Order("chunks.id desc").First(chunk).Error
+	err = db.Joins(joinObjectChunk, o.ID).Order("object_chunk.number desc").First(chunk).Error
 	return chunk, err
 }
 
@@ -81,7 +85,7 @@ func (o *Object) LastChunkNumber(db *gorm.DB) (int, error) {
 // LastObjectChunk return the middle value between chunk and object
 func (o *Object) LastObjectChunk(db *gorm.DB) (*ObjectChunk, error) {
 	err := db.Preload("ObjectChunks", func(db *gorm.DB) *gorm.DB {
-		return db.Order("object_chunk.id desc").Limit(1)
+		return db.Order("object_chunk.number desc").Limit(1)
 	}).Find(o).Error
 	if len(o.ObjectChunks) == 0 {
 		return nil, err
@@ -89,24 +93,26 @@ func (o *Object) LastObjectChunk(db *gorm.DB) (*ObjectChunk, error) {
 	return &o.ObjectChunks[0], nil
 }
 
-// AppendFromReader will append content from reader to object
-func (o *Object) AppendFromReader(reader io.Reader, rootPath *string, db *gorm.DB) (*Object, int, error) {
+// AppendFromReader will append content of app from reader to object. Content is
+// read chunk by chunk, so memory use is bounded by the chunk size. The content that
+// completes the last chunk is kept in memory until the end, so that the last
+// chunk is changed only when the new object is really needed.
+func (o *Object) AppendFromReader(appID uint64, reader io.Reader, rootPath *string, db *gorm.DB) (*Object, int, error) {
 	var (
-		err              error
-		lastOc           *ObjectChunk
-		object           *Object
-		lastChunk        *Chunk
-		stateHash        hash.Hash
-		readerContent    []byte
-		completeHashStr  string
-		readerContentLen int
+		err             error
+		size            int
+		lastOc          *ObjectChunk
+		object          *Object
+		lastChunk       = &Chunk{}
+		stateHash       hash.Hash
+		lackContent     []byte
+		lackHashState   string
+		objectChunks    []ObjectChunk
+		completeHashStr string
 	)
-	if readerContent, err = ioutil.ReadAll(reader); err != nil {
-		return o, 0, err
-	}
 
-	if readerContentLen = len(readerContent); readerContentLen <= 0 {
-		return o, 0, nil
+	if appID, err = contentScope(appID); err != nil {
+		return o, 0, err
 	}
 
 	if lastOc, err = o.LastObjectChunk(db); err != nil {
@@ -115,23 +121,52 @@ func (o *Object) AppendFromReader(reader io.Reader, rootPath *string, db *gorm.D
 		return o, 0, errors.New("unexpected error happened, object must have some chunks")
 	}
 
+	if err = db.Where("id = ?", lastOc.ChunkID).Find(lastChunk).Error; err != nil {
+		return o, 0, err
+	}
+
+	if !isFixedChunking() {
+		return o.appendFromReaderByCDC(appID, lastOc, lastChunk, reader, rootPath, db)
+	}
+
 	if stateHash, err = sha2562.NewHashWithStateText(*lastOc.HashState); err != nil {
 		return o, 0, err
 	}
-	if _, err = stateHash.Write(readerContent); err != nil {
+
+	if lackSize := ChunkSize - lastChunk.Size; lackSize > 0 {
+		var readLen int
+		lackContent = make([]byte, lackSize)
+		if readLen, err = io.ReadFull(reader, lackContent); err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return o, 0, err
+		}
+		lackContent = lackContent[:readLen]
+		if _, err = stateHash.Write(lackContent); err != nil {
+			return o, 0, err
+		}
+		if lackHashState, err = sha2562.GetHashStateText(stateHash); err != nil {
+			return o, 0, err
+		}
+	}
+
+	if objectChunks, size, err = writeChunksFromReader(appID, reader, lastOc.Number, stateHash, rootPath, db); err != nil {
 		return o, 0, err
 	}
+
+	if size += len(lackContent); size <= 0 {
+		return o, 0, nil
+	}
+
 	completeHashStr = hex.EncodeToString(stateHash.Sum(nil))
-	if object, err = FindObjectByHash(completeHashStr, db); err == nil && object != nil {
-		return object, len(readerContent), nil
+	if object, err = findReusableObject(appID, completeHashStr, db); err == nil {
+		return object, size, nil
 	}
 
 	object = &Object{
-		Size: o.Size + len(readerContent),
-		Hash: completeHashStr,
+		AppID: appID,
+		Size:  o.Size + int64(size),
+		Hash:  completeHashStr,
 	}
-	stateHash, _ = sha2562.NewHashWithStateText(*lastOc.HashState)
-	if err = db.Where("objectId = ?", o.ID).Find(&object.ObjectChunks).Error; err != nil {
+	if err = db.Where("objectId = ?", o.ID).Order("number asc").Find(&object.ObjectChunks).Error; err != nil {
 		return o, 0, err
 	}
 	// determine if we need to copy the object
@@ -146,93 +181,176 @@ func (o *Object) AppendFromReader(reader io.Reader, rootPath *string, db *gorm.D
 		}
 	}
 
-	// get the last chunk of object, determine if we need to complete the last chunk
-	if lastChunk, err = o.LastChunk(db); err != nil {
+	if len(lackContent) > 0 {
+		var chunk *Chunk
+		if chunk, _, err = lastChunk.AppendBytes(appID, lackContent, rootPath, db); err != nil {
+			return o, 0, err
+		}
+		object.ObjectChunks[len(object.ObjectChunks)-1].ChunkID = chunk.ID
+		object.ObjectChunks[len(object.ObjectChunks)-1].HashState = &lackHashState
+	}
+
+	if object, err = saveObjectWithChunks(object, append(object.ObjectChunks, objectChunks...), db); err != nil {
 		return o, 0, err
 	}
 
-	if lackSize := ChunkSize - lastChunk.Size; lackSize > 0 {
-		var (
-			err       error
-			chunk     *Chunk
-			hashState string
-		)
+	return object, size, nil
+}
 
-		if lackSize > len(readerContent) {
-			lackSize = len(readerContent)
-		}
+// appendFromReaderByCDC is used to append content when content-defined chunking is used.
+// The boundary of the last chunk may move after new content arrives, so content is split
+// again from the start of the last chunk. The last chunk isn't changed, and the chunks
+// before it are reused.
+func (o *Object) appendFromReaderByCDC(
+	appID uint64, lastOc *ObjectChunk, lastChunk *Chunk, reader io.Reader, rootPath *string, db *gorm.DB) (*Object, int, error) {
+	var (
+		err             error
+		size            int
+		object          *Object
+		chunkReader     ChunkReader
+		lastContent     []byte
+		stateHash       = sha256.New()
+		objectChunks    []ObjectChunk
+		completeHashStr string
+	)
+
+	if chunkReader, err = lastChunk.Reader(rootPath); err != nil {
+		return o, 0, err
+	}
+	lastContent, err = ioutil.ReadAll(chunkReader)
+	_ = chunkReader.Close()
+	if err != nil {
+		return o, 0, err
+	}
 
-		if chunk, _, err = lastChunk.AppendBytes(readerContent[:lackSize], rootPath, db); err != nil {
+	if lastOc.Number > 1 {
+		var prevOc = &ObjectChunk{}
+		if err = db.Where("objectId = ? and number = ?", o.ID, lastOc.Number-1).First(prevOc).Error; err != nil {
 			return o, 0, err
 		}
-		if chunk.ID != lastChunk.ID {
-			object.ObjectChunks[len(object.ObjectChunks)-1].ChunkID = chunk.ID
-		}
-		if _, err := stateHash.Write(readerContent[:lackSize]); err != nil {
+		if stateHash, err = sha2562.NewHashWithStateText(*prevOc.HashState); err != nil {
 			return o, 0, err
 		}
-		if hashState, err = sha2562.GetHashStateText(stateHash); err != nil {
+	}
+
+	reader = io.MultiReader(bytes.NewReader(lastContent), reader)
+	if objectChunks, size, err = writeChunksFromReader(appID, reader, lastOc.Number-1, stateHash, rootPath, db); err != nil {
+		return o, 0, err
+	}
+
+	if size -= len(lastContent); size <= 0 {
+		return o, 0, nil
+	}
+
+	completeHashStr = hex.EncodeToString(stateHash.Sum(nil))
+	if object, err = findReusableObject(appID, completeHashStr, db); err == nil {
+		return object, size, nil
+	}
+
+	object = &Object{
+		AppID: appID,
+		Size:  o.Size + int64(size),
+		Hash:  completeHashStr,
+	}
+	if err = db.Where("objectId = ? and number < ?", o.ID, lastOc.Number).
+		Order("number asc").Find(&object.ObjectChunks).Error; err != nil {
+		return o, 0, err
+	}
+	if o.FileCount(db)+db.Model(o).Association("Histories").Count() <= 1 {
+		object.ID = o.ID
+		object.CreatedAt = o.CreatedAt
+		object.UpdatedAt = o.UpdatedAt
+		if err = db.Delete(lastOc).Error; err != nil {
 			return o, 0, err
 		}
-		object.ObjectChunks[len(object.ObjectChunks)-1].HashState = &hashState
-		readerContent = readerContent[lackSize:]
+	} else {
+		for index := range object.ObjectChunks {
+			object.ObjectChunks[index].ID = 0
+		}
 	}
-	if err = appendContentToObject(
-		object, object.ObjectChunks, readerContent, lastOc.Number, stateHash, rootPath, db); err != nil {
-		return o, 0, nil
+
+	if object, err = saveObjectWithChunks(object, append(object.ObjectChunks, objectChunks...), db); err != nil {
+		return o, 0, err
 	}
 
-	return object, readerContentLen, nil
+	return object, size, nil
 }
 
-// Reader is used to implement io.Reader
-func (o *Object) Reader(rootPath *string) (io.Reader, error) {
+// Reader is used to get a reader of the content of object, the content of
+// quarantined object can't be read
+func (o *Object) Reader(rootPath *string) (ObjectReader, error) {
+	if o.Quarantined == 1 {
+		return nil, ErrObjectQuarantined
+	}
 	return NewObjectReader(o, rootPath)
 }
 
-// FindObjectByHash will find object by the specify hash
-func FindObjectByHash(h string, db *gorm.DB) (*Object, error) {
+// findReusableObject is used to find the object of app that has the same content for
+// dedup, the quarantined object can't be reused. Its updatedAt is bumped, so that it
+// won't be collected by gc while it's reused.
+func findReusableObject(appID uint64, h string, db *gorm.DB) (*Object, error) {
+	object, err := FindObjectByHash(appID, h, db)
+	if err == nil && object.Quarantined == 1 {
+		err = ErrObjectQuarantined
+	}
+	if err == nil {
+		err = touch(object, db)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// FindObjectByHash will find the object of app by the specify hash, appID 0
+// represent the objects shared by all apps
+func FindObjectByHash(appID uint64, h string, db *gorm.DB) (*Object, error) {
 	var object Object
-	var err = db.Where("hash = ?", h).First(&object).Error
+	var err = db.Where("appId = ? and hash = ?", appID, h).First(&object).Error
 	return &object, err
 }
 
-// CreateObjectFromReader reads data from reader to create an object
-func CreateObjectFromReader(reader io.Reader, rootPath *string, db *gorm.DB) (*Object, error) {
+// CreateObjectFromReader reads data of app from reader to create an object. Content
+// is read and saved chunk by chunk, the hash is calculated incrementally. So,
+// memory use is bounded by the chunk size, whatever the size of content.
+func CreateObjectFromReader(appID uint64, reader io.Reader, rootPath *string, db *gorm.DB) (*Object, error) {
 	var (
-		err           error
-		object        *Object
-		sha256Hash    = sha256.New()
-		contentHash   string
-		readerContent []byte
+		err          error
+		size         int
+		object       *Object
+		sha256Hash   = sha256.New()
+		contentHash  string
+		objectChunks []ObjectChunk
 	)
 
-	if readerContent, err = ioutil.ReadAll(reader); err != nil {
+	if appID, err = contentScope(appID); err != nil {
 		return nil, err
 	}
 
-	if len(readerContent) == 0 {
-		return CreateEmptyObject(rootPath, db)
+	if objectChunks, size, err = writeChunksFromReader(appID, reader, 0, sha256Hash, rootPath, db); err != nil {
+		return nil, err
 	}
 
-	if contentHash, err = util.Sha256Hash2String(readerContent); err != nil {
-		return nil, err
+	if size == 0 {
+		return CreateEmptyObject(appID, rootPath, db)
 	}
 
-	if object, err = FindObjectByHash(contentHash, db); err == nil && object != nil {
+	contentHash = hex.EncodeToString(sha256Hash.Sum(nil))
+	if object, err = findReusableObject(appID, contentHash, db); err == nil {
 		return object, nil
 	}
 
 	object = &Object{
-		Size: len(readerContent),
-		Hash: contentHash,
+		AppID: appID,
+		Size:  int64(size),
+		Hash:  contentHash,
 	}
 
-	return object, appendContentToObject(object, nil, readerContent, 0, sha256Hash, rootPath, db)
+	return saveObjectWithChunks(object, objectChunks, db)
 }
 
-// CreateEmptyObject is used to create an empty object
-func CreateEmptyObject(rootPath *string, db *gorm.DB) (*Object, error) {
+// CreateEmptyObject is used to create an empty object of app
+func CreateEmptyObject(appID uint64, rootPath *string, db *gorm.DB) (*Object, error) {
 	var (
 		h                = sha256.New()
 		err              error
@@ -242,11 +360,15 @@ func CreateEmptyObject(rootPath *string, db *gorm.DB) (*Object, error) {
 		emptyContentHash = hex.EncodeToString(h.Sum(nil))
 	)
 
-	if object, err = FindObjectByHash(emptyContentHash, db); err == nil && object != nil {
+	if appID, err = contentScope(appID); err != nil {
+		return nil, err
+	}
+
+	if object, err = findReusableObject(appID, emptyContentHash, db); err == nil {
 		return object, nil
 	}
 
-	if chunk, err = CreateEmptyContentChunk(rootPath, db); err != nil {
+	if chunk, err = CreateEmptyContentChunk(appID, rootPath, db); err != nil {
 		return nil, err
 	}
 
@@ -255,8 +377,9 @@ func CreateEmptyObject(rootPath *string, db *gorm.DB) (*Object, error) {
 	}
 
 	object = &Object{
-		Size: 0,
-		Hash: emptyContentHash,
+		AppID: appID,
+		Size:  0,
+		Hash:  emptyContentHash,
 		ObjectChunks: []ObjectChunk{
 			{
 				ChunkID:   chunk.ID,
@@ -266,51 +389,112 @@ func CreateEmptyObject(rootPath *string, db *gorm.DB) (*Object, error) {
 		},
 	}
 
-	return object, db.Set("gorm:association_autocreate", true).Save(object).Error
+	if err = db.Set("gorm:association_autocreate", true).Save(object).Error; err != nil {
+		return findDuplicateObject(appID, object.Hash, object.ObjectChunks, err, db)
+	}
+
+	return object, nil
 }
 
-func appendContentToObject(obj *Object, oc []ObjectChunk, readerContent []byte, index int, hash hash.Hash, rootPath *string, db *gorm.DB) error {
+// writeChunksFromReader splits content from reader into chunks by the default chunker,
+// and saves every chunk of app as soon as it's split. The numbers of chunks start from
+// index+1. It returns the object chunks that aren't saved and the total size of content.
+func writeChunksFromReader(appID uint64, reader io.Reader, index int, hash hash.Hash, rootPath *string, db *gorm.DB) ([]ObjectChunk, int, error) {
 	var (
-		err        error
-		contentBuf = bytes.NewReader(readerContent)
+		err     error
+		oc      []ObjectChunk
+		size    int
+		chunker Chunker
 	)
 
-	for i := index; contentBuf.Len() > 0; i++ {
+	if chunker, err = newDefaultChunker(reader); err != nil {
+		return nil, 0, err
+	}
+
+	for i := index; ; i++ {
 		var (
 			chunk     *Chunk
-			content   = make([]byte, ChunkSize)
-			readLen   int
+			content   []byte
 			hashState string
 		)
-		if readLen, err = contentBuf.Read(content); err != nil {
-			return err
+		if content, err = chunker.Next(); err == io.EOF {
+			return oc, size, nil
+		} else if err != nil {
+			return nil, 0, err
 		}
-		if chunk, err = CreateChunkFromBytes(content[:readLen], rootPath, db); err != nil {
-			return err
+		if chunk, err = CreateChunkFromBytes(appID, content, rootPath, db); err != nil {
+			return nil, 0, err
 		}
-		if _, err := hash.Write(content[:readLen]); err != nil {
-			return err
+		if _, err = hash.Write(content); err != nil {
+			return nil, 0, err
 		}
 		if hashState, err = sha2562.GetHashStateText(hash); err != nil {
-			return err
+			return nil, 0, err
 		}
 		oc = append(oc, ObjectChunk{
 			ChunkID:   chunk.ID,
 			Number:    i + 1,
 			HashState: &hashState,
 		})
+		size += len(content)
 	}
+}
 
-	if err = db.Save(obj).Error; err != nil {
-		return err
+// saveObjectWithChunks is used to save object and its object chunks. If the same
+// content is saved by another request concurrently, the object saved by it is
+// returned instead.
+func saveObjectWithChunks(obj *Object, oc []ObjectChunk, db *gorm.DB) (*Object, error) {
+	if err := db.Save(obj).Error; err != nil {
+		return findDuplicateObject(obj.AppID, obj.Hash, oc, err, db)
 	}
 
 	for _, objectChunk := range oc {
 		objectChunk.ObjectID = obj.ID
 		if err := db.Save(&objectChunk).Error; err != nil {
-			return err
+			return nil, err
+		}
+	}
+
+	return obj, nil
+}
+
+// findDuplicateObject is used to find the object of app that has the same hash when
+// err is caused by the unique index of hash, it's read by a locking read, so the row
+// committed by others can be seen in a transaction. If the object is quarantined,
+// it's repaired by oc that has the same content. Otherwise, err is returned.
+func findDuplicateObject(appID uint64, h string, oc []ObjectChunk, err error, db *gorm.DB) (*Object, error) {
+	if !util.IsDuplicateEntry(err) {
+		return nil, err
+	}
+	object, findErr := FindObjectByHash(appID, h, forUpdate(db))
+	if findErr == nil && object.Quarantined == 1 {
+		if findErr = object.repair(oc, db); findErr != nil {
+			return nil, findErr
 		}
 	}
+	if findErr == nil {
+		findErr = touch(object, db)
+	}
+	if findErr != nil {
+		return nil, err
+	}
+	return object, nil
+}
 
-	return nil
+// repair replaces the chunks of quarantined object by oc, and releases the quarantine
+// of it and its files. The chunks of oc are written just now, so they are intact.
+func (o *Object) repair(oc []ObjectChunk, db *gorm.DB) error {
+	if err := db.Where("objectId = ?", o.ID).Delete(&ObjectChunk{}).Error; err != nil {
+		return err
+	}
+	for _, objectChunk := range oc {
+		// the rows of oc may belong to another object, new rows are created
+		objectChunk.ID = 0
+		objectChunk.ObjectID = o.ID
+		if err := db.Save(&objectChunk).Error; err != nil {
+			return err
+		}
+	}
+	o.Quarantined = 0
+	return quarantineObject(o, 0, true, db)
 }
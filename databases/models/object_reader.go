@@ -7,7 +7,6 @@ package models
 import (
 	"errors"
 	"io"
-	"os"
 )
 
 var (
@@ -15,20 +14,38 @@ var (
 	ErrInvalidObject = errors.New("invalid object")
 	// ErrObjectNoChunks represent that a object has no any chunks
 	ErrObjectNoChunks = errors.New("object has no any chunks")
+	// ErrInvalidWhence represent that the whence of seek is unknown
+	ErrInvalidWhence = errors.New("seek: invalid whence")
+	// ErrNegativePosition represent that try to seek to a negative position
+	ErrNegativePosition = errors.New("seek: negative position")
+	// ErrObjectReaderClosed represent that the reader has been closed
+	ErrObjectReaderClosed = errors.New("object reader has been closed")
 )
 
-// ObjectReader is used to read data from underlying chunk.
-// until read all data, it will return io.EOF
+// ObjectReader is used to read the content of object, it must be closed after
+// reading, so that the chunk that is being read is closed.
+type ObjectReader interface {
+	io.Reader
+	io.Seeker
+	io.Closer
+}
+
+// objectReader is used to read data from underlying chunk.
+// until read all data, it will return io.EOF. Chunks may have
+// different sizes when content-defined chunking is used, so the
+// chunk that contains a position is found by the sizes of chunks.
 type objectReader struct {
 	object *Object
 
 	rootPath           *string
+	offset             int64
+	positioned         bool
 	currentChunkIndex  int
-	currentChunkReader *os.File
+	currentChunkReader ChunkReader
 }
 
 // NewObjectReader is used to create a reader that read data from underlying chunk
-func NewObjectReader(object *Object, rootPath *string) (io.Reader, error) {
+func NewObjectReader(object *Object, rootPath *string) (ObjectReader, error) {
 
 	if object == nil {
 		return nil, ErrInvalidObject
@@ -40,7 +57,7 @@ func NewObjectReader(object *Object, rootPath *string) (io.Reader, error) {
 
 	var (
 		err         error
-		chunkReader *os.File
+		chunkReader ChunkReader
 	)
 
 	if chunkReader, err = object.Chunks[0].Reader(rootPath); err != nil {
@@ -52,6 +69,7 @@ func NewObjectReader(object *Object, rootPath *string) (io.Reader, error) {
 		currentChunkIndex:  0,
 		currentChunkReader: chunkReader,
 		rootPath:           rootPath,
+		positioned:         true,
 	}, nil
 }
 
@@ -61,12 +79,24 @@ func (or *objectReader) Read(p []byte) (int, error) {
 		return 0, nil
 	}
 
+	if or.currentChunkReader == nil {
+		return 0, ErrObjectReaderClosed
+	}
+
 	var (
 		err       error
 		readCount int
 	)
 
-	if readCount, err = or.currentChunkReader.Read(p); err != nil {
+	if !or.positioned {
+		if err = or.position(); err != nil {
+			return 0, err
+		}
+	}
+
+	readCount, err = or.currentChunkReader.Read(p)
+	or.offset += int64(readCount)
+	if err != nil {
 		if err == io.EOF {
 			if or.currentChunkIndex == len(or.object.Chunks)-1 {
 				return readCount, io.EOF
@@ -83,3 +113,69 @@ func (or *objectReader) Read(p []byte) (int, error) {
 
 	return readCount, nil
 }
+
+// Close implements io.Closer, the current chunk reader is closed
+func (or *objectReader) Close() error {
+	if or.currentChunkReader == nil {
+		return nil
+	}
+	err := or.currentChunkReader.Close()
+	or.currentChunkReader = nil
+	return err
+}
+
+// Seek implements io.Seeker, the real seek is delayed until next Read
+func (or *objectReader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = or.offset + offset
+	case io.SeekEnd:
+		abs = int64(or.object.Size) + offset
+	default:
+		return 0, ErrInvalidWhence
+	}
+	if abs < 0 {
+		return 0, ErrNegativePosition
+	}
+	if abs != or.offset {
+		or.offset = abs
+		or.positioned = false
+	}
+	return abs, nil
+}
+
+// position is used to open the chunk that contains offset, and move
+// the chunk reader to the right place
+func (or *objectReader) position() error {
+	var (
+		err        error
+		chunkIndex int
+		chunkStart int64
+	)
+
+	for ; chunkIndex < len(or.object.Chunks)-1; chunkIndex++ {
+		chunkSize := int64(or.object.Chunks[chunkIndex].Size)
+		if or.offset < chunkStart+chunkSize {
+			break
+		}
+		chunkStart += chunkSize
+	}
+
+	if chunkIndex != or.currentChunkIndex {
+		_ = or.currentChunkReader.Close()
+		if or.currentChunkReader, err = or.object.Chunks[chunkIndex].Reader(or.rootPath); err != nil {
+			return err
+		}
+		or.currentChunkIndex = chunkIndex
+	}
+
+	if _, err = or.currentChunkReader.Seek(or.offset-chunkStart, io.SeekStart); err != nil {
+		return err
+	}
+
+	or.positioned = true
+	return nil
+}
@@ -6,11 +6,13 @@ package models
 
 import (
 	"bytes"
+	"io"
 	"io/ioutil"
 	"os"
 	"testing"
 
 	"github.com/bigfile/bigfile/internal/util"
+	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,7 +39,7 @@ func newObjectForObjectReaderTest(t *testing.T) (*Object, *string, func(*testing
 	trx, down := setUpTestCaseWithTrx(nil, t)
 	randomBytesHash, err = util.Sha256Hash2String(randomBytes)
 	assert.Nil(t, err)
-	object, err = CreateObjectFromReader(randomBytesReader, &tempDir, trx)
+	object, err = CreateObjectFromReader(0, randomBytesReader, &tempDir, trx)
 	assert.Nil(t, err)
 	assert.Equal(t, object.Hash, randomBytesHash)
 	assert.Nil(t, trx.Preload("Chunks").Find(object).Error)
@@ -68,3 +70,128 @@ func TestObjectReader_Read(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, allContentHash, object.Hash)
 }
+
+func TestObjectReader_Seek(t *testing.T) {
+	object, rootPath, down := newObjectForObjectReaderTest(t)
+	defer down(t)
+	or, err := NewObjectReader(object, rootPath)
+	assert.Nil(t, err)
+
+	allContent, err := ioutil.ReadAll(or)
+	assert.Nil(t, err)
+
+	for _, offset := range []int64{ChunkSize + 10, 0, ChunkSize*2 + 100, ChunkSize - 1} {
+		position, err := or.Seek(offset, io.SeekStart)
+		assert.Nil(t, err)
+		assert.Equal(t, offset, position)
+		content := make([]byte, 20)
+		readCount, err := io.ReadFull(or, content)
+		if offset+20 > int64(len(allContent)) {
+			assert.Equal(t, io.ErrUnexpectedEOF, err)
+		} else {
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, allContent[offset:offset+int64(readCount)], content[:readCount])
+	}
+
+	position, err := or.Seek(-23, io.SeekEnd)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(allContent)-23), position)
+	position, err = or.Seek(3, io.SeekCurrent)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(allContent)-20), position)
+	tail, err := ioutil.ReadAll(or)
+	assert.Nil(t, err)
+	assert.Equal(t, allContent[len(allContent)-20:], tail)
+
+	_, err = or.Seek(-1, io.SeekStart)
+	assert.Equal(t, ErrNegativePosition, err)
+	_, err = or.Seek(0, 3)
+	assert.Equal(t, ErrInvalidWhence, err)
+}
+
+func TestObjectReader_Seek2(t *testing.T) {
+	defer useCDCForTest()()
+	var (
+		tempDir = NewTempDirForTest()
+		content = Random(64 << 10)
+	)
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	object, err := CreateObjectFromReader(0, bytes.NewReader(content), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, trx.Preload("Chunks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("object_chunk.number asc")
+	}).Find(object).Error)
+	assert.True(t, len(object.Chunks) > 1)
+
+	or, err := NewObjectReader(object, &tempDir)
+	assert.Nil(t, err)
+
+	// the chunks have different sizes, every boundary is checked
+	var offsets = []int64{int64(len(content) - 5)}
+	for index, start := 0, 0; index < len(object.Chunks); index++ {
+		offsets = append(offsets, int64(start), int64(start+object.Chunks[index].Size-1))
+		start += object.Chunks[index].Size
+	}
+	for _, offset := range offsets {
+		_, err = or.Seek(offset, io.SeekStart)
+		assert.Nil(t, err)
+		readContent := make([]byte, 10)
+		readCount, _ := io.ReadFull(or, readContent)
+		assert.Equal(t, content[offset:offset+int64(readCount)], readContent[:readCount])
+	}
+}
+
+func TestObjectReader_Seek3(t *testing.T) {
+	defer useCompressionForTest(ChunkCodecGzip)()
+	var (
+		tempDir = NewTempDirForTest()
+		content = bytes.Repeat([]byte("0123456789"), ChunkSize/5)
+	)
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	object, err := CreateObjectFromReader(0, bytes.NewReader(content), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(content)), object.Size)
+	assert.Nil(t, trx.Preload("Chunks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("object_chunk.number asc")
+	}).Find(object).Error)
+
+	or, err := NewObjectReader(object, &tempDir)
+	assert.Nil(t, err)
+	_, err = or.Seek(ChunkSize+3, io.SeekStart)
+	assert.Nil(t, err)
+	readContent, err := ioutil.ReadAll(or)
+	assert.Nil(t, err)
+	assert.Equal(t, content[ChunkSize+3:], readContent)
+}
+
+func TestObjectReader_Close(t *testing.T) {
+	object, rootPath, down := newObjectForObjectReaderTest(t)
+	defer down(t)
+	or, err := NewObjectReader(object, rootPath)
+	assert.Nil(t, err)
+
+	p := make([]byte, 10)
+	_, err = or.Read(p)
+	assert.Nil(t, err)
+	assert.Nil(t, or.Close())
+	assert.Nil(t, or.(*objectReader).currentChunkReader)
+	assert.Nil(t, or.Close())
+
+	_, err = or.Read(p)
+	assert.Equal(t, ErrObjectReaderClosed, err)
+}
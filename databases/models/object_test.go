@@ -8,10 +8,13 @@ import (
 	"bytes"
 	sha2562 "crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"hash"
+	"io/ioutil"
 	"os"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/bigfile/bigfile/internal/sha256"
 	"github.com/bigfile/bigfile/internal/util"
@@ -33,7 +36,7 @@ func TestObject_ChunkCount(t *testing.T) {
 	defer down(t)
 	h, err := util.Sha256Hash2String([]byte(content))
 	assert.Nil(t, err)
-	object := &Object{Size: size, Hash: h}
+	object := &Object{Size: int64(size), Hash: h}
 	assert.Nil(t, trx.Save(object).Error)
 	assert.True(t, object.ID > 0)
 	assert.Equal(t, 0, object.ChunkCount(trx))
@@ -50,7 +53,7 @@ func TestObject_ChunkCount2(t *testing.T) {
 	h, err := util.Sha256Hash2String([]byte(content))
 	assert.Nil(t, err)
 	object := &Object{
-		Size: size,
+		Size: int64(size),
 		Hash: h,
 		ObjectChunks: []ObjectChunk{
 			{
@@ -77,7 +80,7 @@ func TestObject_LastChunk(t *testing.T) {
 	defer down(t)
 	h, err := util.Sha256Hash2String([]byte(content))
 	assert.Nil(t, err)
-	object := &Object{Size: size, Hash: h}
+	object := &Object{Size: int64(size), Hash: h}
 	assert.Nil(t, trx.Save(object).Error)
 	assert.True(t, object.ID > 0)
 	assert.Equal(t, 0, object.ChunkCount(trx))
@@ -101,7 +104,7 @@ func TestObject_LastChunk2(t *testing.T) {
 	hash2, err := util.Sha256Hash2String([]byte(content2))
 	assert.Nil(t, err)
 	object := &Object{
-		Size: size,
+		Size: int64(size),
 		Hash: h,
 		ObjectChunks: []ObjectChunk{
 			{
@@ -141,7 +144,7 @@ func TestObject_LastChunkNumber(t *testing.T) {
 	defer down(t)
 	h, err := util.Sha256Hash2String([]byte(content))
 	assert.Nil(t, err)
-	object = &Object{Size: size, Hash: h}
+	object = &Object{Size: int64(size), Hash: h}
 	assert.Nil(t, trx.Save(object).Error)
 	assert.True(t, object.ID > 0)
 	assert.Equal(t, 0, object.ChunkCount(trx))
@@ -166,7 +169,7 @@ func TestObject_LastChunkNumber2(t *testing.T) {
 	hash2, err := util.Sha256Hash2String([]byte(content2))
 	assert.Nil(t, err)
 	object := &Object{
-		Size: size,
+		Size: int64(size),
 		Hash: h,
 		ObjectChunks: []ObjectChunk{
 			{
@@ -205,7 +208,7 @@ func TestObject_LastObjectChunk(t *testing.T) {
 	)
 	h, err := util.Sha256Hash2String([]byte(content))
 	assert.Nil(t, err)
-	object := &Object{Size: size, Hash: h}
+	object := &Object{Size: int64(size), Hash: h}
 	assert.Nil(t, trx.Save(object).Error)
 	assert.True(t, object.ID > 0)
 
@@ -230,9 +233,9 @@ func TestFindObjectByHash(t *testing.T) {
 	defer down(t)
 	h, err := util.Sha256Hash2String([]byte(content))
 	assert.Nil(t, err)
-	object := &Object{Size: size, Hash: h}
+	object := &Object{Size: int64(size), Hash: h}
 	assert.Nil(t, trx.Save(object).Error)
-	objectTmp, err := FindObjectByHash(h, trx)
+	objectTmp, err := FindObjectByHash(0, h, trx)
 	assert.Nil(t, err)
 	assert.Equal(t, objectTmp.ID, object.ID)
 }
@@ -244,7 +247,7 @@ func TestCreateObjectFromReader(t *testing.T) {
 		reader    = strings.NewReader(string(randomStr))
 	)
 	trx, down := setUpTestCaseWithTrx(nil, t)
-	object, err := CreateObjectFromReader(reader, &tempDir, trx)
+	object, err := CreateObjectFromReader(0, reader, &tempDir, trx)
 	assert.Nil(t, err)
 	defer func() {
 		if util.IsDir(tempDir) {
@@ -255,7 +258,38 @@ func TestCreateObjectFromReader(t *testing.T) {
 	h, err := util.Sha256Hash2String(randomStr)
 	assert.Nil(t, err)
 	assert.Equal(t, h, object.Hash)
-	assert.Equal(t, int(ChunkSize*2.5), object.Size)
+	assert.Equal(t, int64(ChunkSize*2.5), object.Size)
+}
+
+func TestCreateObjectFromReader2(t *testing.T) {
+	var (
+		tempDir     = NewTempDirForTest()
+		randomBytes = Random(ChunkSize*3 + 5)
+		// HalfReader makes every read return less data than requested
+		reader = iotest.HalfReader(bytes.NewReader(randomBytes))
+	)
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	defer func() {
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+		down(t)
+	}()
+
+	object, err := CreateObjectFromReader(0, reader, &tempDir, trx)
+	assert.Nil(t, err)
+	h, err := util.Sha256Hash2String(randomBytes)
+	assert.Nil(t, err)
+	assert.Equal(t, h, object.Hash)
+	assert.Equal(t, int64(ChunkSize*3+5), object.Size)
+	assert.Nil(t, trx.Preload("Chunks").Find(object).Error)
+	assert.Equal(t, 4, len(object.Chunks))
+	for _, chunk := range object.Chunks {
+		assert.True(t, chunk.Size == ChunkSize || chunk.Size == 5)
+	}
+
+	_, err = CreateObjectFromReader(0, iotest.TimeoutReader(bytes.NewReader(Random(ChunkSize*2))), &tempDir, trx)
+	assert.Equal(t, iotest.ErrTimeout, err)
 }
 
 func TestObject_FileCount(t *testing.T) {
@@ -268,7 +302,7 @@ func TestObject_FileCount(t *testing.T) {
 	defer down(t)
 	h, err := util.Sha256Hash2String([]byte(content))
 	assert.Nil(t, err)
-	object := &Object{Size: size, Hash: h}
+	object := &Object{Size: int64(size), Hash: h}
 	assert.Nil(t, trx.Save(object).Error)
 	file1 := &File{UID: bson.NewObjectId().Hex(), ObjectID: object.ID}
 	assert.Nil(t, trx.Save(file1).Error)
@@ -285,7 +319,7 @@ func TestObject_FileCount2(t *testing.T) {
 	defer down(t)
 	h, err := util.Sha256Hash2String([]byte(content))
 	assert.Nil(t, err)
-	object := &Object{Size: size, Hash: h}
+	object := &Object{Size: int64(size), Hash: h}
 	assert.Nil(t, trx.Save(object).Error)
 	assert.Equal(t, object.FileCount(trx), 0)
 }
@@ -309,7 +343,7 @@ func TestCreateEmptyObject(t *testing.T) {
 			os.RemoveAll(tempDir)
 		}
 	}()
-	object, err = CreateEmptyObject(&tempDir, trx)
+	object, err = CreateEmptyObject(0, &tempDir, trx)
 	assert.Nil(t, err)
 	assert.True(t, object.ID > 0)
 	assert.Equal(t, emptyContentHash, object.Hash)
@@ -335,10 +369,10 @@ func TestObject_AppendFromReader(t *testing.T) {
 		stateHash hash.Hash
 		object    *Object
 		reader    = strings.NewReader(string(randomStr))
-		prevSize  int
+		prevSize  int64
 	)
 	trx, down := setUpTestCaseWithTrx(nil, t)
-	object, err = CreateObjectFromReader(reader, &tempDir, trx)
+	object, err = CreateObjectFromReader(0, reader, &tempDir, trx)
 	assert.Nil(t, err)
 	defer func() {
 		if util.IsDir(tempDir) {
@@ -349,15 +383,15 @@ func TestObject_AppendFromReader(t *testing.T) {
 	_, err = h.Write(randomStr)
 	assert.Nil(t, err)
 	assert.Equal(t, hex.EncodeToString(h.Sum(nil)), object.Hash)
-	assert.Equal(t, int(ChunkSize*2.5), object.Size)
+	assert.Equal(t, int64(ChunkSize*2.5), object.Size)
 	assert.Equal(t, 3, object.ChunkCount(trx))
 
 	randomStr = Random(uint(ChunkSize * 0.5))
 	prevSize = object.Size
-	object, size, err = object.AppendFromReader(bytes.NewReader(randomStr), &tempDir, trx)
+	object, size, err = object.AppendFromReader(0, bytes.NewReader(randomStr), &tempDir, trx)
 	assert.Nil(t, err)
 	assert.Equal(t, int(ChunkSize*0.5), size)
-	assert.Equal(t, prevSize+int(ChunkSize*0.5), object.Size)
+	assert.Equal(t, prevSize+int64(ChunkSize*0.5), object.Size)
 	_, err = h.Write(randomStr)
 	assert.Nil(t, err)
 	assert.Equal(t, hex.EncodeToString(h.Sum(nil)), object.Hash)
@@ -371,10 +405,10 @@ func TestObject_AppendFromReader(t *testing.T) {
 	chunkSize := ChunkSize
 	randomStr = Random(uint(float64(chunkSize) * 0.12))
 	prevSize = object.Size
-	object, size, err = object.AppendFromReader(bytes.NewReader(randomStr), &tempDir, trx)
+	object, size, err = object.AppendFromReader(0, bytes.NewReader(randomStr), &tempDir, trx)
 	assert.Nil(t, err)
 	assert.Equal(t, int(float64(chunkSize)*0.12), size)
-	assert.Equal(t, int(float64(chunkSize)*0.12)+prevSize, object.Size)
+	assert.Equal(t, int64(float64(chunkSize)*0.12)+prevSize, object.Size)
 	_, err = h.Write(randomStr)
 	assert.Nil(t, err)
 	assert.Equal(t, hex.EncodeToString(h.Sum(nil)), object.Hash)
@@ -404,7 +438,7 @@ func TestObject_AppendFromReader2(t *testing.T) {
 		originContentHash string
 	)
 	trx, down := setUpTestCaseWithTrx(nil, t)
-	object, err = CreateObjectFromReader(reader, &tempDir, trx)
+	object, err = CreateObjectFromReader(0, reader, &tempDir, trx)
 	assert.Nil(t, err)
 	defer func() {
 		if util.IsDir(tempDir) {
@@ -416,7 +450,7 @@ func TestObject_AppendFromReader2(t *testing.T) {
 	assert.Nil(t, err)
 	originContentHash = hex.EncodeToString(h.Sum(nil))
 	assert.Equal(t, originContentHash, object.Hash)
-	assert.Equal(t, int(ChunkSize*2.5), object.Size)
+	assert.Equal(t, int64(ChunkSize*2.5), object.Size)
 	assert.Equal(t, 3, object.ChunkCount(trx))
 	oc, err = object.LastObjectChunk(trx)
 	assert.Nil(t, err)
@@ -431,7 +465,7 @@ func TestObject_AppendFromReader2(t *testing.T) {
 
 	chunkSize := ChunkSize
 	randomStr = Random(uint(float64(chunkSize) * 0.12))
-	object2, size, err = object.AppendFromReader(bytes.NewReader(randomStr), &tempDir, trx)
+	object2, size, err = object.AppendFromReader(0, bytes.NewReader(randomStr), &tempDir, trx)
 	assert.Nil(t, err)
 	assert.Equal(t, int(float64(chunkSize)*0.12), size)
 	_, err = h.Write(randomStr)
@@ -465,7 +499,7 @@ func TestObject_AppendFromReader3(t *testing.T) {
 		reader    = bytes.NewBuffer(randomStr)
 	)
 	trx, down := setUpTestCaseWithTrx(nil, t)
-	object, err = CreateObjectFromReader(reader, &tempDir, trx)
+	object, err = CreateObjectFromReader(0, reader, &tempDir, trx)
 	assert.Nil(t, err)
 	defer func() {
 		if util.IsDir(tempDir) {
@@ -476,7 +510,7 @@ func TestObject_AppendFromReader3(t *testing.T) {
 	_, err = h.Write(randomStr)
 	assert.Nil(t, err)
 	assert.Equal(t, hex.EncodeToString(h.Sum(nil)), object.Hash)
-	assert.Equal(t, ChunkSize, object.Size)
+	assert.Equal(t, int64(ChunkSize), object.Size)
 	assert.Equal(t, 1, object.ChunkCount(trx))
 	oc, err = object.LastObjectChunk(trx)
 	assert.Nil(t, err)
@@ -484,7 +518,7 @@ func TestObject_AppendFromReader3(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, object.Hash, hex.EncodeToString(stateHash.Sum(nil)))
 
-	object2, size, err = object.AppendFromReader(bytes.NewReader(randomStr), &tempDir, trx)
+	object2, size, err = object.AppendFromReader(0, bytes.NewReader(randomStr), &tempDir, trx)
 	assert.Nil(t, err)
 	assert.Equal(t, ChunkSize, size)
 	assert.Equal(t, object2.ID, object.ID)
@@ -500,3 +534,82 @@ func TestObject_Reader(t *testing.T) {
 	_, err := object.Reader(rootPath)
 	assert.Nil(t, err)
 }
+
+func TestObject_AppendFromReader4(t *testing.T) {
+	defer useCDCForTest()()
+	var (
+		tempDir = NewTempDirForTest()
+		content = Random(64 << 10)
+	)
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	object, err := CreateObjectFromReader(0, bytes.NewReader(content[:30<<10]), &tempDir, trx)
+	assert.Nil(t, err)
+	object2, size, err := object.AppendFromReader(0, bytes.NewReader(content[30<<10:]), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, len(content)-30<<10, size)
+	assert.Equal(t, object.ID, object2.ID)
+	assert.Equal(t, int64(len(content)), object2.Size)
+
+	// the appended object is split in the same way as it's created at once
+	oc, _, err := writeChunksFromReader(0, bytes.NewReader(content), 0, sha2562.New(), &tempDir, trx)
+	assert.Nil(t, err)
+
+	var appended []ObjectChunk
+	assert.Nil(t, trx.Where("objectId = ?", object2.ID).Order("number asc").Find(&appended).Error)
+	assert.Equal(t, len(oc), len(appended))
+	for index := range oc {
+		assert.Equal(t, oc[index].ChunkID, appended[index].ChunkID)
+		assert.Equal(t, index+1, appended[index].Number)
+	}
+
+	file := &File{Object: *object2, ObjectID: object2.ID}
+	reader, err := file.Reader(&tempDir, trx)
+	assert.Nil(t, err)
+	readContent, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, content, readContent)
+	hashStr, err := util.Sha256Hash2String(content)
+	assert.Nil(t, err)
+	assert.Equal(t, hashStr, object2.Hash)
+}
+
+func TestSaveObjectWithChunks(t *testing.T) {
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	defer down(t)
+
+	h := RandomWithMd5(256)
+	object := &Object{Size: 10, Hash: h}
+	assert.Nil(t, trx.Save(object).Error)
+
+	// the same content is saved by others concurrently
+	saved, err := saveObjectWithChunks(&Object{Size: 10, Hash: h}, []ObjectChunk{{ChunkID: 1, Number: 1}}, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, object.ID, saved.ID)
+
+	_, err = findDuplicateObject(0, h, nil, errors.New("connection refused"), trx)
+	assert.Equal(t, "connection refused", err.Error())
+
+	// the quarantined object isn't reused, it's repaired by the chunks of new content
+	assert.Nil(t, trx.Save(&ObjectChunk{ObjectID: object.ID, ChunkID: 2, Number: 1}).Error)
+	assert.Nil(t, quarantineObject(object, 1, false, trx))
+	_, err = findReusableObject(0, h, trx)
+	assert.Equal(t, ErrObjectQuarantined, err)
+	saved, err = saveObjectWithChunks(&Object{Size: 10, Hash: h}, []ObjectChunk{{ChunkID: 3, Number: 1}}, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, object.ID, saved.ID)
+	assert.Equal(t, int8(0), saved.Quarantined)
+	var oc []ObjectChunk
+	assert.Nil(t, trx.Where("objectId = ?", object.ID).Find(&oc).Error)
+	assert.Equal(t, 1, len(oc))
+	assert.Equal(t, uint64(3), oc[0].ChunkID)
+	saved, err = findReusableObject(0, h, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, object.ID, saved.ID)
+}
@@ -0,0 +1,146 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"errors"
+
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrQuotaExceeded represent that the bytes or the count of files will exceed the quota
+	ErrQuotaExceeded = errors.New("quota is exceeded")
+	// ErrInvalidQuota represent that the limits of quota are negative
+	ErrInvalidQuota = errors.New("limits of quota can't be negative")
+)
+
+// Quota represent the limits and the current usage of an application or the
+// path of a token. Zero limit means no limit. Only the files that aren't deleted
+// are counted, it's the same as the size of directory.
+type Quota struct {
+	MaxSize  int
+	MaxFiles int
+	Size     int
+	Files    int
+}
+
+// Allow is used to check whether size bytes and files files can be added. size
+// may be a negative number, for example, a file is overwritten by a smaller one.
+func (q *Quota) Allow(size, files int) bool {
+	if q.MaxSize > 0 && size > 0 && q.Size+size > q.MaxSize {
+		return false
+	}
+	if q.MaxFiles > 0 && files > 0 && q.Files+files > q.MaxFiles {
+		return false
+	}
+	return true
+}
+
+// Quota return the quota of application, the usage is the size of root directory
+func (app *App) Quota(db *gorm.DB) (*Quota, error) {
+	var (
+		root  = &File{}
+		quota = &Quota{MaxSize: app.MaxSize, MaxFiles: app.MaxFiles}
+	)
+	if err := db.Where("appId = ? and pid = 0", app.ID).Find(root).Error; err != nil {
+		return nil, err
+	}
+	quota.Size = root.Size
+	return quota, db.Model(&File{}).Where("appId = ? and isDir = 0", app.ID).Count(&quota.Files).Error
+}
+
+// SetQuota is used to update the limits of application quota
+func (app *App) SetQuota(maxSize, maxFiles int, db *gorm.DB) error {
+	if maxSize < 0 || maxFiles < 0 {
+		return ErrInvalidQuota
+	}
+	return db.Model(app).UpdateColumns(map[string]interface{}{
+		"maxSize":  maxSize,
+		"maxFiles": maxFiles,
+	}).Error
+}
+
+// Quota return the quota of token, the usage is the size of its path. If the
+// path doesn't exist, nothing is used.
+func (t *Token) Quota(db *gorm.DB) (*Quota, error) {
+	var quota = &Quota{MaxSize: t.MaxSize, MaxFiles: t.MaxFiles}
+	dir, err := FindFileByPath(&t.App, t.Path, db)
+	if err != nil {
+		if util.IsRecordNotFound(err) {
+			return quota, nil
+		}
+		return nil, err
+	}
+	quota.Size = dir.Size
+	quota.Files, err = dir.CountFiles(db)
+	return quota, err
+}
+
+// SetQuota is used to update the limits of token quota
+func (t *Token) SetQuota(maxSize, maxFiles int, db *gorm.DB) error {
+	if maxSize < 0 || maxFiles < 0 {
+		return ErrInvalidQuota
+	}
+	return db.Model(t).UpdateColumns(map[string]interface{}{
+		"maxSize":  maxSize,
+		"maxFiles": maxFiles,
+	}).Error
+}
+
+// CheckQuota is used to check whether size bytes and files files can be added
+// by token. Both the quota of application and the quota of token are checked.
+func CheckQuota(token *Token, size, files int, db *gorm.DB) error {
+	return checkQuota(token, db, func(quota *Quota) bool {
+		return quota.Allow(size, files)
+	})
+}
+
+// CheckUsedQuota is used to check the quotas after size bytes and files files
+// have been added by token in the transaction db. The usage read in db has
+// included them, so the actual change is checked rather than the expected one,
+// and the changes should be rolled back if ErrQuotaExceeded is returned.
+func CheckUsedQuota(token *Token, size, files int, db *gorm.DB) error {
+	return checkQuota(token, db, func(quota *Quota) bool {
+		quota.Size, quota.Files = quota.Size-size, quota.Files-files
+		return quota.Allow(size, files)
+	})
+}
+
+// checkQuota is used to check the quotas of token by allow
+func checkQuota(token *Token, db *gorm.DB, allow func(quota *Quota) bool) error {
+	var quotas []func(db *gorm.DB) (*Quota, error)
+	// the usage is only calculated when there are limits
+	if token.App.MaxSize > 0 || token.App.MaxFiles > 0 {
+		quotas = append(quotas, token.App.Quota)
+	}
+	if token.MaxSize > 0 || token.MaxFiles > 0 {
+		quotas = append(quotas, token.Quota)
+	}
+	for _, getQuota := range quotas {
+		quota, err := getQuota(db)
+		if err != nil {
+			return err
+		}
+		if !allow(quota) {
+			return ErrQuotaExceeded
+		}
+	}
+	return nil
+}
+
+// CountFiles return the count of files under the directory, it's 1 for a file
+func (f *File) CountFiles(db *gorm.DB) (int, error) {
+	if f.IsDir == 0 {
+		return 1, nil
+	}
+	var total int
+	if err := db.Model(&File{}).Where("appId = ? and isDir = 0 and path like ? escape '!'",
+		f.AppID, subtreePattern(f.FullPath)).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
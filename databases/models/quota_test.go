@@ -0,0 +1,79 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuota_Allow(t *testing.T) {
+	var quota = &Quota{MaxSize: 100, MaxFiles: 2, Size: 90, Files: 1}
+	assert.True(t, quota.Allow(10, 1))
+	assert.False(t, quota.Allow(11, 0))
+	assert.False(t, quota.Allow(0, 2))
+	assert.True(t, quota.Allow(-20, 0))
+	assert.True(t, (&Quota{Size: 90, Files: 1}).Allow(1000, 1000))
+	// it's full already, but nothing is added
+	assert.True(t, (&Quota{MaxSize: 10, Size: 20}).Allow(0, 0))
+}
+
+func TestCheckQuota(t *testing.T) {
+	token, trx, down, err := newTokenForTest(nil, t, "/quota/token", nil, nil, nil, -1, 0)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		os.RemoveAll(tempDir)
+	}()
+
+	_, err = CreateFileFromReader(&token.App, "/quota/token/a/b.bytes", bytes.NewReader(Random(20)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = CreateFileFromReader(&token.App, "/quota/token/c.bytes", bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = CreateFileFromReader(&token.App, "/quota/d.bytes", bytes.NewReader(Random(40)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+
+	quota, err := token.App.Quota(trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 70, quota.Size)
+	assert.Equal(t, 3, quota.Files)
+	quota, err = token.Quota(trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 30, quota.Size)
+	assert.Equal(t, 2, quota.Files)
+
+	assert.Nil(t, CheckQuota(token, 1000, 1000, trx))
+
+	assert.Equal(t, ErrInvalidQuota, token.App.SetQuota(-1, 0, trx))
+	assert.Nil(t, token.App.SetQuota(100, 0, trx))
+	assert.Equal(t, 100, token.App.MaxSize)
+	assert.Nil(t, CheckQuota(token, 30, 1000, trx))
+	assert.Equal(t, ErrQuotaExceeded, CheckQuota(token, 31, 0, trx))
+
+	assert.Equal(t, ErrInvalidQuota, token.SetQuota(0, -1, trx))
+	assert.Nil(t, token.SetQuota(0, 3, trx))
+	assert.Nil(t, CheckQuota(token, 10, 1, trx))
+	assert.Equal(t, ErrQuotaExceeded, CheckQuota(token, 10, 2, trx))
+
+	// the usage has included the added files
+	_, err = CreateFileFromReader(&token.App, "/quota/token/e.bytes", bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, CheckUsedQuota(token, 10, 1, trx))
+	_, err = CreateFileFromReader(&token.App, "/quota/token/f.bytes", bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrQuotaExceeded, CheckUsedQuota(token, 10, 1, trx))
+	assert.Nil(t, CheckUsedQuota(token, -10, 0, trx))
+
+	// nothing is used if the path of token doesn't exist
+	token.Path = "/quota/none"
+	quota, err = token.Quota(trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, quota.Size)
+	assert.Equal(t, 0, quota.Files)
+}
@@ -0,0 +1,200 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrInvalidRetentionPolicy represent that a policy doesn't limit anything
+var ErrInvalidRetentionPolicy = errors.New("retention policy must keep last versions or keep days")
+
+// RetentionPolicy decides how many histories are kept for the files of app under
+// PathPrefix. KeepLast keeps the latest n versions, KeepDays keeps the histories
+// that are created in recent n days, zero means no limit. If both are set, a history
+// is kept only when it satisfies both. When several policies match a file, the one
+// with the longest PathPrefix wins.
+type RetentionPolicy struct {
+	ID         uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT;primary_key"`
+	AppID      uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL;column:appId"`
+	PathPrefix string    `gorm:"type:VARCHAR(1000) NOT NULL;column:pathPrefix;DEFAULT:'/'"`
+	KeepLast   int       `gorm:"type:INT UNSIGNED NOT NULL;column:keepLast;DEFAULT:0"`
+	KeepDays   int       `gorm:"type:INT UNSIGNED NOT NULL;column:keepDays;DEFAULT:0"`
+	CreatedAt  time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:createdAt"`
+	UpdatedAt  time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:updatedAt"`
+
+	App App `gorm:"foreignkey:appId;association_autoupdate:false;association_autocreate:false"`
+}
+
+// TableName represent the name of retention policy table
+func (r *RetentionPolicy) TableName() string {
+	return "retention_policies"
+}
+
+// Match represent whether the file specified by filePath is under the prefix of policy
+func (r *RetentionPolicy) Match(filePath string) bool {
+	return r.PathPrefix == "/" || filePath == r.PathPrefix || strings.HasPrefix(filePath, r.PathPrefix+"/")
+}
+
+// Expired is used to find the histories that should be deleted by this policy,
+// histories must be sorted from the latest to the oldest. The moved histories
+// aren't counted as versions by KeepLast, they are kept along with the versions
+// that are newer than them.
+func (r *RetentionPolicy) Expired(histories []History, now time.Time) []History {
+	var (
+		expired  []History
+		versions int
+		deadline = now.AddDate(0, 0, -r.KeepDays)
+	)
+	for _, history := range histories {
+		if (r.KeepLast > 0 && versions >= r.KeepLast) || (r.KeepDays > 0 && history.CreatedAt.Before(deadline)) {
+			expired = append(expired, history)
+		}
+		if history.Moved == 0 {
+			versions++
+		}
+	}
+	return expired
+}
+
+func cleanPathPrefix(prefix string) string {
+	return path.Clean("/" + strings.TrimSpace(prefix))
+}
+
+// SetRetentionPolicy is used to create the policy of app for pathPrefix, or update
+// it if it has already existed.
+func SetRetentionPolicy(app *App, pathPrefix string, keepLast, keepDays int, db *gorm.DB) (*RetentionPolicy, error) {
+	if keepLast < 0 || keepDays < 0 || (keepLast == 0 && keepDays == 0) {
+		return nil, ErrInvalidRetentionPolicy
+	}
+	var (
+		err    error
+		policy = &RetentionPolicy{}
+	)
+	pathPrefix = cleanPathPrefix(pathPrefix)
+	if err = db.Where("appId = ? and pathPrefix = ?", app.ID, pathPrefix).First(policy).Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	policy.AppID = app.ID
+	policy.PathPrefix = pathPrefix
+	policy.KeepLast = keepLast
+	policy.KeepDays = keepDays
+	if err = db.Save(policy).Error; err != nil {
+		return nil, err
+	}
+	policy.App = *app
+	return policy, nil
+}
+
+// FindRetentionPolicies is used to find the policies of app, nil app represent all apps
+func FindRetentionPolicies(app *App, db *gorm.DB) ([]RetentionPolicy, error) {
+	var policies []RetentionPolicy
+	if app != nil {
+		db = db.Where("appId = ?", app.ID)
+	}
+	err := db.Preload("App").Order("appId asc, pathPrefix asc").Find(&policies).Error
+	return policies, err
+}
+
+// DeleteRetentionPolicy is used to delete the policy by id
+func DeleteRetentionPolicy(id uint64, db *gorm.DB) error {
+	var policy = &RetentionPolicy{}
+	if err := db.Where("id = ?", id).First(policy).Error; err != nil {
+		return err
+	}
+	return db.Delete(policy).Error
+}
+
+// matchRetentionPolicy find the policy with the longest prefix that matches filePath
+func matchRetentionPolicy(policies []RetentionPolicy, filePath string) *RetentionPolicy {
+	var matched *RetentionPolicy
+	for index := range policies {
+		policy := &policies[index]
+		if policy.Match(filePath) && (matched == nil || len(policy.PathPrefix) > len(matched.PathPrefix)) {
+			matched = policy
+		}
+	}
+	return matched
+}
+
+// ApplyRetentionPolicies is used to delete the histories that are expired by policies,
+// files in trash are included. The objects of deleted histories are collected by
+// CollectGarbage later, if nothing else references them. If dryRun is true, nothing
+// will be deleted, only the expired histories are returned.
+func ApplyRetentionPolicies(dryRun bool, db *gorm.DB) ([]History, error) {
+	var (
+		err      error
+		policies []RetentionPolicy
+		result   []History
+		appIDs   []uint64
+		byApp    = make(map[uint64][]RetentionPolicy)
+		now      = time.Now()
+	)
+
+	if policies, err = FindRetentionPolicies(nil, db); err != nil {
+		return nil, err
+	}
+	for _, policy := range policies {
+		if _, ok := byApp[policy.AppID]; !ok {
+			appIDs = append(appIDs, policy.AppID)
+		}
+		byApp[policy.AppID] = append(byApp[policy.AppID], policy)
+	}
+
+	for _, appID := range appIDs {
+		var fileIDs []uint64
+		if err = db.Model(&History{}).Joins("join files on files.id = histories.fileId").
+			Where("files.appId = ?", appID).Order("histories.fileId asc").
+			Pluck("distinct histories.fileId", &fileIDs).Error; err != nil {
+			return result, err
+		}
+		for _, fileID := range fileIDs {
+			var expired []History
+			if expired, err = applyRetentionPolicyToFile(fileID, byApp[appID], now, dryRun, db); err != nil {
+				return result, err
+			}
+			result = append(result, expired...)
+		}
+	}
+
+	return result, nil
+}
+
+func applyRetentionPolicyToFile(fileID uint64, policies []RetentionPolicy, now time.Time, dryRun bool, db *gorm.DB) ([]History, error) {
+	var (
+		err       error
+		filePath  string
+		file      = &File{}
+		policy    *RetentionPolicy
+		histories []History
+		expired   []History
+		ids       []uint64
+	)
+
+	if err = db.Unscoped().Where("id = ?", fileID).First(file).Error; err != nil {
+		return nil, err
+	}
+	if filePath, err = file.Path(db); err != nil {
+		return nil, err
+	}
+	if policy = matchRetentionPolicy(policies, filePath); policy == nil {
+		return nil, nil
+	}
+	if err = db.Where("fileId = ?", fileID).Order("id desc").Find(&histories).Error; err != nil {
+		return nil, err
+	}
+	if expired = policy.Expired(histories, now); len(expired) == 0 || dryRun {
+		return expired, nil
+	}
+	for _, history := range expired {
+		ids = append(ids, history.ID)
+	}
+	return expired, db.Where("id in (?)", ids).Delete(&History{}).Error
+}
@@ -0,0 +1,213 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionPolicy_TableName(t *testing.T) {
+	assert.Equal(t, "retention_policies", (&RetentionPolicy{}).TableName())
+}
+
+func TestRetentionPolicy_Match(t *testing.T) {
+	var policy = &RetentionPolicy{PathPrefix: "/"}
+	assert.True(t, policy.Match("/random.bytes"))
+
+	policy.PathPrefix = "/images"
+	assert.True(t, policy.Match("/images"))
+	assert.True(t, policy.Match("/images/random.bytes"))
+	assert.False(t, policy.Match("/images2/random.bytes"))
+	assert.False(t, policy.Match("/random.bytes"))
+}
+
+func TestRetentionPolicy_Expired(t *testing.T) {
+	var (
+		now       = time.Now()
+		histories = []History{
+			{ID: 4, CreatedAt: now},
+			{ID: 3, CreatedAt: now.AddDate(0, 0, -2)},
+			{ID: 2, CreatedAt: now.AddDate(0, 0, -5)},
+			{ID: 1, CreatedAt: now.AddDate(0, 0, -10)},
+		}
+	)
+
+	expired := (&RetentionPolicy{KeepLast: 2}).Expired(histories, now)
+	assert.Equal(t, 2, len(expired))
+	assert.Equal(t, uint64(2), expired[0].ID)
+
+	expired = (&RetentionPolicy{KeepDays: 3}).Expired(histories, now)
+	assert.Equal(t, 2, len(expired))
+	assert.Equal(t, uint64(2), expired[0].ID)
+
+	expired = (&RetentionPolicy{KeepLast: 3, KeepDays: 7}).Expired(histories, now)
+	assert.Equal(t, 1, len(expired))
+	assert.Equal(t, uint64(1), expired[0].ID)
+
+	assert.Equal(t, 0, len((&RetentionPolicy{KeepLast: 10}).Expired(histories, now)))
+
+	// the moved histories aren't counted as versions
+	histories = []History{
+		{ID: 5, CreatedAt: now, Moved: 1},
+		{ID: 4, CreatedAt: now},
+		{ID: 3, CreatedAt: now, Moved: 1},
+		{ID: 2, CreatedAt: now},
+		{ID: 1, CreatedAt: now, Moved: 1},
+	}
+	expired = (&RetentionPolicy{KeepLast: 2}).Expired(histories, now)
+	assert.Equal(t, 1, len(expired))
+	assert.Equal(t, uint64(1), expired[0].ID)
+	expired = (&RetentionPolicy{KeepLast: 1}).Expired(histories, now)
+	assert.Equal(t, 3, len(expired))
+	assert.Equal(t, uint64(3), expired[0].ID)
+}
+
+func TestSetRetentionPolicy(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	defer down(t)
+
+	_, err = SetRetentionPolicy(app, "/", 0, 0, trx)
+	assert.Equal(t, ErrInvalidRetentionPolicy, err)
+	_, err = SetRetentionPolicy(app, "/", -1, 10, trx)
+	assert.Equal(t, ErrInvalidRetentionPolicy, err)
+
+	policy, err := SetRetentionPolicy(app, "images/", 3, 0, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, "/images", policy.PathPrefix)
+
+	policy2, err := SetRetentionPolicy(app, "/images", 5, 30, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, policy.ID, policy2.ID)
+	assert.Equal(t, 5, policy2.KeepLast)
+	assert.Equal(t, 30, policy2.KeepDays)
+
+	_, err = SetRetentionPolicy(app, "/", 10, 0, trx)
+	assert.Nil(t, err)
+
+	policies, err := FindRetentionPolicies(app, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(policies))
+	assert.Equal(t, "/", policies[0].PathPrefix)
+	assert.Equal(t, app.UID, policies[0].App.UID)
+
+	assert.Nil(t, DeleteRetentionPolicy(policy.ID, trx))
+	assert.True(t, gorm.IsRecordNotFoundError(DeleteRetentionPolicy(policy.ID, trx)))
+	policies, err = FindRetentionPolicies(app, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(policies))
+}
+
+func TestApplyRetentionPolicies(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	var files []*File
+	for _, path := range []string{"/random.bytes", "/images/random.bytes", "/videos/random.bytes"} {
+		file, err := CreateFileFromReader(app, path, bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+		assert.Nil(t, err)
+		for i := 0; i < 4; i++ {
+			assert.Nil(t, file.OverWriteFromReader(bytes.NewReader(Random(10)), int8(0), &tempDir, trx))
+		}
+		files = append(files, file)
+	}
+	// histories of deleted files are handled too
+	assert.Nil(t, files[2].Delete(trx))
+
+	_, err = SetRetentionPolicy(app, "/", 3, 0, trx)
+	assert.Nil(t, err)
+	_, err = SetRetentionPolicy(app, "/images", 1, 0, trx)
+	assert.Nil(t, err)
+
+	var counts = func() []int {
+		var result []int
+		for _, file := range files {
+			total, _, err := file.FindHistories(0, 1, trx)
+			assert.Nil(t, err)
+			result = append(result, total)
+		}
+		return result
+	}
+	before := counts()
+
+	expired, err := ApplyRetentionPolicies(true, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, before, counts())
+	assert.Equal(t, (before[0]-3)+(before[1]-1)+(before[2]-3), len(expired))
+
+	expired2, err := ApplyRetentionPolicies(false, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, expired, expired2)
+	assert.Equal(t, []int{3, 1, 3}, counts())
+
+	// the latest histories are kept
+	_, histories, err := files[1].FindHistories(0, 10, trx)
+	assert.Nil(t, err)
+	for _, history := range expired {
+		if history.FileID == files[1].ID {
+			assert.True(t, history.ID < histories[0].ID)
+		}
+	}
+
+	expired, err = ApplyRetentionPolicies(false, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(expired))
+}
+
+func TestApplyRetentionPolicies2(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	file, err := CreateFileFromReader(app, "/dir/random.bytes", bytes.NewReader(Random(10)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, file.OverWriteFromReader(bytes.NewReader(Random(10)), int8(0), &tempDir, trx))
+	dir, err := FindFileByPath(app, "/dir", trx)
+	assert.Nil(t, err)
+	for _, path := range []string{"/moved", "/moved2"} {
+		assert.Nil(t, dir.MoveTo(path, trx))
+	}
+	_, histories, err := file.FindHistories(0, 10, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(histories))
+	assert.Equal(t, int8(1), histories[0].Moved)
+	assert.Equal(t, "/moved/random.bytes", histories[0].Path)
+
+	// the moves of directory don't push the version out
+	_, err = SetRetentionPolicy(app, "/", 1, 0, trx)
+	assert.Nil(t, err)
+	expired, err := ApplyRetentionPolicies(false, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(expired))
+
+	assert.Nil(t, file.OverWriteFromReader(bytes.NewReader(Random(10)), int8(0), &tempDir, trx))
+	expired, err = ApplyRetentionPolicies(false, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(expired))
+	_, histories, err = file.FindHistories(0, 10, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(histories))
+	assert.Equal(t, int8(0), histories[0].Moved)
+}
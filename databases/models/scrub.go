@@ -0,0 +1,277 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+
+	sha2562 "github.com/bigfile/bigfile/internal/sha256"
+	"github.com/jinzhu/gorm"
+)
+
+// scrubBatchSize is the count of rows that are loaded at once by scrubber
+const scrubBatchSize = 100
+
+var (
+	// ErrObjectQuarantined represent that the content of object is found corrupted by scrubber
+	ErrObjectQuarantined = errors.New("object is quarantined, its content is corrupted")
+	// ErrChunkSizeMismatch represent that the size of content in store isn't equal to the size of chunk
+	ErrChunkSizeMismatch = errors.New("the size of content doesn't match the chunk")
+	// ErrChunkHashMismatch represent that the hash of content in store isn't equal to the hash of chunk
+	ErrChunkHashMismatch = errors.New("the hash of content doesn't match the chunk")
+	// ErrObjectSizeMismatch represent that the total size of chunks isn't equal to the size of object
+	ErrObjectSizeMismatch = errors.New("the size of chunks doesn't match the object")
+	// ErrObjectHashMismatch represent that the hash of chunks joined in order isn't equal to the hash of object
+	ErrObjectHashMismatch = errors.New("the hash of chunks doesn't match the object")
+)
+
+// ChunkScrubFailure represent a chunk that fails to be verified
+type ChunkScrubFailure struct {
+	Chunk Chunk
+	Err   error
+}
+
+// ObjectScrubFailure represent an object that fails to be verified
+type ObjectScrubFailure struct {
+	Object Object
+	Err    error
+}
+
+// ScrubResult represent the result of scrubbing
+type ScrubResult struct {
+	Chunks         int
+	Objects        int
+	ChunkFailures  []ChunkScrubFailure
+	ObjectFailures []ObjectScrubFailure
+	// ChunkErrors and ObjectErrors are the ones that can't be verified, for
+	// example, the store is unavailable, they are left unchanged
+	ChunkErrors  []ChunkScrubFailure
+	ObjectErrors []ObjectScrubFailure
+	// Released are the quarantined objects that pass verification again
+	Released []Object
+}
+
+// isCorrupted represent whether err proves that the content is corrupted. The
+// other errors, for example, the store times out or the key isn't found in
+// keyring, say nothing about the content.
+func isCorrupted(err error) bool {
+	switch err {
+	case ErrChunkNotExist, ErrChunkSizeMismatch, ErrChunkHashMismatch, ErrObjectSizeMismatch, ErrObjectHashMismatch:
+		return true
+	}
+	return false
+}
+
+// scrubbedChunks records the chunks that have been verified by scrubber, only the
+// errors of failed chunks are kept, the others whose ids aren't greater than lastID
+// have passed.
+type scrubbedChunks struct {
+	lastID uint64
+	errs   map[uint64]error
+}
+
+// verify return the result of chunk, the chunk that hasn't been scrubbed, for
+// example, it's created after chunks are scrubbed, is verified now.
+func (s *scrubbedChunks) verify(chunk *Chunk, rootPath *string) error {
+	if chunk.ID > s.lastID {
+		return chunk.Verify(rootPath)
+	}
+	return s.errs[chunk.ID]
+}
+
+// writeContent streams the decoded content of chunk to h, the size of content
+// is returned
+func (c *Chunk) writeContent(h hash.Hash, rootPath *string) (int, error) {
+	reader, err := c.Reader(rootPath)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	size, err := io.Copy(h, reader)
+	return int(size), err
+}
+
+// Verify is used to check whether the content of chunk in store still matches
+// its size and hash
+func (c *Chunk) Verify(rootPath *string) error {
+	var h = sha256.New()
+	size, err := c.writeContent(h, rootPath)
+	if err != nil {
+		return err
+	}
+	if size != c.Size {
+		return ErrChunkSizeMismatch
+	}
+	if hex.EncodeToString(h.Sum(nil)) != c.Hash {
+		return ErrChunkHashMismatch
+	}
+	return nil
+}
+
+// Verify is used to check whether the hash of chunks joined in order still
+// matches the hash of object
+func (o *Object) Verify(rootPath *string, db *gorm.DB) error {
+	var (
+		err    error
+		size   int64
+		loaded Object
+		h      = sha256.New()
+	)
+	if err = db.Preload("Chunks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("object_chunk.number asc")
+	}).Where("id = ?", o.ID).Find(&loaded).Error; err != nil {
+		return err
+	}
+	for index := range loaded.Chunks {
+		var n int
+		if n, err = loaded.Chunks[index].writeContent(h, rootPath); err != nil {
+			return err
+		}
+		size += int64(n)
+	}
+	if size != o.Size {
+		return ErrObjectSizeMismatch
+	}
+	if hex.EncodeToString(h.Sum(nil)) != o.Hash {
+		return ErrObjectHashMismatch
+	}
+	return nil
+}
+
+// verifyByChunks is used to check object with the results of its chunks, so the
+// content isn't read again. The size of object must be equal to the total size of
+// chunks, and the hash state after the last chunk must be the hash of object.
+func (o *Object) verifyByChunks(scrubbed *scrubbedChunks, rootPath *string, db *gorm.DB) error {
+	var (
+		err          error
+		size         int64
+		h            = sha256.New()
+		objectChunks []ObjectChunk
+	)
+	if err = db.Preload("Chunk").Where("objectId = ?", o.ID).Order("number asc").Find(&objectChunks).Error; err != nil {
+		return err
+	}
+	for index := range objectChunks {
+		if err = scrubbed.verify(&objectChunks[index].Chunk, rootPath); err != nil {
+			return err
+		}
+		size += int64(objectChunks[index].Chunk.Size)
+	}
+	if size != o.Size {
+		return ErrObjectSizeMismatch
+	}
+	if len(objectChunks) > 0 {
+		if h, err = sha2562.NewHashWithStateText(*objectChunks[len(objectChunks)-1].HashState); err != nil {
+			return err
+		}
+	}
+	if hex.EncodeToString(h.Sum(nil)) != o.Hash {
+		return ErrObjectHashMismatch
+	}
+	return nil
+}
+
+// Scrub verifies all chunks and objects against the content in store. Chunks and
+// objects that are found corrupted are quarantined, so that their content won't be
+// served or reused anymore. The ones that can't be verified, for example, the store
+// is unavailable, are reported only. Objects are verified with the results of their
+// chunks, so the content is read only once.
+// If markFiles is true, the files of them are marked as quarantined as well.
+// Quarantined objects that pass verification again, for example, the store is
+// restored from backup, are released.
+func Scrub(markFiles bool, rootPath *string, db *gorm.DB) (*ScrubResult, error) {
+	var (
+		err      error
+		result   = &ScrubResult{}
+		scrubbed = &scrubbedChunks{errs: make(map[uint64]error)}
+	)
+
+	if err = scrubChunks(result, scrubbed, rootPath, db); err != nil {
+		return result, err
+	}
+
+	return result, scrubObjects(result, scrubbed, markFiles, rootPath, db)
+}
+
+func scrubChunks(result *ScrubResult, scrubbed *scrubbedChunks, rootPath *string, db *gorm.DB) error {
+	for {
+		var chunks []Chunk
+		if err := db.Where("id > ?", scrubbed.lastID).Order("id asc").Limit(scrubBatchSize).Find(&chunks).Error; err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			scrubbed.lastID = chunk.ID
+			err := chunk.Verify(rootPath)
+			if err != nil {
+				scrubbed.errs[chunk.ID] = err
+			}
+			// the quarantined chunk isn't reused by dedup, it's repaired when the
+			// same content is written again
+			if isCorrupted(err) {
+				result.ChunkFailures = append(result.ChunkFailures, ChunkScrubFailure{Chunk: chunk, Err: err})
+				if err = db.Model(&chunk).UpdateColumn("quarantined", 1).Error; err != nil {
+					return err
+				}
+			} else if err != nil {
+				result.ChunkErrors = append(result.ChunkErrors, ChunkScrubFailure{Chunk: chunk, Err: err})
+			} else if chunk.Quarantined == 1 {
+				if err = db.Model(&chunk).UpdateColumn("quarantined", 0).Error; err != nil {
+					return err
+				}
+			}
+		}
+		result.Chunks += len(chunks)
+		if len(chunks) < scrubBatchSize {
+			return nil
+		}
+	}
+}
+
+func scrubObjects(result *ScrubResult, scrubbed *scrubbedChunks, markFiles bool, rootPath *string, db *gorm.DB) error {
+	var lastID uint64
+	for {
+		var objects []Object
+		if err := db.Where("id > ?", lastID).Order("id asc").Limit(scrubBatchSize).Find(&objects).Error; err != nil {
+			return err
+		}
+		for _, object := range objects {
+			lastID = object.ID
+			err := object.verifyByChunks(scrubbed, rootPath, db)
+			if isCorrupted(err) {
+				result.ObjectFailures = append(result.ObjectFailures, ObjectScrubFailure{Object: object, Err: err})
+				if err = quarantineObject(&object, 1, markFiles, db); err != nil {
+					return err
+				}
+			} else if err != nil {
+				result.ObjectErrors = append(result.ObjectErrors, ObjectScrubFailure{Object: object, Err: err})
+			} else if object.Quarantined == 1 {
+				result.Released = append(result.Released, object)
+				if err = quarantineObject(&object, 0, true, db); err != nil {
+					return err
+				}
+			}
+		}
+		result.Objects += len(objects)
+		if len(objects) < scrubBatchSize {
+			return nil
+		}
+	}
+}
+
+// quarantineObject set the quarantined flag of object, and of its files if withFiles
+// is true. Files in trash are included.
+func quarantineObject(object *Object, quarantined int8, withFiles bool, db *gorm.DB) error {
+	if err := db.Model(object).UpdateColumn("quarantined", quarantined).Error; err != nil {
+		return err
+	}
+	if !withFiles {
+		return nil
+	}
+	return db.Unscoped().Model(&File{}).Where("objectId = ?", object.ID).UpdateColumn("quarantined", quarantined).Error
+}
@@ -0,0 +1,220 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunk_Verify(t *testing.T) {
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+	defer useCompressionForTest(ChunkCodecGzip)()
+
+	content := bytes.Repeat([]byte("bigfile"), 100)
+	chunk, err := CreateChunkFromBytes(0, content, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, chunk.Verify(&tempDir))
+
+	store := NewLocalChunkStore(tempDir)
+	assert.Nil(t, store.Put(chunk.ID, content[:10]))
+	assert.NotNil(t, chunk.Verify(&tempDir))
+
+	// the raw content is the same size, but different
+	chunk.Codec = ChunkCodecNone
+	corrupted := append([]byte{}, content...)
+	corrupted[0] = 'B'
+	assert.Nil(t, store.Put(chunk.ID, corrupted))
+	assert.Equal(t, ErrChunkHashMismatch, chunk.Verify(&tempDir))
+	assert.Nil(t, store.Put(chunk.ID, content[:10]))
+	assert.Equal(t, ErrChunkSizeMismatch, chunk.Verify(&tempDir))
+
+	assert.Nil(t, store.Delete(chunk.ID))
+	assert.NotNil(t, chunk.Verify(&tempDir))
+}
+
+func TestScrub(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	content := Random(ChunkSize + 100)
+	file, err := CreateFileFromReader(app, "/scrub/random.bytes", bytes.NewReader(content), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	var object Object
+	assert.Nil(t, trx.Preload("Chunks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("object_chunk.number asc")
+	}).Where("id = ?", file.ObjectID).Find(&object).Error)
+	assert.Nil(t, object.Verify(&tempDir, trx))
+	assert.Equal(t, 2, len(object.Chunks))
+
+	// the object is corrupted if its size is changed
+	object.Size++
+	assert.Equal(t, ErrObjectSizeMismatch, object.Verify(&tempDir, trx))
+	object.Size--
+
+	var (
+		store     = NewLocalChunkStore(tempDir)
+		corrupted = object.Chunks[1]
+		original  = content[ChunkSize:]
+	)
+	assert.Nil(t, store.Put(corrupted.ID, Random(uint(len(original)))))
+	assert.Equal(t, ErrObjectHashMismatch, object.Verify(&tempDir, trx))
+
+	result, err := Scrub(true, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.True(t, result.Chunks >= 2)
+	assert.True(t, result.Objects >= 1)
+	assert.Contains(t, chunkIDsOfScrubResult(result), corrupted.ID)
+	assert.NotContains(t, chunkIDsOfScrubResult(result), object.Chunks[0].ID)
+	assert.Contains(t, objectIDsOfScrubResult(result), object.ID)
+
+	file, err = FindFileByUID(file.UID, false, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, int8(1), file.Quarantined)
+	_, err = file.Reader(&tempDir, trx)
+	assert.Equal(t, ErrObjectQuarantined, err)
+
+	// the quarantined object is released after its content is restored
+	assert.Nil(t, store.Put(corrupted.ID, original))
+	result, err = Scrub(false, &tempDir, trx)
+	assert.Nil(t, err)
+	assert.NotContains(t, objectIDsOfScrubResult(result), object.ID)
+	var released []uint64
+	for _, object := range result.Released {
+		released = append(released, object.ID)
+	}
+	assert.Contains(t, released, object.ID)
+
+	file, err = FindFileByUID(file.UID, false, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, int8(0), file.Quarantined)
+	_, err = file.Reader(&tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, int8(0), file.Object.Quarantined)
+}
+
+// TestScrub2 is used to test that the quarantined content is repaired by dedup
+func TestScrub2(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	content := Random(ChunkSize + 100)
+	file, err := CreateFileFromReader(app, "/scrub/random.bytes", bytes.NewReader(content), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	var chunks []ObjectChunk
+	assert.Nil(t, trx.Where("objectId = ?", file.ObjectID).Order("number asc").Find(&chunks).Error)
+	assert.Nil(t, NewLocalChunkStore(tempDir).Put(chunks[1].ChunkID, Random(100)))
+	_, err = Scrub(true, &tempDir, trx)
+	assert.Nil(t, err)
+	corrupted := &Chunk{}
+	assert.Nil(t, trx.Where("id = ?", chunks[1].ChunkID).Find(corrupted).Error)
+	assert.Equal(t, int8(1), corrupted.Quarantined)
+
+	// the same content is uploaded again
+	other, err := CreateFileFromReader(app, "/scrub/other.bytes", bytes.NewReader(content), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, file.ObjectID, other.ObjectID)
+	assert.Nil(t, trx.Where("id = ?", chunks[1].ChunkID).Find(corrupted).Error)
+	assert.Equal(t, int8(0), corrupted.Quarantined)
+
+	file, err = FindFileByUID(file.UID, false, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, int8(0), file.Quarantined)
+	reader, err := file.Reader(&tempDir, trx)
+	assert.Nil(t, err)
+	defer reader.Close()
+	readContent, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, content, readContent)
+}
+
+// TestScrub3 is used to test that nothing is quarantined if the store fails
+func TestScrub3(t *testing.T) {
+	var store = &faultyChunkStore{ChunkStore: NewMemoryChunkStore()}
+	SetDefaultChunkStore(store)
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		SetDefaultChunkStore(nil)
+		down(t)
+	}()
+
+	file, err := CreateFileFromReader(app, "/scrub/random.bytes", bytes.NewReader(Random(256)), int8(0), nil, trx)
+	assert.Nil(t, err)
+	var oc ObjectChunk
+	assert.Nil(t, trx.Where("objectId = ?", file.ObjectID).First(&oc).Error)
+
+	store.err = errors.New("store is unavailable")
+	result, err := Scrub(true, nil, trx)
+	assert.Nil(t, err)
+	assert.NotContains(t, chunkIDsOfScrubResult(result), oc.ChunkID)
+	assert.NotContains(t, objectIDsOfScrubResult(result), file.ObjectID)
+	var unverified []uint64
+	for _, failure := range result.ObjectErrors {
+		assert.Equal(t, store.err, failure.Err)
+		unverified = append(unverified, failure.Object.ID)
+	}
+	assert.Contains(t, unverified, file.ObjectID)
+	file, err = FindFileByUID(file.UID, false, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, int8(0), file.Quarantined)
+
+	// the content is lost
+	store.err = nil
+	assert.Nil(t, store.Delete(oc.ChunkID))
+	result, err = Scrub(true, nil, trx)
+	assert.Nil(t, err)
+	assert.Contains(t, chunkIDsOfScrubResult(result), oc.ChunkID)
+	assert.Contains(t, objectIDsOfScrubResult(result), file.ObjectID)
+	for _, failure := range result.ObjectFailures {
+		if failure.Object.ID == file.ObjectID {
+			assert.Equal(t, ErrChunkNotExist, failure.Err)
+		}
+	}
+}
+
+func chunkIDsOfScrubResult(result *ScrubResult) []uint64 {
+	var ids []uint64
+	for _, failure := range result.ChunkFailures {
+		ids = append(ids, failure.Chunk.ID)
+	}
+	return ids
+}
+
+func objectIDsOfScrubResult(result *ScrubResult) []uint64 {
+	var ids []uint64
+	for _, failure := range result.ObjectFailures {
+		ids = append(ids, failure.Object.ID)
+	}
+	return ids
+}
@@ -29,6 +29,8 @@ type Token struct {
 	ReadOnly       int8       `gorm:"type:tinyint;column:readOnly;DEFAULT:0"`
 	Path           string     `gorm:"type:tinyint;column:path"`
 	ExpiredAt      *time.Time `gorm:"type:TIMESTAMP;column:expiredAt"`
+	MaxSize        int        `gorm:"type:BIGINT(20) UNSIGNED NOT NULL;column:maxSize;DEFAULT:0"`
+	MaxFiles       int        `gorm:"type:INT(10) UNSIGNED NOT NULL;column:maxFiles;DEFAULT:0"`
 	CreatedAt      time.Time  `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:createdAt"`
 	UpdatedAt      time.Time  `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:updatedAt"`
 	DeletedAt      *time.Time `gorm:"type:TIMESTAMP(6);INDEX;column:deletedAt"`
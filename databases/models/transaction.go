@@ -0,0 +1,95 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"database/sql"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/log"
+	"github.com/jinzhu/gorm"
+)
+
+// trackerKey is the key that transactionTracker is saved in gorm.DB by
+const trackerKey = "bigfile:transaction_tracker"
+
+// transactionTracker records how to undo the changes of chunk store in a
+// transaction, the store isn't rolled back along with database. It also holds
+// the locks of files that are taken in the transaction until it ends.
+type transactionTracker struct {
+	undos     []func() error
+	fileLocks map[uint64]func()
+}
+
+// undo reverts the changes of chunk store in reverse order, all of them are
+// tried even if some fail, the chunks left in store will be collected by gc.
+func (t *transactionTracker) undo() {
+	for index := len(t.undos) - 1; index >= 0; index-- {
+		if err := t.undos[index](); err != nil {
+			log.MustNewLogger(&config.DefaultConfig.Log).Warningf("failed to undo the change of chunk store: %s", err)
+		}
+	}
+}
+
+// unlock releases the locks of files that are taken in the transaction
+func (t *transactionTracker) unlock() {
+	for id, unlock := range t.fileLocks {
+		unlock()
+		delete(t.fileLocks, id)
+	}
+}
+
+// Transaction run fn in a transaction, it's committed if fn returns nil, otherwise
+// it's rolled back, and the chunks created in fn are removed from store. If db is in a transaction already, fn is run
+// in it directly, so the outer transaction decides whether to commit. The locks of files taken in fn are held until
+// the transaction ends.
+func Transaction(db *gorm.DB, fn func(tx *gorm.DB) error) (err error) {
+	if _, ok := db.CommonDB().(*sql.Tx); ok {
+		return fn(db)
+	}
+	var (
+		tracker = &transactionTracker{fileLocks: make(map[uint64]func())}
+		tx      = db.Set(trackerKey, tracker).Begin()
+	)
+	if err = tx.Error; err != nil {
+		return err
+	}
+	defer tracker.unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			tracker.undo()
+			panic(r)
+		}
+	}()
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		tracker.undo()
+		return err
+	}
+	if err = tx.Commit().Error; err != nil {
+		tracker.undo()
+	}
+	return err
+}
+
+// getTracker return the tracker of transaction, nil is returned if db isn't in
+// a transaction that is started by Transaction
+func getTracker(db *gorm.DB) *transactionTracker {
+	if value, ok := db.Get(trackerKey); ok {
+		return value.(*transactionTracker)
+	}
+	return nil
+}
+
+// trackChunkCreated is used to remove the chunk from store if the transaction
+// is rolled back
+func trackChunkCreated(store ChunkStore, id uint64, db *gorm.DB) {
+	if tracker := getTracker(db); tracker != nil {
+		tracker.undos = append(tracker.undos, func() error {
+			return store.Delete(id)
+		})
+	}
+}
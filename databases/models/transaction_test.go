@@ -0,0 +1,128 @@
+//  Copyright 2019 The bigfile Authors. All rights reserved.
+//  Use of this source code is governed by a MIT-style
+//  license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bigfile/bigfile/databases"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransaction(t *testing.T) {
+	var (
+		tempDir = NewTempDirForTest()
+		db      = databases.MustNewConnection(nil)
+		store   = chunkStore(&tempDir)
+		chunk   *Chunk
+		err     error
+	)
+	defer func() {
+		if chunk != nil && chunk.ID > 0 {
+			db.Delete(chunk)
+		}
+		os.RemoveAll(tempDir)
+	}()
+
+	err = Transaction(db, func(tx *gorm.DB) error {
+		chunk, err = CreateChunkFromBytes(0, Random(256), &tempDir, tx)
+		return err
+	})
+	assert.Nil(t, err)
+	assert.True(t, chunkExists(store, chunk.ID))
+	assert.Nil(t, db.Where("id = ?", chunk.ID).Find(&Chunk{}).Error)
+}
+
+func TestTransaction2(t *testing.T) {
+	var (
+		tempDir = NewTempDirForTest()
+		db      = databases.MustNewConnection(nil)
+		store   = chunkStore(&tempDir)
+		chunk   *Chunk
+		errFail = errors.New("fail")
+	)
+	defer os.RemoveAll(tempDir)
+
+	err := Transaction(db, func(tx *gorm.DB) error {
+		var err error
+		if chunk, err = CreateChunkFromBytes(0, Random(256), &tempDir, tx); err != nil {
+			return err
+		}
+		assert.True(t, chunkExists(store, chunk.ID))
+		return errFail
+	})
+	assert.Equal(t, errFail, err)
+	assert.False(t, chunkExists(store, chunk.ID))
+	assert.True(t, util.IsRecordNotFound(db.Where("id = ?", chunk.ID).Find(&Chunk{}).Error))
+
+	func() {
+		defer func() {
+			assert.Equal(t, errFail, recover())
+		}()
+		_ = Transaction(db, func(tx *gorm.DB) error {
+			var err error
+			if chunk, err = CreateChunkFromBytes(0, Random(256), &tempDir, tx); err != nil {
+				return err
+			}
+			panic(errFail)
+		})
+	}()
+	assert.False(t, chunkExists(store, chunk.ID))
+}
+
+func TestTransaction3(t *testing.T) {
+	var (
+		tempDir = NewTempDirForTest()
+		db      = databases.MustNewConnection(nil)
+		store   = chunkStore(&tempDir)
+		content = Random(256)
+		chunk   *Chunk
+		err     error
+	)
+	defer func() {
+		if chunk != nil && chunk.ID > 0 {
+			db.Delete(chunk)
+		}
+		os.RemoveAll(tempDir)
+	}()
+
+	assert.Nil(t, Transaction(db, func(tx *gorm.DB) error {
+		chunk, err = CreateChunkFromBytes(0, content, &tempDir, tx)
+		return err
+	}))
+
+	err = Transaction(db, func(tx *gorm.DB) error {
+		if _, _, err := chunk.AppendBytes(0, Random(128), &tempDir, tx); err != nil {
+			return err
+		}
+		return errors.New("fail")
+	})
+	assert.NotNil(t, err)
+
+	reader, err := store.Get(chunk.ID)
+	assert.Nil(t, err)
+	defer reader.Close()
+	stored, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, content, stored)
+}
+
+func TestTransaction4(t *testing.T) {
+	trx, down := setUpTestCaseWithTrx(nil, t)
+	defer down(t)
+
+	var called bool
+	assert.Nil(t, Transaction(trx, func(tx *gorm.DB) error {
+		called = true
+		assert.Equal(t, trx, tx)
+		return nil
+	}))
+	assert.True(t, called)
+}
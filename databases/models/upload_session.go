@@ -0,0 +1,429 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	sha2562 "github.com/bigfile/bigfile/internal/sha256"
+	"github.com/jinzhu/gorm"
+	"labix.org/v2/mgo/bson"
+)
+
+// UploadSessionTTL represent how long an upload session is kept after it's
+// touched last time, the session that isn't touched within it is abandoned.
+const UploadSessionTTL = 24 * time.Hour
+
+// uploadSessionLocks serializes the appending of upload sessions in this process
+var uploadSessionLocks = newFileLockManager()
+
+var (
+	// ErrUploadSessionExpired represent that the upload session has expired
+	ErrUploadSessionExpired = errors.New("upload session has expired")
+	// ErrUploadOffsetMismatch represent that the offset of request doesn't
+	// match the offset of upload session
+	ErrUploadOffsetMismatch = errors.New("upload offset doesn't match")
+	// ErrUploadSessionNotComplete represent that try to complete a session
+	// that hasn't received all content
+	ErrUploadSessionNotComplete = errors.New("upload session hasn't received all content")
+)
+
+// UploadSession represent a resumable upload. The received content is saved as
+// chunks, the hash state of received content is saved as well, so the upload
+// can be continued from the offset at any time. When all content is received,
+// the session turns into a file.
+type UploadSession struct {
+	ID        uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT;primary_key"`
+	UID       string    `gorm:"type:CHAR(32) NOT NULL;UNIQUE;column:uid"`
+	AppID     uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL;column:appId"`
+	Path      string    `gorm:"type:VARCHAR(1000);NOT NULL;column:path"`
+	Size      int       `gorm:"type:BIGINT(20);column:size"`
+	Offset    int       `gorm:"type:BIGINT(20);column:offset"`
+	HashState *string   `gorm:"type:text;column:hashState"`
+	Hidden    int8      `gorm:"type:tinyint;column:hidden;DEFAULT:0"`
+	ExpiredAt time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;column:expiredAt"`
+	CreatedAt time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:createdAt"`
+	UpdatedAt time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:updatedAt"`
+
+	App    App                  `gorm:"foreignkey:appId;association_autoupdate:false;association_autocreate:false"`
+	Chunks []UploadSessionChunk `gorm:"foreignkey:sessionId;association_autoupdate:false;association_autocreate:false"`
+}
+
+// TableName represent the name of upload session table
+func (s *UploadSession) TableName() string {
+	return "upload_sessions"
+}
+
+// Expired is used to check whether the session has expired
+func (s *UploadSession) Expired() bool {
+	return !s.ExpiredAt.After(time.Now())
+}
+
+// Completed is used to check whether all content has been received
+func (s *UploadSession) Completed() bool {
+	return s.Offset >= s.Size
+}
+
+// CanBeAccessedByToken represent whether the session can be accessed by the token
+func (s *UploadSession) CanBeAccessedByToken(token *Token) error {
+	if s.AppID != token.AppID || !strings.HasPrefix(s.Path, token.Path) {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+// lastChunk return the last chunk of session, nil represent no chunks
+func (s *UploadSession) lastChunk(db *gorm.DB) (*UploadSessionChunk, error) {
+	var sc = &UploadSessionChunk{}
+	if err := db.Preload("Chunk").Where("sessionId = ?", s.ID).Order("number desc").First(sc).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return sc, nil
+}
+
+// AppendFromReader will append content from reader to session at offset, offset must
+// be equal to the offset of session. The content that exceeds the size of session is
+// ignored. Content is saved chunk by chunk, the offset of session is moved forward as
+// soon as a chunk is saved, so the content received before an interruption is kept.
+// The last chunk is never changed in place, because chunks may be shared by others.
+// If it isn't full, or content-defined chunking is used, content is split again from
+// the start of the last chunk, and the row of the last chunk is reused. The chunks
+// that hold the content of the last chunk are saved in one transaction, so the row
+// of the last chunk is kept until all its content is saved again. The session is
+// loaded again after the lock of it is acquired, so only one of the requests at the
+// same offset succeeds.
+func (s *UploadSession) AppendFromReader(offset int, reader io.Reader, rootPath *string, db *gorm.DB) (int, error) {
+	var (
+		err       error
+		size      int
+		number    int
+		last      *UploadSessionChunk
+		pending   []byte
+		batch     []sessionChunk
+		chunker   Chunker
+		stateHash hash.Hash
+	)
+
+	unlock := uploadSessionLocks.lock(s.ID)
+	defer unlock()
+
+	// the session may be changed by others before the lock is acquired
+	if err = db.Where("id = ?", s.ID).Find(s).Error; err != nil {
+		return 0, err
+	}
+
+	if s.Expired() {
+		return 0, ErrUploadSessionExpired
+	}
+
+	if offset != s.Offset {
+		return 0, ErrUploadOffsetMismatch
+	}
+
+	if last, err = s.lastChunk(db); err != nil {
+		return 0, err
+	}
+
+	if last != nil && (!isFixedChunking() || last.Chunk.Size < ChunkSize) {
+		if pending, stateHash, err = s.reopenLastChunk(last, rootPath, db); err != nil {
+			return 0, err
+		}
+		number = last.Number - 1
+	} else {
+		if stateHash, err = sha2562.NewHashWithStateText(*s.HashState); err != nil {
+			return 0, err
+		}
+		if last != nil {
+			number = last.Number
+		}
+		last = nil
+	}
+
+	reader = io.MultiReader(bytes.NewReader(pending), io.LimitReader(reader, int64(s.Size-s.Offset)))
+	if chunker, err = newDefaultChunker(reader); err != nil {
+		return 0, err
+	}
+
+	for {
+		var (
+			content []byte
+			advance int
+		)
+		if content, err = chunker.Next(); err == io.EOF {
+			return size, nil
+		} else if err != nil {
+			return size, err
+		}
+
+		// the content of the last chunk has been counted already
+		if advance = len(content) - len(pending); advance < 0 {
+			pending, advance = pending[len(content):], 0
+		} else {
+			pending = nil
+		}
+
+		number++
+		if last == nil || last.Number != number {
+			last = &UploadSessionChunk{SessionID: s.ID, Number: number}
+		}
+		batch = append(batch, sessionChunk{sc: last, content: content, advance: advance})
+		if len(pending) > 0 {
+			// content is reused by chunker, and it's saved along with the rest of
+			// the last chunk
+			batch[len(batch)-1].content = append([]byte(nil), content...)
+			continue
+		}
+		if err = s.saveChunks(batch, stateHash, rootPath, db); err != nil {
+			return size, err
+		}
+		for _, c := range batch {
+			size += c.advance
+		}
+		batch = nil
+	}
+}
+
+// sessionChunk is the content that will be saved as the chunk of sc, advance
+// is the length of the new content at the end of it
+type sessionChunk struct {
+	sc      *UploadSessionChunk
+	content []byte
+	advance int
+}
+
+// reopenLastChunk return the content of the last chunk and the hash state of the
+// content before it, so that the content can be split again from there.
+func (s *UploadSession) reopenLastChunk(
+	last *UploadSessionChunk, rootPath *string, db *gorm.DB) ([]byte, hash.Hash, error) {
+	var (
+		err       error
+		reader    ChunkReader
+		content   []byte
+		stateHash = sha256.New()
+	)
+
+	if reader, err = last.Chunk.Reader(rootPath); err != nil {
+		return nil, nil, err
+	}
+	content, err = ioutil.ReadAll(reader)
+	_ = reader.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if last.Number > 1 {
+		var prev = &UploadSessionChunk{}
+		if err = db.Where("sessionId = ? and number = ?", s.ID, last.Number-1).First(prev).Error; err != nil {
+			return nil, nil, err
+		}
+		if stateHash, err = sha2562.NewHashWithStateText(*prev.HashState); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return content, stateHash, nil
+}
+
+// saveChunks will save the content of chunks in order, and the session is saved
+// after that. The row of session is locked in a transaction, and its offset is
+// checked again, so that the session can't be appended by the other instances at
+// the same time. All chunks are created before the rows of session are changed,
+// so the rows are kept if any of them fails.
+func (s *UploadSession) saveChunks(chunks []sessionChunk, stateHash hash.Hash, rootPath *string, db *gorm.DB) error {
+	var (
+		err       error
+		hashState *string
+		offset    = s.Offset
+		current   = &UploadSession{}
+		created   = make([]*Chunk, len(chunks))
+	)
+
+	return Transaction(db, func(tx *gorm.DB) error {
+		if err = forUpdate(tx).Select("id, offset").Where("id = ?", s.ID).Find(current).Error; err != nil {
+			return err
+		}
+		if current.Offset != s.Offset {
+			return ErrUploadOffsetMismatch
+		}
+		for index, c := range chunks {
+			if created[index], err = CreateChunkFromBytes(s.AppID, c.content, rootPath, tx); err != nil {
+				return err
+			}
+		}
+		for index, c := range chunks {
+			var state string
+			if _, err = stateHash.Write(c.content); err != nil {
+				return err
+			}
+			if state, err = sha2562.GetHashStateText(stateHash); err != nil {
+				return err
+			}
+			hashState = &state
+			c.sc.ChunkID = created[index].ID
+			c.sc.Chunk = *created[index]
+			c.sc.HashState = hashState
+			if err = tx.Save(c.sc).Error; err != nil {
+				return err
+			}
+			offset += c.advance
+		}
+
+		s.Offset = offset
+		s.HashState = hashState
+		s.ExpiredAt = time.Now().Add(UploadSessionTTL)
+
+		return tx.Model(s).Updates(map[string]interface{}{
+			"offset":    s.Offset,
+			"hashState": s.HashState,
+			"expiredAt": s.ExpiredAt,
+		}).Error
+	})
+}
+
+// Complete will turn the session into a file, the session is deleted after that.
+// The chunks of session are taken over by the object of file, all changes are
+// made in a transaction.
+func (s *UploadSession) Complete(rootPath *string, db *gorm.DB) (*File, error) {
+	var (
+		err       error
+		file      *File
+		object    *Object
+		stateHash hash.Hash
+	)
+
+	if !s.Completed() {
+		return nil, ErrUploadSessionNotComplete
+	}
+
+	if err = db.Preload("App").Preload("Chunks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("upload_session_chunk.number asc")
+	}).Find(s).Error; err != nil {
+		return nil, err
+	}
+
+	if stateHash, err = sha2562.NewHashWithStateText(*s.HashState); err != nil {
+		return nil, err
+	}
+
+	if err = Transaction(db, func(tx *gorm.DB) error {
+		if s.Size == 0 {
+			object, err = CreateEmptyObject(s.AppID, rootPath, tx)
+		} else {
+			object, err = s.createObject(hex.EncodeToString(stateHash.Sum(nil)), tx)
+		}
+		if err != nil {
+			return err
+		}
+		if file, err = CreateFileFromObject(&s.App, s.Path, object, s.Hidden, tx); err != nil {
+			return err
+		}
+		return s.Delete(tx)
+	}); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// createObject is used to create an object from the chunks of session
+func (s *UploadSession) createObject(h string, db *gorm.DB) (*Object, error) {
+	var (
+		err          error
+		appID        uint64
+		object       *Object
+		objectChunks = make([]ObjectChunk, len(s.Chunks))
+	)
+
+	if appID, err = contentScope(s.AppID); err != nil {
+		return nil, err
+	}
+
+	if object, err = findReusableObject(appID, h, db); err == nil {
+		return object, nil
+	}
+
+	for index, sc := range s.Chunks {
+		objectChunks[index] = ObjectChunk{
+			ChunkID:   sc.ChunkID,
+			Number:    sc.Number,
+			HashState: sc.HashState,
+		}
+	}
+
+	return saveObjectWithChunks(&Object{AppID: appID, Size: int64(s.Size), Hash: h}, objectChunks, db)
+}
+
+// Delete is used to delete the session and its chunk rows, the content of chunks
+// isn't deleted here, it will be collected by garbage collection.
+func (s *UploadSession) Delete(db *gorm.DB) error {
+	if err := db.Where("sessionId = ?", s.ID).Delete(&UploadSessionChunk{}).Error; err != nil {
+		return err
+	}
+	return db.Delete(s).Error
+}
+
+// NewUploadSession is used to create an upload session that will save content to path
+func NewUploadSession(app *App, path string, size int, hidden int8, db *gorm.DB) (*UploadSession, error) {
+	var (
+		err       error
+		hashState string
+		session   *UploadSession
+	)
+
+	if f, err := FindFileByPath(app, path, db); err == nil && f.ID > 0 {
+		return nil, ErrFileExisted
+	}
+
+	if hashState, err = sha2562.GetHashStateText(sha256.New()); err != nil {
+		return nil, err
+	}
+
+	session = &UploadSession{
+		UID:       bson.NewObjectId().Hex(),
+		AppID:     app.ID,
+		Path:      path,
+		Size:      size,
+		HashState: &hashState,
+		Hidden:    hidden,
+		ExpiredAt: time.Now().Add(UploadSessionTTL),
+		App:       *app,
+	}
+
+	return session, db.Save(session).Error
+}
+
+// FindUploadSessionByUID is used to find an upload session by uid
+func FindUploadSessionByUID(uid string, db *gorm.DB) (*UploadSession, error) {
+	var (
+		err     error
+		session = &UploadSession{}
+	)
+	if err = db.Preload("App").Where("uid = ?", uid).Find(session).Error; err != nil {
+		return session, err
+	}
+	return session, nil
+}
+
+// DeleteExpiredUploadSessions is used to delete the sessions that have expired before
+// deadline. Their chunks become unreferenced, and will be collected by garbage collection.
+func DeleteExpiredUploadSessions(deadline time.Time, db *gorm.DB) (int64, error) {
+	if _, err := execAffected(db, "delete upload_session_chunk from upload_session_chunk "+
+		"join upload_sessions on upload_sessions.id = upload_session_chunk.sessionId "+
+		"where upload_sessions.expiredAt < ?", deadline); err != nil {
+		return 0, err
+	}
+	return execAffected(db, "delete from upload_sessions where expiredAt < ?", deadline)
+}
@@ -0,0 +1,26 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import "time"
+
+// UploadSessionChunk associates chunk and upload session, it's similar to ObjectChunk.
+// HashState is the hash state of the content that ends with this chunk.
+type UploadSessionChunk struct {
+	ID        uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT;primary_key"`
+	SessionID uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL;column:sessionId"`
+	ChunkID   uint64    `gorm:"type:BIGINT(20) UNSIGNED NOT NULL;column:chunkId"`
+	Number    int       `gorm:"type:int;column:number"`
+	HashState *string   `gorm:"type:text;column:hashState"`
+	CreatedAt time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:createdAt"`
+	UpdatedAt time.Time `gorm:"type:TIMESTAMP(6) NOT NULL;DEFAULT:CURRENT_TIMESTAMP(6);column:updatedAt"`
+
+	Chunk Chunk `gorm:"foreignkey:chunkId;association_autoupdate:false;association_autocreate:false"`
+}
+
+// TableName represent the db table name
+func (sc UploadSessionChunk) TableName() string {
+	return "upload_session_chunk"
+}
@@ -0,0 +1,326 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadSession_TableName(t *testing.T) {
+	assert.Equal(t, "upload_sessions", (&UploadSession{}).TableName())
+	assert.Equal(t, "upload_session_chunk", UploadSessionChunk{}.TableName())
+}
+
+func TestNewUploadSession(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	session, err := NewUploadSession(app, "/upload/random.bytes", 1024, int8(1), trx)
+	assert.Nil(t, err)
+	assert.True(t, session.ID > 0)
+	assert.Equal(t, 0, session.Offset)
+	assert.False(t, session.Expired())
+	assert.False(t, session.Completed())
+
+	session2, err := FindUploadSessionByUID(session.UID, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, session.ID, session2.ID)
+	assert.Equal(t, app.ID, session2.App.ID)
+
+	token, err := NewToken(app, "/upload", nil, nil, nil, -1, 0, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, session.CanBeAccessedByToken(token))
+	token.Path = "/other"
+	assert.Equal(t, ErrAccessDenied, session.CanBeAccessedByToken(token))
+
+	_, err = CreateFileFromReader(app, "/upload/existed.bytes", bytes.NewReader(Random(12)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = NewUploadSession(app, "/upload/existed.bytes", 1024, int8(0), trx)
+	assert.Equal(t, ErrFileExisted, err)
+}
+
+func TestUploadSession_AppendFromReader(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	var (
+		content = Random(2*ChunkSize + 100)
+		size    = len(content)
+	)
+	session, err := NewUploadSession(app, "/upload/random.bytes", size, int8(0), trx)
+	assert.Nil(t, err)
+
+	// offset doesn't match
+	_, err = session.AppendFromReader(10, bytes.NewReader(content[10:]), &tempDir, trx)
+	assert.Equal(t, ErrUploadOffsetMismatch, err)
+
+	// small pieces are merged into one chunk
+	n, err := session.AppendFromReader(0, bytes.NewReader(content[:100]), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 100, n)
+	n, err = session.AppendFromReader(100, bytes.NewReader(content[100:300]), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, n)
+	assert.Equal(t, 300, session.Offset)
+	assert.Equal(t, 1, trx.Model(session).Association("Chunks").Count())
+
+	// interrupted, the content received before is kept
+	n, err = session.AppendFromReader(300, iotest.TimeoutReader(bytes.NewReader(content[300:ChunkSize+10])), &tempDir, trx)
+	assert.Equal(t, iotest.ErrTimeout, err)
+	assert.True(t, n > 0)
+	assert.Equal(t, 300+n, session.Offset)
+	assert.False(t, session.Completed())
+
+	// resume from the offset of session, the content that exceeds size is ignored
+	session, err = FindUploadSessionByUID(session.UID, trx)
+	assert.Nil(t, err)
+	n, err = session.AppendFromReader(session.Offset, bytes.NewReader(append(content[session.Offset:], 'a')), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, size, session.Offset)
+	assert.True(t, session.Completed())
+	assert.Equal(t, 3, trx.Model(session).Association("Chunks").Count())
+
+	file, err := session.Complete(&tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, size, file.Size)
+	hash, err := util.Sha256Hash2String(content)
+	assert.Nil(t, err)
+	assert.Equal(t, hash, file.Object.Hash)
+
+	reader, err := file.Reader(&tempDir, trx)
+	assert.Nil(t, err)
+	readContent, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, content, readContent)
+
+	_, err = FindUploadSessionByUID(session.UID, trx)
+	assert.True(t, util.IsRecordNotFound(err))
+	assert.Equal(t, 0, trx.Model(session).Association("Chunks").Count())
+
+	// expired
+	session, err = NewUploadSession(app, "/upload/expired.bytes", size, int8(0), trx)
+	assert.Nil(t, err)
+	assert.Nil(t, trx.Model(session).Update("expiredAt", time.Now().Add(-time.Second)).Error)
+	_, err = session.AppendFromReader(0, bytes.NewReader(content), &tempDir, trx)
+	assert.Equal(t, ErrUploadSessionExpired, err)
+}
+
+func TestUploadSession_Complete(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	session, err := NewUploadSession(app, "/upload/random.bytes", 10, int8(1), trx)
+	assert.Nil(t, err)
+	_, err = session.Complete(&tempDir, trx)
+	assert.Equal(t, ErrUploadSessionNotComplete, err)
+
+	// empty file
+	session, err = NewUploadSession(app, "/upload/empty.bytes", 0, int8(1), trx)
+	assert.Nil(t, err)
+	assert.True(t, session.Completed())
+	file, err := session.Complete(&tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, file.Size)
+	assert.Equal(t, int8(1), file.Hidden)
+
+	// the object that has the same content is reused
+	content := Random(128)
+	existed, err := CreateFileFromReader(app, "/upload/existed.bytes", bytes.NewReader(content), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	session, err = NewUploadSession(app, "/upload/same.bytes", len(content), int8(0), trx)
+	assert.Nil(t, err)
+	_, err = session.AppendFromReader(0, bytes.NewReader(content), &tempDir, trx)
+	assert.Nil(t, err)
+	file, err = session.Complete(&tempDir, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, existed.ObjectID, file.ObjectID)
+}
+
+func TestDeleteExpiredUploadSessions(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	session, err := NewUploadSession(app, "/upload/random.bytes", 1024, int8(0), trx)
+	assert.Nil(t, err)
+	_, err = session.AppendFromReader(0, bytes.NewReader(Random(512)), &tempDir, trx)
+	assert.Nil(t, err)
+	alive, err := NewUploadSession(app, "/upload/alive.bytes", 1024, int8(0), trx)
+	assert.Nil(t, err)
+
+	deleted, err := DeleteExpiredUploadSessions(time.Now().Add(UploadSessionTTL+time.Minute), trx)
+	assert.Nil(t, err)
+	assert.True(t, deleted >= 2)
+	_, err = FindUploadSessionByUID(session.UID, trx)
+	assert.True(t, util.IsRecordNotFound(err))
+	_, err = FindUploadSessionByUID(alive.UID, trx)
+	assert.True(t, util.IsRecordNotFound(err))
+	assert.Equal(t, 0, trx.Model(session).Association("Chunks").Count())
+
+	alive, err = NewUploadSession(app, "/upload/alive.bytes", 1024, int8(0), trx)
+	assert.Nil(t, err)
+	_, err = DeleteExpiredUploadSessions(time.Now(), trx)
+	assert.Nil(t, err)
+	_, err = FindUploadSessionByUID(alive.UID, trx)
+	assert.Nil(t, err)
+}
+
+func TestUploadSession_AppendFromReader2(t *testing.T) {
+	defer useCDCForTest()()
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	var content = Random(64 << 10)
+	session, err := NewUploadSession(app, "/upload/cdc.bytes", len(content), int8(0), trx)
+	assert.Nil(t, err)
+
+	// content is received in pieces, the boundaries of chunks are decided by content
+	for _, end := range []int{100, 10 << 10, 33 << 10, len(content)} {
+		n, err := session.AppendFromReader(session.Offset, bytes.NewReader(content[session.Offset:end]), &tempDir, trx)
+		assert.Nil(t, err)
+		assert.Equal(t, end, session.Offset)
+		assert.True(t, n > 0)
+	}
+
+	oc, _, err := writeChunksFromReader(0, bytes.NewReader(content), 0, sha256.New(), &tempDir, trx)
+	assert.Nil(t, err)
+	var chunks []UploadSessionChunk
+	assert.Nil(t, trx.Where("sessionId = ?", session.ID).Order("number asc").Find(&chunks).Error)
+	assert.Equal(t, len(oc), len(chunks))
+	for index := range oc {
+		assert.Equal(t, oc[index].ChunkID, chunks[index].ChunkID)
+	}
+
+	file, err := session.Complete(&tempDir, trx)
+	assert.Nil(t, err)
+	reader, err := file.Reader(&tempDir, trx)
+	assert.Nil(t, err)
+	readContent, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, content, readContent)
+}
+
+func TestUploadSession_AppendFromReader3(t *testing.T) {
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	tempDir := NewTempDirForTest()
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	var content = Random(256)
+	session, err := NewUploadSession(app, "/upload/stale.bytes", len(content), int8(0), trx)
+	assert.Nil(t, err)
+	stale, err := FindUploadSessionByUID(session.UID, trx)
+	assert.Nil(t, err)
+
+	_, err = session.AppendFromReader(0, bytes.NewReader(content[:100]), &tempDir, trx)
+	assert.Nil(t, err)
+
+	// the offset is checked with the latest session
+	_, err = stale.AppendFromReader(0, bytes.NewReader(content[:100]), &tempDir, trx)
+	assert.Equal(t, ErrUploadOffsetMismatch, err)
+	assert.Equal(t, 100, stale.Offset)
+	assert.Equal(t, 1, trx.Model(session).Association("Chunks").Count())
+	assert.Empty(t, uploadSessionLocks.locks)
+
+	// the offset is changed by others while the content is being read
+	assert.Nil(t, trx.Model(session).Update("offset", 110).Error)
+	sc := &UploadSessionChunk{SessionID: session.ID, Number: 2}
+	chunks := []sessionChunk{{sc: sc, content: content[100:110], advance: 10}}
+	assert.Equal(t, ErrUploadOffsetMismatch, stale.saveChunks(chunks, sha256.New(), &tempDir, trx))
+	assert.Equal(t, 1, trx.Model(session).Association("Chunks").Count())
+}
+
+func TestUploadSession_AppendFromReader4(t *testing.T) {
+	var store = &faultyChunkStore{ChunkStore: NewMemoryChunkStore()}
+	SetDefaultChunkStore(store)
+	app, trx, down, err := newAppForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		SetDefaultChunkStore(nil)
+		down(t)
+	}()
+
+	var content = Random(64 << 10)
+	session, err := NewUploadSession(app, "/upload/resumed.bytes", len(content), int8(0), trx)
+	assert.Nil(t, err)
+	_, err = session.AppendFromReader(0, bytes.NewReader(content[:20<<10]), nil, trx)
+	assert.Nil(t, err)
+	last, err := session.lastChunk(trx)
+	assert.Nil(t, err)
+
+	// the last chunk is split again into smaller chunks, the second of them fails
+	defer useCDCForTest()()
+	store.puts, store.failPut = 0, 2
+	_, err = session.AppendFromReader(session.Offset, bytes.NewReader(content[20<<10:40<<10]), nil, trx)
+	assert.NotNil(t, err)
+	assert.True(t, store.puts >= 2)
+	assert.Nil(t, trx.Where("id = ?", session.ID).Find(session).Error)
+	assert.Equal(t, 20<<10, session.Offset)
+	current, err := session.lastChunk(trx)
+	assert.Nil(t, err)
+	assert.Equal(t, last.ChunkID, current.ChunkID)
+	assert.Equal(t, *last.HashState, *current.HashState)
+
+	// the upload is resumed from the offset of session
+	store.failPut = 0
+	n, err := session.AppendFromReader(session.Offset, bytes.NewReader(content[session.Offset:]), nil, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 44<<10, n)
+	file, err := session.Complete(nil, trx)
+	assert.Nil(t, err)
+	reader, err := file.Reader(nil, trx)
+	assert.Nil(t, err)
+	readContent, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, content, readContent)
+}
@@ -0,0 +1,99 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type directoryListInput struct {
+	Token      string  `form:"token" binding:"required"`
+	Nonce      *string `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
+	Sign       *string `form:"sign" binding:"omitempty"`
+	Path       string  `form:"path,default=/" binding:"omitempty,max=1000"`
+	Sort       string  `form:"sort,default=name" binding:"omitempty"`
+	Ext        *string `form:"ext" binding:"omitempty,max=255"`
+	ShowHidden *bool   `form:"showHidden,default=0" binding:"omitempty"`
+	Offset     *int    `form:"offset,default=0" binding:"omitempty,min=0"`
+	Limit      *int    `form:"limit,default=20" binding:"omitempty,min=1,max=100"`
+}
+
+// DirectoryListHandler is used to list the children of directory
+func DirectoryListHandler(ctx *gin.Context) {
+	var (
+		ip                    = ctx.ClientIP()
+		db                    = ctx.MustGet("db").(*gorm.DB)
+		err                   error
+		token                 = ctx.MustGet("token").(*models.Token)
+		input                 = ctx.MustGet("inputParam").(*directoryListInput)
+		directoryListSrv      *service.DirectoryList
+		directoryListSrvValue interface{}
+		directoryListValue    *service.DirectoryListValue
+
+		code     = 400
+		reErrors map[string][]string
+		success  bool
+		data     interface{}
+	)
+
+	defer func() {
+		ctx.JSON(code, &Response{
+			RequestID: ctx.GetInt64("requestId"),
+			Success:   success,
+			Errors:    reErrors,
+			Data:      data,
+		})
+	}()
+
+	directoryListSrv = &service.DirectoryList{
+		BaseService: service.BaseService{
+			DB: db,
+		},
+		Token:  token,
+		IP:     &ip,
+		Path:   input.Path,
+		Sort:   input.Sort,
+		Ext:    input.Ext,
+		Offset: *input.Offset,
+		Limit:  *input.Limit,
+	}
+
+	if input.ShowHidden != nil && *input.ShowHidden {
+		directoryListSrv.ShowHidden = 1
+	}
+
+	if err = directoryListSrv.Validate(); !reflect.ValueOf(err).IsNil() {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if directoryListSrvValue, err = directoryListSrv.Execute(context.Background()); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	directoryListValue = directoryListSrvValue.(*service.DirectoryListValue)
+	items := make([]map[string]interface{}, len(directoryListValue.Files))
+	for index := range directoryListValue.Files {
+		file := &directoryListValue.Files[index]
+		if items[index], err = fileRespWithPath(file, file.FullPath, db); err != nil {
+			reErrors = generateErrors(err, "")
+			return
+		}
+	}
+
+	data = map[string]interface{}{
+		"total": directoryListValue.Total,
+		"items": items,
+	}
+	code = 200
+	success = true
+}
@@ -0,0 +1,143 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDirectoryListForTest(t *testing.T) (*gin.Context, func(*testing.T)) {
+	var (
+		ctx     *gin.Context
+		trx     *gorm.DB
+		err     error
+		token   *models.Token
+		down    func(*testing.T)
+		offset  = 0
+		limit   = 20
+		tempDir = models.NewTempDirForTest()
+	)
+
+	testingChunkRootPath = &tempDir
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Writer = &bodyWriter{ResponseWriter: ctx.Writer, body: bytes.NewBufferString("")}
+	ctx.Request, _ = http.NewRequest("GET", "http://bigfile.io", strings.NewReader(""))
+	ctx.Request.Header.Set("X-Forwarded-For", "192.168.0.1")
+	ctx.Set("db", trx)
+	ctx.Set("token", token)
+	reqRecord := models.MustNewRequestWithProtocol("http", trx)
+	ctx.Set("reqRecord", reqRecord)
+	ctx.Set("requestId", int64(reqRecord.ID))
+
+	_, err = models.CreateOrGetLastDirectory(&token.App, "/test/images", trx)
+	assert.Nil(t, err)
+	_, err = models.CreateFileFromReader(&token.App, "/test/random.bytes", bytes.NewReader(models.Random(128)), int8(0), testingChunkRootPath, trx)
+	assert.Nil(t, err)
+
+	ctx.Set("inputParam", &directoryListInput{
+		Path:   "/test",
+		Sort:   "name",
+		Offset: &offset,
+		Limit:  &limit,
+	})
+
+	return ctx, func(t *testing.T) {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}
+}
+
+func TestDirectoryListHandler(t *testing.T) {
+	ctx, down := newDirectoryListForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	input := ctx.MustGet("inputParam").(*directoryListInput)
+	input.Path = "/not/exist"
+
+	DirectoryListHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, "record not found", response.Errors["system"][0])
+}
+
+func TestDirectoryListHandler2(t *testing.T) {
+	ctx, down := newDirectoryListForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	DirectoryListHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, float64(2), responseData["total"].(float64))
+	items := responseData["items"].([]interface{})
+	assert.Equal(t, "/test/images", items[0].(map[string]interface{})["path"].(string))
+	assert.Equal(t, "/test/random.bytes", items[1].(map[string]interface{})["path"].(string))
+	assert.Equal(t, "bytes", items[1].(map[string]interface{})["ext"].(string))
+}
+
+func TestDirectoryListHandler3(t *testing.T) {
+	var (
+		w      = httptest.NewRecorder()
+		api    = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/directory/list")
+		trx    *gorm.DB
+		err    error
+		down   func(*testing.T)
+		token  *models.Token
+		secret = models.RandomWithMd5(222)
+	)
+
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	token.Secret = &secret
+	assert.Nil(t, trx.Save(token).Error)
+	testDBConn = trx
+	defer down(t)
+
+	for _, path := range []string{"/save/to/a", "/save/to/b", "/save/to/c"} {
+		_, err = models.CreateOrGetLastDirectory(&token.App, path, trx)
+		assert.Nil(t, err)
+	}
+
+	qs := getParamsSignBody(map[string]interface{}{
+		"token": token.UID,
+		"nonce": models.RandomWithMd5(333),
+		"path":  "/save/to",
+		"sort":  "-name",
+		"limit": 2,
+	}, secret)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("%s?%s", api, qs), nil)
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, float64(3), responseData["total"].(float64))
+	items := responseData["items"].([]interface{})
+	assert.Equal(t, 2, len(items))
+	assert.Equal(t, "/save/to/c", items[0].(map[string]interface{})["path"].(string))
+}
@@ -0,0 +1,100 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type fileCopyInput struct {
+	Token     string  `form:"token" binding:"required"`
+	FileUID   string  `form:"fileUid" binding:"omitempty"`
+	FilePath  *string `form:"filePath" binding:"omitempty,max=1000"`
+	Nonce     string  `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
+	Sign      *string `form:"sign" binding:"omitempty"`
+	Path      string  `form:"path" binding:"required,max=1000"`
+	Overwrite *bool   `form:"overwrite,default=0" binding:"omitempty"`
+	Rename    *bool   `form:"rename,default=0" binding:"omitempty"`
+}
+
+// FileCopyHandler is used to copy a file or a directory to another path
+func FileCopyHandler(ctx *gin.Context) {
+	var (
+		ip               = ctx.ClientIP()
+		db               = ctx.MustGet("db").(*gorm.DB)
+		err              error
+		file             *models.File
+		errKey           string
+		token            = ctx.MustGet("token").(*models.Token)
+		input            = ctx.MustGet("inputParam").(*fileCopyInput)
+		fileCopySrv      *service.FileCopy
+		fileCopySrvValue interface{}
+
+		code     = 400
+		reErrors map[string][]string
+		success  bool
+		data     interface{}
+	)
+
+	defer func() {
+		ctx.JSON(code, &Response{
+			RequestID: ctx.GetInt64("requestId"),
+			Success:   success,
+			Errors:    reErrors,
+			Data:      data,
+		})
+	}()
+
+	if file, errKey, err = findFileByUIDOrPath(input.FileUID, input.FilePath, token, db); err != nil {
+		reErrors = generateErrors(err, errKey)
+		return
+	}
+
+	fileCopySrv = &service.FileCopy{
+		BaseService: service.BaseService{
+			DB: db,
+		},
+		Token: token,
+		File:  file,
+		Path:  input.Path,
+		IP:    &ip,
+	}
+
+	if input.Overwrite != nil && *input.Overwrite {
+		fileCopySrv.Overwrite = 1
+	}
+
+	if input.Rename != nil && *input.Rename {
+		fileCopySrv.Rename = 1
+	}
+
+	if isTesting {
+		fileCopySrv.RootPath = testingChunkRootPath
+	}
+
+	if err = fileCopySrv.Validate(); !reflect.ValueOf(err).IsNil() {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if fileCopySrvValue, err = fileCopySrv.Execute(context.Background()); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if data, err = fileResp(fileCopySrvValue.(*models.File), db); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	code = 200
+	success = true
+}
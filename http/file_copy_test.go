@@ -0,0 +1,84 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileCopyHandler(t *testing.T) {
+	var (
+		w       = httptest.NewRecorder()
+		api     = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/file/copy")
+		secret  = models.RandomWithMd5(222)
+		tempDir = models.NewTempDirForTest()
+	)
+
+	testingChunkRootPath = &tempDir
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+	token.Secret = &secret
+	assert.Nil(t, trx.Save(token).Error)
+	testDBConn = trx
+
+	file, err := models.CreateFileFromReader(&token.App, "/copy/from/random.bytes", bytes.NewReader(models.Random(128)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = models.CreateFileFromReader(&token.App, "/copy/to/random.bytes", bytes.NewReader(models.Random(64)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+
+	body := getParamsSignBody(map[string]interface{}{
+		"token":    token.UID,
+		"filePath": "/copy/from",
+		"path":     "/copy/to",
+		"nonce":    models.RandomWithMd5(333),
+	}, secret)
+	req, _ := http.NewRequest("POST", api, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Errors["system"][0], "existed")
+
+	w = httptest.NewRecorder()
+	body = getParamsSignBody(map[string]interface{}{
+		"token":     token.UID,
+		"filePath":  "/copy/from",
+		"path":      "/copy/to",
+		"overwrite": 1,
+		"nonce":     models.RandomWithMd5(333),
+	}, secret)
+	req, _ = http.NewRequest("POST", api, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response, err = parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, "/copy/to", responseData["path"])
+	assert.Equal(t, float64(128), responseData["size"])
+
+	copied, err := models.FindFileByPath(&token.App, "/copy/to/random.bytes", trx)
+	assert.Nil(t, err)
+	assert.Equal(t, file.ObjectID, copied.ObjectID)
+}
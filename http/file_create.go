@@ -5,22 +5,26 @@
 package http
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"hash"
 	"io"
-	"mime/multipart"
-	"net/http"
 	"reflect"
 
 	"github.com/bigfile/bigfile/databases/models"
-	"github.com/bigfile/bigfile/internal/util"
 	"github.com/bigfile/bigfile/service"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
 )
 
-var testingChunkRootPath *string
+var (
+	testingChunkRootPath *string
+
+	errFileSizeNotMatch = errors.New("the size of file doesn't match")
+	errFileHashNotMatch = errors.New("the hash of file doesn't match")
+)
 
 type fileCreateInput struct {
 	Token     string  `form:"token" binding:"required"`
@@ -33,14 +37,14 @@ type fileCreateInput struct {
 	Rename    *bool   `form:"rename,default=0" binding:"omitempty"`
 	Append    *bool   `form:"append,default=0" binding:"omitempty"`
 	Hidden    *bool   `form:"hidden,default=0" binding:"omitempty"`
+	// metas are sent as meta[name]=value, they are read by PostFormMap
+	ReplaceMetas *bool `form:"replaceMetas,default=0" binding:"omitempty"`
 }
 
 // FileCreateHandler is used to create file or directory
 func FileCreateHandler(ctx *gin.Context) {
 	var (
-		fh     *multipart.FileHeader
 		err    error
-		buf    = bytes.NewBuffer(nil)
 		reader io.Reader
 
 		code     = 400
@@ -72,51 +76,21 @@ func FileCreateHandler(ctx *gin.Context) {
 		})
 	}()
 
-	if fh, err = ctx.FormFile("file"); err != nil {
-		if err != http.ErrMissingFile {
-			reErrors = generateErrors(err, "file")
-			return
-		}
-	} else {
-		if reader, err = fh.Open(); err != nil {
-			reErrors = generateErrors(err, "file")
-			return
-		}
-
-		if _, err = io.Copy(buf, reader); err != nil {
-			reErrors = generateErrors(err, "")
-			return
-		}
-
-		if buf.Len() > models.ChunkSize {
-			reErrors = generateErrors(models.ErrChunkExceedLimit, "file")
-			return
+	if err = parseMultipartFields(ctx); err != nil {
+		reErrors = generateErrors(err, "file")
+		return
+	}
+	if file, ok := ctx.Get("multipartFile"); ok && file.(*multipartFile) != nil {
+		// the content is hashed and counted while it's being saved, the mismatch
+		// is returned by the last read, so that the saved content is rolled back
+		reader = &verifiedReader{
+			reader:       file.(*multipartFile),
+			hash:         sha256.New(),
+			expectedHash: input.Hash,
+			expectedSize: input.Size,
 		}
-
-		// here, assign buf to reader, because the origin reader is multipart.sectionReadCloser
-		// and the content of the origin reader has exhausted
-		reader = buf
-
-		if input.Hash != nil || input.Size != nil {
-
-			if input.Size != nil && buf.Len() != *input.Size {
-				reErrors = generateErrors(errors.New("the size of file doesn't match"), "size")
-				return
-			}
-			if input.Hash != nil {
-				var (
-					h   string
-					err error
-				)
-				if h, err = util.Sha256Hash2String(buf.Bytes()); err != nil {
-					reErrors = generateErrors(err, "")
-					return
-				}
-				if h != *input.Hash {
-					reErrors = generateErrors(errors.New("the hash of file doesn't match"), "hash")
-					return
-				}
-			}
+		if input.Size != nil {
+			fileCreateSrv.Size = *input.Size
 		}
 	}
 	fileCreateSrv.Reader = reader
@@ -132,6 +106,10 @@ func FileCreateHandler(ctx *gin.Context) {
 	if input.Rename != nil && *input.Rename {
 		fileCreateSrv.Rename = 1
 	}
+	if input.ReplaceMetas != nil && *input.ReplaceMetas {
+		fileCreateSrv.ReplaceMetas = 1
+	}
+	fileCreateSrv.Metas = ctx.PostFormMap("meta")
 
 	if isTesting {
 		fileCreateSrv.RootPath = testingChunkRootPath
@@ -143,7 +121,14 @@ func FileCreateHandler(ctx *gin.Context) {
 	}
 
 	if fileCreateValue, err = fileCreateSrv.Execute(context.Background()); err != nil {
-		reErrors = generateErrors(err, "")
+		switch err {
+		case errFileSizeNotMatch:
+			reErrors = generateErrors(err, "size")
+		case errFileHashNotMatch:
+			reErrors = generateErrors(err, "hash")
+		default:
+			reErrors = generateErrors(err, "")
+		}
 		return
 	}
 
@@ -155,3 +140,32 @@ func FileCreateHandler(ctx *gin.Context) {
 	code = 200
 	success = true
 }
+
+// verifiedReader hashes and counts the content while it's being read, it returns
+// an error instead of io.EOF if the size or hash doesn't match the expected one.
+type verifiedReader struct {
+	reader       io.Reader
+	hash         hash.Hash
+	size         int64
+	expectedHash *string
+	expectedSize *int
+}
+
+// Read implements io.Reader
+func (v *verifiedReader) Read(p []byte) (n int, err error) {
+	n, err = v.reader.Read(p)
+	v.size += int64(n)
+	_, _ = v.hash.Write(p[:n])
+	if v.expectedSize != nil && v.size > int64(*v.expectedSize) {
+		return n, errFileSizeNotMatch
+	}
+	if err == io.EOF {
+		if v.expectedSize != nil && v.size != int64(*v.expectedSize) {
+			return n, errFileSizeNotMatch
+		}
+		if v.expectedHash != nil && hex.EncodeToString(v.hash.Sum(nil)) != *v.expectedHash {
+			return n, errFileHashNotMatch
+		}
+	}
+	return n, err
+}
@@ -134,9 +134,11 @@ func TestFileCreateHandler2(t *testing.T) {
 	assert.Equal(t, 0, int(responseData["hidden"].(float64)))
 	assert.Equal(t, "/save/to/random.bytes", responseData["path"].(string))
 	assert.Equal(t, randomBytesHash, responseData["hash"].(string))
+	assert.Equal(t, 0, int(responseData["quarantined"].(float64)))
 	writer.body.Reset()
 
 	// size error
+	setRequestForCtx(ctx, 256)
 	input.Path = "/save/to/random1.bytes"
 	size = 255
 	input.Size = &size
@@ -148,6 +150,7 @@ func TestFileCreateHandler2(t *testing.T) {
 	writer.body.Reset()
 
 	// hash error
+	setRequestForCtx(ctx, 256)
 	input.Path = "/save/to/random2.bytes"
 	fakeHash := "fake hash"
 	size = 256
@@ -183,6 +186,8 @@ func TestFileCreateHandler2(t *testing.T) {
 	writer.body.Reset()
 
 	// path has been occupied, but append
+	randomBytesHash = setRequestForCtx(ctx, size)
+	input.Hash = &randomBytesHash
 	input.Append = &trueValue
 	input.Rename = &falseValue
 	input.Overwrite = &falseValue
@@ -232,6 +237,23 @@ func TestFileCreateHandler2(t *testing.T) {
 	assert.Equal(t, randomBytesHash, responseData["hash"].(string))
 	assert.NotEqual(t, "/save/to/random3.bytes", responseData["path"].(string))
 	writer.body.Reset()
+
+	// file is larger than one chunk
+	size = 2*models.ChunkSize + 7
+	randomBytesHash = setRequestForCtx(ctx, size)
+	input.Path = "/save/to/random4.bytes"
+	input.Hash = &randomBytesHash
+	input.Size = &size
+	input.Rename = &falseValue
+	FileCreateHandler(ctx)
+	response, err = parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData = response.Data.(map[string]interface{})
+	assert.Equal(t, size, int(responseData["size"].(float64)))
+	assert.Equal(t, randomBytesHash, responseData["hash"].(string))
+	assert.Equal(t, "/save/to/random4.bytes", responseData["path"].(string))
+	writer.body.Reset()
 }
 
 func TestFileCreateHandler3(t *testing.T) {
@@ -265,9 +287,10 @@ func TestFileCreateHandler3(t *testing.T) {
 	assert.Nil(t, trx.Save(token).Error)
 
 	params = map[string]interface{}{
-		"token": token.UID,
-		"path":  "/test/create/binary/file.binary",
-		"nonce": models.RandomWithMd5(255),
+		"token":          token.UID,
+		"path":           "/test/create/binary/file.binary",
+		"nonce":          models.RandomWithMd5(255),
+		"meta[Owner-Id]": "1",
 	}
 	params["sign"] = getParamsSignature(params, secret)
 	for k, v := range params {
@@ -295,6 +318,64 @@ func TestFileCreateHandler3(t *testing.T) {
 	assert.Equal(t, 399, int(responseData["size"].(float64)))
 	assert.Equal(t, randomBytesHash, responseData["hash"].(string))
 	assert.Equal(t, "/test/create/binary/file.binary", responseData["path"].(string))
+	assert.Equal(t, map[string]interface{}{"owner-id": "1"}, responseData["metas"])
+}
+
+// TestFileCreateHandler4 is used to test that the size of file exceeds the quota
+func TestFileCreateHandler4(t *testing.T) {
+	var (
+		body           = &bytes.Buffer{}
+		formBodyWriter = multipart.NewWriter(body)
+	)
+	ctx, down := newFileCreateForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+	token := ctx.MustGet("token").(*models.Token)
+	assert.Nil(t, token.App.SetQuota(50, 0, ctx.MustGet("db").(*gorm.DB)))
+	ctx.MustGet("inputParam").(*fileCreateInput).Path = "/quota/random.bytes"
+
+	formFileWriter, err := formBodyWriter.CreateFormFile("file", "random.bytes")
+	assert.Nil(t, err)
+	_, err = formFileWriter.Write(models.Random(100))
+	assert.Nil(t, err)
+	assert.Nil(t, formBodyWriter.Close())
+	ctx.Request, _ = http.NewRequest("POST", "http://bigfile.io", body)
+	ctx.Request.Header.Set("Content-Type", formBodyWriter.FormDataContentType())
+
+	FileCreateHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, models.ErrQuotaExceeded.Error(), response.Errors["FileCreate.Quota"][0])
+}
+
+// TestFileCreateHandler5 is used to test that the fields after the file are rejected
+func TestFileCreateHandler5(t *testing.T) {
+	var (
+		body           = &bytes.Buffer{}
+		formBodyWriter = multipart.NewWriter(body)
+	)
+	ctx, down := newFileCreateForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+	ctx.MustGet("inputParam").(*fileCreateInput).Path = "/after/random.bytes"
+
+	formFileWriter, err := formBodyWriter.CreateFormFile("file", "random.bytes")
+	assert.Nil(t, err)
+	_, err = formFileWriter.Write(models.Random(100))
+	assert.Nil(t, err)
+	assert.Nil(t, formBodyWriter.WriteField("hidden", "1"))
+	assert.Nil(t, formBodyWriter.Close())
+	ctx.Request, _ = http.NewRequest("POST", "http://bigfile.io", body)
+	ctx.Request.Header.Set("Content-Type", formBodyWriter.FormDataContentType())
+
+	FileCreateHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Contains(t, writer.body.String(), errMultipartPartAfterFile.Error())
+	_, err = models.FindFileByPath(&ctx.MustGet("token").(*models.Token).App, "/after/random.bytes", ctx.MustGet("db").(*gorm.DB))
+	assert.NotNil(t, err)
 }
 
 func BenchmarkFileCreateHandler(b *testing.B) {
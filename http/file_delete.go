@@ -0,0 +1,88 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type fileDeleteInput struct {
+	Token    string  `form:"token" binding:"required"`
+	FileUID  string  `form:"fileUid" binding:"omitempty"`
+	FilePath *string `form:"filePath" binding:"omitempty,max=1000"`
+	Nonce    string  `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
+	Sign     *string `form:"sign" binding:"omitempty"`
+}
+
+// FileDeleteHandler is used to handle file delete request
+func FileDeleteHandler(ctx *gin.Context) {
+	var (
+		ip                 = ctx.ClientIP()
+		db                 = ctx.MustGet("db").(*gorm.DB)
+		err                error
+		file               *models.File
+		errKey             string
+		token              = ctx.MustGet("token").(*models.Token)
+		input              = ctx.MustGet("inputParam").(*fileDeleteInput)
+		fileDeleteSrv      *service.FileDelete
+		fileDeleteSrvValue interface{}
+
+		code     = 400
+		reErrors map[string][]string
+		success  bool
+		data     interface{}
+	)
+
+	defer func() {
+		ctx.JSON(code, &Response{
+			RequestID: ctx.GetInt64("requestId"),
+			Success:   success,
+			Errors:    reErrors,
+			Data:      data,
+		})
+	}()
+
+	if file, errKey, err = findFileByUIDOrPath(input.FileUID, input.FilePath, token, db); err != nil {
+		reErrors = generateErrors(err, errKey)
+		return
+	}
+
+	fileDeleteSrv = &service.FileDelete{
+		BaseService: service.BaseService{
+			DB: db,
+		},
+		Token: token,
+		File:  file,
+		IP:    &ip,
+	}
+
+	if isTesting {
+		fileDeleteSrv.RootPath = testingChunkRootPath
+	}
+
+	if err = fileDeleteSrv.Validate(); !reflect.ValueOf(err).IsNil() {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if fileDeleteSrvValue, err = fileDeleteSrv.Execute(context.Background()); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if data, err = trashResp(fileDeleteSrvValue.(*models.File), db); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	code = 200
+	success = true
+}
@@ -0,0 +1,174 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFileDeleteForTest(t *testing.T) (*gin.Context, func(*testing.T)) {
+	var (
+		ctx     *gin.Context
+		trx     *gorm.DB
+		err     error
+		token   *models.Token
+		down    func(*testing.T)
+		tempDir = models.NewTempDirForTest()
+	)
+
+	testingChunkRootPath = &tempDir
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Writer = &bodyWriter{ResponseWriter: ctx.Writer, body: bytes.NewBufferString("")}
+	ctx.Request, _ = http.NewRequest("DELETE", "http://bigfile.io", strings.NewReader(""))
+	ctx.Request.Header.Set("X-Forwarded-For", "192.168.0.1")
+	ctx.Set("db", trx)
+	ctx.Set("token", token)
+	reqRecord := models.MustNewRequestWithProtocol("http", trx)
+	ctx.Set("reqRecord", reqRecord)
+	ctx.Set("requestId", int64(reqRecord.ID))
+
+	randomBytesReader := bytes.NewReader(models.Random(128))
+	file, err := models.CreateFileFromReader(&token.App, "/test/random.bytes", randomBytesReader, int8(0), testingChunkRootPath, trx)
+	assert.Nil(t, err)
+
+	ctx.Set("inputParam", &fileDeleteInput{
+		FileUID: file.UID,
+	})
+
+	return ctx, func(t *testing.T) {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}
+}
+
+func TestFileDeleteHandler(t *testing.T) {
+	ctx, down := newFileDeleteForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	input := ctx.MustGet("inputParam").(*fileDeleteInput)
+	input.FileUID = ""
+
+	FileDeleteHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, "record not found", response.Errors["fileUid"][0])
+}
+
+func TestFileDeleteHandler2(t *testing.T) {
+	ctx, down := newFileDeleteForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	db := ctx.MustGet("db").(*gorm.DB)
+	token := ctx.MustGet("token").(*models.Token)
+	token.ReadOnly = 1
+	assert.Nil(t, db.Save(token).Error)
+
+	FileDeleteHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, "this token is read only", response.Errors["FileDelete.Token"][0])
+}
+
+func TestFileDeleteHandler3(t *testing.T) {
+	ctx, down := newFileDeleteForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	FileDeleteHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, "/test/random.bytes", responseData["path"].(string))
+
+	db := ctx.MustGet("db").(*gorm.DB)
+	token := ctx.MustGet("token").(*models.Token)
+	_, err = models.FindFileByPath(&token.App, "/test/random.bytes", db)
+	assert.NotNil(t, err)
+}
+
+func TestFileDeleteHandler4(t *testing.T) {
+	var (
+		w       = httptest.NewRecorder()
+		api     = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/file/delete")
+		trx     *gorm.DB
+		err     error
+		down    func(*testing.T)
+		token   *models.Token
+		secret  = models.RandomWithMd5(222)
+		tempDir = models.NewTempDirForTest()
+	)
+
+	testingChunkRootPath = &tempDir
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	token.Secret = &secret
+	assert.Nil(t, trx.Save(token).Error)
+	testDBConn = trx
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	dir, err := models.CreateOrGetLastDirectory(&token.App, "/save/to", trx)
+	assert.Nil(t, err)
+
+	qs := getParamsSignBody(map[string]interface{}{
+		"token":   token.UID,
+		"fileUid": dir.UID,
+		"nonce":   models.RandomWithMd5(333),
+	}, secret)
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("%s?%s", api, qs), nil)
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, "/save/to", responseData["path"].(string))
+	assert.Equal(t, float64(1), responseData["isDir"].(float64))
+}
+
+func TestFileDeleteHandler5(t *testing.T) {
+	ctx, down := newFileDeleteForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	filePath := "/test/random.bytes"
+	input := ctx.MustGet("inputParam").(*fileDeleteInput)
+	input.FileUID = ""
+	input.FilePath = &filePath
+
+	FileDeleteHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, filePath, responseData["path"].(string))
+}
@@ -7,12 +7,10 @@ package http
 import (
 	"context"
 	"fmt"
-	"io"
 	"mime"
 	"net/http"
 	"path"
 	"reflect"
-	"time"
 
 	"github.com/bigfile/bigfile/databases/models"
 	"github.com/bigfile/bigfile/service"
@@ -20,9 +18,13 @@ import (
 	"github.com/jinzhu/gorm"
 )
 
+// metaHeaderPrefix is the prefix of response headers that carry the metas of file
+const metaHeaderPrefix = "X-Bigfile-Meta-"
+
 type fileReadInput struct {
 	Token         string  `form:"token" binding:"required"`
-	FileUID       string  `form:"fileUid" binding:"required"`
+	FileUID       string  `form:"fileUid" binding:"omitempty"`
+	FilePath      *string `form:"filePath" binding:"omitempty,max=1000"`
 	Nonce         *string `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
 	Sign          *string `form:"sign" binding:"omitempty"`
 	OpenInBrowser bool    `form:"openInBrowser,default=0" binding:"omitempty"`
@@ -35,19 +37,21 @@ func FileReadHandler(ctx *gin.Context) {
 		db                     = ctx.MustGet("db").(*gorm.DB)
 		err                    error
 		file                   *models.File
+		errKey                 string
 		token                  = ctx.MustGet("token").(*models.Token)
 		input                  = ctx.MustGet("inputParam").(*fileReadInput)
 		requestID              = ctx.GetInt64("requestId")
 		fileReadSrv            *service.FileRead
 		fileReadSrvValue       interface{}
-		fileReadSrvValueReader io.Reader
+		fileReadSrvValueReader models.ObjectReader
+		metas                  map[string]string
 	)
 
-	if file, err = models.FindFileByUID(input.FileUID, false, db); err != nil {
+	if file, errKey, err = findFileByUIDOrPath(input.FileUID, input.FilePath, token, db); err != nil {
 		ctx.JSON(400, &Response{
 			RequestID: requestID,
 			Success:   false,
-			Errors:    generateErrors(err, "fileUid"),
+			Errors:    generateErrors(err, errKey),
 		})
 		return
 	}
@@ -82,25 +86,37 @@ func FileReadHandler(ctx *gin.Context) {
 		})
 		return
 	}
+	fileReadSrvValueReader = fileReadSrvValue.(models.ObjectReader)
+	defer fileReadSrvValueReader.Close()
 
-	fileReadSrvValueReader = fileReadSrvValue.(io.Reader)
+	if metas, err = file.FindMetas(db); err != nil {
+		ctx.JSON(400, &Response{
+			RequestID: requestID,
+			Success:   false,
+			Errors:    generateErrors(err, ""),
+		})
+		return
+	}
 
-	extraHeaders := map[string]string{
-		"Content-Type":  "application/octet-stream",
-		"ETag":          file.Object.Hash,
-		"Last-Modified": file.UpdatedAt.Format(time.RFC1123),
+	for name, value := range metas {
+		ctx.Header(metaHeaderPrefix+name, value)
 	}
 
+	ctx.Header("Content-Type", "application/octet-stream")
 	if contentType := mime.TypeByExtension(path.Ext(file.Name)); contentType != "" {
-		extraHeaders["Content-Type"] = contentType
+		ctx.Header("Content-Type", contentType)
 	}
 
+	// ETag must be quoted, otherwise If-Range can't be matched
+	ctx.Header("ETag", fmt.Sprintf(`"%s"`, file.Object.Hash))
+
 	if input.OpenInBrowser {
-		extraHeaders["Content-Disposition"] = fmt.Sprintf(`inline; filename="%s"`, file.Name)
+		ctx.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, file.Name))
 	} else {
-		extraHeaders["Content-Disposition"] = fmt.Sprintf(`attachment; filename="%s"`, file.Name)
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, file.Name))
 	}
 
+	// ServeContent takes care of Range, If-Range and conditional request
 	ctx.Set("ignoreRespBody", true)
-	ctx.DataFromReader(http.StatusOK, int64(file.Size), extraHeaders["Content-Type"], fileReadSrvValueReader, extraHeaders)
+	http.ServeContent(ctx.Writer, ctx.Request, file.Name, file.UpdatedAt, fileReadSrvValueReader)
 }
@@ -191,6 +191,106 @@ func TestFileReadHandler5(t *testing.T) {
 	assert.Equal(t, responseBodyHash, randomBytesHash)
 }
 
+func TestFileReadHandler6(t *testing.T) {
+	var (
+		w       = httptest.NewRecorder()
+		api     = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/file/read")
+		trx     *gorm.DB
+		err     error
+		down    func(*testing.T)
+		token   *models.Token
+		tempDir = models.NewTempDirForTest()
+	)
+
+	testingChunkRootPath = &tempDir
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	token.Path = "/save"
+	assert.Nil(t, trx.Save(token).Error)
+	testDBConn = trx
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	randomBytes := models.Random(128)
+	randomBytesHash, err := util.Sha256Hash2String(randomBytes)
+	assert.Nil(t, err)
+	randomBytesReader := bytes.NewReader(randomBytes)
+	_, err = models.CreateFileFromReader(&token.App, "/save/to/random.bytes", randomBytesReader, int8(0), testingChunkRootPath, trx)
+	assert.Nil(t, err)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("%s?token=%s&filePath=%s", api, token.UID, "/to/random.bytes"), nil)
+	Routers().ServeHTTP(w, req)
+	responseBodyHash, err := util.Sha256Hash2String(w.Body.Bytes())
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, err)
+	assert.Equal(t, responseBodyHash, randomBytesHash)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("%s?token=%s&filePath=%s", api, token.UID, "/random.bytes"), nil)
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.Equal(t, "record not found", response.Errors["filePath"][0])
+}
+
+func TestFileReadHandler7(t *testing.T) {
+	var (
+		api     = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/file/read")
+		trx     *gorm.DB
+		err     error
+		down    func(*testing.T)
+		token   *models.Token
+		tempDir = models.NewTempDirForTest()
+	)
+
+	testingChunkRootPath = &tempDir
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	testDBConn = trx
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	randomBytes := models.Random(models.ChunkSize + 256)
+	file, err := models.CreateFileFromReader(&token.App, "/random.bytes", bytes.NewReader(randomBytes), int8(0), testingChunkRootPath, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, trx.Preload("Object").Find(file).Error)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("%s?token=%s&fileUid=%s", api, token.UID, file.UID), nil)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", models.ChunkSize-10, models.ChunkSize+9))
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, randomBytes[models.ChunkSize-10:models.ChunkSize+10], w.Body.Bytes())
+	assert.Equal(t, fmt.Sprintf("bytes %d-%d/%d", models.ChunkSize-10, models.ChunkSize+9, len(randomBytes)), w.Header().Get("Content-Range"))
+	assert.Equal(t, fmt.Sprintf(`"%s"`, file.Object.Hash), w.Header().Get("ETag"))
+
+	w = httptest.NewRecorder()
+	req.Header.Set("If-Range", fmt.Sprintf(`"%s"`, file.Object.Hash))
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req.Header.Set("If-Range", `"mismatched"`)
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, len(randomBytes), w.Body.Len())
+
+	w = httptest.NewRecorder()
+	req.Header.Del("If-Range")
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", len(randomBytes)+1))
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+}
+
 func BenchmarkFileReadHandler(b *testing.B) {
 	b.StopTimer()
 
@@ -242,3 +342,18 @@ func BenchmarkFileReadHandler(b *testing.B) {
 		}
 	}
 }
+
+// TestFileReadHandler8 is used to test that metas are sent as headers
+func TestFileReadHandler8(t *testing.T) {
+	ctx, down := newFileReadForTest(t)
+	defer down(t)
+	db := ctx.MustGet("db").(*gorm.DB)
+	file, err := models.FindFileByUID(ctx.MustGet("inputParam").(*fileReadInput).FileUID, false, db)
+	assert.Nil(t, err)
+	assert.Nil(t, file.SetMetas(map[string]string{"Owner-Id": "1", "desc": "random bytes"}, false, db))
+
+	FileReadHandler(ctx)
+	assert.Equal(t, 200, ctx.Writer.Status())
+	assert.Equal(t, "1", ctx.Writer.Header().Get("X-Bigfile-Meta-Owner-Id"))
+	assert.Equal(t, "random bytes", ctx.Writer.Header().Get("X-Bigfile-Meta-Desc"))
+}
@@ -0,0 +1,122 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type fileSearchInput struct {
+	Token         string     `form:"token" binding:"required"`
+	Nonce         *string    `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
+	Sign          *string    `form:"sign" binding:"omitempty"`
+	Path          string     `form:"path,default=/" binding:"omitempty,max=1000"`
+	Name          string     `form:"name" binding:"omitempty,max=255"`
+	Ext           *string    `form:"ext" binding:"omitempty,max=255"`
+	MinSize       *int       `form:"minSize" binding:"omitempty,min=0"`
+	MaxSize       *int       `form:"maxSize" binding:"omitempty,min=0"`
+	CreatedAfter  *time.Time `form:"createdAfter" time_format:"unix" binding:"omitempty"`
+	CreatedBefore *time.Time `form:"createdBefore" time_format:"unix" binding:"omitempty"`
+	UpdatedAfter  *time.Time `form:"updatedAfter" time_format:"unix" binding:"omitempty"`
+	UpdatedBefore *time.Time `form:"updatedBefore" time_format:"unix" binding:"omitempty"`
+	Hidden        *bool      `form:"hidden" binding:"omitempty"`
+	Hash          *string    `form:"hash" binding:"omitempty,len=64"`
+	Sort          string     `form:"sort,default=name" binding:"omitempty"`
+	Offset        *int       `form:"offset,default=0" binding:"omitempty,min=0"`
+	Limit         *int       `form:"limit,default=20" binding:"omitempty,min=1,max=100"`
+	// metas are sent as meta[name]=value, they are read by QueryMap
+}
+
+// FileSearchHandler is used to search the files under a directory
+func FileSearchHandler(ctx *gin.Context) {
+	var (
+		ip                 = ctx.ClientIP()
+		db                 = ctx.MustGet("db").(*gorm.DB)
+		err                error
+		token              = ctx.MustGet("token").(*models.Token)
+		input              = ctx.MustGet("inputParam").(*fileSearchInput)
+		fileSearchSrv      *service.FileSearch
+		fileSearchSrvValue interface{}
+		fileSearchValue    *service.FileSearchValue
+
+		code     = 400
+		reErrors map[string][]string
+		success  bool
+		data     interface{}
+	)
+
+	defer func() {
+		ctx.JSON(code, &Response{
+			RequestID: ctx.GetInt64("requestId"),
+			Success:   success,
+			Errors:    reErrors,
+			Data:      data,
+		})
+	}()
+
+	fileSearchSrv = &service.FileSearch{
+		BaseService: service.BaseService{
+			DB: db,
+		},
+		Token:         token,
+		IP:            &ip,
+		Path:          input.Path,
+		Name:          input.Name,
+		Ext:           input.Ext,
+		MinSize:       input.MinSize,
+		MaxSize:       input.MaxSize,
+		CreatedAfter:  input.CreatedAfter,
+		CreatedBefore: input.CreatedBefore,
+		UpdatedAfter:  input.UpdatedAfter,
+		UpdatedBefore: input.UpdatedBefore,
+		Hash:          input.Hash,
+		Metas:         ctx.QueryMap("meta"),
+		Sort:          input.Sort,
+		Offset:        *input.Offset,
+		Limit:         *input.Limit,
+	}
+
+	if input.Hidden != nil {
+		var hidden int8
+		if *input.Hidden {
+			hidden = 1
+		}
+		fileSearchSrv.Hidden = &hidden
+	}
+
+	if err = fileSearchSrv.Validate(); !reflect.ValueOf(err).IsNil() {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if fileSearchSrvValue, err = fileSearchSrv.Execute(context.Background()); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	fileSearchValue = fileSearchSrvValue.(*service.FileSearchValue)
+	items := make([]map[string]interface{}, len(fileSearchValue.Files))
+	for index := range fileSearchValue.Files {
+		file := &fileSearchValue.Files[index]
+		if items[index], err = fileRespWithPath(file, file.FullPath, db); err != nil {
+			reErrors = generateErrors(err, "")
+			return
+		}
+	}
+
+	data = map[string]interface{}{
+		"total": fileSearchValue.Total,
+		"items": items,
+	}
+	code = 200
+	success = true
+}
@@ -0,0 +1,121 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSearchHandler(t *testing.T) {
+	var (
+		ctx    *gin.Context
+		offset = 0
+		limit  = 20
+	)
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer down(t)
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	writer := &bodyWriter{ResponseWriter: ctx.Writer, body: bytes.NewBufferString("")}
+	ctx.Writer = writer
+	ctx.Request, _ = http.NewRequest("GET", "http://bigfile.io", strings.NewReader(""))
+	ctx.Set("db", trx)
+	ctx.Set("token", token)
+	ctx.Set("requestId", int64(1))
+	ctx.Set("inputParam", &fileSearchInput{
+		Path:   "/not/exist",
+		Sort:   "name",
+		Offset: &offset,
+		Limit:  &limit,
+	})
+
+	FileSearchHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, "record not found", response.Errors["system"][0])
+}
+
+func TestFileSearchHandler2(t *testing.T) {
+	var (
+		w       = httptest.NewRecorder()
+		api     = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/file/search")
+		trx     *gorm.DB
+		err     error
+		down    func(*testing.T)
+		file    *models.File
+		token   *models.Token
+		secret  = models.RandomWithMd5(222)
+		tempDir = models.NewTempDirForTest()
+	)
+
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	token.Secret = &secret
+	assert.Nil(t, trx.Save(token).Error)
+	testDBConn = trx
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	for _, path := range []string{"/search/a/report.pdf", "/search/b/report.txt", "/search/report.jpg"} {
+		file, err = models.CreateFileFromReader(&token.App, path, bytes.NewReader(models.Random(64)), int8(0), &tempDir, trx)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, file.SetMetas(map[string]string{"owner": "1"}, false, trx))
+
+	qs := getParamsSignBody(map[string]interface{}{
+		"token": token.UID,
+		"nonce": models.RandomWithMd5(333),
+		"path":  "/search",
+		"name":  "report.p*",
+		"sort":  "-name",
+	}, secret)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("%s?%s", api, qs), nil)
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, float64(1), responseData["total"].(float64))
+	items := responseData["items"].([]interface{})
+	assert.Equal(t, "/search/a/report.pdf", items[0].(map[string]interface{})["path"].(string))
+
+	w = httptest.NewRecorder()
+	qs = getParamsSignBody(map[string]interface{}{
+		"token":       token.UID,
+		"nonce":       models.RandomWithMd5(333),
+		"meta[owner]": "1",
+	}, secret)
+	req, _ = http.NewRequest("GET", fmt.Sprintf("%s?%s", api, qs), nil)
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response, err = parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData = response.Data.(map[string]interface{})
+	assert.Equal(t, float64(1), responseData["total"].(float64))
+	items = responseData["items"].([]interface{})
+	assert.Equal(t, "/search/report.jpg", items[0].(map[string]interface{})["path"].(string))
+	assert.Equal(t, "1", items[0].(map[string]interface{})["metas"].(map[string]interface{})["owner"].(string))
+}
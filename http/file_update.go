@@ -15,12 +15,15 @@ import (
 )
 
 type fileUpdateInput struct {
-	Token   string  `form:"token" binding:"required"`
-	FileUID string  `form:"fileUid" binding:"required"`
-	Nonce   string  `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
-	Sign    *string `form:"sign" binding:"omitempty"`
-	Hidden  *int8   `form:"hidden" binding:"omitempty"`
-	Path    *string `form:"path" binding:"required,max=1000"`
+	Token    string  `form:"token" binding:"required"`
+	FileUID  string  `form:"fileUid" binding:"omitempty"`
+	FilePath *string `form:"filePath" binding:"omitempty,max=1000"`
+	Nonce    string  `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
+	Sign     *string `form:"sign" binding:"omitempty"`
+	Hidden   *int8   `form:"hidden" binding:"omitempty"`
+	Path     *string `form:"path" binding:"omitempty,max=1000"`
+	// metas are sent as meta[name]=value, they are read by PostFormMap
+	ReplaceMetas *bool `form:"replaceMetas,default=0" binding:"omitempty"`
 }
 
 // FileUpdateHandler is used to handle file update request
@@ -30,6 +33,7 @@ func FileUpdateHandler(ctx *gin.Context) {
 		db                 = ctx.MustGet("db").(*gorm.DB)
 		err                error
 		file               *models.File
+		errKey             string
 		token              = ctx.MustGet("token").(*models.Token)
 		input              = ctx.MustGet("inputParam").(*fileUpdateInput)
 		fileUpdateSrv      *service.FileUpdate
@@ -50,8 +54,8 @@ func FileUpdateHandler(ctx *gin.Context) {
 		})
 	}()
 
-	if file, err = models.FindFileByUID(input.FileUID, false, db); err != nil {
-		reErrors = generateErrors(err, "fileUid")
+	if file, errKey, err = findFileByUIDOrPath(input.FileUID, input.FilePath, token, db); err != nil {
+		reErrors = generateErrors(err, errKey)
 		return
 	}
 
@@ -64,6 +68,11 @@ func FileUpdateHandler(ctx *gin.Context) {
 		IP:     &ip,
 		Hidden: input.Hidden,
 		Path:   input.Path,
+		Metas:  ctx.PostFormMap("meta"),
+	}
+
+	if input.ReplaceMetas != nil && *input.ReplaceMetas {
+		fileUpdateSrv.ReplaceMetas = 1
 	}
 
 	if isTesting {
@@ -117,6 +117,24 @@ func TestFileUpdateHandler3(t *testing.T) {
 	assert.Equal(t, ctx.GetString("path"), responseData["path"].(string))
 }
 
+// TestFileUpdateHandler5 is used to update metas without moving file
+func TestFileUpdateHandler5(t *testing.T) {
+	ctx, down := newFileUpdateForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+	ctx.MustGet("inputParam").(*fileUpdateInput).Path = nil
+	ctx.Request, _ = http.NewRequest("PATCH", "http://bigfile.io", strings.NewReader("meta[owner-id]=1&meta[desc]=random"))
+	ctx.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	FileUpdateHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, "/test/random.bytes", responseData["path"].(string))
+	assert.Equal(t, map[string]interface{}{"owner-id": "1", "desc": "random"}, responseData["metas"])
+}
+
 func TestFileUpdateHandler4(t *testing.T) {
 	var (
 		w       = httptest.NewRecorder()
@@ -0,0 +1,94 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type historyListInput struct {
+	Token    string  `form:"token" binding:"required"`
+	FileUID  string  `form:"fileUid" binding:"omitempty"`
+	FilePath *string `form:"filePath" binding:"omitempty,max=1000"`
+	Nonce    *string `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
+	Sign     *string `form:"sign" binding:"omitempty"`
+	Offset   *int    `form:"offset,default=0" binding:"omitempty,min=0"`
+	Limit    *int    `form:"limit,default=20" binding:"omitempty,min=1,max=100"`
+}
+
+// HistoryListHandler is used to list the histories of file
+func HistoryListHandler(ctx *gin.Context) {
+	var (
+		ip                  = ctx.ClientIP()
+		db                  = ctx.MustGet("db").(*gorm.DB)
+		err                 error
+		file                *models.File
+		errKey              string
+		token               = ctx.MustGet("token").(*models.Token)
+		input               = ctx.MustGet("inputParam").(*historyListInput)
+		historyListSrv      *service.HistoryList
+		historyListSrvValue interface{}
+		historyListValue    *service.HistoryListValue
+
+		code     = 400
+		reErrors map[string][]string
+		success  bool
+		data     interface{}
+	)
+
+	defer func() {
+		ctx.JSON(code, &Response{
+			RequestID: ctx.GetInt64("requestId"),
+			Success:   success,
+			Errors:    reErrors,
+			Data:      data,
+		})
+	}()
+
+	if file, errKey, err = findFileByUIDOrPath(input.FileUID, input.FilePath, token, db); err != nil {
+		reErrors = generateErrors(err, errKey)
+		return
+	}
+
+	historyListSrv = &service.HistoryList{
+		BaseService: service.BaseService{
+			DB: db,
+		},
+		Token:  token,
+		File:   file,
+		IP:     &ip,
+		Offset: *input.Offset,
+		Limit:  *input.Limit,
+	}
+
+	if err = historyListSrv.Validate(); !reflect.ValueOf(err).IsNil() {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if historyListSrvValue, err = historyListSrv.Execute(context.Background()); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	historyListValue = historyListSrvValue.(*service.HistoryListValue)
+	items := make([]map[string]interface{}, len(historyListValue.Histories))
+	for index := range historyListValue.Histories {
+		items[index] = historyResp(&historyListValue.Histories[index])
+	}
+
+	data = map[string]interface{}{
+		"total": historyListValue.Total,
+		"items": items,
+	}
+	code = 200
+	success = true
+}
@@ -0,0 +1,105 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFileWithHistoriesForTest create a file whose content is overwritten by contents
+// one by one, the token that is returned has a secret.
+func newFileWithHistoriesForTest(t *testing.T, contents ...[]byte) (*models.Token, *models.File, *gorm.DB, func(*testing.T)) {
+	var (
+		secret  = models.RandomWithMd5(222)
+		tempDir = models.NewTempDirForTest()
+	)
+
+	testingChunkRootPath = &tempDir
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	token.Secret = &secret
+	assert.Nil(t, trx.Save(token).Error)
+	testDBConn = trx
+
+	file, err := models.CreateFileFromReader(&token.App, "/history/random.bytes", bytes.NewReader(contents[0]), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	for _, content := range contents[1:] {
+		assert.Nil(t, file.OverWriteFromReader(bytes.NewReader(content), int8(0), &tempDir, trx))
+	}
+
+	return token, file, trx, func(t *testing.T) {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}
+}
+
+func TestHistoryListHandler(t *testing.T) {
+	var (
+		w   = httptest.NewRecorder()
+		api = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/history/list")
+	)
+	token, _, _, down := newFileWithHistoriesForTest(t, models.Random(10))
+	defer down(t)
+
+	qs := getParamsSignBody(map[string]interface{}{
+		"token":    token.UID,
+		"filePath": "/history/not-exist.bytes",
+		"nonce":    models.RandomWithMd5(333),
+	}, *token.Secret)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("%s?%s", api, qs), nil)
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, "record not found", response.Errors["filePath"][0])
+}
+
+func TestHistoryListHandler2(t *testing.T) {
+	var (
+		w   = httptest.NewRecorder()
+		api = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/history/list")
+	)
+	token, file, _, down := newFileWithHistoriesForTest(t, models.Random(10), models.Random(20), models.Random(30))
+	defer down(t)
+
+	qs := getParamsSignBody(map[string]interface{}{
+		"token":   token.UID,
+		"fileUid": file.UID,
+		"limit":   1,
+		"nonce":   models.RandomWithMd5(333),
+	}, *token.Secret)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("%s?%s", api, qs), nil)
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	data := response.Data.(map[string]interface{})
+	assert.Equal(t, float64(2), data["total"])
+	items := data["items"].([]interface{})
+	assert.Equal(t, 1, len(items))
+	item := items[0].(map[string]interface{})
+	assert.Equal(t, float64(20), item["size"])
+	assert.Equal(t, "/history/random.bytes", item["path"])
+	assert.NotEmpty(t, item["hash"])
+	assert.NotEmpty(t, item["historyId"])
+	assert.NotEmpty(t, item["createdAt"])
+}
@@ -0,0 +1,115 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"reflect"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type historyReadInput struct {
+	Token         string  `form:"token" binding:"required"`
+	FileUID       string  `form:"fileUid" binding:"omitempty"`
+	FilePath      *string `form:"filePath" binding:"omitempty,max=1000"`
+	HistoryID     uint64  `form:"historyId" binding:"required"`
+	Nonce         *string `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
+	Sign          *string `form:"sign" binding:"omitempty"`
+	OpenInBrowser bool    `form:"openInBrowser,default=0" binding:"omitempty"`
+}
+
+// HistoryReadHandler is used to download the content of a previous version of file
+func HistoryReadHandler(ctx *gin.Context) {
+	var (
+		ip                  = ctx.ClientIP()
+		db                  = ctx.MustGet("db").(*gorm.DB)
+		err                 error
+		file                *models.File
+		history             *models.History
+		errKey              string
+		token               = ctx.MustGet("token").(*models.Token)
+		input               = ctx.MustGet("inputParam").(*historyReadInput)
+		requestID           = ctx.GetInt64("requestId")
+		historyReadSrv      *service.HistoryRead
+		historyReadSrvValue interface{}
+		historyReader       models.ObjectReader
+		name                string
+	)
+
+	if file, errKey, err = findFileByUIDOrPath(input.FileUID, input.FilePath, token, db); err == nil {
+		if history, err = file.FindHistory(input.HistoryID, db); err != nil {
+			errKey = "historyId"
+		}
+	}
+	if err != nil {
+		ctx.JSON(400, &Response{
+			RequestID: requestID,
+			Success:   false,
+			Errors:    generateErrors(err, errKey),
+		})
+		return
+	}
+
+	historyReadSrv = &service.HistoryRead{
+		BaseService: service.BaseService{
+			DB: db,
+		},
+		Token:   token,
+		File:    file,
+		History: history,
+		IP:      &ip,
+	}
+
+	if isTesting {
+		historyReadSrv.RootPath = testingChunkRootPath
+	}
+
+	if err = historyReadSrv.Validate(); !reflect.ValueOf(err).IsNil() {
+		ctx.JSON(400, &Response{
+			RequestID: requestID,
+			Success:   false,
+			Errors:    generateErrors(err, ""),
+		})
+		return
+	}
+
+	if historyReadSrvValue, err = historyReadSrv.Execute(context.Background()); err != nil {
+		ctx.JSON(400, &Response{
+			RequestID: requestID,
+			Success:   false,
+			Errors:    generateErrors(err, ""),
+		})
+		return
+	}
+
+	historyReader = historyReadSrvValue.(models.ObjectReader)
+	defer historyReader.Close()
+
+	name = path.Base(history.Path)
+	ctx.Header("Content-Type", "application/octet-stream")
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		ctx.Header("Content-Type", contentType)
+	}
+
+	// ETag must be quoted, otherwise If-Range can't be matched
+	ctx.Header("ETag", fmt.Sprintf(`"%s"`, history.Object.Hash))
+
+	if input.OpenInBrowser {
+		ctx.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, name))
+	} else {
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	}
+
+	ctx.Set("ignoreRespBody", true)
+	http.ServeContent(ctx.Writer, ctx.Request, name, history.CreatedAt, historyReader)
+}
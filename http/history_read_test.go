@@ -0,0 +1,61 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryReadHandler(t *testing.T) {
+	var (
+		w        = httptest.NewRecorder()
+		api      = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/history/read")
+		content  = models.Random(128)
+		content2 = models.Random(10)
+	)
+	token, file, trx, down := newFileWithHistoriesForTest(t, content, content2)
+	defer down(t)
+	other, err := models.CreateFileFromReader(&token.App, "/history/other.bytes", bytes.NewReader(content2), int8(0), testingChunkRootPath, trx)
+	assert.Nil(t, err)
+	_, histories, err := file.FindHistories(0, 1, trx)
+	assert.Nil(t, err)
+
+	// history of another file can't be read
+	qs := getParamsSignBody(map[string]interface{}{
+		"token":     token.UID,
+		"fileUid":   other.UID,
+		"historyId": histories[0].ID,
+		"nonce":     models.RandomWithMd5(333),
+	}, *token.Secret)
+	req, _ := http.NewRequest("GET", fmt.Sprintf("%s?%s", api, qs), nil)
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.Equal(t, "record not found", response.Errors["historyId"][0])
+
+	w = httptest.NewRecorder()
+	qs = getParamsSignBody(map[string]interface{}{
+		"token":     token.UID,
+		"fileUid":   file.UID,
+		"historyId": histories[0].ID,
+		"nonce":     models.RandomWithMd5(333),
+	}, *token.Secret)
+	req, _ = http.NewRequest("GET", fmt.Sprintf("%s?%s", api, qs), nil)
+	req.Header.Set("Range", "bytes=0-9")
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, content[:10], w.Body.Bytes())
+	assert.Equal(t, fmt.Sprintf(`"%s"`, histories[0].Object.Hash), w.Header().Get("ETag"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "random.bytes")
+}
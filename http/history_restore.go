@@ -0,0 +1,92 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type historyRestoreInput struct {
+	Token     string  `form:"token" binding:"required"`
+	FileUID   string  `form:"fileUid" binding:"omitempty"`
+	FilePath  *string `form:"filePath" binding:"omitempty,max=1000"`
+	HistoryID uint64  `form:"historyId" binding:"required"`
+	Nonce     *string `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
+	Sign      *string `form:"sign" binding:"omitempty"`
+}
+
+// HistoryRestoreHandler is used to restore file to a previous version
+func HistoryRestoreHandler(ctx *gin.Context) {
+	var (
+		ip                     = ctx.ClientIP()
+		db                     = ctx.MustGet("db").(*gorm.DB)
+		err                    error
+		file                   *models.File
+		history                *models.History
+		errKey                 string
+		token                  = ctx.MustGet("token").(*models.Token)
+		input                  = ctx.MustGet("inputParam").(*historyRestoreInput)
+		historyRestoreSrv      *service.HistoryRestore
+		historyRestoreSrvValue interface{}
+
+		code     = 400
+		reErrors map[string][]string
+		success  bool
+		data     interface{}
+	)
+
+	defer func() {
+		ctx.JSON(code, &Response{
+			RequestID: ctx.GetInt64("requestId"),
+			Success:   success,
+			Errors:    reErrors,
+			Data:      data,
+		})
+	}()
+
+	if file, errKey, err = findFileByUIDOrPath(input.FileUID, input.FilePath, token, db); err != nil {
+		reErrors = generateErrors(err, errKey)
+		return
+	}
+
+	if history, err = file.FindHistory(input.HistoryID, db); err != nil {
+		reErrors = generateErrors(err, "historyId")
+		return
+	}
+
+	historyRestoreSrv = &service.HistoryRestore{
+		BaseService: service.BaseService{
+			DB: db,
+		},
+		Token:   token,
+		File:    file,
+		History: history,
+		IP:      &ip,
+	}
+
+	if err = historyRestoreSrv.Validate(); !reflect.ValueOf(err).IsNil() {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if historyRestoreSrvValue, err = historyRestoreSrv.Execute(context.Background()); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if data, err = fileResp(historyRestoreSrvValue.(*models.File), db); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	code = 200
+	success = true
+}
@@ -0,0 +1,72 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryRestoreHandler(t *testing.T) {
+	var (
+		w   = httptest.NewRecorder()
+		api = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/history/restore")
+	)
+	token, file, _, down := newFileWithHistoriesForTest(t, models.Random(10))
+	defer down(t)
+
+	body := getParamsSignBody(map[string]interface{}{
+		"token":     token.UID,
+		"fileUid":   file.UID,
+		"historyId": 1 << 40,
+		"nonce":     models.RandomWithMd5(333),
+	}, *token.Secret)
+	req, _ := http.NewRequest("PATCH", api, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, "record not found", response.Errors["historyId"][0])
+}
+
+func TestHistoryRestoreHandler2(t *testing.T) {
+	var (
+		w   = httptest.NewRecorder()
+		api = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/history/restore")
+	)
+	token, file, trx, down := newFileWithHistoriesForTest(t, models.Random(10), models.Random(20))
+	defer down(t)
+	_, histories, err := file.FindHistories(0, 1, trx)
+	assert.Nil(t, err)
+
+	body := getParamsSignBody(map[string]interface{}{
+		"token":     token.UID,
+		"fileUid":   file.UID,
+		"historyId": histories[0].ID,
+		"nonce":     models.RandomWithMd5(333),
+	}, *token.Secret)
+	req, _ := http.NewRequest("PATCH", api, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	data := response.Data.(map[string]interface{})
+	assert.Equal(t, float64(10), data["size"])
+	assert.Equal(t, histories[0].Object.Hash, data["hash"])
+
+	total, _, err := file.FindHistories(0, 10, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, total)
+}
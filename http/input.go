@@ -4,6 +4,14 @@
 
 package http
 
+import (
+	"net/http"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/jinzhu/gorm"
+)
+
 // AppUIDInput represent 'AppUid' request param
 type AppUIDInput struct {
 	AppUID string `form:"appUid" binding:"required"`
@@ -18,3 +26,40 @@ type NonceInput struct {
 type TokenInput struct {
 	Token string `form:"token" binding:"required"`
 }
+
+// findFileByUIDOrPath is used to find the file that request param point to. fileUid
+// takes precedence over filePath, filePath is relative to the path of token. The
+// name of the request param that cause error is also returned.
+func findFileByUIDOrPath(fileUID string, filePath *string, token *models.Token, db *gorm.DB) (*models.File, string, error) {
+	var (
+		err  error
+		file *models.File
+	)
+	if fileUID == "" && filePath != nil {
+		if file, err = models.FindFileByPath(&token.App, token.PathWithScope(*filePath), db); err != nil {
+			return nil, "filePath", err
+		}
+		return file, "", nil
+	}
+	if file, err = models.FindFileByUID(fileUID, false, db); err != nil {
+		return nil, "fileUid", err
+	}
+	return file, "", nil
+}
+
+// findUploadSession is used to find the upload session that request param point to.
+// The http status code that represent the error is also returned, 404 if the session
+// doesn't exist, 410 if the session has expired.
+func findUploadSession(uploadID string, db *gorm.DB) (*models.UploadSession, int, error) {
+	session, err := models.FindUploadSessionByUID(uploadID, db)
+	if err != nil {
+		if util.IsRecordNotFound(err) {
+			return nil, http.StatusNotFound, err
+		}
+		return nil, http.StatusBadRequest, err
+	}
+	if session.Expired() {
+		return nil, http.StatusGone, models.ErrUploadSessionExpired
+	}
+	return session, http.StatusOK, nil
+}
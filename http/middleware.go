@@ -8,7 +8,13 @@ import (
 	"bytes"
 	"crypto/md5"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/url"
 	"sort"
 	"time"
 
@@ -22,10 +28,20 @@ import (
 	"golang.org/x/time/rate"
 )
 
+// maxMultipartFieldSize is the max size of a field of multipart request
+const maxMultipartFieldSize = 1 << 20
+
 var (
 	isTesting  bool
 	testDBConn *gorm.DB
 	limiterSet = cache.New(5*time.Minute, 10*time.Minute)
+
+	// errMultipartFieldTooLarge represents a field of multipart request exceeds maxMultipartFieldSize
+	errMultipartFieldTooLarge = errors.New("the field of multipart request is too large")
+	// errMultipartUnexpectedFile represents a file part of multipart request isn't named 'file'
+	errMultipartUnexpectedFile = errors.New("the file of multipart request must be named 'file'")
+	// errMultipartPartAfterFile represents some parts of multipart request are sent after the file
+	errMultipartPartAfterFile = errors.New("the fields of multipart request must be sent before the file")
 )
 
 type bodyWriter struct {
@@ -352,3 +368,113 @@ func SignStrWithSecret(paramStr, secret string) string {
 	_, _ = m.Write([]byte(secret))
 	return hex.EncodeToString(m.Sum(nil))
 }
+
+// StreamMultipartMiddleware is used to parse the fields of multipart request
+// without reading the file. The fields in front of the file are set to the form
+// of request, the file is left in the body and saved in context as
+// 'multipartFile', so that it can be streamed by handler rather than be spooled
+// to memory or temporary file. So, the fields must be sent before the file, the
+// parts after the file make the reading of file fail, see multipartFile.
+//
+// For developers, this middleware should be put in front of the middlewares
+// that bind the input of request, and only be used by the routes that upload
+// file.
+func StreamMultipartMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if err := parseMultipartFields(ctx); err != nil {
+			ctx.AbortWithStatusJSON(400, &Response{
+				RequestID: ctx.GetInt64("requestId"),
+				Success:   false,
+				Errors: map[string][]string{
+					"inputParamError": {err.Error()},
+				},
+			})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// multipartFile is the file part of multipart request, the file must be the last
+// part. When the file is read to the end, errMultipartPartAfterFile is returned
+// if there are parts after it, so that the content saved from it is rolled back
+// rather than the fields are ignored silently.
+type multipartFile struct {
+	*multipart.Part
+	reader *multipart.Reader
+	err    error
+}
+
+// Read implements io.Reader
+func (m *multipartFile) Read(p []byte) (int, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	n, err := m.Part.Read(p)
+	if err == io.EOF {
+		if _, err = m.reader.NextPart(); err == nil {
+			err = errMultipartPartAfterFile
+		}
+		m.err = err
+	}
+	return n, err
+}
+
+// parseMultipartFields reads the parts of multipart request until the file,
+// the file part named 'file' is saved in context as 'multipartFile'. It does
+// nothing if the form of request has already been parsed.
+func parseMultipartFields(ctx *gin.Context) error {
+	var (
+		req    = ctx.Request
+		values = url.Values{}
+		reader *multipart.Reader
+		part   *multipart.Part
+		file   *multipartFile
+		err    error
+	)
+
+	if req.MultipartForm != nil {
+		return nil
+	}
+	if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType != "multipart/form-data" {
+		return nil
+	}
+	if err = req.ParseForm(); err != nil {
+		return err
+	}
+	if reader, err = req.MultipartReader(); err != nil {
+		return err
+	}
+
+	for {
+		if part, err = reader.NextPart(); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if part.FileName() != "" {
+			if part.FormName() != "file" {
+				return errMultipartUnexpectedFile
+			}
+			file = &multipartFile{Part: part, reader: reader}
+			break
+		}
+		var value []byte
+		if value, err = ioutil.ReadAll(io.LimitReader(part, maxMultipartFieldSize+1)); err != nil {
+			return err
+		}
+		if len(value) > maxMultipartFieldSize {
+			return errMultipartFieldTooLarge
+		}
+		values.Add(part.FormName(), string(value))
+	}
+
+	for k, v := range values {
+		req.Form[k] = append(req.Form[k], v...)
+		req.PostForm[k] = append(req.PostForm[k], v...)
+	}
+	// the later ParseMultipartForm returns directly because MultipartForm isn't nil
+	req.MultipartForm = &multipart.Form{Value: values, File: make(map[string][]*multipart.FileHeader)}
+	ctx.Set("multipartFile", file)
+	return nil
+}
@@ -9,6 +9,8 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -397,3 +399,93 @@ func TestSignWithTokenMiddleware(t *testing.T) {
 	assert.Equal(t, 0, bw.body.Len())
 	bw.body.Reset()
 }
+
+func TestStreamMultipartMiddleware(t *testing.T) {
+	var (
+		body           = &bytes.Buffer{}
+		formBodyWriter = multipart.NewWriter(body)
+		content        = models.Random(256)
+	)
+	assert.Nil(t, formBodyWriter.WriteField("token", "token"))
+	assert.Nil(t, formBodyWriter.WriteField("meta[desc]", "random"))
+	formFileWriter, err := formBodyWriter.CreateFormFile("file", "random.bytes")
+	assert.Nil(t, err)
+	_, err = formFileWriter.Write(content)
+	assert.Nil(t, err)
+	assert.Nil(t, formBodyWriter.Close())
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request, _ = http.NewRequest("POST", "http://bigfile.io?path=/random.bytes", body)
+	ctx.Request.Header.Set("Content-Type", formBodyWriter.FormDataContentType())
+	StreamMultipartMiddleware()(ctx)
+	assert.False(t, ctx.IsAborted())
+
+	var input struct {
+		Token string `form:"token"`
+	}
+	assert.Nil(t, ctx.ShouldBind(&input))
+	assert.Equal(t, "token", input.Token)
+	assert.Equal(t, "/random.bytes", ctx.Request.FormValue("path"))
+	assert.Equal(t, map[string]string{"desc": "random"}, ctx.PostFormMap("meta"))
+
+	// the file is left in body
+	file := ctx.MustGet("multipartFile").(*multipartFile)
+	assert.Equal(t, "random.bytes", file.FileName())
+	read, err := ioutil.ReadAll(file)
+	assert.Nil(t, err)
+	assert.Equal(t, content, read)
+
+	// the field is too large
+	body.Reset()
+	formBodyWriter = multipart.NewWriter(body)
+	assert.Nil(t, formBodyWriter.WriteField("token", string(models.Random(maxMultipartFieldSize+1))))
+	assert.Nil(t, formBodyWriter.Close())
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request, _ = http.NewRequest("POST", "http://bigfile.io", body)
+	ctx.Request.Header.Set("Content-Type", formBodyWriter.FormDataContentType())
+	StreamMultipartMiddleware()(ctx)
+	assert.True(t, ctx.IsAborted())
+
+	// the file isn't named 'file'
+	body.Reset()
+	formBodyWriter = multipart.NewWriter(body)
+	_, err = formBodyWriter.CreateFormFile("upload", "random.bytes")
+	assert.Nil(t, err)
+	assert.Nil(t, formBodyWriter.Close())
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request, _ = http.NewRequest("POST", "http://bigfile.io", body)
+	ctx.Request.Header.Set("Content-Type", formBodyWriter.FormDataContentType())
+	StreamMultipartMiddleware()(ctx)
+	assert.True(t, ctx.IsAborted())
+}
+
+func TestStreamMultipartMiddleware2(t *testing.T) {
+	var (
+		body           = &bytes.Buffer{}
+		formBodyWriter = multipart.NewWriter(body)
+		content        = models.Random(256)
+	)
+	assert.Nil(t, formBodyWriter.WriteField("token", "token"))
+	formFileWriter, err := formBodyWriter.CreateFormFile("file", "random.bytes")
+	assert.Nil(t, err)
+	_, err = formFileWriter.Write(content)
+	assert.Nil(t, err)
+	assert.Nil(t, formBodyWriter.WriteField("path", "/random.bytes"))
+	assert.Nil(t, formBodyWriter.Close())
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request, _ = http.NewRequest("POST", "http://bigfile.io", body)
+	ctx.Request.Header.Set("Content-Type", formBodyWriter.FormDataContentType())
+	StreamMultipartMiddleware()(ctx)
+	assert.False(t, ctx.IsAborted())
+	assert.Equal(t, "token", ctx.Request.FormValue("token"))
+	assert.Equal(t, "", ctx.Request.FormValue("path"))
+
+	// the field after the file makes the reading of file fail
+	file := ctx.MustGet("multipartFile").(*multipartFile)
+	read, err := ioutil.ReadAll(file)
+	assert.Equal(t, errMultipartPartAfterFile, err)
+	assert.Equal(t, content, read)
+	_, err = file.Read(make([]byte, 1))
+	assert.Equal(t, errMultipartPartAfterFile, err)
+}
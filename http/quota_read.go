@@ -0,0 +1,73 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type quotaReadInput struct {
+	Token string  `form:"token" binding:"required"`
+	Nonce *string `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
+	Sign  *string `form:"sign" binding:"omitempty"`
+}
+
+// QuotaReadHandler is used to read the usage and the limits of the application and the token
+func QuotaReadHandler(ctx *gin.Context) {
+	var (
+		ip                = ctx.ClientIP()
+		db                = ctx.MustGet("db").(*gorm.DB)
+		err               error
+		quotaReadSrv      *service.QuotaRead
+		quotaReadSrvValue interface{}
+		quotaReadValue    *service.QuotaReadValue
+
+		code     = 400
+		reErrors map[string][]string
+		success  bool
+		data     interface{}
+	)
+
+	defer func() {
+		ctx.JSON(code, &Response{
+			RequestID: ctx.GetInt64("requestId"),
+			Success:   success,
+			Errors:    reErrors,
+			Data:      data,
+		})
+	}()
+
+	quotaReadSrv = &service.QuotaRead{
+		BaseService: service.BaseService{
+			DB: db,
+		},
+		Token: ctx.MustGet("token").(*models.Token),
+		IP:    &ip,
+	}
+
+	if err = quotaReadSrv.Validate(); !reflect.ValueOf(err).IsNil() {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if quotaReadSrvValue, err = quotaReadSrv.Execute(context.Background()); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	quotaReadValue = quotaReadSrvValue.(*service.QuotaReadValue)
+	data = map[string]interface{}{
+		"app":   quotaResp(quotaReadValue.App),
+		"token": quotaResp(quotaReadValue.Token),
+	}
+	code = 200
+	success = true
+}
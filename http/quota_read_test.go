@@ -0,0 +1,52 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaReadHandler(t *testing.T) {
+	var (
+		w   = httptest.NewRecorder()
+		api = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/quota/read")
+	)
+	token, _, trx, down := newFileWithHistoriesForTest(t, models.Random(10), models.Random(20))
+	defer down(t)
+	assert.Nil(t, token.SetQuota(1024, 10, trx))
+	assert.Nil(t, token.App.SetQuota(0, 100, trx))
+
+	qs := getParamsSignBody(map[string]interface{}{
+		"token": token.UID,
+		"nonce": models.RandomWithMd5(333),
+	}, *token.Secret)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("%s?%s", api, qs), nil)
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	data := response.Data.(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"maxSize":  float64(0),
+		"maxFiles": float64(100),
+		"size":     float64(20),
+		"files":    float64(1),
+	}, data["app"])
+	assert.Equal(t, map[string]interface{}{
+		"maxSize":  float64(1024),
+		"maxFiles": float64(10),
+		"size":     float64(20),
+		"files":    float64(1),
+	}, data["token"])
+}
@@ -5,11 +5,18 @@
 package http
 
 import (
+	"net/http"
+	"strconv"
+
 	"github.com/bigfile/bigfile/databases/models"
 	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
 )
 
+// tusResumable represent the version of tus protocol that upload sessions follow
+const tusResumable = "1.0.0"
+
 // Response represent http response for client
 type Response struct {
 	RequestID int64               `json:"requestId"`
@@ -54,40 +61,115 @@ func tokenResp(token *models.Token) map[string]interface{} {
 		"expiredAt":      expiredAt,
 		"path":           token.Path,
 		"secret":         token.Secret,
+		"maxSize":        token.MaxSize,
+		"maxFiles":       token.MaxFiles,
 	}
 }
 
 // fileResp is used to generate file json response
 func fileResp(file *models.File, db *gorm.DB) (map[string]interface{}, error) {
+	path, err := file.Path(db)
+	if err != nil {
+		return nil, err
+	}
+	return fileRespWithPath(file, path, db)
+}
+
+// fileRespWithPath is used to generate file json response when the path of file
+// is loaded already, such as the files that are just listed or searched
+func fileRespWithPath(file *models.File, path string, db *gorm.DB) (map[string]interface{}, error) {
 
 	var (
 		err    error
-		path   string
+		metas  map[string]string
 		result map[string]interface{}
 	)
 
-	if path, err = file.Path(db); err != nil {
-		return nil, err
-	}
-
 	if file.Object.ID == 0 {
 		if err = db.Preload("Object").Find(file).Error; err != nil {
 			return nil, err
 		}
 	}
 
+	if metas, err = file.FindMetas(db); err != nil {
+		return nil, err
+	}
+
 	result = map[string]interface{}{
 		"fileUid": file.UID,
 		"path":    path,
 		"size":    file.Size,
 		"isDir":   file.IsDir,
 		"hidden":  file.Hidden,
+		"metas":   metas,
 	}
 
 	if file.IsDir == 0 {
 		result["hash"] = file.Object.Hash
 		result["ext"] = file.Ext
+		result["quarantined"] = file.Quarantined
 	}
 
 	return result, err
 }
+
+// trashResp is used to generate json response for file in trash
+func trashResp(file *models.File, db *gorm.DB) (map[string]interface{}, error) {
+	var (
+		err    error
+		result map[string]interface{}
+	)
+
+	if result, err = fileResp(file, db.Unscoped()); err != nil {
+		return nil, err
+	}
+
+	if file.DeletedAt != nil {
+		result["deletedAt"] = file.DeletedAt.Unix()
+	}
+
+	return result, nil
+}
+
+// historyResp is used to generate json response for history
+func historyResp(history *models.History) map[string]interface{} {
+	return map[string]interface{}{
+		"historyId": history.ID,
+		"path":      history.Path,
+		"size":      history.Object.Size,
+		"hash":      history.Object.Hash,
+		"moved":     history.Moved == 1,
+		"createdAt": history.CreatedAt.Unix(),
+	}
+}
+
+// quotaResp is used to generate json response for quota
+func quotaResp(quota *models.Quota) map[string]interface{} {
+	return map[string]interface{}{
+		"maxSize":  quota.MaxSize,
+		"maxFiles": quota.MaxFiles,
+		"size":     quota.Size,
+		"files":    quota.Files,
+	}
+}
+
+// uploadSessionResp is used to generate json response for upload session
+func uploadSessionResp(session *models.UploadSession) map[string]interface{} {
+	return map[string]interface{}{
+		"uploadId":  session.UID,
+		"path":      session.Path,
+		"size":      session.Size,
+		"offset":    session.Offset,
+		"hidden":    session.Hidden,
+		"expiredAt": session.ExpiredAt.Unix(),
+	}
+}
+
+// setUploadSessionHeaders is used to set the headers of tus protocol that describe
+// the upload session, so that tus clients are able to resume the upload.
+func setUploadSessionHeaders(ctx *gin.Context, session *models.UploadSession) {
+	ctx.Header("Tus-Resumable", tusResumable)
+	ctx.Header("Upload-Offset", strconv.Itoa(session.Offset))
+	ctx.Header("Upload-Length", strconv.Itoa(session.Size))
+	ctx.Header("Upload-Expires", session.ExpiredAt.UTC().Format(http.TimeFormat))
+}
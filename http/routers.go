@@ -51,10 +51,27 @@ func Routers() *gin.Engine {
 	requestWithAppGroup.POST(brw("/token/create"), SignWithAppMiddleware(&tokenCreateInput{}), TokenCreateHandler)
 	requestWithAppGroup.POST(brw("/token/update"), SignWithAppMiddleware(&tokenUpdateInput{}), TokenUpdateHandler)
 
+	// the fields of multipart request are parsed before the token is bound, so that the file is streamed
+	uploadWithTokenGroup := r.Group("", StreamMultipartMiddleware(), ParseTokenMiddleware(), ReplayAttackMiddleware())
+	uploadWithTokenGroup.POST(brw("/file/create"), SignWithTokenMiddleware(&fileCreateInput{}), FileCreateHandler)
+
 	requestWithTokenGroup := r.Group("", ParseTokenMiddleware(), ReplayAttackMiddleware())
-	requestWithTokenGroup.POST(brw("/file/create"), SignWithTokenMiddleware(&fileCreateInput{}), FileCreateHandler)
 	requestWithTokenGroup.GET(brw("/file/read"), SignWithTokenMiddleware(&fileReadInput{}), FileReadHandler)
 	requestWithTokenGroup.PATCH(brw("/file/update"), SignWithTokenMiddleware(&fileUpdateInput{}), FileUpdateHandler)
+	requestWithTokenGroup.DELETE(brw("/file/delete"), SignWithTokenMiddleware(&fileDeleteInput{}), FileDeleteHandler)
+	requestWithTokenGroup.POST(brw("/file/copy"), SignWithTokenMiddleware(&fileCopyInput{}), FileCopyHandler)
+	requestWithTokenGroup.GET(brw("/directory/list"), SignWithTokenMiddleware(&directoryListInput{}), DirectoryListHandler)
+	requestWithTokenGroup.GET(brw("/file/search"), SignWithTokenMiddleware(&fileSearchInput{}), FileSearchHandler)
+	requestWithTokenGroup.GET(brw("/trash/list"), SignWithTokenMiddleware(&trashListInput{}), TrashListHandler)
+	requestWithTokenGroup.PATCH(brw("/trash/restore"), SignWithTokenMiddleware(&trashRestoreInput{}), TrashRestoreHandler)
+	requestWithTokenGroup.DELETE(brw("/trash/purge"), SignWithTokenMiddleware(&trashPurgeInput{}), TrashPurgeHandler)
+	requestWithTokenGroup.POST(brw("/upload/create"), SignWithTokenMiddleware(&uploadCreateInput{}), UploadCreateHandler)
+	requestWithTokenGroup.HEAD(brw("/upload/offset"), SignWithTokenMiddleware(&uploadOffsetInput{}), UploadOffsetHandler)
+	requestWithTokenGroup.PATCH(brw("/upload/append"), SignWithTokenMiddleware(&uploadAppendInput{}), UploadAppendHandler)
+	requestWithTokenGroup.GET(brw("/history/list"), SignWithTokenMiddleware(&historyListInput{}), HistoryListHandler)
+	requestWithTokenGroup.GET(brw("/history/read"), SignWithTokenMiddleware(&historyReadInput{}), HistoryReadHandler)
+	requestWithTokenGroup.PATCH(brw("/history/restore"), SignWithTokenMiddleware(&historyRestoreInput{}), HistoryRestoreHandler)
+	requestWithTokenGroup.GET(brw("/quota/read"), SignWithTokenMiddleware(&quotaReadInput{}), QuotaReadHandler)
 
 	r.Routes()
 	return r
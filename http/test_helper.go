@@ -13,7 +13,7 @@ import (
 )
 
 func assertTokenRespStructure(data interface{}) bool {
-	keys := []string{"availableTimes", "token", "ip", "readOnly", "expiredAt", "path", "secret"}
+	keys := []string{"availableTimes", "token", "ip", "readOnly", "expiredAt", "path", "secret", "maxSize", "maxFiles"}
 	mData := data.(map[string]interface{})
 	for _, k := range keys {
 		if _, ok := mData[k]; !ok {
@@ -25,6 +25,8 @@ type tokenCreateInput struct {
 	Secret         *string    `form:"secret" binding:"omitempty,len=32"`
 	AvailableTimes *int       `form:"availableTimes,default=-1" binding:"omitempty,max=2147483647"`
 	ReadOnly       *bool      `form:"readOnly,default=0"`
+	MaxSize        *int       `form:"maxSize,default=0" binding:"omitempty,min=0"`
+	MaxFiles       *int       `form:"maxFiles,default=0" binding:"omitempty,min=0"`
 }
 
 // TokenCreateHandler is used to handle token create http request
@@ -74,6 +76,13 @@ func TokenCreateHandler(ctx *gin.Context) {
 		AvailableTimes: *input.AvailableTimes,
 	}
 
+	if input.MaxSize != nil {
+		tokenCreateSrv.MaxSize = *input.MaxSize
+	}
+	if input.MaxFiles != nil {
+		tokenCreateSrv.MaxFiles = *input.MaxFiles
+	}
+
 	if err := tokenCreateSrv.Validate(); !reflect.ValueOf(err).IsNil() {
 		reErrors = generateErrors(err, "")
 		return
@@ -26,6 +26,8 @@ type tokenUpdateInput struct {
 	Secret         *string    `form:"secret" binding:"omitempty,len=32"`
 	AvailableTimes *int       `form:"availableTimes" binding:"omitempty,max=2147483647"`
 	ReadOnly       *bool      `form:"readOnly"`
+	MaxSize        *int       `form:"maxSize" binding:"omitempty,min=0"`
+	MaxFiles       *int       `form:"maxFiles" binding:"omitempty,min=0"`
 }
 
 // TokenUpdateHandler is used to handle request for update token
@@ -71,6 +73,8 @@ func TokenUpdateHandler(ctx *gin.Context) {
 		ExpiredAt:      input.ExpiredAt,
 		AvailableTimes: input.AvailableTimes,
 		ReadOnly:       &readOnlyI8,
+		MaxSize:        input.MaxSize,
+		MaxFiles:       input.MaxFiles,
 	}
 
 	if err = tokenUpdateSrv.Validate(); !reflect.ValueOf(err).IsNil() {
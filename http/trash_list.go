@@ -0,0 +1,87 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type trashListInput struct {
+	Token  string  `form:"token" binding:"required"`
+	Nonce  *string `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
+	Sign   *string `form:"sign" binding:"omitempty"`
+	Offset *int    `form:"offset,default=0" binding:"omitempty,min=0"`
+	Limit  *int    `form:"limit,default=20" binding:"omitempty,min=1,max=100"`
+}
+
+// TrashListHandler is used to list files in trash
+func TrashListHandler(ctx *gin.Context) {
+	var (
+		ip                = ctx.ClientIP()
+		db                = ctx.MustGet("db").(*gorm.DB)
+		err               error
+		token             = ctx.MustGet("token").(*models.Token)
+		input             = ctx.MustGet("inputParam").(*trashListInput)
+		trashListSrv      *service.TrashList
+		trashListSrvValue interface{}
+		trashListValue    *service.TrashListValue
+
+		code     = 400
+		reErrors map[string][]string
+		success  bool
+		data     interface{}
+	)
+
+	defer func() {
+		ctx.JSON(code, &Response{
+			RequestID: ctx.GetInt64("requestId"),
+			Success:   success,
+			Errors:    reErrors,
+			Data:      data,
+		})
+	}()
+
+	trashListSrv = &service.TrashList{
+		BaseService: service.BaseService{
+			DB: db,
+		},
+		Token:  token,
+		IP:     &ip,
+		Offset: *input.Offset,
+		Limit:  *input.Limit,
+	}
+
+	if err = trashListSrv.Validate(); !reflect.ValueOf(err).IsNil() {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if trashListSrvValue, err = trashListSrv.Execute(context.Background()); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	trashListValue = trashListSrvValue.(*service.TrashListValue)
+	items := make([]map[string]interface{}, len(trashListValue.Files))
+	for index := range trashListValue.Files {
+		if items[index], err = trashResp(&trashListValue.Files[index], db); err != nil {
+			reErrors = generateErrors(err, "")
+			return
+		}
+	}
+
+	data = map[string]interface{}{
+		"total": trashListValue.Total,
+		"items": items,
+	}
+	code = 200
+	success = true
+}
@@ -0,0 +1,128 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTrashListForTest(t *testing.T) (*gin.Context, func(*testing.T)) {
+	var (
+		ctx    *gin.Context
+		trx    *gorm.DB
+		err    error
+		token  *models.Token
+		down   func(*testing.T)
+		offset = 0
+		limit  = 20
+	)
+
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Writer = &bodyWriter{ResponseWriter: ctx.Writer, body: bytes.NewBufferString("")}
+	ctx.Request, _ = http.NewRequest("GET", "http://bigfile.io", strings.NewReader(""))
+	ctx.Request.Header.Set("X-Forwarded-For", "192.168.0.1")
+	ctx.Set("db", trx)
+	ctx.Set("token", token)
+	reqRecord := models.MustNewRequestWithProtocol("http", trx)
+	ctx.Set("reqRecord", reqRecord)
+	ctx.Set("requestId", int64(reqRecord.ID))
+
+	for _, path := range []string{"/test/a", "/test/b"} {
+		dir, err := models.CreateOrGetLastDirectory(&token.App, path, trx)
+		assert.Nil(t, err)
+		assert.Nil(t, dir.Delete(trx))
+	}
+
+	ctx.Set("inputParam", &trashListInput{
+		Offset: &offset,
+		Limit:  &limit,
+	})
+
+	return ctx, down
+}
+
+func TestTrashListHandler(t *testing.T) {
+	ctx, down := newTrashListForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	input := ctx.MustGet("inputParam").(*trashListInput)
+	limit := 101
+	input.Limit = &limit
+
+	TrashListHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Errors["TrashList.Limit"][0], "limit must be between 1 and 100")
+}
+
+func TestTrashListHandler2(t *testing.T) {
+	ctx, down := newTrashListForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	TrashListHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, float64(2), responseData["total"].(float64))
+	items := responseData["items"].([]interface{})
+	assert.Equal(t, 2, len(items))
+	assert.Equal(t, "/test/b", items[0].(map[string]interface{})["path"].(string))
+	assert.NotNil(t, items[0].(map[string]interface{})["deletedAt"])
+}
+
+func TestTrashListHandler3(t *testing.T) {
+	var (
+		w      = httptest.NewRecorder()
+		api    = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/trash/list")
+		trx    *gorm.DB
+		err    error
+		down   func(*testing.T)
+		token  *models.Token
+		secret = models.RandomWithMd5(222)
+	)
+
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	token.Secret = &secret
+	assert.Nil(t, trx.Save(token).Error)
+	testDBConn = trx
+	defer down(t)
+
+	dir, err := models.CreateOrGetLastDirectory(&token.App, "/save/to", trx)
+	assert.Nil(t, err)
+	assert.Nil(t, dir.Delete(trx))
+
+	qs := getParamsSignBody(map[string]interface{}{
+		"token": token.UID,
+		"nonce": models.RandomWithMd5(333),
+		"limit": 10,
+	}, secret)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("%s?%s", api, qs), nil)
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, float64(1), responseData["total"].(float64))
+}
@@ -0,0 +1,84 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type trashPurgeInput struct {
+	Token   string  `form:"token" binding:"required"`
+	FileUID string  `form:"fileUid" binding:"required"`
+	Nonce   string  `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
+	Sign    *string `form:"sign" binding:"omitempty"`
+}
+
+// TrashPurgeHandler is used to delete file in trash permanently
+func TrashPurgeHandler(ctx *gin.Context) {
+	var (
+		ip            = ctx.ClientIP()
+		db            = ctx.MustGet("db").(*gorm.DB)
+		err           error
+		file          *models.File
+		token         = ctx.MustGet("token").(*models.Token)
+		input         = ctx.MustGet("inputParam").(*trashPurgeInput)
+		trashPurgeSrv *service.TrashPurge
+		fileData      map[string]interface{}
+
+		code     = 400
+		reErrors map[string][]string
+		success  bool
+		data     interface{}
+	)
+
+	defer func() {
+		ctx.JSON(code, &Response{
+			RequestID: ctx.GetInt64("requestId"),
+			Success:   success,
+			Errors:    reErrors,
+			Data:      data,
+		})
+	}()
+
+	if file, err = models.FindFileByUID(input.FileUID, true, db); err != nil {
+		reErrors = generateErrors(err, "fileUid")
+		return
+	}
+
+	trashPurgeSrv = &service.TrashPurge{
+		BaseService: service.BaseService{
+			DB: db,
+		},
+		Token: token,
+		File:  file,
+		IP:    &ip,
+	}
+
+	if err = trashPurgeSrv.Validate(); !reflect.ValueOf(err).IsNil() {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	// the file won't exist after purged, so generate response in advance
+	if fileData, err = trashResp(file, db); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if _, err = trashPurgeSrv.Execute(context.Background()); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	data = fileData
+	code = 200
+	success = true
+}
@@ -0,0 +1,121 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTrashPurgeForTest(t *testing.T) (*gin.Context, func(*testing.T)) {
+	var (
+		ctx   *gin.Context
+		trx   *gorm.DB
+		err   error
+		token *models.Token
+		down  func(*testing.T)
+	)
+
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Writer = &bodyWriter{ResponseWriter: ctx.Writer, body: bytes.NewBufferString("")}
+	ctx.Request, _ = http.NewRequest("DELETE", "http://bigfile.io", strings.NewReader(""))
+	ctx.Request.Header.Set("X-Forwarded-For", "192.168.0.1")
+	ctx.Set("db", trx)
+	ctx.Set("token", token)
+	reqRecord := models.MustNewRequestWithProtocol("http", trx)
+	ctx.Set("reqRecord", reqRecord)
+	ctx.Set("requestId", int64(reqRecord.ID))
+
+	dir, err := models.CreateOrGetLastDirectory(&token.App, "/save/to", trx)
+	assert.Nil(t, err)
+	assert.Nil(t, dir.Delete(trx))
+
+	ctx.Set("inputParam", &trashPurgeInput{
+		FileUID: dir.UID,
+	})
+
+	return ctx, down
+}
+
+func TestTrashPurgeHandler(t *testing.T) {
+	ctx, down := newTrashPurgeForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	input := ctx.MustGet("inputParam").(*trashPurgeInput)
+	input.FileUID = ""
+
+	TrashPurgeHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, "record not found", response.Errors["fileUid"][0])
+}
+
+func TestTrashPurgeHandler2(t *testing.T) {
+	ctx, down := newTrashPurgeForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	TrashPurgeHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, "/save/to", responseData["path"].(string))
+
+	db := ctx.MustGet("db").(*gorm.DB)
+	input := ctx.MustGet("inputParam").(*trashPurgeInput)
+	_, err = models.FindFileByUID(input.FileUID, true, db)
+	assert.NotNil(t, err)
+}
+
+func TestTrashPurgeHandler3(t *testing.T) {
+	var (
+		w      = httptest.NewRecorder()
+		api    = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/trash/purge")
+		trx    *gorm.DB
+		err    error
+		down   func(*testing.T)
+		token  *models.Token
+		secret = models.RandomWithMd5(222)
+	)
+
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	token.Secret = &secret
+	assert.Nil(t, trx.Save(token).Error)
+	testDBConn = trx
+	defer down(t)
+
+	dir, err := models.CreateOrGetLastDirectory(&token.App, "/save/to", trx)
+	assert.Nil(t, err)
+
+	qs := getParamsSignBody(map[string]interface{}{
+		"token":   token.UID,
+		"fileUid": dir.UID,
+		"nonce":   models.RandomWithMd5(333),
+	}, secret)
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("%s?%s", api, qs), nil)
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Errors["TrashPurge.File"][0], models.ErrFileNotInTrash.Error())
+}
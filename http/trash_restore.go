@@ -0,0 +1,84 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type trashRestoreInput struct {
+	Token   string  `form:"token" binding:"required"`
+	FileUID string  `form:"fileUid" binding:"required"`
+	Nonce   string  `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
+	Sign    *string `form:"sign" binding:"omitempty"`
+	Path    *string `form:"path" binding:"omitempty,max=1000"`
+}
+
+// TrashRestoreHandler is used to restore file from trash
+func TrashRestoreHandler(ctx *gin.Context) {
+	var (
+		ip                   = ctx.ClientIP()
+		db                   = ctx.MustGet("db").(*gorm.DB)
+		err                  error
+		file                 *models.File
+		token                = ctx.MustGet("token").(*models.Token)
+		input                = ctx.MustGet("inputParam").(*trashRestoreInput)
+		trashRestoreSrv      *service.TrashRestore
+		trashRestoreSrvValue interface{}
+
+		code     = 400
+		reErrors map[string][]string
+		success  bool
+		data     interface{}
+	)
+
+	defer func() {
+		ctx.JSON(code, &Response{
+			RequestID: ctx.GetInt64("requestId"),
+			Success:   success,
+			Errors:    reErrors,
+			Data:      data,
+		})
+	}()
+
+	if file, err = models.FindFileByUID(input.FileUID, true, db); err != nil {
+		reErrors = generateErrors(err, "fileUid")
+		return
+	}
+
+	trashRestoreSrv = &service.TrashRestore{
+		BaseService: service.BaseService{
+			DB: db,
+		},
+		Token: token,
+		File:  file,
+		IP:    &ip,
+		Path:  input.Path,
+	}
+
+	if err = trashRestoreSrv.Validate(); !reflect.ValueOf(err).IsNil() {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if trashRestoreSrvValue, err = trashRestoreSrv.Execute(context.Background()); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if data, err = fileResp(trashRestoreSrvValue.(*models.File), db); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	code = 200
+	success = true
+}
@@ -0,0 +1,149 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTrashRestoreForTest(t *testing.T) (*gin.Context, func(*testing.T)) {
+	var (
+		ctx     *gin.Context
+		trx     *gorm.DB
+		err     error
+		token   *models.Token
+		down    func(*testing.T)
+		tempDir = models.NewTempDirForTest()
+	)
+
+	testingChunkRootPath = &tempDir
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Writer = &bodyWriter{ResponseWriter: ctx.Writer, body: bytes.NewBufferString("")}
+	ctx.Request, _ = http.NewRequest("PATCH", "http://bigfile.io", strings.NewReader(""))
+	ctx.Request.Header.Set("X-Forwarded-For", "192.168.0.1")
+	ctx.Set("db", trx)
+	ctx.Set("token", token)
+	reqRecord := models.MustNewRequestWithProtocol("http", trx)
+	ctx.Set("reqRecord", reqRecord)
+	ctx.Set("requestId", int64(reqRecord.ID))
+
+	randomBytesReader := bytes.NewReader(models.Random(128))
+	file, err := models.CreateFileFromReader(&token.App, "/test/random.bytes", randomBytesReader, int8(0), testingChunkRootPath, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, file.Delete(trx))
+
+	ctx.Set("inputParam", &trashRestoreInput{
+		FileUID: file.UID,
+	})
+
+	return ctx, func(t *testing.T) {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}
+}
+
+func TestTrashRestoreHandler(t *testing.T) {
+	ctx, down := newTrashRestoreForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	input := ctx.MustGet("inputParam").(*trashRestoreInput)
+	input.FileUID = ""
+
+	TrashRestoreHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, "record not found", response.Errors["fileUid"][0])
+}
+
+func TestTrashRestoreHandler2(t *testing.T) {
+	ctx, down := newTrashRestoreForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	db := ctx.MustGet("db").(*gorm.DB)
+	token := ctx.MustGet("token").(*models.Token)
+	_, err := models.CreateFileFromReader(&token.App, "/test/random.bytes", bytes.NewReader(models.Random(6)), int8(0), testingChunkRootPath, db)
+	assert.Nil(t, err)
+
+	TrashRestoreHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Errors["system"][0], "path has already existed")
+}
+
+func TestTrashRestoreHandler3(t *testing.T) {
+	ctx, down := newTrashRestoreForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	path := "/restore/random.bytes"
+	input := ctx.MustGet("inputParam").(*trashRestoreInput)
+	input.Path = &path
+
+	TrashRestoreHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, path, responseData["path"].(string))
+}
+
+func TestTrashRestoreHandler4(t *testing.T) {
+	var (
+		w      = httptest.NewRecorder()
+		api    = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/trash/restore")
+		trx    *gorm.DB
+		err    error
+		down   func(*testing.T)
+		token  *models.Token
+		secret = models.RandomWithMd5(222)
+	)
+
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	token.Secret = &secret
+	assert.Nil(t, trx.Save(token).Error)
+	testDBConn = trx
+	defer down(t)
+
+	dir, err := models.CreateOrGetLastDirectory(&token.App, "/save/to", trx)
+	assert.Nil(t, err)
+	assert.Nil(t, dir.Delete(trx))
+
+	body := getParamsSignBody(map[string]interface{}{
+		"token":   token.UID,
+		"fileUid": dir.UID,
+		"nonce":   models.RandomWithMd5(333),
+	}, secret)
+
+	req, _ := http.NewRequest("PATCH", api, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, "/save/to", responseData["path"].(string))
+}
@@ -0,0 +1,135 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// uploadContentType is the content type of PATCH request in tus protocol
+const uploadContentType = "application/offset+octet-stream"
+
+var (
+	// errUploadContentType represent that the content type of request is wrong
+	errUploadContentType = errors.New("content type must be " + uploadContentType)
+	// errUploadOffsetHeader represent that the Upload-Offset header is missing or illegal
+	errUploadOffsetHeader = errors.New("the Upload-Offset header must be a non-negative integer")
+	// errUploadExceedSize represent that the content exceeds the size of upload session
+	errUploadExceedSize = errors.New("the content exceeds the size of upload session")
+)
+
+type uploadAppendInput struct {
+	Token    string  `form:"token" binding:"required"`
+	Nonce    string  `form:"nonce" header:"X-Request-Nonce" binding:"required,min=32,max=48"`
+	Sign     *string `form:"sign" binding:"omitempty"`
+	UploadID string  `form:"uploadId" binding:"required"`
+}
+
+// UploadAppendHandler is used to append content to upload session, as PATCH request
+// of tus protocol. The request params are passed by query string, the body is the
+// content, and the offset is passed by the Upload-Offset header. As tus protocol,
+// 204 is responded without body when content is appended, the new offset is
+// returned by the Upload-Offset header. When the last byte arrives, the upload
+// session turns into the file at the path of session, it can be read by path.
+// Errors are still responded in json.
+func UploadAppendHandler(ctx *gin.Context) {
+	var (
+		ip                   = ctx.ClientIP()
+		db                   = ctx.MustGet("db").(*gorm.DB)
+		err                  error
+		token                = ctx.MustGet("token").(*models.Token)
+		input                = ctx.MustGet("inputParam").(*uploadAppendInput)
+		offset               int
+		session              *models.UploadSession
+		uploadAppendSrv      *service.UploadAppend
+		uploadAppendSrvValue interface{}
+
+		code     = 400
+		reErrors map[string][]string
+		success  bool
+		data     interface{}
+	)
+
+	defer func() {
+		ctx.Header("Tus-Resumable", tusResumable)
+		if success {
+			ctx.Status(code)
+			return
+		}
+		ctx.JSON(code, &Response{
+			RequestID: ctx.GetInt64("requestId"),
+			Success:   success,
+			Errors:    reErrors,
+			Data:      data,
+		})
+	}()
+
+	if ctx.ContentType() != uploadContentType {
+		code = http.StatusUnsupportedMediaType
+		reErrors = generateErrors(errUploadContentType, "Content-Type")
+		return
+	}
+
+	if offset, err = strconv.Atoi(ctx.GetHeader("Upload-Offset")); err != nil || offset < 0 {
+		reErrors = generateErrors(errUploadOffsetHeader, "Upload-Offset")
+		return
+	}
+
+	if session, code, err = findUploadSession(input.UploadID, db); err != nil {
+		reErrors = generateErrors(err, "uploadId")
+		return
+	}
+	code = 400
+
+	if ctx.Request.ContentLength > int64(session.Size-offset) {
+		code = http.StatusRequestEntityTooLarge
+		reErrors = generateErrors(errUploadExceedSize, "Content-Length")
+		return
+	}
+
+	uploadAppendSrv = &service.UploadAppend{
+		BaseService: service.BaseService{
+			DB: db,
+		},
+		Token:   token,
+		Session: session,
+		IP:      &ip,
+		Offset:  offset,
+		Reader:  ctx.Request.Body,
+	}
+
+	if isTesting {
+		uploadAppendSrv.RootPath = testingChunkRootPath
+	}
+
+	if err = uploadAppendSrv.Validate(); !reflect.ValueOf(err).IsNil() {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	uploadAppendSrvValue, err = uploadAppendSrv.Execute(context.Background())
+	if uploadAppendSrvValue != nil {
+		setUploadSessionHeaders(ctx, uploadAppendSrvValue.(*service.UploadAppendValue).Session)
+	}
+	if err != nil {
+		if err == models.ErrUploadOffsetMismatch {
+			code = http.StatusConflict
+		}
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	code = http.StatusNoContent
+	success = true
+}
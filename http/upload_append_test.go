@@ -0,0 +1,224 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func newUploadAppendForTest(t *testing.T, content []byte) (*gin.Context, func(*testing.T)) {
+	var (
+		ctx     *gin.Context
+		trx     *gorm.DB
+		err     error
+		token   *models.Token
+		down    func(*testing.T)
+		session *models.UploadSession
+		tempDir = models.NewTempDirForTest()
+	)
+
+	testingChunkRootPath = &tempDir
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Writer = &bodyWriter{ResponseWriter: ctx.Writer, body: bytes.NewBufferString("")}
+	ctx.Set("db", trx)
+	ctx.Set("token", token)
+	reqRecord := models.MustNewRequestWithProtocol("http", trx)
+	ctx.Set("reqRecord", reqRecord)
+	ctx.Set("requestId", int64(reqRecord.ID))
+
+	session, err = models.NewUploadSession(&token.App, "/upload/random.bytes", len(content), int8(0), trx)
+	assert.Nil(t, err)
+
+	ctx.Set("inputParam", &uploadAppendInput{
+		UploadID: session.UID,
+	})
+
+	return ctx, func(t *testing.T) {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}
+}
+
+func setUploadAppendRequestForCtx(ctx *gin.Context, offset int, content []byte) {
+	ctx.Request, _ = http.NewRequest("PATCH", "http://bigfile.io", bytes.NewReader(content))
+	ctx.Request.Header.Set("X-Forwarded-For", "192.168.0.1")
+	ctx.Request.Header.Set("Content-Type", uploadContentType)
+	ctx.Request.Header.Set("Upload-Offset", strconv.Itoa(offset))
+}
+
+func TestUploadAppendHandler(t *testing.T) {
+	content := models.Random(256)
+	ctx, down := newUploadAppendForTest(t, content)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	// content type is wrong
+	setUploadAppendRequestForCtx(ctx, 0, content)
+	ctx.Request.Header.Set("Content-Type", "application/octet-stream")
+	UploadAppendHandler(ctx)
+	assert.Equal(t, http.StatusUnsupportedMediaType, writer.Status())
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, errUploadContentType.Error(), response.Errors["Content-Type"][0])
+	writer.body.Reset()
+
+	// Upload-Offset header is missing
+	setUploadAppendRequestForCtx(ctx, 0, content)
+	ctx.Request.Header.Del("Upload-Offset")
+	UploadAppendHandler(ctx)
+	response, err = parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, errUploadOffsetHeader.Error(), response.Errors["Upload-Offset"][0])
+	writer.body.Reset()
+
+	// content exceeds the size of session
+	setUploadAppendRequestForCtx(ctx, 0, append(content, 'a'))
+	UploadAppendHandler(ctx)
+	response, err = parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, errUploadExceedSize.Error(), response.Errors["Content-Length"][0])
+	writer.body.Reset()
+
+	// upload session doesn't exist
+	input := ctx.MustGet("inputParam").(*uploadAppendInput)
+	uploadID := input.UploadID
+	input.UploadID = "fake upload id"
+	setUploadAppendRequestForCtx(ctx, 0, content)
+	UploadAppendHandler(ctx)
+	response, err = parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, "record not found", response.Errors["uploadId"][0])
+	writer.body.Reset()
+
+	// offset doesn't match
+	input.UploadID = uploadID
+	setUploadAppendRequestForCtx(ctx, 10, content[10:])
+	UploadAppendHandler(ctx)
+	response, err = parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, models.ErrUploadOffsetMismatch.Error(), response.Errors["system"][0])
+	writer.body.Reset()
+}
+
+func TestUploadAppendHandler2(t *testing.T) {
+	content := models.Random(256)
+	ctx, down := newUploadAppendForTest(t, content)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	setUploadAppendRequestForCtx(ctx, 0, content[:100])
+	UploadAppendHandler(ctx)
+	assert.Equal(t, http.StatusNoContent, writer.Status())
+	assert.Equal(t, 0, writer.body.Len())
+	assert.Equal(t, "100", writer.Header().Get("Upload-Offset"))
+	assert.Equal(t, tusResumable, writer.Header().Get("Tus-Resumable"))
+
+	setUploadAppendRequestForCtx(ctx, 100, content[100:])
+	UploadAppendHandler(ctx)
+	assert.Equal(t, http.StatusNoContent, writer.Status())
+	assert.Equal(t, 0, writer.body.Len())
+	assert.Equal(t, "256", writer.Header().Get("Upload-Offset"))
+
+	// the upload session turns into the file at its path
+	file, err := models.FindFileByPath(&ctx.MustGet("token").(*models.Token).App, "/upload/random.bytes", ctx.MustGet("db").(*gorm.DB))
+	assert.Nil(t, err)
+	hash, err := util.Sha256Hash2String(content)
+	assert.Nil(t, err)
+	assert.Nil(t, ctx.MustGet("db").(*gorm.DB).Preload("Object").Find(file).Error)
+	assert.Equal(t, hash, file.Object.Hash)
+	assert.Equal(t, 256, file.Size)
+}
+
+func TestUploadAppendHandler3(t *testing.T) {
+	var (
+		w       *httptest.ResponseRecorder
+		api     = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/upload/append")
+		trx     *gorm.DB
+		err     error
+		down    func(*testing.T)
+		token   *models.Token
+		session *models.UploadSession
+		secret  = models.RandomWithMd5(222)
+		tempDir = models.NewTempDirForTest()
+		content = models.Random(models.ChunkSize + 100)
+	)
+
+	testingChunkRootPath = &tempDir
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	token.Secret = &secret
+	assert.Nil(t, trx.Save(token).Error)
+	testDBConn = trx
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	session, err = models.NewUploadSession(&token.App, "/upload/random.bytes", len(content), int8(0), trx)
+	assert.Nil(t, err)
+
+	patch := func(offset int, p []byte) {
+		qs := getParamsSignBody(map[string]interface{}{
+			"token":    token.UID,
+			"uploadId": session.UID,
+			"nonce":    models.RandomWithMd5(333),
+		}, secret)
+		req, _ := http.NewRequest("PATCH", fmt.Sprintf("%s?%s", api, qs), bytes.NewReader(p))
+		req.Header.Set("Content-Type", uploadContentType)
+		req.Header.Set("Upload-Offset", strconv.Itoa(offset))
+		w = httptest.NewRecorder()
+		Routers().ServeHTTP(w, req)
+		assert.Equal(t, tusResumable, w.Header().Get("Tus-Resumable"))
+	}
+	failed := func() {
+		response, err := parseResponse(w.Body.String())
+		assert.Nil(t, err)
+		assert.False(t, response.Success)
+	}
+
+	patch(0, content[:models.ChunkSize-10])
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, 0, w.Body.Len())
+	assert.Equal(t, strconv.Itoa(models.ChunkSize-10), w.Header().Get("Upload-Offset"))
+
+	patch(0, content)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	failed()
+
+	patch(models.ChunkSize-10, content[models.ChunkSize-10:])
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, strconv.Itoa(len(content)), w.Header().Get("Upload-Offset"))
+	file, err := models.FindFileByPath(&token.App, "/upload/random.bytes", trx)
+	assert.Nil(t, err)
+	assert.Equal(t, len(content), file.Size)
+
+	patch(len(content), []byte{})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	failed()
+}
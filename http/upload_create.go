@@ -0,0 +1,82 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type uploadCreateInput struct {
+	Token  string  `form:"token" binding:"required"`
+	Nonce  string  `form:"nonce" header:"X-Request-Nonce" binding:"required,min=32,max=48"`
+	Sign   *string `form:"sign" binding:"omitempty"`
+	Path   string  `form:"path" binding:"required,max=1000"`
+	Size   *int    `form:"size" binding:"required,min=0"`
+	Hidden *bool   `form:"hidden,default=0" binding:"omitempty"`
+}
+
+// UploadCreateHandler is used to create a resumable upload session
+func UploadCreateHandler(ctx *gin.Context) {
+	var (
+		ip                   = ctx.ClientIP()
+		db                   = ctx.MustGet("db").(*gorm.DB)
+		err                  error
+		token                = ctx.MustGet("token").(*models.Token)
+		input                = ctx.MustGet("inputParam").(*uploadCreateInput)
+		session              *models.UploadSession
+		uploadCreateSrv      *service.UploadCreate
+		uploadCreateSrvValue interface{}
+
+		code     = 400
+		reErrors map[string][]string
+		success  bool
+		data     interface{}
+	)
+
+	defer func() {
+		ctx.JSON(code, &Response{
+			RequestID: ctx.GetInt64("requestId"),
+			Success:   success,
+			Errors:    reErrors,
+			Data:      data,
+		})
+	}()
+
+	uploadCreateSrv = &service.UploadCreate{
+		BaseService: service.BaseService{
+			DB: db,
+		},
+		Token: token,
+		IP:    &ip,
+		Path:  input.Path,
+		Size:  *input.Size,
+	}
+
+	if input.Hidden != nil && *input.Hidden {
+		uploadCreateSrv.Hidden = 1
+	}
+
+	if err = uploadCreateSrv.Validate(); !reflect.ValueOf(err).IsNil() {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if uploadCreateSrvValue, err = uploadCreateSrv.Execute(context.Background()); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	session = uploadCreateSrvValue.(*models.UploadSession)
+	setUploadSessionHeaders(ctx, session)
+	data = uploadSessionResp(session)
+	code = 200
+	success = true
+}
@@ -0,0 +1,130 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func newUploadCreateForTest(t *testing.T) (*gin.Context, func(*testing.T)) {
+	var (
+		ctx   *gin.Context
+		trx   *gorm.DB
+		err   error
+		token *models.Token
+		down  func(*testing.T)
+		size  = 1024
+	)
+
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Writer = &bodyWriter{ResponseWriter: ctx.Writer, body: bytes.NewBufferString("")}
+	ctx.Request, _ = http.NewRequest("POST", "http://bigfile.io", strings.NewReader(""))
+	ctx.Request.Header.Set("X-Forwarded-For", "192.168.0.1")
+	ctx.Set("db", trx)
+	ctx.Set("token", token)
+	reqRecord := models.MustNewRequestWithProtocol("http", trx)
+	ctx.Set("reqRecord", reqRecord)
+	ctx.Set("requestId", int64(reqRecord.ID))
+
+	ctx.Set("inputParam", &uploadCreateInput{
+		Path: "/upload/random.bytes",
+		Size: &size,
+	})
+
+	return ctx, down
+}
+
+func TestUploadCreateHandler(t *testing.T) {
+	ctx, down := newUploadCreateForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	input := ctx.MustGet("inputParam").(*uploadCreateInput)
+	input.Path = "/!!!/random.bytes"
+
+	UploadCreateHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Errors["UploadCreate.Path"][0], "legal unix path")
+}
+
+func TestUploadCreateHandler2(t *testing.T) {
+	ctx, down := newUploadCreateForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	trueValue := true
+	input := ctx.MustGet("inputParam").(*uploadCreateInput)
+	input.Hidden = &trueValue
+
+	UploadCreateHandler(ctx)
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, "/upload/random.bytes", responseData["path"].(string))
+	assert.Equal(t, 1024, int(responseData["size"].(float64)))
+	assert.Equal(t, 0, int(responseData["offset"].(float64)))
+	assert.Equal(t, 1, int(responseData["hidden"].(float64)))
+	assert.Equal(t, tusResumable, writer.Header().Get("Tus-Resumable"))
+	assert.Equal(t, "0", writer.Header().Get("Upload-Offset"))
+	assert.Equal(t, "1024", writer.Header().Get("Upload-Length"))
+
+	db := ctx.MustGet("db").(*gorm.DB)
+	session, err := models.FindUploadSessionByUID(responseData["uploadId"].(string), db)
+	assert.Nil(t, err)
+	assert.Equal(t, int8(1), session.Hidden)
+}
+
+func TestUploadCreateHandler3(t *testing.T) {
+	var (
+		w      = httptest.NewRecorder()
+		api    = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/upload/create")
+		trx    *gorm.DB
+		err    error
+		down   func(*testing.T)
+		token  *models.Token
+		secret = models.RandomWithMd5(222)
+	)
+
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	token.Secret = &secret
+	assert.Nil(t, trx.Save(token).Error)
+	testDBConn = trx
+	defer down(t)
+
+	body := getParamsSignBody(map[string]interface{}{
+		"token": token.UID,
+		"path":  "/upload/random.bytes",
+		"size":  2048,
+		"nonce": models.RandomWithMd5(333),
+	}, secret)
+
+	req, _ := http.NewRequest("POST", api, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response, err := parseResponse(w.Body.String())
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	responseData := response.Data.(map[string]interface{})
+	assert.Equal(t, fmt.Sprintf("%d", 2048), w.Header().Get("Upload-Length"))
+	assert.Equal(t, 2048, int(responseData["size"].(float64)))
+}
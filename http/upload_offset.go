@@ -0,0 +1,83 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/service"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type uploadOffsetInput struct {
+	Token    string  `form:"token" binding:"required"`
+	Nonce    *string `form:"nonce" header:"X-Request-Nonce" binding:"omitempty,min=32,max=48"`
+	Sign     *string `form:"sign" binding:"omitempty"`
+	UploadID string  `form:"uploadId" binding:"required"`
+}
+
+// UploadOffsetHandler is used to get the offset of upload session, the offset
+// is returned by the Upload-Offset header, as HEAD request of tus protocol.
+func UploadOffsetHandler(ctx *gin.Context) {
+	var (
+		ip                   = ctx.ClientIP()
+		db                   = ctx.MustGet("db").(*gorm.DB)
+		err                  error
+		token                = ctx.MustGet("token").(*models.Token)
+		input                = ctx.MustGet("inputParam").(*uploadOffsetInput)
+		session              *models.UploadSession
+		uploadOffsetSrv      *service.UploadOffset
+		uploadOffsetSrvValue interface{}
+
+		code     = 400
+		reErrors map[string][]string
+		success  bool
+		data     interface{}
+	)
+
+	defer func() {
+		ctx.Header("Cache-Control", "no-store")
+		ctx.JSON(code, &Response{
+			RequestID: ctx.GetInt64("requestId"),
+			Success:   success,
+			Errors:    reErrors,
+			Data:      data,
+		})
+	}()
+
+	if session, code, err = findUploadSession(input.UploadID, db); err != nil {
+		reErrors = generateErrors(err, "uploadId")
+		return
+	}
+	code = 400
+
+	uploadOffsetSrv = &service.UploadOffset{
+		BaseService: service.BaseService{
+			DB: db,
+		},
+		Token:   token,
+		Session: session,
+		IP:      &ip,
+	}
+
+	if err = uploadOffsetSrv.Validate(); !reflect.ValueOf(err).IsNil() {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	if uploadOffsetSrvValue, err = uploadOffsetSrv.Execute(context.Background()); err != nil {
+		reErrors = generateErrors(err, "")
+		return
+	}
+
+	session = uploadOffsetSrvValue.(*models.UploadSession)
+	setUploadSessionHeaders(ctx, session)
+	data = uploadSessionResp(session)
+	code = 200
+	success = true
+}
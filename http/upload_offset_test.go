@@ -0,0 +1,124 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bigfile/bigfile/config"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func newUploadOffsetForTest(t *testing.T) (*gin.Context, func(*testing.T)) {
+	var (
+		ctx     *gin.Context
+		trx     *gorm.DB
+		err     error
+		token   *models.Token
+		down    func(*testing.T)
+		session *models.UploadSession
+	)
+
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Writer = &bodyWriter{ResponseWriter: ctx.Writer, body: bytes.NewBufferString("")}
+	ctx.Request, _ = http.NewRequest("HEAD", "http://bigfile.io", strings.NewReader(""))
+	ctx.Request.Header.Set("X-Forwarded-For", "192.168.0.1")
+	ctx.Set("db", trx)
+	ctx.Set("token", token)
+	reqRecord := models.MustNewRequestWithProtocol("http", trx)
+	ctx.Set("reqRecord", reqRecord)
+	ctx.Set("requestId", int64(reqRecord.ID))
+
+	session, err = models.NewUploadSession(&token.App, "/upload/random.bytes", 1024, int8(0), trx)
+	assert.Nil(t, err)
+
+	ctx.Set("inputParam", &uploadOffsetInput{
+		UploadID: session.UID,
+	})
+
+	return ctx, down
+}
+
+func TestUploadOffsetHandler(t *testing.T) {
+	ctx, down := newUploadOffsetForTest(t)
+	defer down(t)
+	writer := ctx.Writer.(*bodyWriter)
+
+	input := ctx.MustGet("inputParam").(*uploadOffsetInput)
+	uploadID := input.UploadID
+	input.UploadID = "fake upload id"
+
+	UploadOffsetHandler(ctx)
+	assert.Equal(t, http.StatusNotFound, writer.Status())
+	response, err := parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, "record not found", response.Errors["uploadId"][0])
+
+	// expired
+	db := ctx.MustGet("db").(*gorm.DB)
+	assert.Nil(t, db.Model(&models.UploadSession{}).Where("uid = ?", uploadID).
+		Update("expiredAt", time.Now().Add(-time.Second)).Error)
+	writer.body.Reset()
+	input.UploadID = uploadID
+	UploadOffsetHandler(ctx)
+	response, err = parseResponse(writer.body.String())
+	assert.Nil(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, models.ErrUploadSessionExpired.Error(), response.Errors["uploadId"][0])
+}
+
+func TestUploadOffsetHandler2(t *testing.T) {
+	var (
+		w       = httptest.NewRecorder()
+		api     = buildRoute(config.DefaultConfig.HTTP.APIPrefix, "/upload/offset")
+		trx     *gorm.DB
+		err     error
+		down    func(*testing.T)
+		token   *models.Token
+		session *models.UploadSession
+		secret  = models.RandomWithMd5(222)
+		tempDir = models.NewTempDirForTest()
+	)
+
+	testingChunkRootPath = &tempDir
+	token, trx, down, err = models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	token.Secret = &secret
+	assert.Nil(t, trx.Save(token).Error)
+	testDBConn = trx
+	defer down(t)
+
+	session, err = models.NewUploadSession(&token.App, "/upload/random.bytes", 1024, int8(0), trx)
+	assert.Nil(t, err)
+	_, err = session.AppendFromReader(0, bytes.NewReader(models.Random(100)), &tempDir, trx)
+	assert.Nil(t, err)
+
+	qs := getParamsSignBody(map[string]interface{}{
+		"token":    token.UID,
+		"uploadId": session.UID,
+		"nonce":    models.RandomWithMd5(333),
+	}, secret)
+
+	req, _ := http.NewRequest("HEAD", fmt.Sprintf("%s?%s", api, qs), nil)
+	Routers().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "100", w.Header().Get("Upload-Offset"))
+	assert.Equal(t, "1024", w.Header().Get("Upload-Length"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t, tusResumable, w.Header().Get("Tus-Resumable"))
+	assert.NotEmpty(t, w.Header().Get("Upload-Expires"))
+}
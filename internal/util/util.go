@@ -10,6 +10,7 @@ import (
 	"errors"
 	"os"
 	"reflect"
+	"strings"
 )
 
 // IsDir is used to judge whether the specific path is a valid directory
@@ -86,3 +87,16 @@ func Sha256Hash2String(p []byte) (string, error) {
 func IsRecordNotFound(err error) bool {
 	return err != nil && err.Error() == "record not found"
 }
+
+// IsDuplicateEntry is used to determine that some error is caused by the
+// violation of unique index, the messages of mysql, postgres and sqlite are
+// supported.
+func IsDuplicateEntry(err error) bool {
+	if err == nil {
+		return false
+	}
+	var msg = err.Error()
+	return strings.HasPrefix(msg, "Error 1062") ||
+		strings.Contains(msg, "duplicate key value violates unique constraint") ||
+		strings.Contains(msg, "UNIQUE constraint failed")
+}
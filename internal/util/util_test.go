@@ -93,3 +93,11 @@ func TestIsRecordNotFound(t *testing.T) {
 	assert.False(t, IsRecordNotFound(errors.New("")))
 	assert.True(t, IsRecordNotFound(errors.New("record not found")))
 }
+
+func TestIsDuplicateEntry(t *testing.T) {
+	assert.False(t, IsDuplicateEntry(nil))
+	assert.False(t, IsDuplicateEntry(errors.New("record not found")))
+	assert.True(t, IsDuplicateEntry(errors.New("Error 1062: Duplicate entry 'abc' for key 'hash_UNIQUE'")))
+	assert.True(t, IsDuplicateEntry(errors.New(`pq: duplicate key value violates unique constraint "hash_UNIQUE"`)))
+	assert.True(t, IsDuplicateEntry(errors.New("UNIQUE constraint failed: chunks.hash")))
+}
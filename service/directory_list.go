@@ -0,0 +1,91 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// DirectoryList is used to list the children of directory, the path is
+// relative to the path of token, so only the directories under the scope
+// of token can be listed.
+type DirectoryList struct {
+	BaseService
+
+	Token      *models.Token `validate:"required"`
+	IP         *string       `validate:"omitempty"`
+	Path       string        `validate:"required,max=1000"`
+	Sort       string        `validate:"oneof=name -name size -size createdAt -createdAt updatedAt -updatedAt"`
+	Ext        *string       `validate:"omitempty,max=255"`
+	ShowHidden int8          `validate:"oneof=0 1"`
+	Offset     int           `validate:"min=0"`
+	Limit      int           `validate:"min=1,max=100"`
+}
+
+// DirectoryListValue represent the result of DirectoryList
+type DirectoryListValue struct {
+	Total int
+	Files []models.File
+}
+
+// Validate is used to validate service params
+func (dl *DirectoryList) Validate() ValidateErrors {
+	var (
+		validateErrors ValidateErrors
+		errs           error
+	)
+	if errs = Validate.Struct(dl); errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			validateErrors = append(validateErrors, PreDefinedValidateErrors[err.Namespace()])
+		}
+	}
+
+	if err := ValidateToken(dl.DB, dl.IP, true, dl.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("DirectoryList.Token", err))
+	}
+
+	if !ValidatePath(dl.Path) {
+		validateErrors = append(validateErrors, generateErrorByField("DirectoryList.Path", ErrInvalidPath))
+	}
+
+	return validateErrors
+}
+
+// Execute is used to list the children of directory
+func (dl *DirectoryList) Execute(ctx context.Context) (interface{}, error) {
+	var (
+		err   error
+		dir   *models.File
+		path  = dl.Token.PathWithScope(dl.Path)
+		value = &DirectoryListValue{}
+	)
+
+	dl.BaseService.Before = append(dl.BaseService.After, func(ctx context.Context, service Service) error {
+		d := service.(*DirectoryList)
+		return d.Token.UpdateAvailableTimes(-1, d.DB)
+	})
+
+	if err = dl.CallBefore(ctx, dl); err != nil {
+		return nil, err
+	}
+
+	if dir, err = models.FindFileByPath(&dl.Token.App, path, dl.DB); err != nil {
+		return nil, err
+	}
+
+	if value.Total, value.Files, err = dir.FindChildren(
+		dl.Ext, dl.ShowHidden == 1, dl.Sort, dl.Offset, dl.Limit, dl.DB); err != nil {
+		return nil, err
+	}
+
+	if dl.CallAfter(ctx, dl) != nil {
+		return value, err
+	}
+
+	return value, nil
+}
@@ -0,0 +1,93 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectoryList_Validate(t *testing.T) {
+	trx, down := models.SetUpTestCaseWithTrx(nil, t)
+	defer down(t)
+	directoryListSrv := &DirectoryList{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token:      nil,
+		Path:       "/!!!/",
+		Sort:       "id",
+		ShowHidden: 2,
+		Offset:     -1,
+		Limit:      0,
+	}
+	errValidate := directoryListSrv.Validate()
+	confirm := assert.New(t)
+	confirm.NotNil(errValidate)
+	for _, code := range []int{10039, 10040, 10041, 10043, 10044, 10045} {
+		confirm.True(errValidate.ContainsErrCode(code))
+	}
+}
+
+func TestDirectoryList_Execute(t *testing.T) {
+	tempDir := models.NewTempDirForTest()
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	_, err = models.CreateOrGetLastDirectory(&token.App, "/test/images", trx)
+	assert.Nil(t, err)
+	_, err = models.CreateOrGetLastDirectory(&token.App, "/another", trx)
+	assert.Nil(t, err)
+	_, err = models.CreateFileFromReader(&token.App, "/test/random.bytes", bytes.NewReader(models.Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = models.CreateFileFromReader(&token.App, "/test/hidden.bytes", bytes.NewReader(models.Random(256)), int8(1), &tempDir, trx)
+	assert.Nil(t, err)
+
+	token.Path = "/test"
+	assert.Nil(t, trx.Save(token).Error)
+	directoryListSrv := &DirectoryList{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token: token,
+		Path:  "/",
+		Sort:  "name",
+		Limit: 10,
+	}
+	assert.Nil(t, directoryListSrv.Validate())
+	directoryListValue, err := directoryListSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	value, ok := directoryListValue.(*DirectoryListValue)
+	assert.True(t, ok)
+	assert.Equal(t, 2, value.Total)
+	assert.Equal(t, "images", value.Files[0].Name)
+	assert.Equal(t, "random.bytes", value.Files[1].Name)
+
+	directoryListSrv.ShowHidden = 1
+	directoryListValue, err = directoryListSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, directoryListValue.(*DirectoryListValue).Total)
+
+	directoryListSrv.Path = "/random.bytes"
+	_, err = directoryListSrv.Execute(context.TODO())
+	assert.Equal(t, models.ErrListFile, err)
+
+	directoryListSrv.Path = "/another"
+	_, err = directoryListSrv.Execute(context.TODO())
+	assert.NotNil(t, err)
+	assert.True(t, util.IsRecordNotFound(err))
+}
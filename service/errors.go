@@ -226,6 +226,390 @@ var (
 			Field: "FileUpdate.Path",
 			Msg:   "file is required",
 		},
+
+		// FileDelete Field error
+		"FileDelete.Token": {
+			Code:  10029,
+			Field: "FileDelete.Token",
+			Msg:   "token is required",
+		},
+		"FileDelete.File": {
+			Code:  10030,
+			Field: "FileDelete.File",
+			Msg:   "file is required",
+		},
+
+		// TrashList Field error
+		"TrashList.Token": {
+			Code:  10031,
+			Field: "TrashList.Token",
+			Msg:   "token is required",
+		},
+		"TrashList.Offset": {
+			Code:  10032,
+			Field: "TrashList.Offset",
+			Msg:   "offset must be greater than or equal to 0",
+		},
+		"TrashList.Limit": {
+			Code:  10033,
+			Field: "TrashList.Limit",
+			Msg:   "limit must be between 1 and 100",
+		},
+
+		// TrashRestore Field error
+		"TrashRestore.Token": {
+			Code:  10034,
+			Field: "TrashRestore.Token",
+			Msg:   "token is required",
+		},
+		"TrashRestore.File": {
+			Code:  10035,
+			Field: "TrashRestore.File",
+			Msg:   "file is required, and it must be in trash",
+		},
+		"TrashRestore.Path": {
+			Code:  10036,
+			Field: "TrashRestore.Path",
+			Msg:   "max length of path is 1000, and must be a legal unix path, it's optional",
+		},
+
+		// TrashPurge Field error
+		"TrashPurge.Token": {
+			Code:  10037,
+			Field: "TrashPurge.Token",
+			Msg:   "token is required",
+		},
+		"TrashPurge.File": {
+			Code:  10038,
+			Field: "TrashPurge.File",
+			Msg:   "file is required, and it must be in trash",
+		},
+
+		// DirectoryList Field error
+		"DirectoryList.Token": {
+			Code:  10039,
+			Field: "DirectoryList.Token",
+			Msg:   "token is required",
+		},
+		"DirectoryList.Path": {
+			Code:  10040,
+			Field: "DirectoryList.Path",
+			Msg:   "path is required, max length is 1000, and must be a legal unix path",
+		},
+		"DirectoryList.Sort": {
+			Code:  10041,
+			Field: "DirectoryList.Sort",
+			Msg:   "sort must be one of name, size, createdAt and updatedAt, prefix '-' means descending order",
+		},
+		"DirectoryList.Ext": {
+			Code:  10042,
+			Field: "DirectoryList.Ext",
+			Msg:   "max length of ext is 255",
+		},
+		"DirectoryList.ShowHidden": {
+			Code:  10043,
+			Field: "DirectoryList.ShowHidden",
+			Msg:   "showHidden must be one of 0 and 1",
+		},
+		"DirectoryList.Offset": {
+			Code:  10044,
+			Field: "DirectoryList.Offset",
+			Msg:   "offset must be greater than or equal to 0",
+		},
+		"DirectoryList.Limit": {
+			Code:  10045,
+			Field: "DirectoryList.Limit",
+			Msg:   "limit must be between 1 and 100",
+		},
+
+		// UploadCreate Field error
+		"UploadCreate.Token": {
+			Code:  10046,
+			Field: "UploadCreate.Token",
+			Msg:   "can't find specific token by input params",
+		},
+		"UploadCreate.Path": {
+			Code:  10047,
+			Field: "UploadCreate.Path",
+			Msg:   "path of file can't be empty, max of length is 1000, and must be a legal unix path",
+		},
+		"UploadCreate.Size": {
+			Code:  10048,
+			Field: "UploadCreate.Size",
+			Msg:   "size must be greater than or equal to 0",
+		},
+		"UploadCreate.Hidden": {
+			Code:  10049,
+			Field: "UploadCreate.Hidden",
+			Msg:   "hidden must be 0 or 1",
+		},
+
+		// UploadOffset Field error
+		"UploadOffset.Token": {
+			Code:  10050,
+			Field: "UploadOffset.Token",
+			Msg:   "token is required",
+		},
+		"UploadOffset.Session": {
+			Code:  10051,
+			Field: "UploadOffset.Session",
+			Msg:   "upload session is required",
+		},
+
+		// UploadAppend Field error
+		"UploadAppend.Token": {
+			Code:  10052,
+			Field: "UploadAppend.Token",
+			Msg:   "token is required",
+		},
+		"UploadAppend.Session": {
+			Code:  10053,
+			Field: "UploadAppend.Session",
+			Msg:   "upload session is required",
+		},
+		"UploadAppend.Offset": {
+			Code:  10054,
+			Field: "UploadAppend.Offset",
+			Msg:   "offset must be greater than or equal to 0",
+		},
+		"UploadAppend.Reader": {
+			Code:  10055,
+			Field: "UploadAppend.Reader",
+			Msg:   "content is required",
+		},
+
+		// HistoryList Field error
+		"HistoryList.Token": {
+			Code:  10056,
+			Field: "HistoryList.Token",
+			Msg:   "token is required",
+		},
+		"HistoryList.File": {
+			Code:  10057,
+			Field: "HistoryList.File",
+			Msg:   "file is required",
+		},
+		"HistoryList.Offset": {
+			Code:  10058,
+			Field: "HistoryList.Offset",
+			Msg:   "offset must be greater than or equal to 0",
+		},
+		"HistoryList.Limit": {
+			Code:  10059,
+			Field: "HistoryList.Limit",
+			Msg:   "limit must be between 1 and 100",
+		},
+
+		// HistoryRead Field error
+		"HistoryRead.Token": {
+			Code:  10060,
+			Field: "HistoryRead.Token",
+			Msg:   "token is required",
+		},
+		"HistoryRead.File": {
+			Code:  10061,
+			Field: "HistoryRead.File",
+			Msg:   "file is required",
+		},
+		"HistoryRead.History": {
+			Code:  10062,
+			Field: "HistoryRead.History",
+			Msg:   "history is required",
+		},
+
+		// HistoryRestore Field error
+		"HistoryRestore.Token": {
+			Code:  10063,
+			Field: "HistoryRestore.Token",
+			Msg:   "token is required",
+		},
+		"HistoryRestore.File": {
+			Code:  10064,
+			Field: "HistoryRestore.File",
+			Msg:   "file is required",
+		},
+		"HistoryRestore.History": {
+			Code:  10065,
+			Field: "HistoryRestore.History",
+			Msg:   "history is required",
+		},
+
+		// FileCopy Field error
+		"FileCopy.Token": {
+			Code:  10066,
+			Field: "FileCopy.Token",
+			Msg:   "token is required",
+		},
+		"FileCopy.File": {
+			Code:  10067,
+			Field: "FileCopy.File",
+			Msg:   "file is required",
+		},
+		"FileCopy.Path": {
+			Code:  10068,
+			Field: "FileCopy.Path",
+			Msg:   "path can't be empty, max of length is 1000, and must be a legal unix path",
+		},
+		"FileCopy.Overwrite": {
+			Code:  10069,
+			Field: "FileCopy.Overwrite",
+			Msg:   "overwrite must be 0 or 1",
+		},
+		"FileCopy.Rename": {
+			Code:  10070,
+			Field: "FileCopy.Rename",
+			Msg:   "rename must be 0 or 1",
+		},
+
+		// FileCreate quota error
+		"FileCreate.Size": {
+			Code:  10072,
+			Field: "FileCreate.Size",
+			Msg:   "size must be greater than or equal to 0",
+		},
+		"FileCreate.Quota": {
+			Code:  10073,
+			Field: "FileCreate.Quota",
+			Msg:   "quota is exceeded",
+		},
+
+		// Token quota error
+		"TokenCreate.MaxSize": {
+			Code:  10074,
+			Field: "TokenCreate.MaxSize",
+			Msg:   "maxSize must be greater than or equal to 0",
+		},
+		"TokenCreate.MaxFiles": {
+			Code:  10075,
+			Field: "TokenCreate.MaxFiles",
+			Msg:   "maxFiles must be greater than or equal to 0",
+		},
+		"TokenUpdate.MaxSize": {
+			Code:  10076,
+			Field: "TokenUpdate.MaxSize",
+			Msg:   "maxSize must be greater than or equal to 0",
+		},
+		"TokenUpdate.MaxFiles": {
+			Code:  10077,
+			Field: "TokenUpdate.MaxFiles",
+			Msg:   "maxFiles must be greater than or equal to 0",
+		},
+
+		// QuotaRead Field error
+		"QuotaRead.Token": {
+			Code:  10078,
+			Field: "QuotaRead.Token",
+			Msg:   "token is required",
+		},
+
+		// File meta error
+		"FileCreate.Metas": {
+			Code:  10079,
+			Field: "FileCreate.Metas",
+			Msg:   "metas are invalid",
+		},
+		"FileCreate.ReplaceMetas": {
+			Code:  10080,
+			Field: "FileCreate.ReplaceMetas",
+			Msg:   "replaceMetas must be 0 or 1",
+		},
+		"FileUpdate.Metas": {
+			Code:  10081,
+			Field: "FileUpdate.Metas",
+			Msg:   "metas are invalid",
+		},
+		"FileUpdate.ReplaceMetas": {
+			Code:  10082,
+			Field: "FileUpdate.ReplaceMetas",
+			Msg:   "replaceMetas must be 0 or 1",
+		},
+
+		// FileSearch Field error
+		"FileSearch.Token": {
+			Code:  10083,
+			Field: "FileSearch.Token",
+			Msg:   "token is required",
+		},
+		"FileSearch.Path": {
+			Code:  10084,
+			Field: "FileSearch.Path",
+			Msg:   "path is required, max length is 1000, and must be a legal unix path",
+		},
+		"FileSearch.Name": {
+			Code:  10085,
+			Field: "FileSearch.Name",
+			Msg:   "max length of name is 255",
+		},
+		"FileSearch.Ext": {
+			Code:  10086,
+			Field: "FileSearch.Ext",
+			Msg:   "max length of ext is 255",
+		},
+		"FileSearch.MinSize": {
+			Code:  10087,
+			Field: "FileSearch.MinSize",
+			Msg:   "minSize must be greater than or equal to 0",
+		},
+		"FileSearch.MaxSize": {
+			Code:  10088,
+			Field: "FileSearch.MaxSize",
+			Msg:   "maxSize must be greater than or equal to 0",
+		},
+		"FileSearch.Hidden": {
+			Code:  10089,
+			Field: "FileSearch.Hidden",
+			Msg:   "hidden must be one of 0 and 1",
+		},
+		"FileSearch.Hash": {
+			Code:  10090,
+			Field: "FileSearch.Hash",
+			Msg:   "hash must be a sha256 hex string",
+		},
+		"FileSearch.Metas": {
+			Code:  10091,
+			Field: "FileSearch.Metas",
+			Msg:   "metas are invalid",
+		},
+		"FileSearch.Sort": {
+			Code:  10092,
+			Field: "FileSearch.Sort",
+			Msg:   "sort must be one of name, size, createdAt and updatedAt, prefix '-' means descending order",
+		},
+		"FileSearch.Offset": {
+			Code:  10093,
+			Field: "FileSearch.Offset",
+			Msg:   "offset must be greater than or equal to 0",
+		},
+		"FileSearch.Limit": {
+			Code:  10094,
+			Field: "FileSearch.Limit",
+			Msg:   "limit must be between 1 and 100",
+		},
+		"FileCopy.Quota": {
+			Code:  10095,
+			Field: "FileCopy.Quota",
+			Msg:   "quota is exceeded",
+		},
+		"UploadCreate.Quota": {
+			Code:  10096,
+			Field: "UploadCreate.Quota",
+			Msg:   "quota is exceeded",
+		},
+		"UploadAppend.Quota": {
+			Code:  10097,
+			Field: "UploadAppend.Quota",
+			Msg:   "quota is exceeded",
+		},
+		"TrashRestore.Quota": {
+			Code:  10098,
+			Field: "TrashRestore.Quota",
+			Msg:   "quota is exceeded",
+		},
+		"HistoryRestore.Quota": {
+			Code:  10099,
+			Field: "HistoryRestore.Quota",
+			Msg:   "quota is exceeded",
+		},
 	}
 )
 
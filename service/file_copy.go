@@ -0,0 +1,123 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// FileCopy is used to copy a file or a directory to another path. The copies share
+// objects with the source, so no content is copied.
+type FileCopy struct {
+	BaseService
+
+	Token     *models.Token `validate:"required"`
+	File      *models.File  `validate:"required"`
+	Path      string        `validate:"required,max=1000"`
+	IP        *string       `validate:"omitempty"`
+	Overwrite int8          `validate:"oneof=0 1"`
+	Rename    int8          `validate:"oneof=0 1"`
+}
+
+// Validate is used to validate service params
+func (fc *FileCopy) Validate() ValidateErrors {
+	var (
+		validateErrors ValidateErrors
+		errs           error
+	)
+
+	// the same operations as creating file, except that append isn't supported
+	if fc.Overwrite+fc.Rename > 1 {
+		validateErrors = append(
+			validateErrors,
+			generateErrorByField("FileCreate.Operate", ErrOnlyOneRenameAppendOverWrite),
+		)
+	}
+
+	if errs = Validate.Struct(fc); errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			validateErrors = append(validateErrors, PreDefinedValidateErrors[err.Namespace()])
+		}
+	}
+
+	if err := ValidateToken(fc.DB, fc.IP, false, fc.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("FileCopy.Token", err))
+	}
+
+	if err := ValidateFile(fc.DB, fc.File); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("FileCopy.File", err))
+	} else {
+		if err := fc.File.CanBeAccessedByToken(fc.Token, fc.DB); err != nil {
+			validateErrors = append(validateErrors, generateErrorByField("FileCopy.Token", err))
+		}
+	}
+
+	if !ValidatePath(fc.Path) {
+		validateErrors = append(validateErrors, generateErrorByField("FileCopy.Path", ErrInvalidPath))
+	}
+
+	return validateErrors
+}
+
+// Execute is used to copy file, the copy is returned
+func (fc *FileCopy) Execute(ctx context.Context) (interface{}, error) {
+	var (
+		err     error
+		path    = fc.Token.PathWithScope(fc.Path)
+		existed *models.File
+		copied  *models.File
+	)
+
+	fc.BaseService.Before = append(fc.BaseService.After, func(ctx context.Context, service Service) error {
+		f := service.(*FileCopy)
+		return f.Token.UpdateAvailableTimes(-1, f.DB)
+	})
+
+	if err = fc.CallBefore(ctx, fc); err != nil {
+		return nil, err
+	}
+
+	if err = fc.transaction(func() error {
+		var size, files, copiedFiles int
+		if existed, err = models.FindFileByPath(&fc.Token.App, path, fc.DB); err != nil && !util.IsRecordNotFound(err) {
+			return err
+		}
+		if existed != nil && existed.ID > 0 {
+			switch {
+			case fc.Overwrite == 1:
+				size = existed.Size
+				if files, err = existed.CountFiles(fc.DB); err != nil {
+					return err
+				}
+			case fc.Rename == 1:
+				path = fmt.Sprintf("%s/%s_%s", filepath.Dir(path), models.RandomWithMd5(256), filepath.Base(path))
+			default:
+				return ErrPathExisted
+			}
+		}
+		if copied, err = fc.File.CopyTo(path, fc.Overwrite == 1, fc.DB); err != nil {
+			return err
+		}
+		// the quotas are checked with the changes of overwritten files
+		if copiedFiles, err = copied.CountFiles(fc.DB); err != nil {
+			return err
+		}
+		return ValidateUsedQuota(fc.DB, fc.Token, copied.Size-size, copiedFiles-files, "FileCopy.Quota")
+	}); err != nil {
+		return nil, err
+	}
+
+	if fc.CallAfter(ctx, fc) != nil {
+		return copied, err
+	}
+
+	return copied, nil
+}
@@ -0,0 +1,135 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileCopy_Validate(t *testing.T) {
+	var fileCopySrv = &FileCopy{Path: "/!!!/file", Overwrite: 1, Rename: 1}
+
+	confirm := assert.New(t)
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	confirm.Nil(err)
+	defer down(t)
+	fileCopySrv.DB = trx
+
+	errValidate := fileCopySrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.True(errValidate.ContainsErrCode(10066))
+	confirm.True(errValidate.ContainsErrCode(10067))
+	confirm.True(errValidate.ContainsErrCode(10068))
+	confirm.True(errValidate.ContainsErrCode(10022))
+
+	token.Path = "/test"
+	confirm.Nil(trx.Save(token).Error)
+	dir, err := models.CreateOrGetLastDirectory(&token.App, "/save/to", trx)
+	confirm.Nil(err)
+
+	fileCopySrv.Token = token
+	fileCopySrv.File = dir
+	fileCopySrv.Path = "/save/to"
+	fileCopySrv.Rename = 0
+	errValidate = fileCopySrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.Contains(errValidate.Error(), "file can't be accessed by some tokens")
+}
+
+func TestFileCopy_Execute(t *testing.T) {
+	tempDir := models.NewTempDirForTest()
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	token.AvailableTimes = 1000
+	assert.Nil(t, trx.Save(token).Error)
+
+	file, err := models.CreateFileFromReader(&token.App, "/test/random.bytes", bytes.NewReader(models.Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+
+	fileCopySrv := &FileCopy{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token: token,
+		File:  file,
+		Path:  "/test/copied.bytes",
+	}
+	assert.Nil(t, fileCopySrv.Validate())
+	fileCopyValue, err := fileCopySrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	copied := fileCopyValue.(*models.File)
+	assert.Equal(t, file.ObjectID, copied.ObjectID)
+	assert.NotEqual(t, file.ID, copied.ID)
+
+	_, err = fileCopySrv.Execute(context.TODO())
+	assert.Equal(t, ErrPathExisted, err)
+
+	fileCopySrv.Rename = 1
+	fileCopyValue, err = fileCopySrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	renamed := fileCopyValue.(*models.File)
+	assert.NotEqual(t, copied.ID, renamed.ID)
+	assert.True(t, strings.HasSuffix(renamed.Name, "_copied.bytes"))
+
+	fileCopySrv.Rename = 0
+	fileCopySrv.Overwrite = 1
+	fileCopyValue, err = fileCopySrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, copied.ID, fileCopyValue.(*models.File).ID)
+}
+
+func TestFileCopy_Execute2(t *testing.T) {
+	tempDir := models.NewTempDirForTest()
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	token.AvailableTimes = 1000
+	assert.Nil(t, trx.Save(token).Error)
+
+	file, err := models.CreateFileFromReader(&token.App, "/test/random.bytes", bytes.NewReader(models.Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = models.CreateFileFromReader(&token.App, "/test/existed.bytes", bytes.NewReader(models.Random(200)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, token.App.SetQuota(0, 2, trx))
+
+	fileCopySrv := &FileCopy{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token: token,
+		File:  file,
+		Path:  "/test/copied.bytes",
+	}
+	_, err = fileCopySrv.Execute(context.TODO())
+	assert.NotNil(t, err)
+	assert.True(t, err.(ValidateErrors).ContainsErrCode(10095))
+
+	// nothing is added by overwriting
+	fileCopySrv.Path = "/test/existed.bytes"
+	fileCopySrv.Overwrite = 1
+	_, err = fileCopySrv.Execute(context.TODO())
+	assert.Nil(t, err)
+}
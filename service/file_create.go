@@ -27,14 +27,19 @@ var (
 type FileCreate struct {
 	BaseService
 
-	Token     *models.Token `validate:"required"`
-	Path      string        `validate:"required,max=1000"`
-	Hidden    int8          `validate:"oneof=0 1"`
-	IP        *string       `validate:"omitempty"`
-	Reader    io.Reader     `validate:"omitempty"`
-	Overwrite int8          `validate:"oneof=0 1"`
-	Rename    int8          `validate:"oneof=0 1"`
-	Append    int8          `validate:"oneof=0 1"`
+	Token  *models.Token `validate:"required"`
+	Path   string        `validate:"required,max=1000"`
+	Hidden int8          `validate:"oneof=0 1"`
+	IP     *string       `validate:"omitempty"`
+	Reader io.Reader     `validate:"omitempty"`
+	Size   int           `validate:"min=0"`
+	// Metas are set to the file, the meta whose value is empty is deleted. If
+	// ReplaceMetas is 1, the metas that aren't in Metas are deleted too.
+	Metas        map[string]string `validate:"omitempty"`
+	ReplaceMetas int8              `validate:"oneof=0 1"`
+	Overwrite    int8              `validate:"oneof=0 1"`
+	Rename       int8              `validate:"oneof=0 1"`
+	Append       int8              `validate:"oneof=0 1"`
 }
 
 // Validate is used to validate params
@@ -65,9 +70,45 @@ func (f *FileCreate) Validate() ValidateErrors {
 		validateErrors = append(validateErrors, generateErrorByField("FileCreate.Path", ErrInvalidPath))
 	}
 
+	if err := ValidateMetas(f.Metas); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("FileCreate.Metas", err))
+	}
+
+	if len(validateErrors) == 0 && f.Reader != nil {
+		if err := f.checkQuota(); err != nil {
+			validateErrors = append(validateErrors, generateErrorByField("FileCreate.Quota", err))
+		}
+	}
+
 	return validateErrors
 }
 
+// checkQuota is used to check the quotas of app and token before any chunk is
+// written. Size is the size of content, if it's unknown, only the count of files
+// is checked. They are checked again with the actual size in write.
+func (f *FileCreate) checkQuota() error {
+	var (
+		size  = f.Size
+		files = 1
+	)
+	file, err := models.FindFileByPath(&f.Token.App, f.Token.PathWithScope(f.Path), f.DB)
+	if err != nil && !util.IsRecordNotFound(err) {
+		return err
+	}
+	if err == nil {
+		switch {
+		case f.Overwrite == 1:
+			size, files = size-file.Size, 0
+		case f.Append == 1:
+			files = 0
+		case f.Rename == 0:
+			// the path has already existed, nothing will be added
+			return nil
+		}
+	}
+	return models.CheckQuota(f.Token, size, files, f.DB)
+}
+
 // Execute is used to upload file or create directory
 func (f *FileCreate) Execute(ctx context.Context) (interface{}, error) {
 
@@ -86,6 +127,35 @@ func (f *FileCreate) Execute(ctx context.Context) (interface{}, error) {
 		return nil, err
 	}
 
+	if err = f.transaction(func() error {
+		if file, err = f.write(path); err != nil {
+			return err
+		}
+		if len(f.Metas) > 0 || f.ReplaceMetas == 1 {
+			return file.SetMetas(f.Metas, f.ReplaceMetas == 1, f.DB)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if f.CallAfter(ctx, f) != nil {
+		return file, err
+	}
+
+	return file, nil
+}
+
+// write is used to save the content of reader to path, or create the directory
+// if there isn't a reader. The quotas are checked again with the actual size of
+// content after it's saved, the changes are rolled back if they are exceeded.
+func (f *FileCreate) write(path string) (*models.File, error) {
+	var (
+		err  error
+		size int
+		file *models.File
+	)
+
 	if f.Reader == nil {
 		return models.CreateOrGetLastDirectory(&f.Token.App, path, f.DB)
 	}
@@ -95,15 +165,23 @@ func (f *FileCreate) Execute(ctx context.Context) (interface{}, error) {
 	}
 
 	if file == nil || file.ID == 0 {
-		return models.CreateFileFromReader(&f.Token.App, path, f.Reader, f.Hidden, f.RootPath, f.DB)
+		return f.create(path)
 	}
 
 	if f.Overwrite == 1 {
-		return file, file.OverWriteFromReader(f.Reader, f.Hidden, f.RootPath, f.DB)
+		size = file.Size
+		if err = file.OverWriteFromReader(f.Reader, f.Hidden, f.RootPath, f.DB); err != nil {
+			return nil, err
+		}
+		return file, ValidateUsedQuota(f.DB, f.Token, file.Size-size, 0, "FileCreate.Quota")
 	}
 
 	if f.Append == 1 {
-		return file, file.AppendFromReader(f.Reader, f.Hidden, f.RootPath, f.DB)
+		size = file.Size
+		if err = file.AppendFromReader(f.Reader, f.Hidden, f.RootPath, f.DB); err != nil {
+			return nil, err
+		}
+		return file, ValidateUsedQuota(f.DB, f.Token, file.Size-size, 0, "FileCreate.Quota")
 	}
 
 	if f.Rename == 1 {
@@ -111,13 +189,17 @@ func (f *FileCreate) Execute(ctx context.Context) (interface{}, error) {
 			dir      = filepath.Dir(path)
 			basename = filepath.Base(path)
 		)
-		path = fmt.Sprintf("%s/%s_%s", dir, models.RandomWithMd5(256), basename)
-		return models.CreateFileFromReader(&f.Token.App, path, f.Reader, f.Hidden, f.RootPath, f.DB)
+		return f.create(fmt.Sprintf("%s/%s_%s", dir, models.RandomWithMd5(256), basename))
 	}
 
-	if f.CallAfter(ctx, f) != nil {
+	return nil, ErrPathExisted
+}
+
+// create is used to save the content of reader to a new file
+func (f *FileCreate) create(path string) (*models.File, error) {
+	file, err := models.CreateFileFromReader(&f.Token.App, path, f.Reader, f.Hidden, f.RootPath, f.DB)
+	if err != nil {
 		return nil, err
 	}
-
-	return nil, ErrPathExisted
+	return file, ValidateUsedQuota(f.DB, f.Token, file.Size, 1, "FileCreate.Quota")
 }
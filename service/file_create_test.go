@@ -30,12 +30,15 @@ func TestFileCreate_Validate(t *testing.T) {
 		BaseService: BaseService{
 			DB: trx,
 		},
-		Token:     nil,
-		Path:      strings.Repeat("1", 1001),
-		Hidden:    2,
-		Overwrite: 2,
-		Rename:    2,
-		Append:    2,
+		Token:        nil,
+		Path:         strings.Repeat("1", 1001),
+		Hidden:       2,
+		Overwrite:    2,
+		Rename:       2,
+		Append:       2,
+		Size:         -1,
+		Metas:        map[string]string{"owner": "1\n"},
+		ReplaceMetas: 2,
 	}
 	err := fileCreate.Validate()
 	confirm := assert.New(t)
@@ -47,6 +50,9 @@ func TestFileCreate_Validate(t *testing.T) {
 	confirm.True(err.ContainsErrCode(10020))
 	confirm.True(err.ContainsErrCode(10021))
 	confirm.True(err.ContainsErrCode(10022))
+	confirm.True(err.ContainsErrCode(10072))
+	confirm.True(err.ContainsErrCode(10079))
+	confirm.True(err.ContainsErrCode(10080))
 	confirm.Contains(err.Error(), "path is not a legal unix path")
 }
 
@@ -254,3 +260,96 @@ func TestFileCreate_Execute6(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, ErrPathExisted, err)
 }
+
+// TestFileCreate_Validate3 is used to test the quotas of app and token
+func TestFileCreate_Validate3(t *testing.T) {
+	fileCreate, file, _, down := newFileCreateForTestWithFile(t)
+	defer down(t)
+
+	assert.Nil(t, fileCreate.Token.SetQuota(300, 2, fileCreate.DB))
+	fileCreate.Reader = bytes.NewReader(models.Random(10))
+	fileCreate.Size = 10
+	fileCreate.Path = "/create/other.bytes"
+	assert.Nil(t, fileCreate.Validate())
+
+	fileCreate.Size = 45
+	err := fileCreate.Validate()
+	assert.NotNil(t, err)
+	assert.True(t, err.ContainsErrCode(10073))
+
+	// the size of previous content is released by overwriting
+	fileCreate.Path = "/create/random.bytes"
+	fileCreate.Overwrite = 1
+	fileCreate.Size = file.Size + 44
+	assert.Nil(t, fileCreate.Validate())
+	fileCreate.Overwrite = 0
+	fileCreate.Append = 1
+	assert.True(t, fileCreate.Validate().ContainsErrCode(10073))
+
+	assert.Nil(t, fileCreate.Token.SetQuota(0, 1, fileCreate.DB))
+	fileCreate.Append = 0
+	fileCreate.Rename = 1
+	fileCreate.Size = 10
+	assert.True(t, fileCreate.Validate().ContainsErrCode(10073))
+
+	// the quota of app is checked as well
+	assert.Nil(t, fileCreate.Token.SetQuota(0, 0, fileCreate.DB))
+	assert.Nil(t, fileCreate.Validate())
+	assert.Nil(t, fileCreate.Token.App.SetQuota(0, 1, fileCreate.DB))
+	assert.True(t, fileCreate.Validate().ContainsErrCode(10073))
+}
+
+// TestFileCreate_Execute7 is used to set metas of file
+func TestFileCreate_Execute7(t *testing.T) {
+	fileCreate, file, _, down := newFileCreateForTestWithFile(t)
+	defer down(t)
+
+	fileCreate.Reader = bytes.NewReader(models.Random(10))
+	fileCreate.Overwrite = 1
+	fileCreate.Metas = map[string]string{"Owner-Id": "1", "desc": "random bytes"}
+	assert.Nil(t, fileCreate.Validate())
+	_, err := fileCreate.Execute(context.TODO())
+	assert.Nil(t, err)
+	metas, err := file.FindMetas(fileCreate.DB)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"owner-id": "1", "desc": "random bytes"}, metas)
+
+	// a directory can have metas too
+	fileCreate.Reader = nil
+	fileCreate.Path = "/create/dir"
+	fileCreate.Metas = map[string]string{"desc": "directory"}
+	assert.Nil(t, fileCreate.Validate())
+	dirValue, err := fileCreate.Execute(context.TODO())
+	assert.Nil(t, err)
+	metas, err = dirValue.(*models.File).FindMetas(fileCreate.DB)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"desc": "directory"}, metas)
+}
+
+// TestFileCreate_Execute8 is used to test the quotas with the actual size of content
+func TestFileCreate_Execute8(t *testing.T) {
+	fileCreate, file, _, down := newFileCreateForTestWithFile(t)
+	defer down(t)
+
+	// the size of content is unknown, it's checked after content is saved
+	assert.Nil(t, fileCreate.Token.SetQuota(300, 0, fileCreate.DB))
+	fileCreate.Reader = bytes.NewReader(models.Random(100))
+	fileCreate.Path = "/create/other.bytes"
+	assert.Nil(t, fileCreate.Validate())
+	_, err := fileCreate.Execute(context.TODO())
+	assert.NotNil(t, err)
+	assert.True(t, err.(ValidateErrors).ContainsErrCode(10073))
+
+	fileCreate.Reader = bytes.NewReader(models.Random(100))
+	fileCreate.Path = "/create/random.bytes"
+	fileCreate.Append = 1
+	_, err = fileCreate.Execute(context.TODO())
+	assert.True(t, err.(ValidateErrors).ContainsErrCode(10073))
+
+	// the overwritten content is released
+	fileCreate.Reader = bytes.NewReader(models.Random(uint(file.Size + 40)))
+	fileCreate.Append = 0
+	fileCreate.Overwrite = 1
+	_, err = fileCreate.Execute(context.TODO())
+	assert.Nil(t, err)
+}
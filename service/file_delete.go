@@ -0,0 +1,75 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// FileDelete is used to delete a file or a directory. If it's a directory,
+// all files under it will be deleted too. Deleted files are moved to trash.
+type FileDelete struct {
+	BaseService
+
+	Token *models.Token `validate:"required"`
+	File  *models.File  `validate:"required"`
+	IP    *string       `validate:"omitempty"`
+}
+
+// Validate is used to validate service params
+func (fd *FileDelete) Validate() ValidateErrors {
+	var (
+		validateErrors ValidateErrors
+		errs           error
+	)
+	if errs = Validate.Struct(fd); errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			validateErrors = append(validateErrors, PreDefinedValidateErrors[err.Namespace()])
+		}
+	}
+
+	if err := ValidateToken(fd.DB, fd.IP, false, fd.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("FileDelete.Token", err))
+	}
+
+	if err := ValidateFile(fd.DB, fd.File); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("FileDelete.File", err))
+	} else {
+		if err := fd.File.CanBeAccessedByToken(fd.Token, fd.DB); err != nil {
+			validateErrors = append(validateErrors, generateErrorByField("FileDelete.Token", err))
+		}
+	}
+
+	return validateErrors
+}
+
+// Execute is used to delete file
+func (fd *FileDelete) Execute(ctx context.Context) (interface{}, error) {
+	var (
+		err error
+	)
+
+	fd.BaseService.Before = append(fd.BaseService.After, func(ctx context.Context, service Service) error {
+		f := service.(*FileDelete)
+		return f.Token.UpdateAvailableTimes(-1, f.DB)
+	})
+
+	if err = fd.CallBefore(ctx, fd); err != nil {
+		return nil, err
+	}
+
+	if err = fd.File.Delete(fd.DB); err != nil {
+		return nil, err
+	}
+
+	if fd.CallAfter(ctx, fd) != nil {
+		return fd.File, err
+	}
+
+	return fd.File, nil
+}
@@ -0,0 +1,95 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileDelete_Validate(t *testing.T) {
+	var (
+		fileDeleteSrv = &FileDelete{
+			Token: nil,
+			File:  nil,
+			IP:    nil,
+		}
+		errValidate ValidateErrors
+	)
+
+	confirm := assert.New(t)
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	confirm.Nil(err)
+	defer down(t)
+	fileDeleteSrv.DB = trx
+
+	errValidate = fileDeleteSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.True(errValidate.ContainsErrCode(10029))
+	confirm.True(errValidate.ContainsErrCode(10030))
+	confirm.Contains(errValidate.Error(), "invalid token")
+	confirm.Contains(errValidate.Error(), "invalid file")
+
+	dir, err := models.CreateOrGetLastDirectory(&token.App, "/save/to", trx)
+	confirm.Nil(err)
+
+	token.Path = "/test"
+	token.ReadOnly = 1
+	confirm.Nil(trx.Save(token).Error)
+	fileDeleteSrv.Token = token
+	fileDeleteSrv.File = dir
+
+	errValidate = fileDeleteSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.Contains(errValidate.Error(), "this token is read only")
+	confirm.Contains(errValidate.Error(), "file can't be accessed by some tokens")
+}
+
+func TestFileDelete_Execute(t *testing.T) {
+	tempDir := models.NewTempDirForTest()
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	file, err := models.CreateFileFromReader(&token.App, "/test/random.bytes", bytes.NewReader(models.Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	testDir, err := models.FindFileByPath(&token.App, "/test", trx)
+	assert.Nil(t, err)
+
+	fileDeleteSrv := &FileDelete{
+		BaseService: BaseService{
+			DB:       trx,
+			RootPath: &tempDir,
+		},
+		Token: token,
+		File:  testDir,
+	}
+
+	assert.Nil(t, fileDeleteSrv.Validate())
+	fileDeleteValue, err := fileDeleteSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	deletedDir, ok := fileDeleteValue.(*models.File)
+	assert.True(t, ok)
+	assert.NotNil(t, deletedDir.DeletedAt)
+
+	_, err = models.FindFileByUID(file.UID, false, trx)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "record not found")
+
+	rootDir, err := models.CreateOrGetRootPath(&token.App, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, rootDir.Size)
+}
@@ -6,7 +6,6 @@ package service
 
 import (
 	"context"
-	"io"
 
 	"github.com/bigfile/bigfile/databases/models"
 	"gopkg.in/go-playground/validator.v9"
@@ -52,7 +51,7 @@ func (fr *FileRead) Validate() ValidateErrors {
 func (fr *FileRead) Execute(ctx context.Context) (interface{}, error) {
 	var (
 		err        error
-		fileReader io.Reader
+		fileReader models.ObjectReader
 	)
 
 	fr.BaseService.Before = append(fr.BaseService.After, func(ctx context.Context, service Service) error {
@@ -69,6 +68,7 @@ func (fr *FileRead) Execute(ctx context.Context) (interface{}, error) {
 	}
 
 	if fr.CallAfter(ctx, fr) != nil {
+		_ = fileReader.Close()
 		return nil, err
 	}
 
@@ -0,0 +1,118 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// FileSearch is used to search the files under a directory by name, extension,
+// size, time, hidden flag, content hash and metas. The path is relative to the
+// path of token, so only the files under the scope of token can be found.
+type FileSearch struct {
+	BaseService
+
+	Token         *models.Token     `validate:"required"`
+	IP            *string           `validate:"omitempty"`
+	Path          string            `validate:"required,max=1000"`
+	Name          string            `validate:"omitempty,max=255"`
+	Ext           *string           `validate:"omitempty,max=255"`
+	MinSize       *int              `validate:"omitempty,min=0"`
+	MaxSize       *int              `validate:"omitempty,min=0"`
+	CreatedAfter  *time.Time        `validate:"omitempty"`
+	CreatedBefore *time.Time        `validate:"omitempty"`
+	UpdatedAfter  *time.Time        `validate:"omitempty"`
+	UpdatedBefore *time.Time        `validate:"omitempty"`
+	Hidden        *int8             `validate:"omitempty,oneof=0 1"`
+	Hash          *string           `validate:"omitempty,len=64"`
+	Metas         map[string]string `validate:"omitempty"`
+	Sort          string            `validate:"oneof=name -name size -size createdAt -createdAt updatedAt -updatedAt"`
+	Offset        int               `validate:"min=0"`
+	Limit         int               `validate:"min=1,max=100"`
+}
+
+// FileSearchValue represent the result of FileSearch
+type FileSearchValue struct {
+	Total int
+	Files []models.File
+}
+
+// Validate is used to validate service params
+func (fs *FileSearch) Validate() ValidateErrors {
+	var (
+		validateErrors ValidateErrors
+		errs           error
+	)
+	if errs = Validate.Struct(fs); errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			validateErrors = append(validateErrors, PreDefinedValidateErrors[err.Namespace()])
+		}
+	}
+
+	if err := ValidateToken(fs.DB, fs.IP, true, fs.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("FileSearch.Token", err))
+	}
+
+	if !ValidatePath(fs.Path) {
+		validateErrors = append(validateErrors, generateErrorByField("FileSearch.Path", ErrInvalidPath))
+	}
+
+	if err := models.ValidateMetas(fs.Metas); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("FileSearch.Metas", err))
+	}
+
+	return validateErrors
+}
+
+// Execute is used to search files
+func (fs *FileSearch) Execute(ctx context.Context) (interface{}, error) {
+	var (
+		err   error
+		dir   *models.File
+		path  = fs.Token.PathWithScope(fs.Path)
+		value = &FileSearchValue{}
+		cond  = &models.FileSearchCondition{
+			Name:          fs.Name,
+			Ext:           fs.Ext,
+			MinSize:       fs.MinSize,
+			MaxSize:       fs.MaxSize,
+			CreatedAfter:  fs.CreatedAfter,
+			CreatedBefore: fs.CreatedBefore,
+			UpdatedAfter:  fs.UpdatedAfter,
+			UpdatedBefore: fs.UpdatedBefore,
+			Hidden:        fs.Hidden,
+			Hash:          fs.Hash,
+			Metas:         fs.Metas,
+			Sort:          fs.Sort,
+		}
+	)
+
+	fs.BaseService.Before = append(fs.BaseService.After, func(ctx context.Context, service Service) error {
+		f := service.(*FileSearch)
+		return f.Token.UpdateAvailableTimes(-1, f.DB)
+	})
+
+	if err = fs.CallBefore(ctx, fs); err != nil {
+		return nil, err
+	}
+
+	if dir, err = models.FindFileByPath(&fs.Token.App, path, fs.DB); err != nil {
+		return nil, err
+	}
+
+	if value.Total, value.Files, err = dir.Search(cond, fs.Offset, fs.Limit, fs.DB); err != nil {
+		return nil, err
+	}
+
+	if fs.CallAfter(ctx, fs) != nil {
+		return value, err
+	}
+
+	return value, nil
+}
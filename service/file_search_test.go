@@ -0,0 +1,106 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSearch_Validate(t *testing.T) {
+	trx, down := models.SetUpTestCaseWithTrx(nil, t)
+	defer down(t)
+	var (
+		ext     = strings.Repeat("e", 256)
+		minSize = -1
+		hidden  = int8(2)
+		hash    = "hash"
+	)
+	fileSearchSrv := &FileSearch{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token:   nil,
+		Path:    "/!!!/",
+		Name:    strings.Repeat("n", 256),
+		Ext:     &ext,
+		MinSize: &minSize,
+		MaxSize: &minSize,
+		Hidden:  &hidden,
+		Hash:    &hash,
+		Metas:   map[string]string{"bad name": "1"},
+		Sort:    "id",
+		Offset:  -1,
+		Limit:   0,
+	}
+	errValidate := fileSearchSrv.Validate()
+	confirm := assert.New(t)
+	confirm.NotNil(errValidate)
+	for code := 10083; code <= 10094; code++ {
+		confirm.True(errValidate.ContainsErrCode(code), code)
+	}
+}
+
+func TestFileSearch_Execute(t *testing.T) {
+	tempDir := models.NewTempDirForTest()
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	_, err = models.CreateFileFromReader(&token.App, "/test/a/report.pdf", bytes.NewReader(models.Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = models.CreateFileFromReader(&token.App, "/test/b/report.txt", bytes.NewReader(models.Random(128)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = models.CreateFileFromReader(&token.App, "/another/report.pdf", bytes.NewReader(models.Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+
+	token.Path = "/test"
+	assert.Nil(t, trx.Save(token).Error)
+	fileSearchSrv := &FileSearch{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token: token,
+		Path:  "/",
+		Name:  "report",
+		Sort:  "name",
+		Limit: 10,
+	}
+	assert.Nil(t, fileSearchSrv.Validate())
+	fileSearchValue, err := fileSearchSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	value, ok := fileSearchValue.(*FileSearchValue)
+	assert.True(t, ok)
+	assert.Equal(t, 2, value.Total)
+	assert.Equal(t, "/test/a/report.pdf", value.Files[0].FullPath)
+	assert.Equal(t, "/test/b/report.txt", value.Files[1].FullPath)
+
+	var minSize = 200
+	fileSearchSrv.MinSize = &minSize
+	fileSearchValue, err = fileSearchSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, fileSearchValue.(*FileSearchValue).Total)
+
+	fileSearchSrv.Path = "/a/report.pdf"
+	_, err = fileSearchSrv.Execute(context.TODO())
+	assert.Equal(t, models.ErrListFile, err)
+
+	fileSearchSrv.Path = "/another"
+	_, err = fileSearchSrv.Execute(context.TODO())
+	assert.NotNil(t, err)
+	assert.True(t, util.IsRecordNotFound(err))
+}
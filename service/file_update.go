@@ -21,6 +21,10 @@ type FileUpdate struct {
 	IP     *string       `validate:"omitempty"`
 	Hidden *int8         `validate:"omitempty,oneof=0 1"`
 	Path   *string       `validate:"omitempty,max=1000"`
+	// Metas are set to the file, the meta whose value is empty is deleted. If
+	// ReplaceMetas is 1, the metas that aren't in Metas are deleted too.
+	Metas        map[string]string `validate:"omitempty"`
+	ReplaceMetas int8              `validate:"oneof=0 1"`
 }
 
 // Validate is used to validate service params
@@ -53,6 +57,10 @@ func (fu *FileUpdate) Validate() ValidateErrors {
 		}
 	}
 
+	if err := ValidateMetas(fu.Metas); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("FileUpdate.Metas", err))
+	}
+
 	return validateErrors
 }
 
@@ -72,17 +80,25 @@ func (fu *FileUpdate) Execute(ctx context.Context) (interface{}, error) {
 		return nil, err
 	}
 
-	if fu.Path != nil {
-		if err := fu.File.MoveTo(fu.Token.PathWithScope(*fu.Path), fu.DB); err != nil {
-			return nil, err
+	if err = fu.transaction(func() error {
+		if fu.Path != nil {
+			if err := fu.File.MoveTo(fu.Token.PathWithScope(*fu.Path), fu.DB); err != nil {
+				return err
+			}
 		}
-	}
-
-	if fu.Hidden != nil {
-		fu.File.Hidden = *fu.Hidden
-	}
-
-	if err := fu.DB.Save(fu.File).Error; err != nil {
+		// only the changed column is written, the other columns of file may be
+		// changed by others since it was loaded
+		if fu.Hidden != nil {
+			fu.File.Hidden = *fu.Hidden
+			if err := fu.DB.Model(fu.File).UpdateColumn("hidden", *fu.Hidden).Error; err != nil {
+				return err
+			}
+		}
+		if len(fu.Metas) > 0 || fu.ReplaceMetas == 1 {
+			return fu.File.SetMetas(fu.Metas, fu.ReplaceMetas == 1, fu.DB)
+		}
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 
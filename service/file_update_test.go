@@ -24,11 +24,13 @@ func TestFileUpdate_Validate(t *testing.T) {
 		hidden        int8 = 2
 		path               = "/!!!/file"
 		fileUpdateSrv      = &FileUpdate{
-			Token:  nil,
-			File:   nil,
-			IP:     nil,
-			Hidden: &hidden,
-			Path:   &path,
+			Token:        nil,
+			File:         nil,
+			IP:           nil,
+			Hidden:       &hidden,
+			Path:         &path,
+			Metas:        map[string]string{"a b": "c"},
+			ReplaceMetas: 2,
 		}
 
 		err         error
@@ -48,6 +50,8 @@ func TestFileUpdate_Validate(t *testing.T) {
 	confirm.True(errValidate.ContainsErrCode(10026))
 	confirm.True(errValidate.ContainsErrCode(10027))
 	confirm.True(errValidate.ContainsErrCode(10028))
+	confirm.True(errValidate.ContainsErrCode(10081))
+	confirm.True(errValidate.ContainsErrCode(10082))
 	confirm.Contains(errValidate.Error(), "invalid token")
 	confirm.Contains(errValidate.Error(), "invalid file")
 
@@ -98,6 +102,7 @@ func TestFileUpdate_Execute(t *testing.T) {
 		File:   file,
 		Path:   &path,
 		Hidden: &hidden,
+		Metas:  map[string]string{"Owner-Id": "1"},
 	}
 
 	assert.Nil(t, fileUpdateSrv.Validate())
@@ -108,6 +113,20 @@ func TestFileUpdate_Execute(t *testing.T) {
 	filePath, err := file.Path(trx)
 	assert.Nil(t, err)
 	assert.Equal(t, path, filePath)
+	metas, err := file.FindMetas(trx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"owner-id": "1"}, metas)
+
+	// metas are replaced without moving the file
+	fileUpdateSrv.Path = nil
+	fileUpdateSrv.Metas = map[string]string{"desc": "random bytes"}
+	fileUpdateSrv.ReplaceMetas = 1
+	assert.Nil(t, fileUpdateSrv.Validate())
+	_, err = fileUpdateSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	metas, err = file.FindMetas(trx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"desc": "random bytes"}, metas)
 
 	rootDir, err := models.CreateOrGetRootPath(&token.App, trx)
 	assert.Nil(t, err)
@@ -120,4 +139,22 @@ func TestFileUpdate_Execute(t *testing.T) {
 	anotherDir, err := models.FindFileByPath(&token.App, "/another", trx)
 	assert.Nil(t, err)
 	assert.Equal(t, 556, anotherDir.Size)
+
+	// the content of file is overwritten by others, the stale file must not
+	// write it back when it's hidden
+	stale := *file
+	overwritten, err := models.FindFileByPath(&token.App, path, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, overwritten.OverWriteFromReader(bytes.NewReader(models.Random(128)), int8(1), &tempDir, trx))
+	hidden = 0
+	fileUpdateSrv.File = &stale
+	fileUpdateSrv.Metas = nil
+	assert.Nil(t, fileUpdateSrv.Validate())
+	_, err = fileUpdateSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	current, err := models.FindFileByPath(&token.App, path, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, int8(0), current.Hidden)
+	assert.Equal(t, overwritten.ObjectID, current.ObjectID)
+	assert.Equal(t, 128, current.Size)
 }
@@ -0,0 +1,84 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// HistoryList is used to list the histories of file, every history represent
+// a previous version of the file, the latest one is the first.
+type HistoryList struct {
+	BaseService
+
+	Token  *models.Token `validate:"required"`
+	File   *models.File  `validate:"required"`
+	IP     *string       `validate:"omitempty"`
+	Offset int           `validate:"min=0"`
+	Limit  int           `validate:"min=1,max=100"`
+}
+
+// HistoryListValue represent the result of HistoryList
+type HistoryListValue struct {
+	Total     int
+	Histories []models.History
+}
+
+// Validate is used to validate service params
+func (hl *HistoryList) Validate() ValidateErrors {
+	var (
+		validateErrors ValidateErrors
+		errs           error
+	)
+	if errs = Validate.Struct(hl); errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			validateErrors = append(validateErrors, PreDefinedValidateErrors[err.Namespace()])
+		}
+	}
+
+	if err := ValidateToken(hl.DB, hl.IP, true, hl.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("HistoryList.Token", err))
+	}
+
+	if err := ValidateFile(hl.DB, hl.File); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("HistoryList.File", err))
+	} else {
+		if err := hl.File.CanBeAccessedByToken(hl.Token, hl.DB); err != nil {
+			validateErrors = append(validateErrors, generateErrorByField("HistoryList.Token", err))
+		}
+	}
+
+	return validateErrors
+}
+
+// Execute is used to list the histories of file
+func (hl *HistoryList) Execute(ctx context.Context) (interface{}, error) {
+	var (
+		err   error
+		value = &HistoryListValue{}
+	)
+
+	hl.BaseService.Before = append(hl.BaseService.After, func(ctx context.Context, service Service) error {
+		h := service.(*HistoryList)
+		return h.Token.UpdateAvailableTimes(-1, h.DB)
+	})
+
+	if err = hl.CallBefore(ctx, hl); err != nil {
+		return nil, err
+	}
+
+	if value.Total, value.Histories, err = hl.File.FindHistories(hl.Offset, hl.Limit, hl.DB); err != nil {
+		return nil, err
+	}
+
+	if hl.CallAfter(ctx, hl) != nil {
+		return value, err
+	}
+
+	return value, nil
+}
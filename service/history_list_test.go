@@ -0,0 +1,68 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryList_Validate(t *testing.T) {
+	var historyListSrv = &HistoryList{Offset: -1, Limit: 101}
+
+	confirm := assert.New(t)
+	_, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	confirm.Nil(err)
+	defer down(t)
+	historyListSrv.DB = trx
+
+	errValidate := historyListSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.True(errValidate.ContainsErrCode(10056))
+	confirm.True(errValidate.ContainsErrCode(10057))
+	confirm.True(errValidate.ContainsErrCode(10058))
+	confirm.True(errValidate.ContainsErrCode(10059))
+}
+
+func TestHistoryList_Execute(t *testing.T) {
+	tempDir := models.NewTempDirForTest()
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	file, err := models.CreateFileFromReader(&token.App, "/test/random.bytes", bytes.NewReader(models.Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	for _, size := range []uint{10, 20} {
+		assert.Nil(t, file.OverWriteFromReader(bytes.NewReader(models.Random(size)), int8(0), &tempDir, trx))
+	}
+
+	historyListSrv := &HistoryList{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token:  token,
+		File:   file,
+		Offset: 0,
+		Limit:  1,
+	}
+	assert.Nil(t, historyListSrv.Validate())
+	historyListValue, err := historyListSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	value := historyListValue.(*HistoryListValue)
+	assert.Equal(t, 2, value.Total)
+	assert.Equal(t, 1, len(value.Histories))
+	assert.Equal(t, int64(10), value.Histories[0].Object.Size)
+}
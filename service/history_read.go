@@ -0,0 +1,80 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// HistoryRead is used to read the content of a previous version of file
+type HistoryRead struct {
+	BaseService
+
+	Token   *models.Token   `validate:"required"`
+	File    *models.File    `validate:"required"`
+	History *models.History `validate:"required"`
+	IP      *string         `validate:"omitempty"`
+}
+
+// Validate is used to validate service params
+func (hr *HistoryRead) Validate() ValidateErrors {
+	var (
+		validateErrors ValidateErrors
+		errs           error
+	)
+	if errs = Validate.Struct(hr); errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			validateErrors = append(validateErrors, PreDefinedValidateErrors[err.Namespace()])
+		}
+	}
+
+	if err := ValidateToken(hr.DB, hr.IP, true, hr.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("HistoryRead.Token", err))
+	}
+
+	if err := ValidateFile(hr.DB, hr.File); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("HistoryRead.File", err))
+	} else {
+		if err := hr.File.CanBeAccessedByToken(hr.Token, hr.DB); err != nil {
+			validateErrors = append(validateErrors, generateErrorByField("HistoryRead.Token", err))
+		}
+		if err := ValidateHistory(hr.DB, hr.File, hr.History); err != nil {
+			validateErrors = append(validateErrors, generateErrorByField("HistoryRead.History", err))
+		}
+	}
+
+	return validateErrors
+}
+
+// Execute is used to read the content of history
+func (hr *HistoryRead) Execute(ctx context.Context) (interface{}, error) {
+	var (
+		err    error
+		reader models.ObjectReader
+	)
+
+	hr.BaseService.Before = append(hr.BaseService.After, func(ctx context.Context, service Service) error {
+		h := service.(*HistoryRead)
+		return h.Token.UpdateAvailableTimes(-1, h.DB)
+	})
+
+	if err = hr.CallBefore(ctx, hr); err != nil {
+		return nil, err
+	}
+
+	if reader, err = hr.History.Reader(hr.RootPath, hr.DB); err != nil {
+		return nil, err
+	}
+
+	if hr.CallAfter(ctx, hr) != nil {
+		_ = reader.Close()
+		return nil, err
+	}
+
+	return reader, nil
+}
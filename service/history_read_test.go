@@ -0,0 +1,88 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryRead_Validate(t *testing.T) {
+	var historyReadSrv = &HistoryRead{}
+
+	confirm := assert.New(t)
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	confirm.Nil(err)
+	defer down(t)
+	historyReadSrv.DB = trx
+
+	errValidate := historyReadSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.True(errValidate.ContainsErrCode(10060))
+	confirm.True(errValidate.ContainsErrCode(10061))
+	confirm.True(errValidate.ContainsErrCode(10062))
+
+	// history of another file
+	tempDir := models.NewTempDirForTest()
+	defer os.RemoveAll(tempDir)
+	file, err := models.CreateFileFromReader(&token.App, "/test/random.bytes", bytes.NewReader(models.Random(6)), int8(0), &tempDir, trx)
+	confirm.Nil(err)
+	confirm.Nil(file.OverWriteFromReader(bytes.NewReader(models.Random(8)), int8(0), &tempDir, trx))
+	_, histories, err := file.FindHistories(0, 1, trx)
+	confirm.Nil(err)
+	other, err := models.CreateFileFromReader(&token.App, "/test/other.bytes", bytes.NewReader(models.Random(6)), int8(0), &tempDir, trx)
+	confirm.Nil(err)
+
+	historyReadSrv.Token = token
+	historyReadSrv.File = other
+	historyReadSrv.History = &histories[0]
+	errValidate = historyReadSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.True(errValidate.ContainsErrCode(10062))
+	confirm.Contains(errValidate.Error(), models.ErrHistoryNotBelongToFile.Error())
+}
+
+func TestHistoryRead_Execute(t *testing.T) {
+	tempDir := models.NewTempDirForTest()
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	content := models.Random(256)
+	file, err := models.CreateFileFromReader(&token.App, "/test/random.bytes", bytes.NewReader(content), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, file.OverWriteFromReader(bytes.NewReader(models.Random(10)), int8(0), &tempDir, trx))
+	_, histories, err := file.FindHistories(0, 1, trx)
+	assert.Nil(t, err)
+
+	historyReadSrv := &HistoryRead{
+		BaseService: BaseService{
+			DB:       trx,
+			RootPath: &tempDir,
+		},
+		Token:   token,
+		File:    file,
+		History: &models.History{ID: histories[0].ID},
+	}
+	assert.Nil(t, historyReadSrv.Validate())
+	historyReadValue, err := historyReadSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	readContent, err := ioutil.ReadAll(historyReadValue.(io.Reader))
+	assert.Nil(t, err)
+	assert.Equal(t, content, readContent)
+}
@@ -0,0 +1,85 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// HistoryRestore is used to restore the content of file to a previous version.
+// The current content becomes a history, so restoring can be undone as well.
+type HistoryRestore struct {
+	BaseService
+
+	Token   *models.Token   `validate:"required"`
+	File    *models.File    `validate:"required"`
+	History *models.History `validate:"required"`
+	IP      *string         `validate:"omitempty"`
+}
+
+// Validate is used to validate service params
+func (hr *HistoryRestore) Validate() ValidateErrors {
+	var (
+		validateErrors ValidateErrors
+		errs           error
+	)
+	if errs = Validate.Struct(hr); errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			validateErrors = append(validateErrors, PreDefinedValidateErrors[err.Namespace()])
+		}
+	}
+
+	if err := ValidateToken(hr.DB, hr.IP, false, hr.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("HistoryRestore.Token", err))
+	}
+
+	if err := ValidateFile(hr.DB, hr.File); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("HistoryRestore.File", err))
+	} else {
+		if err := hr.File.CanBeAccessedByToken(hr.Token, hr.DB); err != nil {
+			validateErrors = append(validateErrors, generateErrorByField("HistoryRestore.Token", err))
+		}
+		if err := ValidateHistory(hr.DB, hr.File, hr.History); err != nil {
+			validateErrors = append(validateErrors, generateErrorByField("HistoryRestore.History", err))
+		}
+	}
+
+	return validateErrors
+}
+
+// Execute is used to restore file to the version of history
+func (hr *HistoryRestore) Execute(ctx context.Context) (interface{}, error) {
+	var err error
+
+	hr.BaseService.Before = append(hr.BaseService.After, func(ctx context.Context, service Service) error {
+		h := service.(*HistoryRestore)
+		return h.Token.UpdateAvailableTimes(-1, h.DB)
+	})
+
+	if err = hr.CallBefore(ctx, hr); err != nil {
+		return nil, err
+	}
+
+	// the size of file is changed by restoring, the quotas are checked in the
+	// same transaction, so that the restoring is rolled back if exceeded
+	if err = hr.transaction(func() error {
+		var size = hr.File.Size
+		if err = hr.File.RestoreHistory(hr.History, hr.DB); err != nil {
+			return err
+		}
+		return ValidateUsedQuota(hr.DB, hr.Token, hr.File.Size-size, 0, "HistoryRestore.Quota")
+	}); err != nil {
+		return nil, err
+	}
+
+	if hr.CallAfter(ctx, hr) != nil {
+		return hr.File, err
+	}
+
+	return hr.File, nil
+}
@@ -0,0 +1,105 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryRestore_Validate(t *testing.T) {
+	var historyRestoreSrv = &HistoryRestore{}
+
+	confirm := assert.New(t)
+	_, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	confirm.Nil(err)
+	defer down(t)
+	historyRestoreSrv.DB = trx
+
+	errValidate := historyRestoreSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.True(errValidate.ContainsErrCode(10063))
+	confirm.True(errValidate.ContainsErrCode(10064))
+	confirm.True(errValidate.ContainsErrCode(10065))
+}
+
+func TestHistoryRestore_Execute(t *testing.T) {
+	tempDir := models.NewTempDirForTest()
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	file, err := models.CreateFileFromReader(&token.App, "/test/random.bytes", bytes.NewReader(models.Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	previousObjectID := file.ObjectID
+	assert.Nil(t, file.OverWriteFromReader(bytes.NewReader(models.Random(10)), int8(0), &tempDir, trx))
+	_, histories, err := file.FindHistories(0, 1, trx)
+	assert.Nil(t, err)
+
+	historyRestoreSrv := &HistoryRestore{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token:   token,
+		File:    file,
+		History: &models.History{ID: histories[0].ID},
+	}
+	assert.Nil(t, historyRestoreSrv.Validate())
+	historyRestoreValue, err := historyRestoreSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	restored := historyRestoreValue.(*models.File)
+	assert.Equal(t, previousObjectID, restored.ObjectID)
+	assert.Equal(t, 256, restored.Size)
+
+	total, _, err := file.FindHistories(0, 1, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, total)
+}
+
+func TestHistoryRestore_Execute2(t *testing.T) {
+	tempDir := models.NewTempDirForTest()
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	token.AvailableTimes = 1000
+	assert.Nil(t, trx.Save(token).Error)
+
+	file, err := models.CreateFileFromReader(&token.App, "/test/random.bytes", bytes.NewReader(models.Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, file.OverWriteFromReader(bytes.NewReader(models.Random(10)), int8(0), &tempDir, trx))
+	_, histories, err := file.FindHistories(0, 1, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, token.App.SetQuota(100, 0, trx))
+
+	historyRestoreSrv := &HistoryRestore{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token:   token,
+		File:    file,
+		History: &models.History{ID: histories[0].ID},
+	}
+	assert.Nil(t, historyRestoreSrv.Validate())
+	_, err = historyRestoreSrv.Execute(context.TODO())
+	assert.NotNil(t, err)
+	assert.True(t, err.(ValidateErrors).ContainsErrCode(10099))
+}
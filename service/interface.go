@@ -9,6 +9,7 @@ package service
 import (
 	"context"
 
+	"github.com/bigfile/bigfile/databases/models"
 	"github.com/jinzhu/gorm"
 )
 
@@ -86,3 +87,15 @@ func (b *BaseService) Execute(ctx context.Context) (interface{}, error) {
 func (b *BaseService) Validate() ValidateErrors {
 	return nil
 }
+
+// transaction run fn in a database transaction, DB is replaced by the transaction
+// while fn is running, so that all changes made by fn are committed or rolled
+// back together
+func (b *BaseService) transaction(fn func() error) error {
+	var db = b.DB
+	defer func() { b.DB = db }()
+	return models.Transaction(db, func(tx *gorm.DB) error {
+		b.DB = tx
+		return fn()
+	})
+}
@@ -6,9 +6,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/bigfile/bigfile/databases"
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -53,3 +57,24 @@ func TestBaseService_Execute(t *testing.T) {
 func TestBaseService_Validate(t *testing.T) {
 	assert.Nil(t, (&BaseService{}).Validate())
 }
+
+func TestBaseService_transaction(t *testing.T) {
+	var (
+		db          = databases.MustNewConnection(nil)
+		baseService = &BaseService{DB: db}
+		app         *models.App
+		errFail     = errors.New("fail")
+	)
+	err := baseService.transaction(func() error {
+		var err error
+		assert.NotEqual(t, db, baseService.DB)
+		if app, err = models.NewApp("transaction", nil, baseService.DB); err != nil {
+			return err
+		}
+		return errFail
+	})
+	assert.Equal(t, errFail, err)
+	assert.Equal(t, db, baseService.DB)
+	_, err = models.FindAppByUID(app.UID, db)
+	assert.True(t, util.IsRecordNotFound(err))
+}
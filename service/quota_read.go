@@ -0,0 +1,77 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// QuotaRead is used to read the current usage and the limits of the application
+// and the token
+type QuotaRead struct {
+	BaseService
+
+	Token *models.Token `validate:"required"`
+	IP    *string       `validate:"omitempty"`
+}
+
+// QuotaReadValue represent the result of QuotaRead
+type QuotaReadValue struct {
+	App   *models.Quota
+	Token *models.Quota
+}
+
+// Validate is used to validate service params
+func (qr *QuotaRead) Validate() ValidateErrors {
+	var (
+		validateErrors ValidateErrors
+		errs           error
+	)
+	if errs = Validate.Struct(qr); errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			validateErrors = append(validateErrors, PreDefinedValidateErrors[err.Namespace()])
+		}
+	}
+
+	if err := ValidateToken(qr.DB, qr.IP, true, qr.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("QuotaRead.Token", err))
+	}
+
+	return validateErrors
+}
+
+// Execute is used to read the quotas
+func (qr *QuotaRead) Execute(ctx context.Context) (interface{}, error) {
+	var (
+		err   error
+		value = &QuotaReadValue{}
+	)
+
+	qr.BaseService.Before = append(qr.BaseService.After, func(ctx context.Context, service Service) error {
+		q := service.(*QuotaRead)
+		return q.Token.UpdateAvailableTimes(-1, q.DB)
+	})
+
+	if err = qr.CallBefore(ctx, qr); err != nil {
+		return nil, err
+	}
+
+	if value.App, err = qr.Token.App.Quota(qr.DB); err != nil {
+		return nil, err
+	}
+
+	if value.Token, err = qr.Token.Quota(qr.DB); err != nil {
+		return nil, err
+	}
+
+	if qr.CallAfter(ctx, qr) != nil {
+		return value, err
+	}
+
+	return value, nil
+}
@@ -0,0 +1,60 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaRead_Validate(t *testing.T) {
+	var quotaReadSrv = &QuotaRead{}
+
+	trx, down := models.SetUpTestCaseWithTrx(nil, t)
+	defer down(t)
+	quotaReadSrv.DB = trx
+
+	errValidate := quotaReadSrv.Validate()
+	assert.NotNil(t, errValidate)
+	assert.True(t, errValidate.ContainsErrCode(10078))
+}
+
+func TestQuotaRead_Execute(t *testing.T) {
+	tempDir := models.NewTempDirForTest()
+	token, trx, down, err := models.NewTokenForTest(nil, t, "/quota", nil, nil, nil, -1, 0)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	_, err = models.CreateFileFromReader(&token.App, "/quota/random.bytes", bytes.NewReader(models.Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	_, err = models.CreateFileFromReader(&token.App, "/other/random.bytes", bytes.NewReader(models.Random(128)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, token.SetQuota(1024, 10, trx))
+	assert.Nil(t, token.App.SetQuota(2048, 0, trx))
+
+	quotaReadSrv := &QuotaRead{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token: token,
+	}
+	assert.Nil(t, quotaReadSrv.Validate())
+	quotaReadValue, err := quotaReadSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	value := quotaReadValue.(*QuotaReadValue)
+	assert.Equal(t, models.Quota{MaxSize: 2048, MaxFiles: 0, Size: 384, Files: 2}, *value.App)
+	assert.Equal(t, models.Quota{MaxSize: 1024, MaxFiles: 10, Size: 256, Files: 1}, *value.Token)
+}
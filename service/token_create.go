@@ -23,6 +23,8 @@ type TokenCreate struct {
 	ReadOnly       int8        `validate:"oneof=0 1"`
 	ExpiredAt      *time.Time  `validate:"omitempty,gt"`
 	AvailableTimes int         `validate:"omitempty,gte=-1,max=2147483647"`
+	MaxSize        int         `validate:"min=0"`
+	MaxFiles       int         `validate:"min=0"`
 
 	token *models.Token
 }
@@ -66,6 +68,12 @@ func (t *TokenCreate) Execute(ctx context.Context) (interface{}, error) {
 		return nil, err
 	}
 
+	if t.MaxSize > 0 || t.MaxFiles > 0 {
+		if err = t.token.SetQuota(t.MaxSize, t.MaxFiles, t.DB); err != nil {
+			return nil, err
+		}
+	}
+
 	if t.CallAfter(ctx, t) != nil {
 		return t.token, err
 	}
@@ -58,6 +58,8 @@ func TestTokenCreate_Validate(t *testing.T) {
 		Path:           "",
 		IP:             &ip,
 		ReadOnly:       2,
+		MaxSize:        -1,
+		MaxFiles:       -1,
 	}
 	err = tokenCreate.Validate()
 	confirm.NotNil(err)
@@ -66,6 +68,8 @@ func TestTokenCreate_Validate(t *testing.T) {
 	confirm.True(err.ContainsErrCode(10006))
 	confirm.True(err.ContainsErrCode(10004))
 	confirm.True(err.ContainsErrCode(10007))
+	confirm.True(err.ContainsErrCode(10074))
+	confirm.True(err.ContainsErrCode(10075))
 }
 
 func TestTokenCreate_Validate2(t *testing.T) {
@@ -103,6 +107,8 @@ func TestTokenCreate_Execute(t *testing.T) {
 	)
 	tokenCreate, app, down = newTokenCreateForTest(t)
 	defer down(t)
+	tokenCreate.MaxSize = 1024
+	tokenCreate.MaxFiles = 10
 	err = tokenCreate.Validate()
 	assert.Nil(t, err)
 	tokenValue, err = tokenCreate.Execute(context.TODO())
@@ -111,4 +117,6 @@ func TestTokenCreate_Execute(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, app.ID, token.App.ID)
 	assert.True(t, token.ID > 0)
+	assert.Equal(t, 1024, token.MaxSize)
+	assert.Equal(t, 10, token.MaxFiles)
 }
@@ -23,6 +23,8 @@ type TokenUpdate struct {
 	ReadOnly       *int8      `validate:"omitempty,oneof=0 1"`
 	ExpiredAt      *time.Time `validate:"omitempty,gt"`
 	AvailableTimes *int       `validate:"omitempty,gte=-1,max=2147483647"`
+	MaxSize        *int       `validate:"omitempty,min=0"`
+	MaxFiles       *int       `validate:"omitempty,min=0"`
 }
 
 // Validate is used to validate input params
@@ -81,6 +83,12 @@ func (t *TokenUpdate) Execute(ctx context.Context) (result interface{}, err erro
 	if t.AvailableTimes != nil {
 		token.AvailableTimes = *t.AvailableTimes
 	}
+	if t.MaxSize != nil {
+		token.MaxSize = *t.MaxSize
+	}
+	if t.MaxFiles != nil {
+		token.MaxFiles = *t.MaxFiles
+	}
 
 	if t.DB.Save(token).Error != nil {
 		return nil, err
@@ -27,6 +27,7 @@ func TestTokenUpdate_Validate(t *testing.T) {
 		aSecondAgo     = time.Now().Add(time.Duration(-1 * int64(time.Second)))
 		readOnly       = int8(3)
 		availableTimes = -2
+		maxSize        = -1
 	)
 	tokenUpdate.IP = &ip
 	tokenUpdate.Path = &path
@@ -34,6 +35,8 @@ func TestTokenUpdate_Validate(t *testing.T) {
 	tokenUpdate.Secret = &secret
 	tokenUpdate.ReadOnly = &readOnly
 	tokenUpdate.AvailableTimes = &availableTimes
+	tokenUpdate.MaxSize = &maxSize
+	tokenUpdate.MaxFiles = &maxSize
 	err = tokenUpdate.Validate()
 	confirm.NotNil(err)
 	confirm.True(err.ContainsErrCode(10008))
@@ -43,6 +46,8 @@ func TestTokenUpdate_Validate(t *testing.T) {
 	confirm.True(err.ContainsErrCode(10012))
 	confirm.True(err.ContainsErrCode(10013))
 	confirm.True(err.ContainsErrCode(10014))
+	confirm.True(err.ContainsErrCode(10076))
+	confirm.True(err.ContainsErrCode(10077))
 	confirm.Contains(err.Error(), "path is not a legal unix path")
 }
 
@@ -75,6 +80,8 @@ func TestTokenUpdate_Execute(t *testing.T) {
 			aSecondAgo     = time.Now().Add(time.Hour)
 			readOnly       = int8(1)
 			availableTimes = 1000
+			maxSize        = 1024
+			maxFiles       = 10
 		)
 		tokenUpdate.IP = &ip
 		tokenUpdate.Path = &path
@@ -82,6 +89,8 @@ func TestTokenUpdate_Execute(t *testing.T) {
 		tokenUpdate.Secret = &secret
 		tokenUpdate.ReadOnly = &readOnly
 		tokenUpdate.AvailableTimes = &availableTimes
+		tokenUpdate.MaxSize = &maxSize
+		tokenUpdate.MaxFiles = &maxFiles
 		err = tokenUpdate.Validate()
 		confirm.Nil(err)
 		if to, err := tokenUpdate.Execute(context.TODO()); err != nil {
@@ -95,6 +104,8 @@ func TestTokenUpdate_Execute(t *testing.T) {
 			confirm.Equal(*token.Secret, secret)
 			confirm.Equal(token.Path, path)
 			confirm.Equal(*token.IP, ip)
+			confirm.Equal(maxSize, token.MaxSize)
+			confirm.Equal(maxFiles, token.MaxFiles)
 		}
 	}
 }
@@ -0,0 +1,76 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// TrashList is used to list the files in trash, only the files under the
+// scope of token will be listed.
+type TrashList struct {
+	BaseService
+
+	Token  *models.Token `validate:"required"`
+	IP     *string       `validate:"omitempty"`
+	Offset int           `validate:"min=0"`
+	Limit  int           `validate:"min=1,max=100"`
+}
+
+// TrashListValue represent the result of TrashList
+type TrashListValue struct {
+	Total int
+	Files []models.File
+}
+
+// Validate is used to validate service params
+func (tl *TrashList) Validate() ValidateErrors {
+	var (
+		validateErrors ValidateErrors
+		errs           error
+	)
+	if errs = Validate.Struct(tl); errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			validateErrors = append(validateErrors, PreDefinedValidateErrors[err.Namespace()])
+		}
+	}
+
+	if err := ValidateToken(tl.DB, tl.IP, true, tl.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("TrashList.Token", err))
+	}
+
+	return validateErrors
+}
+
+// Execute is used to list files in trash
+func (tl *TrashList) Execute(ctx context.Context) (interface{}, error) {
+	var (
+		err   error
+		value = &TrashListValue{}
+	)
+
+	tl.BaseService.Before = append(tl.BaseService.After, func(ctx context.Context, service Service) error {
+		t := service.(*TrashList)
+		return t.Token.UpdateAvailableTimes(-1, t.DB)
+	})
+
+	if err = tl.CallBefore(ctx, tl); err != nil {
+		return nil, err
+	}
+
+	if value.Total, value.Files, err = models.FindTrashedFiles(
+		&tl.Token.App, tl.Token.Path, tl.Offset, tl.Limit, tl.DB); err != nil {
+		return nil, err
+	}
+
+	if tl.CallAfter(ctx, tl) != nil {
+		return value, err
+	}
+
+	return value, nil
+}
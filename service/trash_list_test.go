@@ -0,0 +1,68 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashList_Validate(t *testing.T) {
+	trx, down := models.SetUpTestCaseWithTrx(nil, t)
+	defer down(t)
+	trashListSrv := &TrashList{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token:  nil,
+		Offset: -1,
+		Limit:  101,
+	}
+	errValidate := trashListSrv.Validate()
+	confirm := assert.New(t)
+	confirm.NotNil(errValidate)
+	confirm.True(errValidate.ContainsErrCode(10031))
+	confirm.True(errValidate.ContainsErrCode(10032))
+	confirm.True(errValidate.ContainsErrCode(10033))
+}
+
+func TestTrashList_Execute(t *testing.T) {
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer down(t)
+
+	for _, path := range []string{"/test/a", "/test/b", "/test/c", "/another/d"} {
+		dir, err := models.CreateOrGetLastDirectory(&token.App, path, trx)
+		assert.Nil(t, err)
+		assert.Nil(t, dir.Delete(trx))
+	}
+
+	token.Path = "/test"
+	assert.Nil(t, trx.Save(token).Error)
+	trashListSrv := &TrashList{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token:  token,
+		Offset: 1,
+		Limit:  1,
+	}
+	assert.Nil(t, trashListSrv.Validate())
+	trashListValue, err := trashListSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	value, ok := trashListValue.(*TrashListValue)
+	assert.True(t, ok)
+	assert.Equal(t, 3, value.Total)
+	assert.Equal(t, 1, len(value.Files))
+	assert.Equal(t, "b", value.Files[0].Name)
+
+	trashListSrv.Offset = 3
+	trashListValue, err = trashListSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(trashListValue.(*TrashListValue).Files))
+}
@@ -0,0 +1,75 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// TrashPurge is used to delete a file in trash permanently, it can't be
+// restored anymore.
+type TrashPurge struct {
+	BaseService
+
+	Token *models.Token `validate:"required"`
+	File  *models.File  `validate:"required"`
+	IP    *string       `validate:"omitempty"`
+}
+
+// Validate is used to validate service params
+func (tp *TrashPurge) Validate() ValidateErrors {
+	var (
+		validateErrors ValidateErrors
+		errs           error
+	)
+	if errs = Validate.Struct(tp); errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			validateErrors = append(validateErrors, PreDefinedValidateErrors[err.Namespace()])
+		}
+	}
+
+	if err := ValidateToken(tp.DB, tp.IP, false, tp.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("TrashPurge.Token", err))
+	}
+
+	if err := ValidateTrashedFile(tp.DB, tp.File); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("TrashPurge.File", err))
+	} else {
+		if err := tp.File.CanBeAccessedByToken(tp.Token, tp.DB); err != nil {
+			validateErrors = append(validateErrors, generateErrorByField("TrashPurge.Token", err))
+		}
+	}
+
+	return validateErrors
+}
+
+// Execute is used to purge file
+func (tp *TrashPurge) Execute(ctx context.Context) (interface{}, error) {
+	var (
+		err error
+	)
+
+	tp.BaseService.Before = append(tp.BaseService.After, func(ctx context.Context, service Service) error {
+		t := service.(*TrashPurge)
+		return t.Token.UpdateAvailableTimes(-1, t.DB)
+	})
+
+	if err = tp.CallBefore(ctx, tp); err != nil {
+		return nil, err
+	}
+
+	if err = tp.File.Purge(tp.DB); err != nil {
+		return nil, err
+	}
+
+	if tp.CallAfter(ctx, tp) != nil {
+		return tp.File, err
+	}
+
+	return tp.File, nil
+}
@@ -0,0 +1,67 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashPurge_Validate(t *testing.T) {
+	trashPurgeSrv := &TrashPurge{
+		Token: nil,
+		File:  nil,
+	}
+
+	confirm := assert.New(t)
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	confirm.Nil(err)
+	defer down(t)
+	trashPurgeSrv.DB = trx
+
+	errValidate := trashPurgeSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.True(errValidate.ContainsErrCode(10037))
+	confirm.True(errValidate.ContainsErrCode(10038))
+
+	dir, err := models.CreateOrGetLastDirectory(&token.App, "/save/to", trx)
+	confirm.Nil(err)
+	assert.Nil(t, dir.Delete(trx))
+	token.Path = "/test"
+	confirm.Nil(trx.Save(token).Error)
+	trashPurgeSrv.Token = token
+	trashPurgeSrv.File = dir
+	errValidate = trashPurgeSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.Contains(errValidate.Error(), "file can't be accessed by some tokens")
+}
+
+func TestTrashPurge_Execute(t *testing.T) {
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer down(t)
+
+	dir, err := models.CreateOrGetLastDirectory(&token.App, "/save/to", trx)
+	assert.Nil(t, err)
+	assert.Nil(t, dir.Delete(trx))
+
+	trashPurgeSrv := &TrashPurge{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token: token,
+		File:  dir,
+	}
+	assert.Nil(t, trashPurgeSrv.Validate())
+	_, err = trashPurgeSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+
+	_, err = models.FindFileByUID(dir.UID, true, trx)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "record not found")
+}
@@ -0,0 +1,102 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// TrashRestore is used to restore a file from trash. By default, the file
+// will be restored to its original path. But, if the original path has been
+// occupied, a new path should be provided.
+type TrashRestore struct {
+	BaseService
+
+	Token *models.Token `validate:"required"`
+	File  *models.File  `validate:"required"`
+	IP    *string       `validate:"omitempty"`
+	Path  *string       `validate:"omitempty,max=1000"`
+}
+
+// Validate is used to validate service params
+func (tr *TrashRestore) Validate() ValidateErrors {
+	var (
+		validateErrors ValidateErrors
+		errs           error
+	)
+	if errs = Validate.Struct(tr); errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			validateErrors = append(validateErrors, PreDefinedValidateErrors[err.Namespace()])
+		}
+	}
+
+	if err := ValidateToken(tr.DB, tr.IP, false, tr.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("TrashRestore.Token", err))
+	}
+
+	if err := ValidateTrashedFile(tr.DB, tr.File); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("TrashRestore.File", err))
+	} else {
+		if err := tr.File.CanBeAccessedByToken(tr.Token, tr.DB); err != nil {
+			validateErrors = append(validateErrors, generateErrorByField("TrashRestore.Token", err))
+		}
+	}
+
+	if tr.Path != nil {
+		if !ValidatePath(*tr.Path) {
+			validateErrors = append(validateErrors, generateErrorByField("TrashRestore.Path", ErrInvalidPath))
+		}
+	}
+
+	return validateErrors
+}
+
+// Execute is used to restore file
+func (tr *TrashRestore) Execute(ctx context.Context) (interface{}, error) {
+	var (
+		err  error
+		path string
+	)
+
+	tr.BaseService.Before = append(tr.BaseService.After, func(ctx context.Context, service Service) error {
+		t := service.(*TrashRestore)
+		return t.Token.UpdateAvailableTimes(-1, t.DB)
+	})
+
+	if err = tr.CallBefore(ctx, tr); err != nil {
+		return nil, err
+	}
+
+	if tr.Path != nil {
+		path = tr.Token.PathWithScope(*tr.Path)
+	}
+
+	// the restored files are counted in the usage again, the quotas are checked
+	// in the same transaction, so that the restoring is rolled back if exceeded
+	if err = tr.transaction(func() error {
+		var files int
+		if err = tr.File.Restore(path, tr.DB); err != nil {
+			return err
+		}
+		if files, err = tr.File.CountFiles(tr.DB); err != nil {
+			return err
+		}
+		return ValidateUsedQuota(tr.DB, tr.Token, tr.File.Size, files, "TrashRestore.Quota")
+	}); err != nil {
+		if err == models.ErrFileExisted {
+			return nil, ErrPathExisted
+		}
+		return nil, err
+	}
+
+	if tr.CallAfter(ctx, tr) != nil {
+		return tr.File, err
+	}
+
+	return tr.File, nil
+}
@@ -0,0 +1,124 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashRestore_Validate(t *testing.T) {
+	var (
+		path            = "/!!!/file"
+		trashRestoreSrv = &TrashRestore{
+			Token: nil,
+			File:  nil,
+			Path:  &path,
+		}
+	)
+
+	confirm := assert.New(t)
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	confirm.Nil(err)
+	defer down(t)
+	trashRestoreSrv.DB = trx
+
+	errValidate := trashRestoreSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.True(errValidate.ContainsErrCode(10034))
+	confirm.True(errValidate.ContainsErrCode(10035))
+	confirm.True(errValidate.ContainsErrCode(10036))
+
+	dir, err := models.CreateOrGetLastDirectory(&token.App, "/save/to", trx)
+	confirm.Nil(err)
+	trashRestoreSrv.Token = token
+	trashRestoreSrv.File = dir
+	trashRestoreSrv.Path = nil
+	errValidate = trashRestoreSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.Contains(errValidate.Error(), models.ErrFileNotInTrash.Error())
+}
+
+func TestTrashRestore_Execute(t *testing.T) {
+	tempDir := models.NewTempDirForTest()
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	file, err := models.CreateFileFromReader(&token.App, "/test/random.bytes", bytes.NewReader(models.Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, file.Delete(trx))
+	_, err = models.CreateFileFromReader(&token.App, "/test/random.bytes", bytes.NewReader(models.Random(6)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+
+	trashRestoreSrv := &TrashRestore{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token: token,
+		File:  file,
+	}
+	assert.Nil(t, trashRestoreSrv.Validate())
+	_, err = trashRestoreSrv.Execute(context.TODO())
+	assert.Equal(t, ErrPathExisted, err)
+
+	path := "/test/restored.bytes"
+	trashRestoreSrv.Path = &path
+	trashRestoreValue, err := trashRestoreSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	restored, ok := trashRestoreValue.(*models.File)
+	assert.True(t, ok)
+	restoredPath, err := restored.Path(trx)
+	assert.Nil(t, err)
+	assert.Equal(t, path, restoredPath)
+
+	rootDir, err := models.CreateOrGetRootPath(&token.App, trx)
+	assert.Nil(t, err)
+	assert.Equal(t, 262, rootDir.Size)
+}
+
+func TestTrashRestore_Execute2(t *testing.T) {
+	tempDir := models.NewTempDirForTest()
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	token.AvailableTimes = 1000
+	assert.Nil(t, trx.Save(token).Error)
+
+	file, err := models.CreateFileFromReader(&token.App, "/test/random.bytes", bytes.NewReader(models.Random(256)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+	assert.Nil(t, file.Delete(trx))
+	_, err = models.CreateFileFromReader(&token.App, "/test/existed.bytes", bytes.NewReader(models.Random(6)), int8(0), &tempDir, trx)
+	assert.Nil(t, err)
+
+	trashRestoreSrv := &TrashRestore{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token: token,
+		File:  file,
+	}
+	assert.Nil(t, token.App.SetQuota(0, 1, trx))
+	_, err = trashRestoreSrv.Execute(context.TODO())
+	assert.NotNil(t, err)
+	assert.True(t, err.(ValidateErrors).ContainsErrCode(10098))
+}
@@ -0,0 +1,96 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+	"io"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// UploadAppend is used to append content to upload session at offset. When
+// the last byte arrives, the session turns into a file.
+type UploadAppend struct {
+	BaseService
+
+	Token   *models.Token         `validate:"required"`
+	Session *models.UploadSession `validate:"required"`
+	IP      *string               `validate:"omitempty"`
+	Offset  int                   `validate:"min=0"`
+	Reader  io.Reader             `validate:"required"`
+}
+
+// UploadAppendValue represent the result of UploadAppend, File isn't nil only
+// if the upload is completed.
+type UploadAppendValue struct {
+	Session *models.UploadSession
+	File    *models.File
+}
+
+// Validate is used to validate service params
+func (ua *UploadAppend) Validate() ValidateErrors {
+	var (
+		validateErrors ValidateErrors
+		errs           error
+	)
+	if errs = Validate.Struct(ua); errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			validateErrors = append(validateErrors, PreDefinedValidateErrors[err.Namespace()])
+		}
+	}
+
+	if err := ValidateToken(ua.DB, ua.IP, false, ua.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("UploadAppend.Token", err))
+	}
+
+	if err := ValidateUploadSession(ua.DB, ua.Session); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("UploadAppend.Session", err))
+	} else if err := ua.Session.CanBeAccessedByToken(ua.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("UploadAppend.Token", err))
+	}
+
+	return validateErrors
+}
+
+// Execute is used to append content to upload session
+func (ua *UploadAppend) Execute(ctx context.Context) (interface{}, error) {
+	var (
+		err   error
+		value = &UploadAppendValue{Session: ua.Session}
+	)
+
+	ua.BaseService.Before = append(ua.BaseService.After, func(ctx context.Context, service Service) error {
+		u := service.(*UploadAppend)
+		return u.Token.UpdateAvailableTimes(-1, u.DB)
+	})
+
+	if err = ua.CallBefore(ctx, ua); err != nil {
+		return nil, err
+	}
+
+	if _, err = ua.Session.AppendFromReader(ua.Offset, ua.Reader, ua.RootPath, ua.DB); err != nil {
+		return value, err
+	}
+
+	if ua.Session.Completed() {
+		if err = ua.transaction(func() error {
+			if value.File, err = ua.Session.Complete(ua.RootPath, ua.DB); err != nil {
+				return err
+			}
+			return ValidateUsedQuota(ua.DB, ua.Token, value.File.Size, 1, "UploadAppend.Quota")
+		}); err != nil {
+			value.File = nil
+			return value, err
+		}
+	}
+
+	if ua.CallAfter(ctx, ua) != nil {
+		return value, err
+	}
+
+	return value, nil
+}
@@ -0,0 +1,128 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/bigfile/bigfile/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadAppend_Validate(t *testing.T) {
+	var uploadAppendSrv = &UploadAppend{Offset: -1}
+
+	confirm := assert.New(t)
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	confirm.Nil(err)
+	defer down(t)
+	uploadAppendSrv.DB = trx
+
+	errValidate := uploadAppendSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.True(errValidate.ContainsErrCode(10052))
+	confirm.True(errValidate.ContainsErrCode(10053))
+	confirm.True(errValidate.ContainsErrCode(10054))
+	confirm.True(errValidate.ContainsErrCode(10055))
+
+	session, err := models.NewUploadSession(&token.App, "/upload/random.bytes", 1024, int8(0), trx)
+	confirm.Nil(err)
+	confirm.Nil(trx.Model(session).Update("expiredAt", time.Now().Add(-time.Second)).Error)
+	uploadAppendSrv.Token = token
+	uploadAppendSrv.Session = session
+	uploadAppendSrv.Offset = 0
+	uploadAppendSrv.Reader = bytes.NewReader(models.Random(10))
+	errValidate = uploadAppendSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.Contains(errValidate.Error(), models.ErrUploadSessionExpired.Error())
+}
+
+func TestUploadAppend_Execute(t *testing.T) {
+	tempDir := models.NewTempDirForTest()
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	content := models.Random(256)
+	session, err := models.NewUploadSession(&token.App, "/upload/random.bytes", len(content), int8(0), trx)
+	assert.Nil(t, err)
+	uploadAppendSrv := &UploadAppend{
+		BaseService: BaseService{
+			DB:       trx,
+			RootPath: &tempDir,
+		},
+		Token:   token,
+		Session: session,
+		Offset:  0,
+		Reader:  bytes.NewReader(content[:100]),
+	}
+	assert.Nil(t, uploadAppendSrv.Validate())
+	uploadAppendValue, err := uploadAppendSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	value := uploadAppendValue.(*UploadAppendValue)
+	assert.Equal(t, 100, value.Session.Offset)
+	assert.Nil(t, value.File)
+
+	// offset doesn't match
+	uploadAppendSrv.Reader = bytes.NewReader(content[100:])
+	_, err = uploadAppendSrv.Execute(context.TODO())
+	assert.Equal(t, models.ErrUploadOffsetMismatch, err)
+
+	uploadAppendSrv.Offset = 100
+	uploadAppendValue, err = uploadAppendSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	value = uploadAppendValue.(*UploadAppendValue)
+	assert.NotNil(t, value.File)
+	assert.Equal(t, len(content), value.File.Size)
+	hash, err := util.Sha256Hash2String(content)
+	assert.Nil(t, err)
+	assert.Equal(t, hash, value.File.Object.Hash)
+
+	file, err := models.FindFileByPath(&token.App, "/upload/random.bytes", trx)
+	assert.Nil(t, err)
+	assert.Equal(t, value.File.ID, file.ID)
+}
+
+func TestUploadAppend_Execute2(t *testing.T) {
+	tempDir := models.NewTempDirForTest()
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer func() {
+		down(t)
+		if util.IsDir(tempDir) {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	content := models.Random(256)
+	session, err := models.NewUploadSession(&token.App, "/upload/random.bytes", len(content), int8(0), trx)
+	assert.Nil(t, err)
+	// the quota is changed after the session is created
+	assert.Nil(t, token.App.SetQuota(100, 0, trx))
+	uploadAppendSrv := &UploadAppend{
+		BaseService: BaseService{
+			DB:       trx,
+			RootPath: &tempDir,
+		},
+		Token:   token,
+		Session: session,
+		Offset:  0,
+		Reader:  bytes.NewReader(content),
+	}
+	uploadAppendValue, err := uploadAppendSrv.Execute(context.TODO())
+	assert.NotNil(t, err)
+	assert.True(t, err.(ValidateErrors).ContainsErrCode(10097))
+	assert.Nil(t, uploadAppendValue.(*UploadAppendValue).File)
+}
@@ -0,0 +1,84 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// UploadCreate is used to create a resumable upload session, the content
+// is uploaded by UploadAppend later. The path is relative to the path of token.
+type UploadCreate struct {
+	BaseService
+
+	Token  *models.Token `validate:"required"`
+	IP     *string       `validate:"omitempty"`
+	Path   string        `validate:"required,max=1000"`
+	Size   int           `validate:"min=0"`
+	Hidden int8          `validate:"oneof=0 1"`
+}
+
+// Validate is used to validate service params
+func (uc *UploadCreate) Validate() ValidateErrors {
+	var (
+		validateErrors ValidateErrors
+		errs           error
+	)
+	if errs = Validate.Struct(uc); errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			validateErrors = append(validateErrors, PreDefinedValidateErrors[err.Namespace()])
+		}
+	}
+
+	if err := ValidateToken(uc.DB, uc.IP, false, uc.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("UploadCreate.Token", err))
+	}
+
+	if !ValidatePath(uc.Path) {
+		validateErrors = append(validateErrors, generateErrorByField("UploadCreate.Path", ErrInvalidPath))
+	}
+
+	return validateErrors
+}
+
+// Execute is used to create an upload session
+func (uc *UploadCreate) Execute(ctx context.Context) (interface{}, error) {
+	var (
+		err     error
+		session *models.UploadSession
+	)
+
+	uc.BaseService.Before = append(uc.BaseService.After, func(ctx context.Context, service Service) error {
+		u := service.(*UploadCreate)
+		return u.Token.UpdateAvailableTimes(-1, u.DB)
+	})
+
+	if err = uc.CallBefore(ctx, uc); err != nil {
+		return nil, err
+	}
+
+	// the size of session is checked before it's uploaded, the actual size is
+	// checked again when the session is completed
+	if err = models.CheckQuota(uc.Token, uc.Size, 1, uc.DB); err != nil {
+		if err == models.ErrQuotaExceeded {
+			err = ValidateErrors{generateErrorByField("UploadCreate.Quota", err)}
+		}
+		return nil, err
+	}
+
+	if session, err = models.NewUploadSession(
+		&uc.Token.App, uc.Token.PathWithScope(uc.Path), uc.Size, uc.Hidden, uc.DB); err != nil {
+		return nil, err
+	}
+
+	if uc.CallAfter(ctx, uc) != nil {
+		return session, err
+	}
+
+	return session, nil
+}
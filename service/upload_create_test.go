@@ -0,0 +1,81 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadCreate_Validate(t *testing.T) {
+	var (
+		uploadCreateSrv = &UploadCreate{
+			Token:  nil,
+			Path:   "/!!!/file",
+			Size:   -1,
+			Hidden: 2,
+		}
+	)
+
+	confirm := assert.New(t)
+	_, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	confirm.Nil(err)
+	defer down(t)
+	uploadCreateSrv.DB = trx
+
+	errValidate := uploadCreateSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.True(errValidate.ContainsErrCode(10046))
+	confirm.True(errValidate.ContainsErrCode(10047))
+	confirm.True(errValidate.ContainsErrCode(10048))
+	confirm.True(errValidate.ContainsErrCode(10049))
+}
+
+func TestUploadCreate_Execute(t *testing.T) {
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer down(t)
+
+	token.Path = "/scope"
+	assert.Nil(t, trx.Save(token).Error)
+
+	uploadCreateSrv := &UploadCreate{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token: token,
+		Path:  "/upload/random.bytes",
+		Size:  1024,
+	}
+	assert.Nil(t, uploadCreateSrv.Validate())
+	uploadCreateValue, err := uploadCreateSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	session := uploadCreateValue.(*models.UploadSession)
+	assert.Equal(t, "/scope/upload/random.bytes", session.Path)
+	assert.Equal(t, 1024, session.Size)
+	assert.Equal(t, 0, session.Offset)
+}
+
+func TestUploadCreate_Execute2(t *testing.T) {
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer down(t)
+
+	assert.Nil(t, token.SetQuota(1000, 0, trx))
+	uploadCreateSrv := &UploadCreate{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token: token,
+		Path:  "/upload/random.bytes",
+		Size:  1024,
+	}
+	_, err = uploadCreateSrv.Execute(context.TODO())
+	assert.NotNil(t, err)
+	assert.True(t, err.(ValidateErrors).ContainsErrCode(10096))
+}
@@ -0,0 +1,67 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// UploadOffset is used to get the offset of upload session, the client
+// should continue to upload from the offset.
+type UploadOffset struct {
+	BaseService
+
+	Token   *models.Token         `validate:"required"`
+	Session *models.UploadSession `validate:"required"`
+	IP      *string               `validate:"omitempty"`
+}
+
+// Validate is used to validate service params
+func (uo *UploadOffset) Validate() ValidateErrors {
+	var (
+		validateErrors ValidateErrors
+		errs           error
+	)
+	if errs = Validate.Struct(uo); errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			validateErrors = append(validateErrors, PreDefinedValidateErrors[err.Namespace()])
+		}
+	}
+
+	if err := ValidateToken(uo.DB, uo.IP, false, uo.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("UploadOffset.Token", err))
+	}
+
+	if err := ValidateUploadSession(uo.DB, uo.Session); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("UploadOffset.Session", err))
+	} else if err := uo.Session.CanBeAccessedByToken(uo.Token); err != nil {
+		validateErrors = append(validateErrors, generateErrorByField("UploadOffset.Token", err))
+	}
+
+	return validateErrors
+}
+
+// Execute is used to get the upload session
+func (uo *UploadOffset) Execute(ctx context.Context) (interface{}, error) {
+	var err error
+
+	uo.BaseService.Before = append(uo.BaseService.After, func(ctx context.Context, service Service) error {
+		u := service.(*UploadOffset)
+		return u.Token.UpdateAvailableTimes(-1, u.DB)
+	})
+
+	if err = uo.CallBefore(ctx, uo); err != nil {
+		return nil, err
+	}
+
+	if uo.CallAfter(ctx, uo) != nil {
+		return uo.Session, err
+	}
+
+	return uo.Session, nil
+}
@@ -0,0 +1,59 @@
+//   Copyright 2019 The bigfile Authors. All rights reserved.
+//   Use of this source code is governed by a MIT-style
+//   license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bigfile/bigfile/databases/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadOffset_Validate(t *testing.T) {
+	var uploadOffsetSrv = &UploadOffset{}
+
+	confirm := assert.New(t)
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	confirm.Nil(err)
+	defer down(t)
+	uploadOffsetSrv.DB = trx
+
+	errValidate := uploadOffsetSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.True(errValidate.ContainsErrCode(10050))
+	confirm.True(errValidate.ContainsErrCode(10051))
+
+	session, err := models.NewUploadSession(&token.App, "/upload/random.bytes", 1024, int8(0), trx)
+	confirm.Nil(err)
+	token.Path = "/other"
+	confirm.Nil(trx.Save(token).Error)
+	uploadOffsetSrv.Token = token
+	uploadOffsetSrv.Session = session
+	errValidate = uploadOffsetSrv.Validate()
+	confirm.NotNil(errValidate)
+	confirm.Contains(errValidate.Error(), models.ErrAccessDenied.Error())
+}
+
+func TestUploadOffset_Execute(t *testing.T) {
+	token, trx, down, err := models.NewArbitrarilyTokenForTest(nil, t)
+	assert.Nil(t, err)
+	defer down(t)
+
+	session, err := models.NewUploadSession(&token.App, "/upload/random.bytes", 1024, int8(0), trx)
+	assert.Nil(t, err)
+	uploadOffsetSrv := &UploadOffset{
+		BaseService: BaseService{
+			DB: trx,
+		},
+		Token:   token,
+		Session: session,
+	}
+	assert.Nil(t, uploadOffsetSrv.Validate())
+	uploadOffsetValue, err := uploadOffsetSrv.Execute(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, session.ID, uploadOffsetValue.(*models.UploadSession).ID)
+	assert.Equal(t, 0, uploadOffsetValue.(*models.UploadSession).Offset)
+}
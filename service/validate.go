@@ -39,6 +39,12 @@ var (
 
 	// ErrInvalidFile represent the file is invalid
 	ErrInvalidFile = errors.New("invalid file")
+
+	// ErrInvalidUploadSession represent the upload session is invalid
+	ErrInvalidUploadSession = errors.New("invalid upload session")
+
+	// ErrInvalidHistory represent the history is invalid
+	ErrInvalidHistory = errors.New("invalid history")
 )
 
 // ValidateFile is used to validate whether a file is valid
@@ -49,6 +55,48 @@ func ValidateFile(db *gorm.DB, file *models.File) error {
 	return db.Where("id = ?", file.ID).Find(file).Error
 }
 
+// ValidateTrashedFile is used to validate whether a file is valid and in trash
+func ValidateTrashedFile(db *gorm.DB, file *models.File) error {
+	if file == nil {
+		return ErrInvalidFile
+	}
+	if err := db.Unscoped().Where("id = ?", file.ID).Find(file).Error; err != nil {
+		return err
+	}
+	if !file.InTrash() {
+		return models.ErrFileNotInTrash
+	}
+	return nil
+}
+
+// ValidateUploadSession is used to validate whether an upload session is valid and not expired
+func ValidateUploadSession(db *gorm.DB, session *models.UploadSession) error {
+	if session == nil {
+		return ErrInvalidUploadSession
+	}
+	if err := db.Where("id = ?", session.ID).Find(session).Error; err != nil {
+		return err
+	}
+	if session.Expired() {
+		return models.ErrUploadSessionExpired
+	}
+	return nil
+}
+
+// ValidateHistory is used to validate whether a history is valid and belongs to file
+func ValidateHistory(db *gorm.DB, file *models.File, history *models.History) error {
+	if history == nil || file == nil {
+		return ErrInvalidHistory
+	}
+	if err := db.Preload("Object").Where("id = ?", history.ID).Find(history).Error; err != nil {
+		return err
+	}
+	if history.FileID != file.ID {
+		return models.ErrHistoryNotBelongToFile
+	}
+	return nil
+}
+
 // ValidateApp is used to validate whether app is valid
 func ValidateApp(db *gorm.DB, app *models.App) error {
 	if app == nil {
@@ -89,6 +137,28 @@ func ValidateToken(db *gorm.DB, ip *string, canReadOnly bool, token *models.Toke
 	return nil
 }
 
+// ValidateMetas is used to validate the metas of file, the count of metas is
+// checked again after they are merged with the existing ones
+func ValidateMetas(metas map[string]string) error {
+	if len(metas) > models.MaxMetaCount {
+		return models.ErrTooManyMetas
+	}
+	return models.ValidateMetas(metas)
+}
+
+// ValidateUsedQuota is used to check the quotas after size bytes and files files
+// have been added by token in the transaction db, ErrQuotaExceeded is returned
+// as the error of field, so that it can be told from the other errors.
+func ValidateUsedQuota(db *gorm.DB, token *models.Token, size, files int, field string) error {
+	if err := models.CheckUsedQuota(token, size, files, db); err != nil {
+		if err == models.ErrQuotaExceeded {
+			return ValidateErrors{generateErrorByField(field, err)}
+		}
+		return err
+	}
+	return nil
+}
+
 // ValidatePath is used to validate whether the given path is legal
 func ValidatePath(path string) bool {
 	var (
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -118,3 +119,31 @@ func TestValidateFile(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Nil(t, ValidateFile(trx, file))
 }
+
+func TestValidateUploadSession(t *testing.T) {
+	assert.Equal(t, ValidateUploadSession(nil, nil), ErrInvalidUploadSession)
+
+	app, trx, down, err := models.NewAppForTest(nil, t)
+	assert.Nil(t, err)
+	defer down(t)
+
+	assert.True(t, util.IsRecordNotFound(ValidateUploadSession(trx, &models.UploadSession{ID: 1 << 60})))
+
+	session, err := models.NewUploadSession(app, "/upload/random.bytes", 1024, int8(0), trx)
+	assert.Nil(t, err)
+	assert.Nil(t, ValidateUploadSession(trx, session))
+
+	assert.Nil(t, trx.Model(session).Update("expiredAt", time.Now().Add(-time.Second)).Error)
+	assert.Equal(t, models.ErrUploadSessionExpired, ValidateUploadSession(trx, session))
+}
+
+func TestValidateMetas(t *testing.T) {
+	var metas = make(map[string]string)
+	assert.Nil(t, ValidateMetas(metas))
+	for i := 0; i <= models.MaxMetaCount; i++ {
+		metas[strconv.Itoa(i)] = "value"
+	}
+	assert.Equal(t, models.ErrTooManyMetas, ValidateMetas(metas))
+	assert.Equal(t, models.ErrInvalidMetaName, ValidateMetas(map[string]string{"a_b": "c"}))
+	assert.Equal(t, models.ErrDuplicateMetaName, ValidateMetas(map[string]string{"A": "b", "a": "c"}))
+}